@@ -0,0 +1,39 @@
+package logx
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+
+	"github.com/go4x/logx/audit"
+)
+
+// ErrAuditNotConfigured is returned by Audit when LoggerConfig.Audit is nil.
+var ErrAuditNotConfigured = errors.New("audit log is not configured")
+
+// globalAudit is the audit logger created by the last successful call to Init.
+var globalAudit *audit.Logger
+
+// Audit writes a tamper-evident audit entry with the key-value pairs carried
+// by ctx followed by keysAndValues. Audit entries bypass the level filter,
+// the console and file outputs and the sinks: they are written to the audit
+// log only, and Audit returns once the entry is synced to disk.
+func Audit(ctx context.Context, msg string, keysAndValues ...any) error {
+	if globalAudit == nil {
+		return ErrAuditNotConfigured
+	}
+	return globalAudit.Log(ctx, msg, keysAndValues...)
+}
+
+// newAudit opens the audit log enabled in the configuration, in <Dir>/audit
+// unless its directory is set.
+func newAudit(c *LoggerConfig) (*audit.Logger, error) {
+	if c.Audit == nil {
+		return nil, nil
+	}
+	cfg := *c.Audit
+	if cfg.Dir == "" {
+		cfg.Dir = filepath.Join(c.Dir, "audit")
+	}
+	return audit.New(cfg)
+}
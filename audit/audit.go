@@ -0,0 +1,516 @@
+// Package audit provides a tamper-evident audit log. Every entry is written
+// synchronously as a JSON line carrying a sequence number and a SHA-256 hash
+// chained to the previous entry, and is flushed to disk with fsync before
+// Log returns. Files are sealed with an HMAC-SHA256 signature when they are
+// rotated and when the logger is closed. Verify detects entries that were
+// deleted, inserted or edited, across rotated and compressed files.
+//
+// Example usage:
+//
+//	config := &logx.LoggerConfig{
+//	    // ...
+//	    Audit: &audit.Config{
+//	        Key: os.Getenv("AUDIT_HMAC_KEY"),
+//	    },
+//	}
+//	err := logx.Audit(ctx, "role granted", "user", "alice", "role", "admin")
+//
+//	// later, possibly on another machine
+//	err = audit.Verify("logs/audit", []byte(os.Getenv("AUDIT_HMAC_KEY")))
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+)
+
+const (
+	// activeName is the name of the file being written.
+	activeName = "audit.log"
+	// rotatedPrefix and rotatedExt form the names of the rotated files,
+	// audit-<first sequence number>.log, optionally followed by .gz.
+	rotatedPrefix = "audit-"
+	rotatedExt    = ".log"
+	gzipExt       = ".gz"
+)
+
+// zeroHash is the previous hash of the first entry.
+var zeroHash = strings.Repeat("0", sha256.Size*2)
+
+// Config holds the configuration of the audit log.
+type Config struct {
+	// Dir is the directory of the audit files. When configured through
+	// logx.LoggerConfig it defaults to <Dir>/audit.
+	Dir string `mapstructure:"dir" yaml:"dir"`
+
+	// Key is the HMAC-SHA256 key of the seals. It is required.
+	Key string `mapstructure:"key" yaml:"key"`
+
+	// MaxSize is the size in MB at which the file is sealed and rotated
+	// (default 100).
+	MaxSize int `mapstructure:"max-size" yaml:"max-size"`
+
+	// Compress gzips the rotated files.
+	Compress bool `mapstructure:"compress" yaml:"compress"`
+}
+
+// Logger writes the audit log. It is safe for concurrent use and implements
+// core.Sink, but unlike other sinks it never drops entries: Write returns
+// once the entry is on disk, or with an error.
+type Logger struct {
+	mu      sync.Mutex
+	cfg     Config
+	key     []byte
+	maxSize int64
+
+	f     *os.File
+	size  int64
+	first uint64 // first sequence number of the active file, 0 if empty
+	seq   uint64 // last sequence number
+	hash  string // hash of the last entry
+	// sealed reports whether the last line of the active file is a seal.
+	sealed bool
+	// rotateErr is the error of a rotation that failed after its entry was
+	// written; the rotation is resumed by the next Write.
+	rotateErr error
+}
+
+// New opens the audit log in c.Dir, resuming the hash chain of the existing
+// files.
+func New(c Config) (*Logger, error) {
+	if c.Dir == "" {
+		return nil, errors.New("audit: dir is required")
+	}
+	if c.Key == "" {
+		return nil, errors.New("audit: key is required")
+	}
+	if c.MaxSize <= 0 {
+		c.MaxSize = 100
+	}
+	if err := os.MkdirAll(c.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	l := &Logger{
+		cfg:     c,
+		key:     []byte(c.Key),
+		maxSize: int64(c.MaxSize) << 20,
+		hash:    zeroHash,
+	}
+	if err := l.recover(); err != nil {
+		return nil, err
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Log writes an audit entry with the key-value pairs carried by ctx followed
+// by keysAndValues.
+func (l *Logger) Log(ctx context.Context, msg string, keysAndValues ...any) error {
+	kv := append(append([]any(nil), core.Fields(ctx)...), keysAndValues...)
+	return l.Write(&core.Entry{Time: time.Now(), Level: core.InfoLevel, Message: msg, Fields: fields(kv)})
+}
+
+// fields converts key-value pairs to fields. A key without value is
+// reported under "!BADKEY", as slog does.
+func fields(kv []any) []core.Field {
+	out := make([]core.Field, 0, (len(kv)+1)/2)
+	for i := 0; i < len(kv); i += 2 {
+		if i+1 == len(kv) {
+			out = append(out, core.Field{Key: "!BADKEY", Value: kv[i]})
+			break
+		}
+		out = append(out, core.Field{Key: fmt.Sprint(kv[i]), Value: kv[i+1]})
+	}
+	return out
+}
+
+// record is the chained part of an entry line; the hash of the line is
+// appended after it.
+type record struct {
+	Seq     uint64         `json:"seq"`
+	Time    string         `json:"ts"`
+	Level   string         `json:"level"`
+	Message string         `json:"msg"`
+	Caller  string         `json:"caller,omitempty"`
+	Fields  map[string]any `json:"fields,omitempty"`
+	Prev    string         `json:"prev"`
+}
+
+// Write implements the core.Sink interface. The entry is appended to the
+// chain and synced to disk before Write returns. Once it is, Write succeeds
+// even if the rotation that follows fails, so that a caller retrying on
+// error cannot write the entry twice: the rotation is resumed by the next
+// Write, which fails without writing its entry if it fails again, or its
+// error is returned by Close.
+func (l *Logger) Write(e *core.Entry) error {
+	rec := record{
+		Time:    e.Time.UTC().Format(time.RFC3339Nano),
+		Level:   e.Level.String(),
+		Message: e.Message,
+		Caller:  e.Caller,
+	}
+	if len(e.Fields) > 0 {
+		rec.Fields = make(map[string]any, len(e.Fields))
+		for _, f := range e.Fields {
+			rec.Fields[f.Key] = sink.JSONValue(f.Value)
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rotateErr != nil {
+		if err := l.rotate(); err != nil {
+			l.rotateErr = err
+			return err
+		}
+		l.rotateErr = nil
+	}
+	if l.f == nil {
+		return errors.New("audit: logger is closed")
+	}
+	rec.Seq = l.seq + 1
+	rec.Prev = l.hash
+	body, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	hash := entryHash(body)
+	line := appendHash(body, hash)
+	if err := l.append(line); err != nil {
+		return err
+	}
+	if l.first == 0 {
+		l.first = rec.Seq
+	}
+	l.seq, l.hash, l.sealed = rec.Seq, hash, false
+	if l.size >= l.maxSize {
+		l.rotateErr = l.rotate()
+	}
+	return nil
+}
+
+// Sync implements the core.Sink interface. Entries are synced as they are
+// written, so there is nothing to flush.
+func (l *Logger) Sync() error {
+	return nil
+}
+
+// Close implements the core.Sink interface. The active file is sealed so
+// that its entries can no longer be truncated unnoticed. Close returns the
+// error of a failed rotation that was not resumed.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	err := l.rotateErr
+	l.rotateErr = nil
+	if l.f == nil {
+		return err
+	}
+	if l.first != 0 && !l.sealed {
+		err = errors.Join(err, l.seal())
+	}
+	err = errors.Join(err, l.f.Close())
+	l.f = nil
+	return err
+}
+
+// entryHash returns the hash of the chained part of an entry line.
+func entryHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// appendHash returns the entry line of body, a JSON object, with its hash
+// added as the last member.
+func appendHash(body []byte, hash string) []byte {
+	line := make([]byte, 0, len(body)+len(hash)+12)
+	line = append(line, body[:len(body)-1]...)
+	line = append(line, `,"hash":"`...)
+	line = append(line, hash...)
+	line = append(line, "\"}\n"...)
+	return line
+}
+
+// seal is the signed summary of the entries of a file.
+type seal struct {
+	First uint64 `json:"first"`
+	Last  uint64 `json:"last"`
+	Hash  string `json:"hash"`
+	Time  string `json:"ts"`
+}
+
+// sealLine is the line of a seal; MAC is the HMAC of the raw Seal object.
+type sealLine struct {
+	Seal json.RawMessage `json:"seal"`
+	MAC  string          `json:"mac"`
+}
+
+// seal appends a seal of the entries of the active file.
+func (l *Logger) seal() error {
+	body, err := json.Marshal(seal{First: l.first, Last: l.seq, Hash: l.hash, Time: time.Now().UTC().Format(time.RFC3339Nano)})
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	line, err := json.Marshal(sealLine{Seal: body, MAC: sign(l.key, body)})
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	if err := l.append(append(line, '\n')); err != nil {
+		return err
+	}
+	l.sealed = true
+	return nil
+}
+
+// sign returns the HMAC-SHA256 of data.
+func sign(key, data []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// append writes a line to the active file and syncs it.
+func (l *Logger) append(line []byte) error {
+	if _, err := l.f.Write(line); err != nil {
+		return fmt.Errorf("audit: write: %w", err)
+	}
+	if err := l.f.Sync(); err != nil {
+		return fmt.Errorf("audit: sync: %w", err)
+	}
+	l.size += int64(len(line))
+	return nil
+}
+
+// rotate seals the active file, renames it after its first sequence number,
+// compresses it if configured and opens a new active file. After a failure,
+// calling rotate again resumes at the failed step.
+func (l *Logger) rotate() error {
+	if l.f != nil {
+		if !l.sealed {
+			if err := l.seal(); err != nil {
+				return err
+			}
+		}
+		if err := l.f.Close(); err != nil {
+			return fmt.Errorf("audit: %w", err)
+		}
+		l.f = nil
+	}
+	active := filepath.Join(l.cfg.Dir, activeName)
+	rotated := filepath.Join(l.cfg.Dir, fmt.Sprintf("%s%020d%s", rotatedPrefix, l.first, rotatedExt))
+	if _, err := os.Stat(active); err == nil {
+		if err := os.Rename(active, rotated); err != nil {
+			return fmt.Errorf("audit: %w", err)
+		}
+	}
+	if _, err := os.Stat(rotated); err == nil && l.cfg.Compress {
+		if err := compress(rotated); err != nil {
+			return err
+		}
+	}
+	if err := syncDir(l.cfg.Dir); err != nil {
+		return err
+	}
+	l.first = 0
+	return l.open()
+}
+
+// open opens the active file for appending.
+func (l *Logger) open() error {
+	f, err := os.OpenFile(filepath.Join(l.cfg.Dir, activeName), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("audit: %w", err)
+	}
+	l.f, l.size = f, info.Size()
+	return nil
+}
+
+// compress replaces name with its gzip-compressed copy name.gz.
+func compress(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	defer src.Close()
+	tmp := name + gzipExt + ".tmp"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	err = errors.Join(err, zw.Close())
+	if err == nil {
+		err = dst.Sync()
+	}
+	err = errors.Join(err, dst.Close())
+	if err == nil {
+		err = os.Rename(tmp, name+gzipExt)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("audit: compress: %w", err)
+	}
+	return os.Remove(name)
+}
+
+// syncDir syncs a directory so that renames are durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, os.ErrInvalid) {
+		return fmt.Errorf("audit: sync dir: %w", err)
+	}
+	return nil
+}
+
+// recover restores the state of the chain from the existing files. An
+// interrupted compression is cleaned up and a line torn by a crash at the
+// end of the active file is removed.
+func (l *Logger) recover() error {
+	rotated, err := rotatedFiles(l.cfg.Dir)
+	if err != nil {
+		return err
+	}
+	if len(rotated) > 0 {
+		last := filepath.Join(l.cfg.Dir, rotated[len(rotated)-1])
+		if err := scanFile(last, func(line []byte) error { return l.restore(line, false) }); err != nil {
+			return err
+		}
+	}
+
+	active := filepath.Join(l.cfg.Dir, activeName)
+	data, err := os.ReadFile(active)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	if i := bytes.LastIndexByte(data, '\n'); i+1 < len(data) {
+		if err := os.Truncate(active, int64(i+1)); err != nil {
+			return fmt.Errorf("audit: %w", err)
+		}
+		data = data[:i+1]
+	}
+	l.first = 0
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if err := l.restore(line, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restore updates the state of the chain with one line of a file.
+func (l *Logger) restore(line []byte, active bool) error {
+	if bytes.HasPrefix(line, []byte(`{"seal":`)) {
+		l.sealed = true
+		return nil
+	}
+	var e struct {
+		Seq  uint64 `json:"seq"`
+		Hash string `json:"hash"`
+	}
+	if err := json.Unmarshal(line, &e); err != nil {
+		return fmt.Errorf("audit: invalid entry after sequence number %d: %w", l.seq, err)
+	}
+	if active && l.first == 0 {
+		l.first = e.Seq
+	}
+	l.seq, l.hash, l.sealed = e.Seq, e.Hash, false
+	return nil
+}
+
+// rotatedFiles returns the names of the rotated files of dir in chain order.
+// Leftovers of an interrupted compression are removed.
+func rotatedFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	present := make(map[string]bool)
+	for _, e := range entries {
+		present[e.Name()] = true
+	}
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		switch {
+		case !strings.HasPrefix(name, rotatedPrefix):
+		case strings.HasSuffix(name, gzipExt+".tmp"):
+			_ = os.Remove(filepath.Join(dir, name))
+		case strings.HasSuffix(name, rotatedExt) && present[name+gzipExt]:
+			// compressed, but not yet removed
+			_ = os.Remove(filepath.Join(dir, name))
+		case strings.HasSuffix(name, rotatedExt), strings.HasSuffix(name, rotatedExt+gzipExt):
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// scanFile calls fn with every line of an audit file, decompressing it if
+// needed.
+func scanFile(name string, fn func(line []byte) error) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("audit: %w", err)
+	}
+	defer f.Close()
+	var r io.Reader = f
+	if strings.HasSuffix(name, gzipExt) {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("audit: %s: %w", filepath.Base(name), err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 64<<20)
+	for sc.Scan() {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		if err := fn(sc.Bytes()); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("audit: %s: %w", filepath.Base(name), err)
+	}
+	return nil
+}
@@ -0,0 +1,285 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go4x/logx/audit"
+)
+
+const key = "test-key"
+
+// writeLog writes n entries of about 20KB to dir, rotating every 1MB.
+func writeLog(t *testing.T, dir string, n int, compress bool) {
+	t.Helper()
+	l, err := audit.New(audit.Config{Dir: dir, Key: key, MaxSize: 1, Compress: compress})
+	if err != nil {
+		t.Fatalf("failed to create audit log: %v", err)
+	}
+	payload := strings.Repeat("x", 20<<10)
+	for i := 0; i < n; i++ {
+		if err := l.Log(context.Background(), "record updated", "index", i, "payload", payload); err != nil {
+			t.Fatalf("failed to log: %v", err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+}
+
+// readLines returns the lines of an uncompressed audit file.
+func readLines(t *testing.T, name string) [][]byte {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", name, err)
+	}
+	return bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+}
+
+func writeLines(t *testing.T, name string, lines [][]byte) {
+	t.Helper()
+	if err := os.WriteFile(name, append(bytes.Join(lines, []byte("\n")), '\n'), 0o644); err != nil {
+		t.Fatalf("failed to write %s: %v", name, err)
+	}
+}
+
+// expectProblem verifies dir and checks that a problem containing reason is reported.
+func expectProblem(t *testing.T, dir, reason string) {
+	t.Helper()
+	err := audit.Verify(dir, []byte(key))
+	if err == nil {
+		t.Fatalf("expected %q to be detected", reason)
+	}
+	var p *audit.Problem
+	if !errors.As(err, &p) {
+		t.Fatalf("expected a Problem, got %v", err)
+	}
+	if !strings.Contains(err.Error(), reason) {
+		t.Errorf("expected %q, got %v", reason, err)
+	}
+}
+
+// rotatedFile returns the path of the i-th rotated file.
+func rotatedFile(t *testing.T, dir string, i int) string {
+	t.Helper()
+	files, _ := filepath.Glob(filepath.Join(dir, "audit-*"))
+	if len(files) <= i {
+		t.Fatalf("expected more than %d rotated files, got %v", i, files)
+	}
+	return files[i]
+}
+
+// TestVerifyIntact tests that an untouched log verifies across rotated and compressed files
+func TestVerifyIntact(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		dir := t.TempDir()
+		writeLog(t, dir, 120, compress)
+
+		files, _ := filepath.Glob(filepath.Join(dir, "audit-*"))
+		if len(files) != 2 {
+			t.Fatalf("expected 2 rotated files, got %v", files)
+		}
+		if compress != strings.HasSuffix(files[0], ".gz") {
+			t.Errorf("unexpected rotated file name: %s", files[0])
+		}
+		if err := audit.Verify(dir, []byte(key)); err != nil {
+			t.Errorf("expected an intact log, got %v", err)
+		}
+	}
+}
+
+// TestReopen tests that the chain continues across restarts and torn lines are discarded
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, 3, false)
+
+	// a crash while writing leaves a partial line
+	f, err := os.OpenFile(filepath.Join(dir, "audit.log"), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatalf("failed to open: %v", err)
+	}
+	f.WriteString(`{"seq":4,"ts":"2024`)
+	f.Close()
+
+	writeLog(t, dir, 2, false)
+	if err := audit.Verify(dir, []byte(key)); err != nil {
+		t.Fatalf("expected an intact log, got %v", err)
+	}
+	lines := readLines(t, filepath.Join(dir, "audit.log"))
+	// 3 entries, seal, 2 entries, seal
+	if len(lines) != 7 || !bytes.Contains(lines[5], []byte(`"seq":5`)) {
+		t.Errorf("unexpected content: %s", bytes.Join(lines, []byte("\n")))
+	}
+}
+
+// TestVerifyEdit tests that an edited entry is detected
+func TestVerifyEdit(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, 5, false)
+	name := filepath.Join(dir, "audit.log")
+	lines := readLines(t, name)
+	lines[2] = bytes.Replace(lines[2], []byte("record updated"), []byte("record deleted"), 1)
+	writeLines(t, name, lines)
+	expectProblem(t, dir, "entry 3 was modified")
+}
+
+// TestVerifyDeletion tests that a deleted entry is detected
+func TestVerifyDeletion(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, 5, false)
+	name := filepath.Join(dir, "audit.log")
+	lines := readLines(t, name)
+	writeLines(t, name, append(lines[:1], lines[2:]...))
+	expectProblem(t, dir, "entries 2 to 2 are missing")
+}
+
+// TestVerifyInsertion tests that a replayed entry is detected
+func TestVerifyInsertion(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, 5, false)
+	name := filepath.Join(dir, "audit.log")
+	lines := readLines(t, name)
+	lines = append(lines[:3], append([][]byte{lines[1]}, lines[3:]...)...)
+	writeLines(t, name, lines)
+	expectProblem(t, dir, "entry 2 is out of sequence")
+}
+
+// TestVerifyDeletedFile tests that a deleted rotated file is detected
+func TestVerifyDeletedFile(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, 120, true)
+	if err := os.Remove(rotatedFile(t, dir, 1)); err != nil {
+		t.Fatalf("failed to remove: %v", err)
+	}
+	expectProblem(t, dir, "are missing")
+}
+
+// TestVerifyTruncatedFile tests that removing the end of a rotated file is detected
+func TestVerifyTruncatedFile(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, 120, false)
+	name := rotatedFile(t, dir, 0)
+	lines := readLines(t, name)
+	writeLines(t, name, lines[:len(lines)-3])
+	expectProblem(t, dir, "file is not sealed")
+}
+
+// TestVerifyRecomputedChain tests that a chain rebuilt after tampering fails the seal
+func TestVerifyRecomputedChain(t *testing.T) {
+	dir := t.TempDir()
+	writeLog(t, dir, 3, false)
+	name := filepath.Join(dir, "audit.log")
+	lines := readLines(t, name)
+
+	// rewrite the log without the second entry, with a valid chain
+	other := t.TempDir()
+	l, err := audit.New(audit.Config{Dir: other, Key: "attacker-key"})
+	if err != nil {
+		t.Fatalf("failed to create audit log: %v", err)
+	}
+	l.Log(context.Background(), "record updated", "index", 0)
+	l.Log(context.Background(), "record updated", "index", 2)
+	l.Close()
+	forged := readLines(t, filepath.Join(other, "audit.log"))
+	writeLines(t, name, forged)
+	expectProblem(t, dir, "seal signature is invalid")
+	writeLines(t, name, append(forged[:2], lines[3]))
+	expectProblem(t, dir, "does not match the entries")
+
+	if err := audit.Verify(other, []byte("attacker-key")); err != nil {
+		t.Errorf("the forged log is consistent with its own key: %v", err)
+	}
+}
+
+// TestNewValidation tests configuration validation
+func TestNewValidation(t *testing.T) {
+	if _, err := audit.New(audit.Config{Dir: t.TempDir()}); err == nil {
+		t.Error("expected error for missing key")
+	}
+	if _, err := audit.New(audit.Config{Key: key}); err == nil {
+		t.Error("expected error for missing dir")
+	}
+}
+
+// TestRotateFailure tests that an entry written before a failed rotation is reported as written, and that the rotation is resumed by the next write
+func TestRotateFailure(t *testing.T) {
+	dir := t.TempDir()
+	l, err := audit.New(audit.Config{Dir: dir, Key: key, MaxSize: 1})
+	if err != nil {
+		t.Fatalf("failed to create audit log: %v", err)
+	}
+	// a directory in place of the first rotated file makes the rename fail
+	blocker := filepath.Join(dir, "audit-00000000000000000001.log")
+	if err := os.Mkdir(blocker, 0o755); err != nil {
+		t.Fatalf("failed to create %s: %v", blocker, err)
+	}
+
+	payload := strings.Repeat("x", 1<<20)
+	if err := l.Log(context.Background(), "record updated", "payload", payload); err != nil {
+		t.Fatalf("expected the entry to be written despite the rotation, got %v", err)
+	}
+	if err := l.Log(context.Background(), "record deleted"); err == nil {
+		t.Fatal("expected the failed rotation to be reported by the next write")
+	}
+	if err := os.Remove(blocker); err != nil {
+		t.Fatalf("failed to remove %s: %v", blocker, err)
+	}
+	if err := l.Log(context.Background(), "record deleted"); err != nil {
+		t.Fatalf("expected the rotation to be resumed, got %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if err := audit.Verify(dir, []byte(key)); err != nil {
+		t.Errorf("expected an intact log, got %v", err)
+	}
+	lines := readLines(t, filepath.Join(dir, "audit.log"))
+	// the entry written after the rotation and its seal
+	if len(lines) != 2 || !bytes.Contains(lines[0], []byte(`"seq":2`)) {
+		t.Errorf("unexpected content: %s", bytes.Join(lines, []byte("\n")))
+	}
+}
+
+// TestRotateFailureClose tests that Close reports a failed rotation that was not resumed
+func TestRotateFailureClose(t *testing.T) {
+	dir := t.TempDir()
+	l, err := audit.New(audit.Config{Dir: dir, Key: key, MaxSize: 1})
+	if err != nil {
+		t.Fatalf("failed to create audit log: %v", err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "audit-00000000000000000001.log"), 0o755); err != nil {
+		t.Fatalf("failed to create the blocker: %v", err)
+	}
+	if err := l.Log(context.Background(), "record updated", "payload", strings.Repeat("x", 1<<20)); err != nil {
+		t.Fatalf("expected the entry to be written despite the rotation, got %v", err)
+	}
+	if err := l.Close(); err == nil {
+		t.Error("expected Close to report the failed rotation")
+	}
+}
+
+// TestNonFinite tests that entries with NaN and infinite floats are written
+func TestNonFinite(t *testing.T) {
+	dir := t.TempDir()
+	l, err := audit.New(audit.Config{Dir: dir, Key: key})
+	if err != nil {
+		t.Fatalf("failed to create audit log: %v", err)
+	}
+	if err := l.Log(context.Background(), "rate computed", "rate", math.NaN(), "max", math.Inf(1)); err != nil {
+		t.Fatalf("failed to log: %v", err)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+	if lines := readLines(t, filepath.Join(dir, "audit.log")); !bytes.Contains(lines[0], []byte(`"rate":"NaN"`)) {
+		t.Errorf("unexpected content: %s", lines[0])
+	}
+}
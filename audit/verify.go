@@ -0,0 +1,172 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Problem is an integrity violation found by Verify.
+type Problem struct {
+	// File is the name of the file, relative to the audit directory.
+	File string
+	// Line is the line number in File, or 0 for the whole file.
+	Line int
+	// Reason describes the violation.
+	Reason string
+}
+
+// Error implements the error interface.
+func (p *Problem) Error() string {
+	if p.Line == 0 {
+		return fmt.Sprintf("%s: %s", p.File, p.Reason)
+	}
+	return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Reason)
+}
+
+// Verify checks the integrity of the audit log in dir, including rotated and
+// compressed files, with the HMAC key of the seals. It returns nil if the
+// log is intact, the problems found joined with errors.Join otherwise (see
+// Problem), or another error if the files cannot be read.
+//
+// Edited, inserted, reordered and deleted entries break the hash chain or
+// the sequence numbers; a deleted or truncated rotated file leaves a gap or
+// a missing seal; and recomputing the chain after tampering is detected by
+// the seals, which cannot be forged without the key. Entries written to the
+// active file after its last seal are only protected by the chain, so their
+// truncation is not detected.
+func Verify(dir string, key []byte) error {
+	rotated, err := verifyFiles(dir)
+	if err != nil {
+		return err
+	}
+	v := &verifier{key: key, seq: 1, prev: zeroHash}
+	for _, name := range rotated {
+		if err := v.file(dir, name, true); err != nil {
+			return err
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, activeName)); err == nil {
+		if err := v.file(dir, activeName, false); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("audit: %w", err)
+	}
+	return errors.Join(v.problems...)
+}
+
+// verifyFiles returns the rotated files of dir in chain order, without
+// modifying the directory.
+func verifyFiles(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, rotatedPrefix+"*"))
+	if err != nil {
+		return nil, fmt.Errorf("audit: %w", err)
+	}
+	var names []string
+	for _, m := range matches {
+		name := filepath.Base(m)
+		if filepath.Ext(name) == rotatedExt || filepath.Ext(name) == gzipExt && filepath.Ext(name[:len(name)-len(gzipExt)]) == rotatedExt {
+			names = append(names, name)
+		}
+	}
+	// Glob returns the names sorted, which is the chain order.
+	return names, nil
+}
+
+// verifier holds the state of the chain during verification.
+type verifier struct {
+	key      []byte
+	seq      uint64 // expected sequence number of the next entry
+	prev     string // hash of the previous entry
+	problems []error
+}
+
+func (v *verifier) report(file string, line int, format string, args ...any) {
+	v.problems = append(v.problems, &Problem{File: file, Line: line, Reason: fmt.Sprintf(format, args...)})
+}
+
+// file verifies the entries and seals of one file. Rotated files must end
+// with a seal.
+func (v *verifier) file(dir, name string, rotated bool) error {
+	n := 0
+	first := uint64(0)
+	sealed := false
+	err := scanFile(filepath.Join(dir, name), func(line []byte) error {
+		n++
+		if bytes.HasPrefix(line, []byte(`{"seal":`)) {
+			v.seal(name, n, line, first)
+			sealed = true
+			return nil
+		}
+		sealed = false
+		seq, ok := v.entry(name, n, line)
+		if ok && first == 0 {
+			first = seq
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if rotated && !sealed {
+		v.report(name, 0, "file is not sealed, entries may have been removed from its end")
+	}
+	return nil
+}
+
+// entry verifies an entry line and advances the chain. It returns the
+// sequence number of the entry and whether it could be parsed.
+func (v *verifier) entry(name string, n int, line []byte) (uint64, bool) {
+	var e struct {
+		Seq  uint64 `json:"seq"`
+		Prev string `json:"prev"`
+		Hash string `json:"hash"`
+	}
+	if err := json.Unmarshal(line, &e); err != nil || e.Seq == 0 || len(e.Hash) != len(zeroHash) {
+		v.report(name, n, "invalid entry")
+		return 0, false
+	}
+	suffix := []byte(`,"hash":"` + e.Hash + `"}`)
+	var body []byte
+	if bytes.HasSuffix(line, suffix) {
+		body = make([]byte, 0, len(line)-len(suffix)+1)
+		body = append(append(body, line[:len(line)-len(suffix)]...), '}')
+	}
+	switch {
+	case body == nil:
+		v.report(name, n, "entry %d is malformed", e.Seq)
+	case entryHash(body) != e.Hash:
+		v.report(name, n, "entry %d was modified", e.Seq)
+	case e.Seq > v.seq:
+		v.report(name, n, "entries %d to %d are missing", v.seq, e.Seq-1)
+	case e.Seq < v.seq:
+		v.report(name, n, "entry %d is out of sequence, %d expected (inserted or reordered)", e.Seq, v.seq)
+	case e.Prev != v.prev:
+		v.report(name, n, "entry %d does not follow the previous entry", e.Seq)
+	}
+	// continue from this entry to report further problems independently
+	v.seq, v.prev = e.Seq+1, e.Hash
+	return e.Seq, true
+}
+
+// seal verifies a seal line against the entries before it.
+func (v *verifier) seal(name string, n int, line []byte, first uint64) {
+	var sl sealLine
+	var s seal
+	if err := json.Unmarshal(line, &sl); err != nil || json.Unmarshal(sl.Seal, &s) != nil {
+		v.report(name, n, "invalid seal")
+		return
+	}
+	if !hmac.Equal([]byte(sign(v.key, sl.Seal)), []byte(sl.MAC)) {
+		v.report(name, n, "seal signature is invalid")
+		return
+	}
+	if s.First != first || s.Last != v.seq-1 || s.Hash != v.prev {
+		v.report(name, n, "seal of entries %d to %d does not match the entries of the file", s.First, s.Last)
+	}
+}
@@ -0,0 +1,67 @@
+package logx_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go4x/logx"
+	"github.com/go4x/logx/audit"
+)
+
+// TestAudit tests that audit entries are written to the audit log only and can be verified
+func TestAudit(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			config := &logx.LoggerConfig{
+				Type:   typ,
+				Level:  "error",
+				Dir:    logDir,
+				Format: "json",
+				Audit:  &audit.Config{Key: "secret"},
+			}
+			if err := logx.Init(config); err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			ctx := logx.NewContext(context.Background(), "actor", "alice")
+			if err := logx.Audit(ctx, "role granted", "role", "admin"); err != nil {
+				t.Fatalf("failed to write audit entry: %v", err)
+			}
+			// reinitializing seals the audit log and resumes its chain
+			if err := logx.Init(config); err != nil {
+				t.Fatalf("failed to reinitialize logger: %v", err)
+			}
+			if err := logx.Audit(ctx, "role revoked", "role", "admin"); err != nil {
+				t.Fatalf("failed to write audit entry: %v", err)
+			}
+
+			auditDir := filepath.Join(logDir, "audit")
+			if err := audit.Verify(auditDir, []byte("secret")); err != nil {
+				t.Errorf("expected an intact audit log, got %v", err)
+			}
+			content := readLogs(t, auditDir)
+			for _, want := range []string{`"seq":2`, `"msg":"role revoked"`, `"actor":"alice"`} {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %s in the audit log, got %q", want, content)
+				}
+			}
+			if strings.Contains(readLogs(t, logDir), "role granted") {
+				t.Error("audit entries should not be written to the regular log")
+			}
+		})
+	}
+}
+
+// TestAuditNotConfigured tests that Audit fails without an audit configuration
+func TestAuditNotConfigured(t *testing.T) {
+	if err := logx.Init(&logx.LoggerConfig{Dir: t.TempDir()}); err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+	if err := logx.Audit(context.Background(), "ignored"); !errors.Is(err, logx.ErrAuditNotConfigured) {
+		t.Errorf("expected ErrAuditNotConfigured, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package logx_test
 
 import (
+	"context"
 	"os"
 	"testing"
 
@@ -187,3 +188,120 @@ func BenchmarkMixedArguments(b *testing.B) {
 		}
 	})
 }
+
+// BenchmarkDisabledLevel benchmarks the entries below the configured level
+func BenchmarkDisabledLevel(b *testing.B) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		b.Run(string(typ), func(b *testing.B) {
+			logDir := "benchmark_logs_disabled"
+			defer os.RemoveAll(logDir)
+
+			cfg := &logx.LoggerConfig{
+				Type:         typ,
+				Level:        "info",
+				LogInConsole: false,
+				Dir:          logDir,
+				Format:       "json",
+			}
+
+			err := logx.Init(cfg)
+			if err != nil {
+				b.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				logx.Debugf("debug message %d", i)
+			}
+		})
+	}
+}
+
+// BenchmarkDisabledVerbose benchmarks the verbosity levels above the configured verbosity
+func BenchmarkDisabledVerbose(b *testing.B) {
+	logDir := "benchmark_logs_verbose"
+	defer os.RemoveAll(logDir)
+
+	cfg := &logx.LoggerConfig{
+		Type:         logx.LoggerTypeZap,
+		Level:        "info",
+		LogInConsole: false,
+		Dir:          logDir,
+		Format:       "json",
+	}
+
+	err := logx.Init(cfg)
+	if err != nil {
+		b.Fatalf("failed to initialize logger: %v", err)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logx.V(2).Infof("verbose message %d", i)
+	}
+}
+
+// BenchmarkDisabledContextLogger benchmarks the entries below the configured level logged through FromContext
+func BenchmarkDisabledContextLogger(b *testing.B) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		b.Run(string(typ), func(b *testing.B) {
+			logDir := "benchmark_logs_disabled_context"
+			defer os.RemoveAll(logDir)
+
+			cfg := &logx.LoggerConfig{
+				Type:         typ,
+				Level:        "info",
+				LogInConsole: false,
+				Dir:          logDir,
+				Format:       "json",
+			}
+
+			err := logx.Init(cfg)
+			if err != nil {
+				b.Fatalf("failed to initialize logger: %v", err)
+			}
+			logger := logx.FromContext(logx.NewContext(context.Background(), "request_id", "abc"))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				logger.Debugf("debug message %s", "text")
+				logger.Trace("trace message", "text")
+			}
+		})
+	}
+}
+
+// BenchmarkDisabledLoggerMethods benchmarks the Trace, Notice and Critical methods of the backends below the configured level
+func BenchmarkDisabledLoggerMethods(b *testing.B) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		b.Run(string(typ), func(b *testing.B) {
+			logDir := "benchmark_logs_disabled_methods"
+			defer os.RemoveAll(logDir)
+
+			cfg := &logx.LoggerConfig{
+				Type:         typ,
+				Level:        "panic",
+				LogInConsole: false,
+				Dir:          logDir,
+				Format:       "json",
+			}
+
+			err := logx.Init(cfg)
+			if err != nil {
+				b.Fatalf("failed to initialize logger: %v", err)
+			}
+			logger := logx.GetLogger()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				logger.Tracef("trace message %s", "text")
+				logger.Notice("notice message", "text")
+				logger.Criticalf("critical message %s", "text")
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package logx
+
+import (
+	"context"
+
+	"github.com/go4x/logx/core"
+)
+
+// WithCallerSkip returns a Logger that writes through the global logger and
+// reports the call site skip frames above its callers, for the helper
+// functions logging on behalf of their callers. With skip 0, it reports its
+// callers as the package-level functions do. Its Log method adds the fields
+// carried by the given context.
+//
+// Example:
+//
+//	func logQuery(query string, d time.Duration) {
+//	    // reported at the call site of logQuery
+//	    logx.WithCallerSkip(1).Infof("query %q took %v", query, d)
+//	}
+func WithCallerSkip(skip int) Logger {
+	return &contextLogger{ctx: context.Background(), logger: globalLogger, skip: skip}
+}
+
+// logCaller logs to l with the call site skip frames above the caller of
+// logCaller, and with the level of the module of the call site if it has
+// one (see LoggerConfig.ModuleLevels) in place of the configured level. The
+// level carried by ctx, if lower, still applies. The message is formatted
+// as by message, once the entry is known to be written: without module
+// levels, the disabled entries are dropped before walking the stack.
+func logCaller(l Logger, skip int, ctx context.Context, level Level, template string, args []any, keysAndValues ...any) {
+	if globalModules.Empty() {
+		// no module levels: neither the stack walk nor the lookup is needed
+		// to drop the disabled entries
+		if enabled(l, ctx, level) {
+			logAt(l, core.CallerPC(skip+1), ctx, level, message(template, args), keysAndValues...)
+		}
+		return
+	}
+	pc := core.CallerPC(skip + 1)
+	if min, ok := globalModules.Lookup(pc); ok {
+		if cl, ok := core.LevelFrom(ctx); ok && cl < min {
+			min = cl
+		}
+		if level < min {
+			return
+		}
+		// the backends write the entries at or above the level carried by
+		// the context, even below the configured level
+		ctx = core.WithLevel(ctx, min)
+	} else if !enabled(l, ctx, level) {
+		return
+	}
+	logAt(l, pc, ctx, level, message(template, args), keysAndValues...)
+}
+
+// enabled reports whether l writes the entries at level logged with ctx.
+// The entries at PanicLevel and above are always logged, since they panic
+// or exit even if they are not written.
+func enabled(l Logger, ctx context.Context, level Level) bool {
+	if level >= PanicLevel {
+		return true
+	}
+	if cl, ok := l.(core.CallerLogger); ok {
+		return cl.LevelEnabled(ctx, level)
+	}
+	return true
+}
+
+// logAt logs to l with the call site at pc, if the backend of l supports
+// it.
+func logAt(l Logger, pc uintptr, ctx context.Context, level Level, msg string, keysAndValues ...any) {
+	if cl, ok := l.(core.CallerLogger); ok {
+		cl.LogCaller(ctx, pc, level, msg, keysAndValues...)
+		return
+	}
+	l.Log(ctx, level, msg, keysAndValues...)
+}
@@ -0,0 +1,129 @@
+package logx_test
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/go4x/logx"
+	"github.com/go4x/logx/encoder"
+)
+
+// sites returns the file of the caller and the lines of the call sites of
+// the file marked with a trailing comment made of marker, a colon and the
+// message, by message.
+func sites(t *testing.T, marker string) (string, map[string]int) {
+	t.Helper()
+	_, file, _, _ := runtime.Caller(1)
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("failed to open the test file: %v", err)
+	}
+	defer f.Close()
+	re := regexp.MustCompile(`^\s*[^/\s].* // ` + marker + `: (.+)$`)
+	lines := make(map[string]int)
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		if m := re.FindStringSubmatch(s.Text()); m != nil {
+			lines[m[1]] = n
+		}
+	}
+	return file, lines
+}
+
+// logHelper logs on behalf of its caller
+func logHelper(msg string) {
+	logx.WithCallerSkip(1).Info(msg)
+}
+
+// TestCaller tests that both backends report the call site of the wrappers, the helpers and the recovered panics, and start the stack traces there
+func TestCaller(t *testing.T) {
+	file, lines := sites(t, "site")
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:            typ,
+				Level:           "info",
+				Dir:             logDir,
+				Format:          "json",
+				StacktraceLevel: "error",
+				Encoder:         &encoder.Config{CallerFormat: encoder.CallerFull},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+			ctx := context.Background()
+
+			logx.Info("pkg info")                                         // site: pkg info
+			logx.Noticef("pkg %s", "noticef")                             // site: pkg noticef
+			logx.Log(ctx, logx.InfoLevel, "pkg log")                      // site: pkg log
+			logx.Error("pkg error")                                       // site: pkg error
+			logx.FromContext(ctx).Warn("context warn")                    // site: context warn
+			logx.FromContext(ctx).Log(ctx, logx.WarnLevel, "context log") // site: context log
+			logx.GetLogger().Info("backend info")                         // site: backend info
+			logx.GetLogger().Critical("backend critical")                 // site: backend critical
+			logx.GetLogger().Log(ctx, logx.InfoLevel, "backend log")      // site: backend log
+			logx.V(0).Info("verbose")                                     // site: verbose
+			logx.EveryN(1).Info("every")                                  // site: every
+			logx.WithCallerSkip(0).Info("no skip")                        // site: no skip
+			logHelper("helper")                                           // site: helper
+
+			span := logx.Start(ctx, "span")
+			span.End(nil) // site: span
+
+			func() {
+				defer logx.Recover(ctx, logx.RecoverOptions{Policy: logx.Continue, Message: "recovered"})
+				var m map[string]int
+				m["boom"]++ // site: recovered
+			}()
+
+			if err := logx.SetModuleLevels(map[string]string{"*/caller_test.go": "debug"}); err != nil {
+				t.Fatalf("failed to set the module levels: %v", err)
+			}
+			logx.Debug("module debug") // site: module debug
+			_ = logx.SetModuleLevels(nil)
+
+			content := readLogs(t, logDir)
+			for msg, line := range lines {
+				want := fmt.Sprintf("%s:%d", file, line)
+				if got := findEntry(t, content, msg)["caller"]; got != want {
+					t.Errorf("%s: expected the caller %s, got %v", msg, want, got)
+				}
+			}
+
+			stack, _ := findEntry(t, content, "pkg error")["stacktrace"].(string)
+			frames := strings.Split(stack, "\n")
+			if len(frames) < 2 || frames[1] != fmt.Sprintf("\t%s:%d", file, lines["pkg error"]) {
+				t.Errorf("expected the stack trace to start at the call site, got %q", stack)
+			}
+		})
+	}
+}
+
+// TestSlogSource tests that the slog backend adds the source of the call site by default
+func TestSlogSource(t *testing.T) {
+	file, lines := sites(t, "source")
+	logDir := t.TempDir()
+	err := logx.Init(&logx.LoggerConfig{
+		Type:   logx.LoggerTypeSlog,
+		Level:  "info",
+		Dir:    logDir,
+		Format: "json",
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+
+	logx.Info("slog source") // source: slog source
+
+	source, _ := findEntry(t, readLogs(t, logDir), "slog source")["source"].(map[string]any)
+	if source["file"] != file || source["line"] != float64(lines["slog source"]) {
+		t.Errorf("expected the source %s:%d, got %v", file, lines["slog source"], source)
+	}
+}
@@ -0,0 +1,189 @@
+package logx
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/flight"
+)
+
+// NewContext returns a copy of ctx that carries the given key-value pairs.
+// Every entry logged through Log or a logger returned by FromContext with the
+// returned context includes these pairs, so request-scoped fields only need to
+// be attached once.
+//
+// Example:
+//
+//	ctx = logx.NewContext(ctx, "request_id", id)
+//	logx.FromContext(ctx).Info("handling request")
+func NewContext(ctx context.Context, keysAndValues ...any) context.Context {
+	return core.WithFields(ctx, keysAndValues...)
+}
+
+// DebugHeader is the request header that enables debug logging for a
+// request in DebugMiddleware.
+const DebugHeader = "X-Debug-Log"
+
+// WithLevel returns a copy of ctx that lowers the minimum level of the
+// entries logged through Log or a logger returned by FromContext with the
+// returned context, so that a single request can be logged at debug level
+// while the configured level is higher. It does not raise the configured
+// level.
+//
+// Example:
+//
+//	ctx = logx.WithLevel(ctx, logx.DebugLevel)
+//	logx.Log(ctx, logx.DebugLevel, "payload", "body", body) // written at level debug with the level set to info
+func WithLevel(ctx context.Context, level Level) context.Context {
+	return core.WithLevel(ctx, level)
+}
+
+// DebugMiddleware enables debug logging for the requests with the
+// DebugHeader header set to 1 or true, by lowering the level of the request
+// context. Requests can then increase the volume of the logs at will, so
+// the header should be stripped from untrusted requests upstream.
+func DebugMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get(DebugHeader) {
+		case "1", "true":
+			r = r.WithContext(WithLevel(r.Context(), DebugLevel))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// WithFlightRecorder returns a copy of ctx that carries a new flight
+// recorder buffer. With LoggerConfig.FlightRecorder set, the entries below
+// the logger level that are logged with the returned context are kept in
+// this buffer, and written when an error is logged with it.
+//
+// Example:
+//
+//	ctx = logx.WithFlightRecorder(r.Context())
+//	logx.Log(ctx, logx.DebugLevel, "cache miss", "key", key)
+func WithFlightRecorder(ctx context.Context) context.Context {
+	return flight.NewContext(ctx)
+}
+
+// FromContext returns a Logger that writes through the global logger and adds
+// the fields carried by ctx to each entry.
+func FromContext(ctx context.Context) Logger {
+	return &contextLogger{ctx: ctx, logger: globalLogger}
+}
+
+// Log logs a message at the given level using the global logger, adding the
+// fields carried by ctx. If the global logger is not initialized, this
+// function does nothing.
+func Log(ctx context.Context, level Level, msg string, keysAndValues ...any) {
+	if globalLogger != nil {
+		logCaller(globalLogger, 0, ctx, level, msg, nil, keysAndValues...)
+	}
+}
+
+// contextLogger binds a context to a Logger so that the plain Logger methods
+// carry the context's fields.
+type contextLogger struct {
+	ctx    context.Context
+	logger Logger
+	// skip is the number of frames above the callers of the logger to
+	// report as the call site (see WithCallerSkip)
+	skip int
+}
+
+// log formats the message with template and args only if the entry is
+// enabled, as the global functions do.
+func (l *contextLogger) log(level Level, template string, args []any) {
+	if l.logger != nil {
+		logCaller(l.logger, l.skip+1, l.ctx, level, template, args)
+	}
+}
+
+// Trace implements the Trace method of the Logger interface.
+func (l *contextLogger) Trace(args ...any) { l.log(TraceLevel, "", args) }
+
+// Tracef implements the Tracef method of the Logger interface.
+func (l *contextLogger) Tracef(template string, args ...any) {
+	l.log(TraceLevel, template, args)
+}
+
+// Debug implements the Debug method of the Logger interface.
+func (l *contextLogger) Debug(args ...any) { l.log(DebugLevel, "", args) }
+
+// Debugf implements the Debugf method of the Logger interface.
+func (l *contextLogger) Debugf(template string, args ...any) {
+	l.log(DebugLevel, template, args)
+}
+
+// Info implements the Info method of the Logger interface.
+func (l *contextLogger) Info(args ...any) { l.log(InfoLevel, "", args) }
+
+// Infof implements the Infof method of the Logger interface.
+func (l *contextLogger) Infof(template string, args ...any) {
+	l.log(InfoLevel, template, args)
+}
+
+// Notice implements the Notice method of the Logger interface.
+func (l *contextLogger) Notice(args ...any) { l.log(NoticeLevel, "", args) }
+
+// Noticef implements the Noticef method of the Logger interface.
+func (l *contextLogger) Noticef(template string, args ...any) {
+	l.log(NoticeLevel, template, args)
+}
+
+// Warn implements the Warn method of the Logger interface.
+func (l *contextLogger) Warn(args ...any) { l.log(WarnLevel, "", args) }
+
+// Warnf implements the Warnf method of the Logger interface.
+func (l *contextLogger) Warnf(template string, args ...any) {
+	l.log(WarnLevel, template, args)
+}
+
+// Error implements the Error method of the Logger interface.
+func (l *contextLogger) Error(args ...any) { l.log(ErrorLevel, "", args) }
+
+// Errorf implements the Errorf method of the Logger interface.
+func (l *contextLogger) Errorf(template string, args ...any) {
+	l.log(ErrorLevel, template, args)
+}
+
+// Critical implements the Critical method of the Logger interface.
+func (l *contextLogger) Critical(args ...any) { l.log(CriticalLevel, "", args) }
+
+// Criticalf implements the Criticalf method of the Logger interface.
+func (l *contextLogger) Criticalf(template string, args ...any) {
+	l.log(CriticalLevel, template, args)
+}
+
+// Panic implements the Panic method of the Logger interface.
+func (l *contextLogger) Panic(args ...any) { l.log(PanicLevel, "", args) }
+
+// Panicf implements the Panicf method of the Logger interface.
+func (l *contextLogger) Panicf(template string, args ...any) {
+	l.log(PanicLevel, template, args)
+}
+
+// Fatal implements the Fatal method of the Logger interface.
+func (l *contextLogger) Fatal(args ...any) { l.log(FatalLevel, "", args) }
+
+// Fatalf implements the Fatalf method of the Logger interface.
+func (l *contextLogger) Fatalf(template string, args ...any) {
+	l.log(FatalLevel, template, args)
+}
+
+// Log implements the Log method of the Logger interface. The fields of ctx
+// are added after the fields of the context the logger was created with, and
+// the level of ctx, if any, replaces the level of that context.
+func (l *contextLogger) Log(ctx context.Context, level Level, msg string, keysAndValues ...any) {
+	if l.logger == nil {
+		return
+	}
+	merged := l.ctx
+	if ctx != nil && ctx != l.ctx {
+		merged = core.WithFields(l.ctx, core.Fields(ctx)...)
+		if min, ok := core.LevelFrom(ctx); ok {
+			merged = core.WithLevel(merged, min)
+		}
+	}
+	logCaller(l.logger, l.skip, merged, level, msg, nil, keysAndValues...)
+}
@@ -0,0 +1,151 @@
+package logx_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go4x/logx"
+	"github.com/go4x/logx/trace"
+)
+
+// readLogs returns the concatenated content of all log files in dir.
+func readLogs(t *testing.T, dir string) string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.log"))
+	if err != nil {
+		t.Fatalf("failed to list log files: %v", err)
+	}
+	var sb strings.Builder
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatalf("failed to read log file: %v", err)
+		}
+		sb.Write(data)
+	}
+	return sb.String()
+}
+
+// TestContextFields tests that fields attached with NewContext are added to context-aware log calls
+func TestContextFields(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "info",
+				Dir:    logDir,
+				Format: "json",
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			ctx := logx.NewContext(context.Background(), "request_id", "req-1")
+			ctx = logx.NewContext(ctx, "user", "alice")
+			logx.FromContext(ctx).Infof("handled %d items", 3)
+			logx.Log(ctx, logx.WarnLevel, "slow request", "elapsed_ms", 1200)
+			logx.FromContext(ctx).Debug("should not appear")
+
+			content := readLogs(t, logDir)
+			for _, want := range []string{`"handled 3 items"`, `"slow request"`, `"request_id":"req-1"`, `"user":"alice"`, `"elapsed_ms":1200`} {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %s in output, got %q", want, content)
+				}
+			}
+			if strings.Contains(content, "should not appear") {
+				t.Errorf("debug entry should be filtered, got %q", content)
+			}
+		})
+	}
+}
+
+// TestTraceCorrelation tests that trace ids carried by the context are added to entries
+func TestTraceCorrelation(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "info",
+				Dir:    logDir,
+				Format: "json",
+				Trace:  trace.Config{TraceIDKey: "dd.trace_id", SpanIDKey: "dd.span_id"},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			ctx := trace.ContextWithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+			logx.FromContext(ctx).Info("traced")
+			logx.Info("untraced")
+
+			content := readLogs(t, logDir)
+			for _, want := range []string{`"dd.trace_id":"4bf92f3577b34da6a3ce929d0e0e4736"`, `"dd.span_id":"00f067aa0ba902b7"`, `"trace_flags":"01"`} {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %s in output, got %q", want, content)
+				}
+			}
+			if strings.Count(content, "dd.trace_id") != 1 {
+				t.Errorf("expected only the traced entry to carry a trace id, got %q", content)
+			}
+		})
+	}
+}
+
+// TestParseLevel tests level name parsing
+func TestParseLevel(t *testing.T) {
+	testCases := map[string]logx.Level{
+		"debug": logx.DebugLevel,
+		"INFO":  logx.InfoLevel,
+		"warn":  logx.WarnLevel,
+		"error": logx.ErrorLevel,
+		"fatal": logx.FatalLevel,
+
+		"trace":    logx.TraceLevel,
+		"Notice":   logx.NoticeLevel,
+		"critical": logx.CriticalLevel,
+		"panic":    logx.PanicLevel,
+		"warning":  logx.WarnLevel,
+		"debug-1":  logx.DebugLevel - 1,
+		"error+1":  logx.ErrorLevel + 1,
+	}
+	for name, want := range testCases {
+		got, err := logx.ParseLevel(name)
+		if err != nil || got != want {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := logx.ParseLevel("verbose"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+	if _, err := logx.ParseLevel("debug+x"); err == nil {
+		t.Error("expected error for an invalid offset")
+	}
+}
+
+// TestLevelString tests that level names round-trip through ParseLevel
+func TestLevelString(t *testing.T) {
+	testCases := map[logx.Level]string{
+		logx.TraceLevel:        "trace",
+		logx.NoticeLevel:       "notice",
+		logx.CriticalLevel:     "critical",
+		logx.DebugLevel - 1:    "debug-1",
+		logx.InfoLevel + 1:     "notice-1",
+		logx.FatalLevel + 4:    "fatal+4",
+		logx.TraceLevel - 2:    "trace-2",
+		logx.PanicLevel:        "panic",
+		logx.CriticalLevel + 1: "panic-1",
+	}
+	for level, want := range testCases {
+		if got := level.String(); got != want {
+			t.Errorf("Level(%d).String() = %q, want %q", int(level), got, want)
+		}
+		if got, err := logx.ParseLevel(want); err != nil || got != level {
+			t.Errorf("ParseLevel(%q) = %v, %v; want %d", want, got, err, int(level))
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// maxStackDepth bounds the number of frames walked by Stacktrace.
+const maxStackDepth = 64
+
+// CallerLogger is implemented by the loggers of the backends, so that the
+// wrappers of logx report the call site of their callers rather than their
+// own.
+type CallerLogger interface {
+	// LogCaller logs as Log does, with the call site at pc, a return
+	// address as reported by runtime.Callers (0 if unknown).
+	LogCaller(ctx context.Context, pc uintptr, level Level, msg string, keysAndValues ...any)
+	// LevelEnabled reports whether LogCaller writes the entries at level
+	// logged with ctx, so that the wrappers of logx can skip building the
+	// others.
+	LevelEnabled(ctx context.Context, level Level) bool
+}
+
+// CallerPC returns the return address of the call site skip frames above
+// the caller of the function calling CallerPC: with skip 0, the call site
+// of that function.
+func CallerPC(skip int) uintptr {
+	var pcs [1]uintptr
+	if runtime.Callers(skip+3, pcs[:]) == 0 {
+		return 0
+	}
+	return pcs[0]
+}
+
+// CallerFrame returns the frame of the call site at pc, and whether pc is
+// known.
+func CallerFrame(pc uintptr) (runtime.Frame, bool) {
+	if pc == 0 {
+		return runtime.Frame{}, false
+	}
+	f, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return f, f.File != ""
+}
+
+// TrimmedCaller returns the call site at file:line as the last directory
+// and the name of file, with the line, as zapcore.EntryCaller.TrimmedPath
+// does.
+func TrimmedCaller(file string, line int) string {
+	return TrimmedPath(file) + ":" + strconv.Itoa(line)
+}
+
+// TrimmedPath returns the last directory and the name of file.
+func TrimmedPath(file string) string {
+	i := strings.LastIndexByte(file, '/')
+	if i < 0 {
+		return file
+	}
+	if j := strings.LastIndexByte(file[:i], '/'); j >= 0 {
+		return file[j+1:]
+	}
+	return file
+}
+
+// Stacktrace returns the stack trace of the calling goroutine starting at
+// the call site at pc, formatted as the stack traces of zap, or "" if the
+// call site is not on the stack.
+func Stacktrace(pc uintptr) string {
+	site, ok := CallerFrame(pc)
+	if !ok {
+		return ""
+	}
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	var b strings.Builder
+	found := false
+	for {
+		f, more := frames.Next()
+		if f.Function == "runtime.goexit" {
+			// zap leaves out the frame starting the goroutines
+			break
+		}
+		if !found {
+			// the frames are compared rather than the program counters,
+			// which differ for the inlined calls
+			found = f.Function == site.Function && f.File == site.File && f.Line == site.Line
+		}
+		if found {
+			if b.Len() > 0 {
+				b.WriteByte('\n')
+			}
+			b.WriteString(f.Function)
+			b.WriteString("\n\t")
+			b.WriteString(f.File)
+			b.WriteByte(':')
+			b.WriteString(strconv.Itoa(f.Line))
+		}
+		if !more {
+			break
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,53 @@
+package core
+
+import "context"
+
+type fieldsKey struct{}
+
+// WithFields returns a copy of ctx that carries the given key-value pairs in
+// addition to any pairs already carried by ctx. Backends add these pairs to
+// every entry logged with the returned context.
+func WithFields(ctx context.Context, keysAndValues ...any) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if len(keysAndValues) == 0 {
+		return ctx
+	}
+	parent := Fields(ctx)
+	fields := make([]any, 0, len(parent)+len(keysAndValues))
+	fields = append(fields, parent...)
+	fields = append(fields, keysAndValues...)
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+// Fields returns the key-value pairs carried by ctx. The returned slice must
+// not be modified.
+func Fields(ctx context.Context) []any {
+	if ctx == nil {
+		return nil
+	}
+	fields, _ := ctx.Value(fieldsKey{}).([]any)
+	return fields
+}
+
+type levelKey struct{}
+
+// WithLevel returns a copy of ctx that lowers the minimum level of the
+// entries logged with it to level. Backends write these entries even when
+// the configured level is higher.
+func WithLevel(ctx context.Context, level Level) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, levelKey{}, level)
+}
+
+// LevelFrom returns the minimum level carried by ctx, if any.
+func LevelFrom(ctx context.Context) (Level, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	level, ok := ctx.Value(levelKey{}).(Level)
+	return level, ok
+}
@@ -0,0 +1,37 @@
+package core
+
+import "time"
+
+// Field is a key-value pair attached to an Entry. Values are plain Go values:
+// strings, bools, integers, floats, time.Time, time.Duration, []any for
+// arrays and map[string]any for nested objects.
+type Field struct {
+	Key   string
+	Value any
+}
+
+// Entry is a backend-neutral log entry. Both backends convert their native
+// entries to Entry before handing them to a Sink.
+type Entry struct {
+	// Time is when the entry was logged.
+	Time time.Time
+	// Level is the level the entry was logged at.
+	Level Level
+	// Message is the log message.
+	Message string
+	// Caller is the file:line of the call site, or empty if unknown.
+	Caller string
+	// Fields are the structured fields of the entry in logging order.
+	Fields []Field
+}
+
+// Sink receives entries from a logger. Implementations must be safe for
+// concurrent use. A sink owns the entries passed to Write.
+type Sink interface {
+	// Write delivers an entry to the sink.
+	Write(e *Entry) error
+	// Sync flushes any buffered entries.
+	Sync() error
+	// Close flushes buffered entries and releases the sink's resources.
+	Close() error
+}
@@ -0,0 +1,134 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+const (
+	// maxErrorDepth bounds the depth of the error trees, which protects
+	// against cyclic chains.
+	maxErrorDepth = 16
+	// maxErrorNodes bounds the number of errors of a tree.
+	maxErrorNodes = 64
+)
+
+// ErrorChain is a field value that logs an error with the chain of the
+// errors it wraps (see errors.Unwrap and errors.Join), their types and the
+// stack traces they carry, as a nested object. It is an ObjectMarshaler, so
+// the object is only built when the entry is written.
+type ErrorChain struct {
+	Err error
+}
+
+// ErrorInfo describes an error of an ErrorChain.
+type ErrorInfo struct {
+	// Message is the message of the error.
+	Message string
+	// Type is the Go type of the error, such as "*fs.PathError".
+	Type string
+	// Stack is the stack trace carried by the error, if it has a
+	// StackTrace method as the errors of github.com/pkg/errors do.
+	Stack []string
+	// Causes are the errors wrapped by the error: one for errors.Unwrap,
+	// several for errors.Join.
+	Causes []ErrorInfo
+}
+
+// Info returns the tree of the errors of c.
+func (c ErrorChain) Info() ErrorInfo {
+	nodes := 0
+	return errorInfo(c.Err, 0, &nodes)
+}
+
+func errorInfo(err error, depth int, nodes *int) ErrorInfo {
+	*nodes++
+	info := ErrorInfo{Message: err.Error(), Type: fmt.Sprintf("%T", err), Stack: errorStack(err)}
+	if depth >= maxErrorDepth {
+		return info
+	}
+	var causes []error
+	switch u := err.(type) {
+	case interface{ Unwrap() []error }:
+		causes = u.Unwrap()
+	default:
+		if cause := errors.Unwrap(err); cause != nil {
+			causes = []error{cause}
+		}
+	}
+	for _, cause := range causes {
+		if cause == nil || *nodes >= maxErrorNodes {
+			continue
+		}
+		info.Causes = append(info.Causes, errorInfo(cause, depth+1, nodes))
+	}
+	return info
+}
+
+// MarshalLogObject implements the ObjectMarshaler interface.
+func (c ErrorChain) MarshalLogObject(enc ObjectEncoder) error {
+	if c.Err == nil {
+		return nil
+	}
+	return c.Info().MarshalLogObject(enc)
+}
+
+// MarshalLogObject implements the ObjectMarshaler interface.
+func (e ErrorInfo) MarshalLogObject(enc ObjectEncoder) error {
+	enc.AddString("message", e.Message)
+	enc.AddString("type", e.Type)
+	if len(e.Stack) > 0 {
+		enc.AddArray("stack", ArrayMarshalerFunc(func(ae ArrayEncoder) error {
+			for _, frame := range e.Stack {
+				ae.AppendString(frame)
+			}
+			return nil
+		}))
+	}
+	if len(e.Causes) > 0 {
+		enc.AddArray("causes", ArrayMarshalerFunc(func(ae ArrayEncoder) error {
+			for _, cause := range e.Causes {
+				ae.AppendObject(cause)
+			}
+			return nil
+		}))
+	}
+	return nil
+}
+
+// errorStack returns the stack trace of err if it has a StackTrace method
+// returning program counters, such as the errors of github.com/pkg/errors.
+func errorStack(err error) []string {
+	m := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !m.IsValid() || m.Type().NumIn() != 0 || m.Type().NumOut() != 1 {
+		return nil
+	}
+	st := m.Call(nil)[0]
+	if st.Kind() != reflect.Slice || st.Type().Elem().Kind() != reflect.Uintptr {
+		return nil
+	}
+	pcs := make([]uintptr, st.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(st.Index(i).Uint())
+	}
+	return FormatFrames(pcs)
+}
+
+// FormatFrames returns the frames of the return program counters pcs, as
+// reported by runtime.Callers, formatted as "function (file:line)".
+func FormatFrames(pcs []uintptr) []string {
+	if len(pcs) == 0 {
+		return nil
+	}
+	frames := runtime.CallersFrames(pcs)
+	out := make([]string, 0, len(pcs))
+	for {
+		f, more := frames.Next()
+		out = append(out, fmt.Sprintf("%s (%s:%d)", f.Function, f.File, f.Line))
+		if !more {
+			return out
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package core defines the backend-neutral types shared by logx and its
+// backends, such as log levels and context-carried fields.
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Level is a logging priority. Higher levels are more important.
+// The numbering follows log/slog so that levels can be converted to
+// slog.Level without a lookup table.
+type Level int
+
+const (
+	// TraceLevel logs are finer-grained than debug logs, such as the
+	// details of each step of an algorithm.
+	TraceLevel Level = -8
+	// DebugLevel logs are typically voluminous and usually disabled in production.
+	DebugLevel Level = -4
+	// InfoLevel is the default logging priority.
+	InfoLevel Level = 0
+	// NoticeLevel logs are normal but significant events, such as
+	// configuration changes.
+	NoticeLevel Level = 2
+	// WarnLevel logs are more important than Info but don't need individual review.
+	WarnLevel Level = 4
+	// ErrorLevel logs are high-priority and should be looked at.
+	ErrorLevel Level = 8
+	// CriticalLevel logs are errors that need immediate attention, such as
+	// the failure of a subsystem.
+	CriticalLevel Level = 10
+	// PanicLevel logs a message and then panics.
+	PanicLevel Level = 12
+	// FatalLevel logs a message and then the program exits.
+	FatalLevel Level = 16
+)
+
+// registry holds the level names.
+var registry = struct {
+	sync.RWMutex
+	// levels maps lower-case names, including aliases, to levels
+	levels map[string]Level
+	// names maps levels to their names, sorted by level
+	names []levelName
+}{levels: make(map[string]Level)}
+
+// levelName is a named level.
+type levelName struct {
+	level Level
+	name  string
+}
+
+func init() {
+	for _, l := range []levelName{
+		{TraceLevel, "trace"},
+		{DebugLevel, "debug"},
+		{InfoLevel, "info"},
+		{NoticeLevel, "notice"},
+		{WarnLevel, "warn"},
+		{ErrorLevel, "error"},
+		{CriticalLevel, "critical"},
+		{PanicLevel, "panic"},
+		{FatalLevel, "fatal"},
+	} {
+		if err := RegisterLevel(l.name, l.level); err != nil {
+			panic(err)
+		}
+	}
+	registry.levels["warning"] = WarnLevel
+	registry.levels["crit"] = CriticalLevel
+	registry.levels["dpanic"] = CriticalLevel
+}
+
+// RegisterLevel registers a custom level name, which String returns for the
+// level and ParseLevel accepts. The first name registered for a level is
+// its name; the following ones are aliases. Names are case-insensitive and
+// cannot contain '+' or '-'. It returns an error if the name is already
+// registered for another level.
+//
+// RegisterLevel is meant to be called at initialization, before the levels
+// are used.
+func RegisterLevel(name string, level Level) error {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" || strings.ContainsAny(name, "+- \t") {
+		return fmt.Errorf("invalid level name: %q", name)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if l, ok := registry.levels[name]; ok {
+		if l == level {
+			return nil
+		}
+		return fmt.Errorf("level name %q is already registered for level %d", name, int(l))
+	}
+	registry.levels[name] = level
+	i := sort.Search(len(registry.names), func(i int) bool { return registry.names[i].level >= level })
+	if i < len(registry.names) && registry.names[i].level == level {
+		return nil
+	}
+	registry.names = append(registry.names, levelName{})
+	copy(registry.names[i+1:], registry.names[i:])
+	registry.names[i] = levelName{level: level, name: name}
+	return nil
+}
+
+// String returns the lower-case name of the level. A level without a name
+// is named after the closest named level above it, with the difference,
+// such as "debug-1"; a level above the highest named level is named after
+// it, such as "fatal+4".
+func (l Level) String() string {
+	registry.RLock()
+	defer registry.RUnlock()
+	names := registry.names
+	i := sort.Search(len(names), func(i int) bool { return names[i].level >= l })
+	switch {
+	case len(names) == 0:
+		return fmt.Sprintf("level(%d)", int(l))
+	case i == len(names):
+		last := names[len(names)-1]
+		return fmt.Sprintf("%s+%d", last.name, int(l-last.level))
+	case names[i].level == l:
+		return names[i].name
+	default:
+		return fmt.Sprintf("%s-%d", names[i].name, int(names[i].level-l))
+	}
+}
+
+// ParseLevel converts a level name, such as trace, debug, info, notice,
+// warn, error, critical, panic, fatal or a name registered with
+// RegisterLevel, to a Level. The name is matched case-insensitively, and
+// can be followed by an offset as returned by String, such as "debug-1".
+// An empty name is the info level.
+func ParseLevel(s string) (Level, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if name == "" {
+		return InfoLevel, nil
+	}
+	var offset int
+	if i := strings.IndexAny(name, "+-"); i > 0 {
+		n, err := strconv.Atoi(name[i:])
+		if err != nil {
+			return InfoLevel, fmt.Errorf("unknown log level: %q", s)
+		}
+		name, offset = name[:i], n
+	}
+	registry.RLock()
+	level, ok := registry.levels[name]
+	registry.RUnlock()
+	if !ok {
+		return InfoLevel, fmt.Errorf("unknown log level: %q", s)
+	}
+	return level + Level(offset), nil
+}
@@ -0,0 +1,388 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// maxMarshalDepth bounds the nesting of the objects and arrays encoded
+	// by MarshalValue, which protects against cyclic structures.
+	maxMarshalDepth = 16
+	// maxMarshalElements bounds the number of fields and elements encoded
+	// by MarshalValue for one value, which protects against huge
+	// structures.
+	maxMarshalElements = 1000
+)
+
+// ObjectMarshaler is implemented by the types that log themselves as a
+// nested object with both backends, like zapcore.ObjectMarshaler does for
+// zap and slog.LogValuer for slog.
+type ObjectMarshaler interface {
+	MarshalLogObject(enc ObjectEncoder) error
+}
+
+// ArrayMarshaler is implemented by the types that log themselves as an
+// array with both backends.
+type ArrayMarshaler interface {
+	MarshalLogArray(enc ArrayEncoder) error
+}
+
+// ObjectMarshalerFunc is a function implementing ObjectMarshaler.
+type ObjectMarshalerFunc func(enc ObjectEncoder) error
+
+// MarshalLogObject implements the ObjectMarshaler interface.
+func (f ObjectMarshalerFunc) MarshalLogObject(enc ObjectEncoder) error {
+	return f(enc)
+}
+
+// ArrayMarshalerFunc is a function implementing ArrayMarshaler.
+type ArrayMarshalerFunc func(enc ArrayEncoder) error
+
+// MarshalLogArray implements the ArrayMarshaler interface.
+func (f ArrayMarshalerFunc) MarshalLogArray(enc ArrayEncoder) error {
+	return f(enc)
+}
+
+// Lazy is a value computed only if the entry it is logged with is enabled.
+// The value it returns may itself be an ObjectMarshaler or an
+// ArrayMarshaler.
+type Lazy func() any
+
+// ObjectEncoder is the encoder passed to ObjectMarshaler.
+type ObjectEncoder interface {
+	AddString(key, value string)
+	AddInt(key string, value int)
+	AddInt64(key string, value int64)
+	AddUint64(key string, value uint64)
+	AddFloat64(key string, value float64)
+	AddBool(key string, value bool)
+	AddDuration(key string, value time.Duration)
+	AddTime(key string, value time.Time)
+	AddObject(key string, m ObjectMarshaler)
+	AddArray(key string, m ArrayMarshaler)
+	// AddAny adds a value of any type, which is encoded as a nested object
+	// or array if it implements ObjectMarshaler or ArrayMarshaler.
+	AddAny(key string, value any)
+}
+
+// ArrayEncoder is the encoder passed to ArrayMarshaler.
+type ArrayEncoder interface {
+	AppendString(value string)
+	AppendInt(value int)
+	AppendInt64(value int64)
+	AppendUint64(value uint64)
+	AppendFloat64(value float64)
+	AppendBool(value bool)
+	AppendDuration(value time.Duration)
+	AppendTime(value time.Time)
+	AppendObject(m ObjectMarshaler)
+	AppendArray(m ArrayMarshaler)
+	// AppendAny appends a value of any type, as AddAny does.
+	AppendAny(value any)
+}
+
+// Object is an encoded ObjectMarshaler: its fields in order, whose values
+// are plain values, Objects or Arrays.
+type Object []Field
+
+// Array is an encoded ArrayMarshaler, whose elements are plain values,
+// Objects or Arrays.
+type Array []any
+
+// Map returns o as nested maps and slices, for encoders without ordered
+// objects, such as the sinks.
+func (o Object) Map() map[string]any {
+	m := make(map[string]any, len(o))
+	for _, f := range o {
+		m[f.Key] = PlainValue(f.Value)
+	}
+	return m
+}
+
+// Slice returns a as nested maps and slices.
+func (a Array) Slice() []any {
+	s := make([]any, len(a))
+	for i, v := range a {
+		s[i] = PlainValue(v)
+	}
+	return s
+}
+
+// MarshalJSON implements the json.Marshaler interface, keeping the order of
+// the fields.
+func (o Object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if err := writeJSON(&b, f.Key); err != nil {
+			return nil, err
+		}
+		b.WriteByte(':')
+		if err := writeJSON(&b, f.Value); err != nil {
+			return nil, err
+		}
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, for the text
+// encoders: the text of o is its JSON encoding.
+func (o Object) MarshalText() ([]byte, error) {
+	return o.MarshalJSON()
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (a Array) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	if err := writeJSON(&b, []any(a)); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface, for the text
+// encoders: the text of a is its JSON encoding.
+func (a Array) MarshalText() ([]byte, error) {
+	return a.MarshalJSON()
+}
+
+// writeJSON writes the JSON encoding of v to b, without escaping HTML as
+// the encoders of zap and slog do. The values json cannot encode are
+// written as strings.
+func writeJSON(b *bytes.Buffer, v any) error {
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		if err := enc.Encode(fmt.Sprint(v)); err != nil {
+			return err
+		}
+	}
+	// Encode ends with a newline
+	b.Truncate(b.Len() - 1)
+	return nil
+}
+
+// PlainValue returns v as nested maps and slices if it is an Object or an
+// Array, and v otherwise.
+func PlainValue(v any) any {
+	switch v := v.(type) {
+	case Object:
+		return v.Map()
+	case Array:
+		return v.Slice()
+	}
+	return v
+}
+
+// IsMarshaler reports whether MarshalValue encodes v.
+func IsMarshaler(v any) bool {
+	switch v.(type) {
+	case ObjectMarshaler, ArrayMarshaler, Lazy:
+		return true
+	}
+	return false
+}
+
+// MarshalValue returns v as it is logged: an ObjectMarshaler is encoded to
+// an Object, an ArrayMarshaler to an Array and a Lazy is computed, and the
+// other values are returned as they are. The objects and arrays deeper than
+// 16 levels are replaced by a marker, and the fields and elements beyond the
+// first 1000 are dropped and counted. The error returned by the marshaler of
+// v is returned with what it encoded; the errors of the nested marshalers
+// are added next to their values, under their key followed by "Error" as
+// zap does.
+func MarshalValue(v any) (any, error) {
+	e := &encoder{}
+	return e.value(v, 0)
+}
+
+// encoder encodes the values of a MarshalValue call.
+type encoder struct {
+	// elements is the number of fields and elements encoded
+	elements int
+}
+
+func (e *encoder) value(v any, depth int) (any, error) {
+	switch m := v.(type) {
+	case Lazy:
+		if m == nil {
+			return nil, nil
+		}
+		if depth >= maxMarshalDepth {
+			return "...[max depth]", nil
+		}
+		return e.value(m(), depth+1)
+	case ObjectMarshaler:
+		if depth >= maxMarshalDepth {
+			return "...[max depth]", nil
+		}
+		enc := &objectEncoder{e: e, depth: depth + 1}
+		err := m.MarshalLogObject(enc)
+		return enc.object(), err
+	case ArrayMarshaler:
+		if depth >= maxMarshalDepth {
+			return "...[max depth]", nil
+		}
+		enc := &arrayEncoder{e: e, depth: depth + 1}
+		err := m.MarshalLogArray(enc)
+		return enc.array(), err
+	}
+	return v, nil
+}
+
+// add reports whether another field or element can be encoded.
+func (e *encoder) add() bool {
+	if e.elements >= maxMarshalElements {
+		return false
+	}
+	e.elements++
+	return true
+}
+
+// objectEncoder is the ObjectEncoder of the objects of an encoder.
+type objectEncoder struct {
+	e       *encoder
+	depth   int
+	fields  Object
+	dropped int
+}
+
+func (o *objectEncoder) object() Object {
+	if o.dropped > 0 {
+		return append(o.fields, Field{Key: "...", Value: fmt.Sprintf("[truncated %d fields]", o.dropped)})
+	}
+	return o.fields
+}
+
+func (o *objectEncoder) add(key string, value any) {
+	if !o.e.add() {
+		o.dropped++
+		return
+	}
+	o.fields = append(o.fields, Field{Key: key, Value: value})
+}
+
+// AddString implements the ObjectEncoder interface.
+func (o *objectEncoder) AddString(key, value string) { o.add(key, value) }
+
+// AddInt implements the ObjectEncoder interface.
+func (o *objectEncoder) AddInt(key string, value int) { o.add(key, int64(value)) }
+
+// AddInt64 implements the ObjectEncoder interface.
+func (o *objectEncoder) AddInt64(key string, value int64) { o.add(key, value) }
+
+// AddUint64 implements the ObjectEncoder interface.
+func (o *objectEncoder) AddUint64(key string, value uint64) { o.add(key, value) }
+
+// AddFloat64 implements the ObjectEncoder interface.
+func (o *objectEncoder) AddFloat64(key string, value float64) { o.add(key, value) }
+
+// AddBool implements the ObjectEncoder interface.
+func (o *objectEncoder) AddBool(key string, value bool) { o.add(key, value) }
+
+// AddDuration implements the ObjectEncoder interface.
+func (o *objectEncoder) AddDuration(key string, value time.Duration) { o.add(key, value) }
+
+// AddTime implements the ObjectEncoder interface.
+func (o *objectEncoder) AddTime(key string, value time.Time) { o.add(key, value) }
+
+// AddObject implements the ObjectEncoder interface.
+func (o *objectEncoder) AddObject(key string, m ObjectMarshaler) { o.AddAny(key, m) }
+
+// AddArray implements the ObjectEncoder interface.
+func (o *objectEncoder) AddArray(key string, m ArrayMarshaler) { o.AddAny(key, m) }
+
+// AddAny implements the ObjectEncoder interface.
+func (o *objectEncoder) AddAny(key string, value any) {
+	if !IsMarshaler(value) {
+		o.add(key, value)
+		return
+	}
+	if !o.e.add() {
+		o.dropped++
+		return
+	}
+	v, err := o.e.value(value, o.depth)
+	o.fields = append(o.fields, Field{Key: key, Value: v})
+	if err != nil {
+		o.add(key+"Error", err.Error())
+	}
+}
+
+// arrayEncoder is the ArrayEncoder of the arrays of an encoder.
+type arrayEncoder struct {
+	e        *encoder
+	depth    int
+	elements Array
+	dropped  int
+}
+
+func (a *arrayEncoder) array() Array {
+	if a.dropped > 0 {
+		return append(a.elements, fmt.Sprintf("...[truncated %d elements]", a.dropped))
+	}
+	return a.elements
+}
+
+func (a *arrayEncoder) append(value any) {
+	if !a.e.add() {
+		a.dropped++
+		return
+	}
+	a.elements = append(a.elements, value)
+}
+
+// AppendString implements the ArrayEncoder interface.
+func (a *arrayEncoder) AppendString(value string) { a.append(value) }
+
+// AppendInt implements the ArrayEncoder interface.
+func (a *arrayEncoder) AppendInt(value int) { a.append(int64(value)) }
+
+// AppendInt64 implements the ArrayEncoder interface.
+func (a *arrayEncoder) AppendInt64(value int64) { a.append(value) }
+
+// AppendUint64 implements the ArrayEncoder interface.
+func (a *arrayEncoder) AppendUint64(value uint64) { a.append(value) }
+
+// AppendFloat64 implements the ArrayEncoder interface.
+func (a *arrayEncoder) AppendFloat64(value float64) { a.append(value) }
+
+// AppendBool implements the ArrayEncoder interface.
+func (a *arrayEncoder) AppendBool(value bool) { a.append(value) }
+
+// AppendDuration implements the ArrayEncoder interface.
+func (a *arrayEncoder) AppendDuration(value time.Duration) { a.append(value) }
+
+// AppendTime implements the ArrayEncoder interface.
+func (a *arrayEncoder) AppendTime(value time.Time) { a.append(value) }
+
+// AppendObject implements the ArrayEncoder interface.
+func (a *arrayEncoder) AppendObject(m ObjectMarshaler) { a.AppendAny(m) }
+
+// AppendArray implements the ArrayEncoder interface.
+func (a *arrayEncoder) AppendArray(m ArrayMarshaler) { a.AppendAny(m) }
+
+// AppendAny implements the ArrayEncoder interface. The error of a nested
+// marshaler is appended after its value.
+func (a *arrayEncoder) AppendAny(value any) {
+	if !IsMarshaler(value) {
+		a.append(value)
+		return
+	}
+	if !a.e.add() {
+		a.dropped++
+		return
+	}
+	v, err := a.e.value(value, a.depth)
+	a.elements = append(a.elements, v)
+	if err != nil {
+		a.append(err.Error())
+	}
+}
@@ -0,0 +1,144 @@
+// Package dedup collapses identical consecutive log entries. The first
+// occurrence of an entry is written immediately; identical entries (same
+// level, message and fields) following it within a window are suppressed,
+// and a summary such as "last message repeated 57 times" with the time of
+// the first and the last occurrence is written when the run ends.
+//
+// Example usage:
+//
+//	config := &logx.LoggerConfig{
+//	    // ...
+//	    Dedup: &dedup.Config{Window: 30 * time.Second},
+//	}
+package dedup
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultWindow is the default window of Config.
+const DefaultWindow = 10 * time.Second
+
+// Config holds the configuration of the duplicate suppression.
+type Config struct {
+	// Window is the maximum time identical entries are collapsed after
+	// their first occurrence (default 10s). A summary is written at the end
+	// of the window at the latest, and the next identical entry is written
+	// again.
+	Window time.Duration `mapstructure:"window" yaml:"window"`
+}
+
+// Repeat describes a run of suppressed entries.
+type Repeat struct {
+	// Count is the number of entries suppressed after the first occurrence.
+	Count int
+	// First is the time of the first occurrence.
+	First time.Time
+	// Last is the time of the last suppressed entry.
+	Last time.Time
+}
+
+// Message returns the message of the summary entry.
+func (r Repeat) Message() string {
+	if r.Count == 1 {
+		return "last message repeated 1 time"
+	}
+	return fmt.Sprintf("last message repeated %d times", r.Count)
+}
+
+// run is the entry whose duplicates are being suppressed.
+type run struct {
+	key   string
+	rep   Repeat
+	flush func(Repeat)
+}
+
+// Deduper tracks the last entry written. It is safe for concurrent use.
+type Deduper struct {
+	window time.Duration
+
+	mu     sync.Mutex
+	last   *run
+	timer  *time.Timer
+	closed bool
+}
+
+// New returns a Deduper with the given configuration.
+func New(c Config) (*Deduper, error) {
+	if c.Window < 0 {
+		return nil, fmt.Errorf("dedup: window must not be negative")
+	}
+	if c.Window == 0 {
+		c.Window = DefaultWindow
+	}
+	d := &Deduper{window: c.Window}
+	d.timer = time.AfterFunc(time.Hour, d.expire)
+	d.timer.Stop()
+	return d, nil
+}
+
+// Check reports whether the entry identified by key, logged at t, repeats
+// the previous entry and must be suppressed. Otherwise the entry starts a
+// new run: flush is called with the suppressed entries of the run when it
+// ends, if there are any. The summary of the previous run is written before
+// Check returns, so that it precedes the new entry.
+func (d *Deduper) Check(key string, t time.Time, flush func(Repeat)) bool {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return false
+	}
+	if r := d.last; r != nil && r.key == key && t.Sub(r.rep.First) < d.window {
+		r.rep.Count++
+		r.rep.Last = t
+		d.mu.Unlock()
+		return true
+	}
+	prev := d.last
+	d.last = &run{key: key, rep: Repeat{First: t, Last: t}, flush: flush}
+	d.timer.Reset(d.window)
+	d.mu.Unlock()
+
+	prev.end()
+	return false
+}
+
+// end writes the summary of the run, if entries were suppressed.
+func (r *run) end() {
+	if r != nil && r.rep.Count > 0 {
+		r.flush(r.rep)
+	}
+}
+
+// expire ends the current run at the end of its window.
+func (d *Deduper) expire() {
+	d.mu.Lock()
+	r := d.last
+	if r == nil {
+		d.mu.Unlock()
+		return
+	}
+	if rest := d.window - time.Since(r.rep.First); rest > 0 {
+		d.timer.Reset(rest)
+		d.mu.Unlock()
+		return
+	}
+	d.last = nil
+	d.mu.Unlock()
+	r.end()
+}
+
+// Close writes the summary of the current run. Entries checked afterwards
+// are never suppressed.
+func (d *Deduper) Close() error {
+	d.mu.Lock()
+	r := d.last
+	d.last = nil
+	d.closed = true
+	d.timer.Stop()
+	d.mu.Unlock()
+	r.end()
+	return nil
+}
@@ -0,0 +1,114 @@
+package dedup_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx/dedup"
+)
+
+// recorder collects the summaries written by a Deduper.
+type recorder struct {
+	mu      sync.Mutex
+	repeats []dedup.Repeat
+}
+
+func (r *recorder) flush(rep dedup.Repeat) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.repeats = append(r.repeats, rep)
+}
+
+func (r *recorder) get() []dedup.Repeat {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]dedup.Repeat(nil), r.repeats...)
+}
+
+func newDeduper(t *testing.T, window time.Duration) *dedup.Deduper {
+	t.Helper()
+	d, err := dedup.New(dedup.Config{Window: window})
+	if err != nil {
+		t.Fatalf("failed to create deduper: %v", err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+// TestCheck tests that consecutive duplicates are suppressed and summarized when the run ends
+func TestCheck(t *testing.T) {
+	d := newDeduper(t, time.Hour)
+	r := &recorder{}
+	start := time.Now()
+	if d.Check("a", start, r.flush) {
+		t.Fatal("the first occurrence must be written")
+	}
+	for i := 1; i <= 57; i++ {
+		if !d.Check("a", start.Add(time.Duration(i)*time.Millisecond), r.flush) {
+			t.Fatal("expected the duplicate to be suppressed")
+		}
+	}
+	if len(r.get()) != 0 {
+		t.Fatal("the summary must be written when the run ends")
+	}
+	if d.Check("b", start.Add(time.Second), r.flush) {
+		t.Fatal("a different entry must be written")
+	}
+	reps := r.get()
+	if len(reps) != 1 {
+		t.Fatalf("expected a summary, got %v", reps)
+	}
+	rep := reps[0]
+	if rep.Count != 57 || !rep.First.Equal(start) || !rep.Last.Equal(start.Add(57*time.Millisecond)) {
+		t.Errorf("unexpected summary: %+v", rep)
+	}
+	if rep.Message() != "last message repeated 57 times" {
+		t.Errorf("unexpected message: %s", rep.Message())
+	}
+}
+
+// TestCheckNonConsecutive tests that only consecutive duplicates are suppressed
+func TestCheckNonConsecutive(t *testing.T) {
+	d := newDeduper(t, time.Hour)
+	r := &recorder{}
+	now := time.Now()
+	for _, key := range []string{"a", "b", "a", "b"} {
+		if d.Check(key, now, r.flush) {
+			t.Errorf("expected %s to be written", key)
+		}
+	}
+	if len(r.get()) != 0 {
+		t.Error("no summary expected without suppressed entries")
+	}
+}
+
+// TestWindow tests that the summary is written at the end of the window
+func TestWindow(t *testing.T) {
+	d := newDeduper(t, 30*time.Millisecond)
+	r := &recorder{}
+	d.Check("a", time.Now(), r.flush)
+	d.Check("a", time.Now(), r.flush)
+	time.Sleep(100 * time.Millisecond)
+	if reps := r.get(); len(reps) != 1 || reps[0].Count != 1 {
+		t.Fatalf("expected a summary after the window, got %v", reps)
+	}
+	if d.Check("a", time.Now(), r.flush) {
+		t.Error("the entry must be written again after the window")
+	}
+}
+
+// TestClose tests that closing writes the pending summary
+func TestClose(t *testing.T) {
+	d := newDeduper(t, time.Hour)
+	r := &recorder{}
+	d.Check("a", time.Now(), r.flush)
+	d.Check("a", time.Now(), r.flush)
+	d.Close()
+	if len(r.get()) != 1 {
+		t.Error("expected the pending summary on close")
+	}
+	if d.Check("a", time.Now(), r.flush) {
+		t.Error("no entry may be suppressed after close")
+	}
+}
@@ -0,0 +1,56 @@
+package logx_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx"
+	"github.com/go4x/logx/dedup"
+)
+
+// TestDedup tests that both backends collapse identical consecutive entries into a summary
+func TestDedup(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "info",
+				Dir:    logDir,
+				Format: "json",
+				Dedup:  &dedup.Config{Window: time.Hour},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			ctx := context.Background()
+			for i := 0; i < 58; i++ {
+				logx.Log(ctx, logx.ErrorLevel, "upstream unavailable", "host", "db-1")
+			}
+			logx.Log(ctx, logx.ErrorLevel, "upstream unavailable", "host", "db-2")
+			logx.Log(ctx, logx.ErrorLevel, "upstream unavailable", "host", "db-2")
+
+			content := readLogs(t, logDir)
+			if n := strings.Count(content, "upstream unavailable"); n != 2 {
+				t.Errorf("expected 2 entries, got %d: %q", n, content)
+			}
+			if !strings.Contains(content, "last message repeated 57 times") || !strings.Contains(content, `"repeated":57`) {
+				t.Errorf("expected a summary, got %q", content)
+			}
+			if !strings.Contains(content, `"first"`) || !strings.Contains(content, `"last"`) {
+				t.Errorf("expected the first and last timestamps, got %q", content)
+			}
+
+			// reinitializing writes the summary of the pending run
+			if err := logx.Init(&logx.LoggerConfig{Type: typ, Level: "info", Dir: t.TempDir()}); err != nil {
+				t.Fatalf("failed to reinitialize logger: %v", err)
+			}
+			if content := readLogs(t, logDir); !strings.Contains(content, "last message repeated 1 time") {
+				t.Errorf("expected the pending summary, got %q", content)
+			}
+		})
+	}
+}
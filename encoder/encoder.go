@@ -0,0 +1,201 @@
+// Package encoder configures the entry keys, the time format, the level
+// encoding and the caller format shared by the zap and slog backends, so
+// that both write the same JSON entries.
+package encoder
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go4x/logx/core"
+)
+
+// Omit is the key name that leaves an entry key out.
+const Omit = "-"
+
+// Default key names.
+const (
+	DefaultMessageKey    = "msg"
+	DefaultLevelKey      = "level"
+	DefaultTimeKey       = "time"
+	DefaultCallerKey     = "caller"
+	DefaultStacktraceKey = "stacktrace"
+)
+
+// Time formats, besides the layouts of the time package.
+const (
+	// TimeRFC3339 formats the time as time.RFC3339.
+	TimeRFC3339 = "rfc3339"
+	// TimeRFC3339Nano formats the time as time.RFC3339Nano (default).
+	TimeRFC3339Nano = "rfc3339nano"
+	// TimeEpoch writes the time as the integer number of seconds since
+	// the Unix epoch.
+	TimeEpoch = "epoch"
+	// TimeEpochMillis writes the time as the integer number of
+	// milliseconds since the Unix epoch.
+	TimeEpochMillis = "epoch-millis"
+	// TimeEpochNanos writes the time as the integer number of nanoseconds
+	// since the Unix epoch.
+	TimeEpochNanos = "epoch-nanos"
+)
+
+// Level cases.
+const (
+	// LevelUpper writes the level names in upper case, as INFO (default).
+	LevelUpper = "upper"
+	// LevelLower writes the level names in lower case, as info.
+	LevelLower = "lower"
+)
+
+// Caller formats.
+const (
+	// CallerShort writes the caller as the last directory and the file
+	// name, with the line, as pkg/file.go:42 (default).
+	CallerShort = "short"
+	// CallerFull writes the caller as the full path of the file, with the
+	// line.
+	CallerFull = "full"
+	// CallerFunction writes the caller as the function followed by the
+	// short caller, as pkg.Func (pkg/file.go:42).
+	CallerFunction = "function"
+)
+
+// Config configures the encoding of the entries. The key names are the
+// default ones if empty, and Omit leaves the key out.
+type Config struct {
+	// MessageKey is the key of the message (msg by default).
+	MessageKey string `mapstructure:"message-key" yaml:"message-key"`
+
+	// LevelKey is the key of the level (level by default).
+	LevelKey string `mapstructure:"level-key" yaml:"level-key"`
+
+	// TimeKey is the key of the time (time by default).
+	TimeKey string `mapstructure:"time-key" yaml:"time-key"`
+
+	// CallerKey is the key of the caller (caller by default).
+	CallerKey string `mapstructure:"caller-key" yaml:"caller-key"`
+
+	// StacktraceKey is the key of the stack traces (stacktrace by
+	// default).
+	StacktraceKey string `mapstructure:"stacktrace-key" yaml:"stacktrace-key"`
+
+	// TimeFormat is rfc3339, rfc3339nano (default), epoch, epoch-millis,
+	// epoch-nanos or a layout of the time package, such as
+	// "2006/01/02 15:04:05.000".
+	TimeFormat string `mapstructure:"time-format" yaml:"time-format"`
+
+	// UTC writes the time in UTC instead of the local time.
+	UTC bool `mapstructure:"utc" yaml:"utc"`
+
+	// LevelCase is upper (default) or lower.
+	LevelCase string `mapstructure:"level-case" yaml:"level-case"`
+
+	// LevelColor colors the level names with ANSI escape sequences, for
+	// terminals.
+	LevelColor bool `mapstructure:"level-color" yaml:"level-color"`
+
+	// CallerFormat is short (default), full or function.
+	CallerFormat string `mapstructure:"caller-format" yaml:"caller-format"`
+}
+
+// Validate reports the invalid level case or caller format of c.
+func (c Config) Validate() error {
+	switch c.LevelCase {
+	case "", LevelUpper, LevelLower:
+	default:
+		return fmt.Errorf("encoder: invalid level case %q", c.LevelCase)
+	}
+	switch c.CallerFormat {
+	case "", CallerShort, CallerFull, CallerFunction:
+	default:
+		return fmt.Errorf("encoder: invalid caller format %q", c.CallerFormat)
+	}
+	return nil
+}
+
+// Keys returns c with the default key names in place of the empty ones, and
+// the empty name in place of Omit, as zap expects.
+func (c Config) Keys() Config {
+	c.MessageKey = key(c.MessageKey, DefaultMessageKey)
+	c.LevelKey = key(c.LevelKey, DefaultLevelKey)
+	c.TimeKey = key(c.TimeKey, DefaultTimeKey)
+	c.CallerKey = key(c.CallerKey, DefaultCallerKey)
+	c.StacktraceKey = key(c.StacktraceKey, DefaultStacktraceKey)
+	return c
+}
+
+func key(k, def string) string {
+	switch k {
+	case "":
+		return def
+	case Omit:
+		return ""
+	default:
+		return k
+	}
+}
+
+// Time returns the encoding of t: a string, or an int64 for the epoch
+// formats.
+func (c Config) Time(t time.Time) any {
+	if c.UTC {
+		t = t.UTC()
+	}
+	switch c.TimeFormat {
+	case "", TimeRFC3339Nano:
+		return t.Format(time.RFC3339Nano)
+	case TimeRFC3339:
+		return t.Format(time.RFC3339)
+	case TimeEpoch:
+		return t.Unix()
+	case TimeEpochMillis:
+		return t.UnixMilli()
+	case TimeEpochNanos:
+		return t.UnixNano()
+	default:
+		return t.Format(c.TimeFormat)
+	}
+}
+
+// levelColors are the ANSI colors of the levels, by the highest level at
+// most the level: the colors of zap.
+var levelColors = []struct {
+	level core.Level
+	color int
+}{
+	{core.ErrorLevel, 31}, // red
+	{core.WarnLevel, 33},  // yellow
+	{core.InfoLevel, 34},  // blue
+}
+
+// Level returns the encoding of level.
+func (c Config) Level(level core.Level) string {
+	name := level.String()
+	if c.LevelCase != LevelLower {
+		name = strings.ToUpper(name)
+	}
+	if !c.LevelColor {
+		return name
+	}
+	color := 35 // magenta, below info
+	for _, lc := range levelColors {
+		if level >= lc.level {
+			color = lc.color
+			break
+		}
+	}
+	return fmt.Sprintf("\x1b[%dm%s\x1b[0m", color, name)
+}
+
+// Caller returns the encoding of the call site of function at file:line.
+func (c Config) Caller(function, file string, line int) string {
+	switch c.CallerFormat {
+	case CallerFull:
+		return fmt.Sprintf("%s:%d", file, line)
+	case CallerFunction:
+		return fmt.Sprintf("%s (%s:%d)", function, core.TrimmedPath(file), line)
+	default:
+		return fmt.Sprintf("%s:%d", core.TrimmedPath(file), line)
+	}
+}
@@ -0,0 +1,95 @@
+package encoder_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/encoder"
+)
+
+// TestKeys tests the default and omitted key names
+func TestKeys(t *testing.T) {
+	k := encoder.Config{MessageKey: "message", TimeKey: encoder.Omit}.Keys()
+	if k.MessageKey != "message" || k.LevelKey != "level" || k.TimeKey != "" || k.CallerKey != "caller" || k.StacktraceKey != "stacktrace" {
+		t.Errorf("unexpected keys: %+v", k)
+	}
+}
+
+// TestTime tests the time formats
+func TestTime(t *testing.T) {
+	ts := time.Date(2024, 5, 6, 7, 8, 9, 123456789, time.FixedZone("CET", 3600))
+	testCases := []struct {
+		format string
+		utc    bool
+		want   any
+	}{
+		{"", false, "2024-05-06T07:08:09.123456789+01:00"},
+		{encoder.TimeRFC3339, true, "2024-05-06T06:08:09Z"},
+		{encoder.TimeEpoch, false, int64(1714975689)},
+		{encoder.TimeEpochMillis, false, int64(1714975689123)},
+		{encoder.TimeEpochNanos, false, int64(1714975689123456789)},
+		{"2006/01/02 15:04:05.000", true, "2024/05/06 06:08:09.123"},
+	}
+	for _, tc := range testCases {
+		c := encoder.Config{TimeFormat: tc.format, UTC: tc.utc}
+		if got := c.Time(ts); got != tc.want {
+			t.Errorf("Time with %q = %v, want %v", tc.format, got, tc.want)
+		}
+	}
+}
+
+// TestLevel tests the level cases and colors
+func TestLevel(t *testing.T) {
+	testCases := []struct {
+		c     encoder.Config
+		level core.Level
+		want  string
+	}{
+		{encoder.Config{}, core.NoticeLevel, "NOTICE"},
+		{encoder.Config{LevelCase: encoder.LevelLower}, core.DebugLevel - 1, "debug-1"},
+		{encoder.Config{LevelColor: true}, core.TraceLevel, "\x1b[35mTRACE\x1b[0m"},
+		{encoder.Config{LevelColor: true}, core.InfoLevel, "\x1b[34mINFO\x1b[0m"},
+		{encoder.Config{LevelColor: true}, core.WarnLevel, "\x1b[33mWARN\x1b[0m"},
+		{encoder.Config{LevelColor: true, LevelCase: encoder.LevelLower}, core.CriticalLevel, "\x1b[31mcritical\x1b[0m"},
+	}
+	for _, tc := range testCases {
+		if got := tc.c.Level(tc.level); got != tc.want {
+			t.Errorf("Level(%v) = %q, want %q", tc.level, got, tc.want)
+		}
+	}
+}
+
+// TestCaller tests the caller formats
+func TestCaller(t *testing.T) {
+	const fn, file = "github.com/acme/app/pay.Charge", "/src/app/pay/charge.go"
+	testCases := []struct {
+		format, want string
+	}{
+		{"", "pay/charge.go:42"},
+		{encoder.CallerFull, "/src/app/pay/charge.go:42"},
+		{encoder.CallerFunction, "github.com/acme/app/pay.Charge (pay/charge.go:42)"},
+	}
+	for _, tc := range testCases {
+		c := encoder.Config{CallerFormat: tc.format}
+		if got := c.Caller(fn, file, 42); got != tc.want {
+			t.Errorf("Caller with %q = %q, want %q", tc.format, got, tc.want)
+		}
+	}
+	if got := (encoder.Config{}).Caller(fn, "charge.go", 1); got != "charge.go:1" {
+		t.Errorf("unexpected caller without directory: %q", got)
+	}
+}
+
+// TestValidate tests the rejection of unknown level cases and caller formats
+func TestValidate(t *testing.T) {
+	if err := (encoder.Config{LevelCase: "title"}).Validate(); err == nil {
+		t.Error("expected an error for an invalid level case")
+	}
+	if err := (encoder.Config{CallerFormat: "long"}).Validate(); err == nil {
+		t.Error("expected an error for an invalid caller format")
+	}
+	if err := (encoder.Config{LevelCase: encoder.LevelLower, CallerFormat: encoder.CallerFull}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package logx_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx"
+	"github.com/go4x/logx/encoder"
+)
+
+// TestEncoderIdentical tests that both backends write identical JSON entries with the same encoder configuration
+func TestEncoderIdentical(t *testing.T) {
+	outputs := make(map[logx.LoggerType]string)
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		logDir := t.TempDir()
+		err := logx.Init(&logx.LoggerConfig{
+			Type:           typ,
+			Level:          "info",
+			Dir:            logDir,
+			Format:         "json",
+			DurationFormat: "string",
+			Encoder: &encoder.Config{
+				MessageKey: "message",
+				LevelKey:   "severity",
+				TimeFormat: "2006-01-02",
+				UTC:        true,
+				LevelCase:  encoder.LevelLower,
+				CallerKey:  encoder.Omit,
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to initialize logger: %v", err)
+		}
+
+		o := order{id: "o-1", items: items{{sku: "a", quantity: 2}}}
+		logx.Log(context.Background(), logx.NoticeLevel, "placed", "order", o, "took", 1500*time.Millisecond,
+			"ok", true, logx.Err(errors.Join(errors.New("a"), errors.New("b"))))
+		outputs[typ] = strings.TrimSpace(readLogs(t, logDir))
+	}
+
+	day := time.Now().UTC().Format("2006-01-02")
+	want := `{"time":"` + day + `","severity":"notice","message":"placed",` +
+		`"order":{"id":"o-1","items":[{"sku":"a","quantity":2}]},"took":"1.5s","ok":true,` +
+		`"error":{"message":"a\nb","type":"*errors.joinError","causes":[{"message":"a","type":"*errors.errorString"},{"message":"b","type":"*errors.errorString"}]}}`
+	for typ, got := range outputs {
+		if got != want {
+			t.Errorf("%s: unexpected entry\n got: %s\nwant: %s", typ, got, want)
+		}
+	}
+}
+
+// TestEncoderOptions tests the time, level and caller options with both backends
+func TestEncoderOptions(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "info",
+				Dir:    logDir,
+				Format: "json",
+				Encoder: &encoder.Config{
+					TimeKey:      "ts",
+					TimeFormat:   encoder.TimeEpochMillis,
+					LevelColor:   true,
+					CallerFormat: encoder.CallerFunction,
+				},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			before := time.Now().UnixMilli()
+			logx.Warn("colored")
+			entry := findEntry(t, readLogs(t, logDir), "colored")
+			if ts, _ := entry["ts"].(float64); int64(ts) < before || int64(ts) > time.Now().UnixMilli() {
+				t.Errorf("expected the time in epoch milliseconds, got %v", entry["ts"])
+			}
+			if entry["level"] != "\x1b[33mWARN\x1b[0m" {
+				t.Errorf("expected the colored level, got %q", entry["level"])
+			}
+			if caller, _ := entry["caller"].(string); !strings.Contains(caller, " (") || !strings.Contains(caller, ".go:") {
+				t.Errorf("expected the function caller format, got %q", entry["caller"])
+			}
+		})
+	}
+
+	if err := logx.Init(&logx.LoggerConfig{Dir: t.TempDir(), Encoder: &encoder.Config{CallerFormat: "long"}}); err == nil {
+		t.Error("expected an error for an invalid caller format")
+	}
+}
@@ -0,0 +1,373 @@
+// Package encrypt encrypts log files at rest. Log output is buffered into
+// chunks that are sealed with AES-GCM and written as self-describing frames,
+// so that a partially written file stays readable up to its last complete
+// frame. Keys come from a KeyProvider and change only when the file is
+// rotated; every frame names the key it was encrypted with.
+//
+// Example usage:
+//
+//	keys, err := encrypt.NewStaticKeys("2024-06", map[string][]byte{
+//	    "2024-06": key, // 16, 24 or 32 bytes
+//	})
+//	config := &logx.LoggerConfig{
+//	    // ...
+//	    Encryption: &encrypt.Config{Keys: keys},
+//	}
+//
+//	// reading an encrypted file
+//	f, _ := os.Open("logs/2024-06-01-info.log")
+//	r := encrypt.NewReader(f, keys)
+//	io.Copy(os.Stdout, r)
+package encrypt
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// Frame layout:
+//
+//	magic   4 bytes  "LXE1"
+//	flags   1 byte   flagDeflate if the plaintext is deflate-compressed
+//	idLen   1 byte
+//	id      idLen bytes, the key id
+//	nonce   12 bytes
+//	length  4 bytes  big-endian length of the ciphertext
+//	ciphertext, including the 16-byte GCM tag
+//
+// The header, from magic to length, is authenticated as additional data,
+// followed by the sequence number of the frame in the file, starting at 0, as
+// 8 big-endian bytes, so that frames cannot be removed, reordered or copied
+// within or between files unnoticed. The sequence number is not written.
+const (
+	magic       = "LXE1"
+	flagDeflate = 1 << 0
+	nonceSize   = 12
+	// maxFrameSize bounds the ciphertext length accepted by the reader.
+	maxFrameSize = 64 << 20
+)
+
+// Defaults of Config.
+const (
+	DefaultChunkSize     = 64 << 10
+	DefaultFlushInterval = time.Second
+)
+
+// KeyProvider supplies the encryption keys. Implementations must be safe for
+// concurrent use.
+type KeyProvider interface {
+	// CurrentKey returns the id and the AES key (16, 24 or 32 bytes) to
+	// encrypt a new file with. It is called when a file is opened or
+	// rotated.
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key with the given id, to decrypt files.
+	Key(id string) ([]byte, error)
+}
+
+// StaticKeys is a KeyProvider with a fixed set of keys.
+type StaticKeys struct {
+	current string
+	keys    map[string][]byte
+}
+
+// NewStaticKeys returns a KeyProvider that encrypts with the key named
+// current and decrypts with any of keys.
+func NewStaticKeys(current string, keys map[string][]byte) (*StaticKeys, error) {
+	for id, key := range keys {
+		if len(id) == 0 || len(id) > 255 {
+			return nil, fmt.Errorf("encrypt: invalid key id %q", id)
+		}
+		if _, err := aes.NewCipher(key); err != nil {
+			return nil, fmt.Errorf("encrypt: key %q: %w", id, err)
+		}
+	}
+	if _, ok := keys[current]; !ok {
+		return nil, fmt.Errorf("encrypt: unknown current key %q", current)
+	}
+	return &StaticKeys{current: current, keys: keys}, nil
+}
+
+// CurrentKey implements the KeyProvider interface.
+func (s *StaticKeys) CurrentKey() (string, []byte, error) {
+	return s.current, s.keys[s.current], nil
+}
+
+// Key implements the KeyProvider interface.
+func (s *StaticKeys) Key(id string) ([]byte, error) {
+	key, ok := s.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("encrypt: unknown key %q", id)
+	}
+	return key, nil
+}
+
+// Config holds the configuration of the encryption of log files.
+type Config struct {
+	// Keys supplies the keys. It is required.
+	Keys KeyProvider `mapstructure:"-" yaml:"-"`
+
+	// ChunkSize is the size of the plaintext chunks in bytes (default 64KB).
+	ChunkSize int `mapstructure:"chunk-size" yaml:"chunk-size"`
+
+	// FlushInterval is the maximum time output stays buffered before it is
+	// encrypted and written (default 1s).
+	FlushInterval time.Duration `mapstructure:"flush-interval" yaml:"flush-interval"`
+}
+
+// Writer encrypts the output written to a rotating log file. It is safe for
+// concurrent use.
+type Writer struct {
+	mu       sync.Mutex
+	cfg      Config
+	out      *lumberjack.Logger
+	compress bool
+	maxSize  int64
+	size     int64  // predicted size of the current file
+	seq      uint64 // sequence number of the next frame in the current file
+	opened   bool   // whether out has opened the current file
+
+	id   string
+	aead cipher.AEAD
+	buf  []byte
+	err  error // last key rotation error, reported by Sync
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewWriter returns a Writer that encrypts to out. The file rotation of out
+// is kept: a new key is obtained from c.Keys for every file. If out
+// compresses its rotated files, the chunks are compressed before they are
+// encrypted instead, since encrypted data does not compress, and out no
+// longer compresses them.
+func NewWriter(out *lumberjack.Logger, c Config) (*Writer, error) {
+	if c.Keys == nil {
+		return nil, errors.New("encrypt: a key provider is required")
+	}
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = DefaultChunkSize
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = DefaultFlushInterval
+	}
+	w := &Writer{
+		cfg:      c,
+		out:      out,
+		compress: out.Compress,
+		maxSize:  int64(out.MaxSize) << 20,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	out.Compress = false
+	if w.maxSize == 0 {
+		// the default of lumberjack
+		w.maxSize = 100 << 20
+	}
+	if info, err := os.Stat(out.Filename); err == nil {
+		w.size = info.Size()
+		// frames are appended to the existing file
+		w.seq = countFrames(out.Filename)
+	}
+	if err := w.rotateKey(); err != nil {
+		return nil, err
+	}
+	go w.flushLoop()
+	return w, nil
+}
+
+// rotateKey obtains the current key from the provider.
+func (w *Writer) rotateKey() error {
+	id, key, err := w.cfg.Keys.CurrentKey()
+	if err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+	if len(id) == 0 || len(id) > 255 {
+		return fmt.Errorf("encrypt: invalid key id %q", id)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return fmt.Errorf("encrypt: key %q: %w", id, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+	w.id, w.aead = id, aead
+	return nil
+}
+
+// Write implements the io.Writer interface. Output is buffered until a chunk
+// is full, Sync is called or the flush interval elapses.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.aead == nil {
+		return 0, errors.New("encrypt: writer is closed")
+	}
+	n := len(p)
+	for len(p) > 0 {
+		room := w.cfg.ChunkSize - len(w.buf)
+		if room > len(p) {
+			room = len(p)
+		}
+		w.buf = append(w.buf, p[:room]...)
+		p = p[room:]
+		if len(w.buf) >= w.cfg.ChunkSize {
+			if err := w.flush(); err != nil {
+				return n - len(p), err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Sync encrypts and writes the buffered output. It also reports a failure
+// to obtain a new key at the last rotation, after which the previous key
+// was kept.
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.flush(); err != nil {
+		return err
+	}
+	err := w.err
+	w.err = nil
+	return err
+}
+
+// Close flushes the buffered output and closes the file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.aead == nil {
+		w.mu.Unlock()
+		return nil
+	}
+	err := w.flush()
+	w.aead = nil
+	w.mu.Unlock()
+	close(w.stop)
+	<-w.done
+	return errors.Join(err, w.out.Close())
+}
+
+// flushLoop flushes the buffer every FlushInterval until the writer is
+// closed.
+func (w *Writer) flushLoop() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			if w.aead != nil {
+				_ = w.flush()
+			}
+			w.mu.Unlock()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// flush encrypts the buffer into a frame and writes it.
+func (w *Writer) flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+	plaintext, flags := w.buf, byte(0)
+	if w.compress {
+		var zb bytes.Buffer
+		zw, _ := flate.NewWriter(&zb, flate.DefaultCompression)
+		zw.Write(plaintext)
+		zw.Close()
+		if zb.Len() < len(plaintext) {
+			plaintext, flags = zb.Bytes(), flagDeflate
+		}
+	}
+
+	// lumberjack rotates before a write that would exceed the maximum size,
+	// or reach it if the write opens the file; the new file gets a new key
+	// and its frames are numbered from 0
+	frameSize := int64(len(magic) + 2 + len(w.id) + nonceSize + 4 + len(plaintext) + w.aead.Overhead())
+	rotate := w.size+frameSize > w.maxSize
+	if !w.opened {
+		rotate = w.size+frameSize >= w.maxSize
+	}
+	if w.size > 0 && rotate {
+		if err := w.rotateKey(); err != nil {
+			w.err = err
+		}
+		w.size, w.seq = 0, 0
+	}
+
+	frame := make([]byte, 0, frameSize)
+	frame = append(frame, magic...)
+	frame = append(frame, flags, byte(len(w.id)))
+	frame = append(frame, w.id...)
+	nonce := make([]byte, nonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+	frame = append(frame, nonce...)
+	frame = binary.BigEndian.AppendUint32(frame, uint32(len(plaintext)+w.aead.Overhead()))
+	frame = w.aead.Seal(frame, nonce, plaintext, additionalData(frame, w.seq))
+
+	if _, err := w.out.Write(frame); err != nil {
+		return fmt.Errorf("encrypt: %w", err)
+	}
+	w.opened = true
+	w.size += int64(len(frame))
+	w.seq++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// additionalData returns the data authenticated with a frame: its header
+// followed by its sequence number.
+func additionalData(header []byte, seq uint64) []byte {
+	ad := make([]byte, len(header), len(header)+8)
+	copy(ad, header)
+	return binary.BigEndian.AppendUint64(ad, seq)
+}
+
+// countFrames returns the number of complete frames at the start of the
+// file name.
+func countFrames(name string) uint64 {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var n uint64
+	for {
+		header := make([]byte, len(magic)+2)
+		if _, err := io.ReadFull(r, header); err != nil || string(header[:len(magic)]) != magic {
+			return n
+		}
+		// the key id and the nonce are skipped, then the ciphertext
+		rest := make([]byte, int(header[len(magic)+1])+nonceSize+4)
+		if _, err := io.ReadFull(r, rest); err != nil {
+			return n
+		}
+		length := int64(binary.BigEndian.Uint32(rest[len(rest)-4:]))
+		if skipped, _ := r.Discard(int(length)); int64(skipped) < length {
+			return n
+		}
+		n++
+	}
+}
@@ -0,0 +1,326 @@
+package encrypt_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx/encrypt"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+var (
+	key1 = bytes.Repeat([]byte{1}, 32)
+	key2 = bytes.Repeat([]byte{2}, 16)
+)
+
+// rotatingKeys is a KeyProvider whose current key changes on every call and
+// which records the keys requested for decryption.
+type rotatingKeys struct {
+	mu        sync.Mutex
+	calls     int
+	requested map[string]bool
+}
+
+func (k *rotatingKeys) CurrentKey() (string, []byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.calls++
+	if k.calls%2 == 1 {
+		return "k1", key1, nil
+	}
+	return "k2", key2, nil
+}
+
+func (k *rotatingKeys) Key(id string) ([]byte, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.requested == nil {
+		k.requested = make(map[string]bool)
+	}
+	k.requested[id] = true
+	switch id {
+	case "k1":
+		return key1, nil
+	case "k2":
+		return key2, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", id)
+}
+
+func staticKeys(t *testing.T) *encrypt.StaticKeys {
+	t.Helper()
+	keys, err := encrypt.NewStaticKeys("k1", map[string][]byte{"k1": key1, "k2": key2})
+	if err != nil {
+		t.Fatalf("failed to create keys: %v", err)
+	}
+	return keys
+}
+
+func newWriter(t *testing.T, out *lumberjack.Logger, c encrypt.Config) *encrypt.Writer {
+	t.Helper()
+	w, err := encrypt.NewWriter(out, c)
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	return w
+}
+
+// decrypt returns the plaintext of an encrypted file.
+func decrypt(t *testing.T, name string, keys encrypt.KeyProvider) (string, error) {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", name, err)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(encrypt.NewReader(f, keys))
+	return string(data), err
+}
+
+// TestRoundTrip tests that the written output is encrypted and decrypts to the original
+func TestRoundTrip(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	keys := staticKeys(t)
+	w := newWriter(t, &lumberjack.Logger{Filename: name}, encrypt.Config{Keys: keys, ChunkSize: 100})
+
+	var want strings.Builder
+	for i := 0; i < 50; i++ {
+		line := fmt.Sprintf("customer c-%d logged in\n", i)
+		want.WriteString(line)
+		w.Write([]byte(line))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	raw, _ := os.ReadFile(name)
+	if bytes.Contains(raw, []byte("customer")) {
+		t.Fatal("the file contains plaintext")
+	}
+	got, err := decrypt(t, name, keys)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	if got != want.String() {
+		t.Errorf("expected %q, got %q", want.String(), got)
+	}
+}
+
+// TestPartialFile tests that a file cut inside a frame is readable up to its last complete frame
+func TestPartialFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	keys := staticKeys(t)
+	w := newWriter(t, &lumberjack.Logger{Filename: name}, encrypt.Config{Keys: keys})
+	w.Write([]byte("first chunk\n"))
+	w.Sync()
+	w.Write([]byte("second chunk\n"))
+	w.Close()
+
+	raw, _ := os.ReadFile(name)
+	os.WriteFile(name, raw[:len(raw)-5], 0o644)
+	got, err := decrypt(t, name, keys)
+	if !errors.Is(err, encrypt.ErrTruncated) {
+		t.Errorf("expected ErrTruncated, got %v", err)
+	}
+	if got != "first chunk\n" {
+		t.Errorf("expected the first chunk, got %q", got)
+	}
+}
+
+// TestTampering tests that modified frames fail authentication
+func TestTampering(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	keys := staticKeys(t)
+	w := newWriter(t, &lumberjack.Logger{Filename: name}, encrypt.Config{Keys: keys})
+	w.Write([]byte("amount=100\n"))
+	w.Close()
+
+	raw, _ := os.ReadFile(name)
+	raw[len(raw)-20] ^= 1
+	os.WriteFile(name, raw, 0o644)
+	if _, err := decrypt(t, name, keys); err == nil || !strings.Contains(err.Error(), "authentication") {
+		t.Errorf("expected an authentication error, got %v", err)
+	}
+}
+
+// TestFrameOrder tests that removed or reordered frames fail authentication
+func TestFrameOrder(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	keys := staticKeys(t)
+	w := newWriter(t, &lumberjack.Logger{Filename: name}, encrypt.Config{Keys: keys})
+	w.Write([]byte("grant admin\n"))
+	w.Sync()
+	w.Write([]byte("revoke admin\n"))
+	w.Close()
+
+	raw, _ := os.ReadFile(name)
+	// both frames have the same size
+	first, second := raw[:len(raw)/2], raw[len(raw)/2:]
+	for desc, data := range map[string][]byte{
+		"removed":   second,
+		"reordered": append(append([]byte(nil), second...), first...),
+	} {
+		os.WriteFile(name, data, 0o644)
+		if _, err := decrypt(t, name, keys); err == nil || !strings.Contains(err.Error(), "authentication") {
+			t.Errorf("%s: expected an authentication error, got %v", desc, err)
+		}
+	}
+}
+
+// TestAppend tests that the frames appended to an existing file by a new writer decrypt
+func TestAppend(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	keys := staticKeys(t)
+	for _, line := range []string{"first run\n", "second run\n"} {
+		w := newWriter(t, &lumberjack.Logger{Filename: name}, encrypt.Config{Keys: keys})
+		w.Write([]byte(line))
+		w.Close()
+	}
+	got, err := decrypt(t, name, keys)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	if got != "first run\nsecond run\n" {
+		t.Errorf("unexpected plaintext: %q", got)
+	}
+}
+
+// TestRotationOnOpen tests that a first write reaching the maximum size exactly starts a new file, as lumberjack does
+func TestRotationOnOpen(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	keys := staticKeys(t)
+	// a frame is 40 bytes larger than its plaintext with the key id k1
+	const overhead = 40
+	first := bytes.Repeat([]byte("a"), 1<<19)
+	second := bytes.Repeat([]byte("b"), 1<<20-len(first)-2*overhead)
+
+	w := newWriter(t, &lumberjack.Logger{Filename: name, MaxSize: 1}, encrypt.Config{Keys: keys, ChunkSize: 1 << 20})
+	w.Write(first)
+	w.Close()
+	w = newWriter(t, &lumberjack.Logger{Filename: name, MaxSize: 1}, encrypt.Config{Keys: keys, ChunkSize: 1 << 20})
+	w.Write(second)
+	w.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "app-*.log"))
+	if len(files) != 1 {
+		t.Fatalf("expected one rotated file, got %v", files)
+	}
+	for file, want := range map[string][]byte{files[0]: first, name: second} {
+		got, err := decrypt(t, file, keys)
+		if err != nil {
+			t.Fatalf("failed to decrypt %s: %v", file, err)
+		}
+		if got != string(want) {
+			t.Errorf("unexpected plaintext of %d bytes in %s", len(got), file)
+		}
+	}
+}
+
+// TestKeyRotation tests that a new key is used for every rotated file
+func TestKeyRotation(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "app.log")
+	keys := &rotatingKeys{}
+	w := newWriter(t, &lumberjack.Logger{Filename: name, MaxSize: 1}, encrypt.Config{Keys: keys})
+	line := []byte(strings.Repeat("x", 1023) + "\n")
+	for i := 0; i < 1500; i++ {
+		w.Write(line)
+	}
+	w.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "app*.log"))
+	if len(files) != 2 {
+		t.Fatalf("expected 2 files, got %v", files)
+	}
+	var total int
+	for _, f := range files {
+		reader := &rotatingKeys{}
+		got, err := decrypt(t, f, reader)
+		if err != nil {
+			t.Fatalf("failed to decrypt %s: %v", f, err)
+		}
+		if len(reader.requested) != 1 {
+			t.Errorf("expected a single key per file, got %v", reader.requested)
+		}
+		total += len(got)
+	}
+	if total != 1500*len(line) {
+		t.Errorf("expected %d bytes, got %d", 1500*len(line), total)
+	}
+	if keys.calls != 2 {
+		t.Errorf("expected one key per file, got %d calls", keys.calls)
+	}
+}
+
+// TestCompress tests that chunks are compressed before encryption and gzipped files are read
+func TestCompress(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	keys := staticKeys(t)
+	out := &lumberjack.Logger{Filename: name, Compress: true}
+	w := newWriter(t, out, encrypt.Config{Keys: keys})
+	if out.Compress {
+		t.Error("the rotated files should not be gzipped after encryption")
+	}
+	want := strings.Repeat("GET /orders 200\n", 10000)
+	w.Write([]byte(want))
+	w.Close()
+
+	raw, _ := os.ReadFile(name)
+	if len(raw) > len(want)/10 {
+		t.Errorf("expected compressed frames, got %d bytes for %d", len(raw), len(want))
+	}
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(raw)
+	zw.Close()
+	os.WriteFile(name, gz.Bytes(), 0o644)
+	got, err := decrypt(t, name, keys)
+	if err != nil || got != want {
+		t.Errorf("failed to read the gzipped file: %v", err)
+	}
+}
+
+// TestFlushInterval tests that buffered output is written after the flush interval
+func TestFlushInterval(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "app.log")
+	keys := staticKeys(t)
+	w := newWriter(t, &lumberjack.Logger{Filename: name}, encrypt.Config{Keys: keys, FlushInterval: 10 * time.Millisecond})
+	defer w.Close()
+	w.Write([]byte("pending\n"))
+
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		if _, err := os.Stat(name); err == nil {
+			if got, _ := decrypt(t, name, keys); got == "pending\n" {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("output was not flushed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestNewValidation tests configuration validation
+func TestNewValidation(t *testing.T) {
+	if _, err := encrypt.NewWriter(&lumberjack.Logger{Filename: filepath.Join(t.TempDir(), "a.log")}, encrypt.Config{}); err == nil {
+		t.Error("expected error for missing key provider")
+	}
+	if _, err := encrypt.NewStaticKeys("k1", map[string][]byte{"k1": []byte("short")}); err == nil {
+		t.Error("expected error for invalid key length")
+	}
+	if _, err := encrypt.NewStaticKeys("k3", map[string][]byte{"k1": key1}); err == nil {
+		t.Error("expected error for unknown current key")
+	}
+}
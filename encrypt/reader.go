@@ -0,0 +1,133 @@
+package encrypt
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrTruncated is returned by Reader after the plaintext of the complete
+// frames when the input ends inside a frame, as in a file that was being
+// written.
+var ErrTruncated = errors.New("encrypt: truncated frame")
+
+// Reader decrypts an encrypted log file.
+type Reader struct {
+	r     *bufio.Reader
+	keys  KeyProvider
+	aeads map[string]cipher.AEAD
+	seq   uint64 // sequence number of the next frame
+	buf   []byte
+	err   error
+}
+
+// NewReader returns a Reader of the plaintext of r, an encrypted log file.
+// A gzip-compressed file is decompressed first.
+func NewReader(r io.Reader, keys KeyProvider) *Reader {
+	return &Reader{r: bufio.NewReader(r), keys: keys, aeads: make(map[string]cipher.AEAD)}
+}
+
+// Read implements the io.Reader interface.
+func (r *Reader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.buf, r.err = r.next()
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// next decrypts the next frame.
+func (r *Reader) next() ([]byte, error) {
+	head, err := r.r.Peek(len(magic))
+	if err == io.EOF && len(head) == 0 {
+		return nil, io.EOF
+	}
+	if len(head) >= 2 && head[0] == 0x1f && head[1] == 0x8b {
+		zr, err := gzip.NewReader(r.r)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt: %w", err)
+		}
+		r.r = bufio.NewReader(zr)
+		return nil, nil
+	}
+
+	header := make([]byte, len(magic)+2, 64)
+	if _, err := io.ReadFull(r.r, header); err != nil {
+		return nil, truncated(err)
+	}
+	if string(header[:len(magic)]) != magic {
+		return nil, errors.New("encrypt: not an encrypted log file")
+	}
+	flags, idLen := header[len(magic)], int(header[len(magic)+1])
+	header = append(header, make([]byte, idLen+nonceSize+4)...)
+	if _, err := io.ReadFull(r.r, header[len(magic)+2:]); err != nil {
+		return nil, truncated(err)
+	}
+	id := string(header[len(magic)+2 : len(magic)+2+idLen])
+	nonce := header[len(magic)+2+idLen : len(magic)+2+idLen+nonceSize]
+	length := binary.BigEndian.Uint32(header[len(header)-4:])
+	if length > maxFrameSize {
+		return nil, fmt.Errorf("encrypt: frame of %d bytes is too large", length)
+	}
+	ciphertext := make([]byte, length)
+	if _, err := io.ReadFull(r.r, ciphertext); err != nil {
+		return nil, truncated(err)
+	}
+
+	aead, err := r.aead(id)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(ciphertext[:0], nonce, ciphertext, additionalData(header, r.seq))
+	if err != nil {
+		return nil, fmt.Errorf("encrypt: frame %d with key %q failed authentication", r.seq, id)
+	}
+	r.seq++
+	if flags&flagDeflate != 0 {
+		plaintext, err = io.ReadAll(flate.NewReader(bytes.NewReader(plaintext)))
+		if err != nil {
+			return nil, fmt.Errorf("encrypt: %w", err)
+		}
+	}
+	return plaintext, nil
+}
+
+// aead returns the cipher of the key id.
+func (r *Reader) aead(id string) (cipher.AEAD, error) {
+	if aead, ok := r.aeads[id]; ok {
+		return aead, nil
+	}
+	key, err := r.keys.Key(id)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt: key %q: %w", id, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("encrypt: %w", err)
+	}
+	r.aeads[id] = aead
+	return aead, nil
+}
+
+// truncated converts the end of the input inside a frame to ErrTruncated.
+func truncated(err error) error {
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return ErrTruncated
+	}
+	return fmt.Errorf("encrypt: %w", err)
+}
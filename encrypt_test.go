@@ -0,0 +1,139 @@
+package logx_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx"
+	"github.com/go4x/logx/encrypt"
+	"github.com/go4x/logx/sink/gelf"
+)
+
+// TestEncryption tests that both backends write encrypted log files that decrypt to the entries
+func TestEncryption(t *testing.T) {
+	keys, err := encrypt.NewStaticKeys("2024-06", map[string][]byte{"2024-06": bytes.Repeat([]byte{7}, 32)})
+	if err != nil {
+		t.Fatalf("failed to create keys: %v", err)
+	}
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:       typ,
+				Level:      "info",
+				Dir:        logDir,
+				Format:     "json",
+				Encryption: &encrypt.Config{Keys: keys, FlushInterval: 10 * time.Millisecond},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+			logx.Info("card issued to alice")
+
+			var plaintext string
+			waitFor(t, func() bool {
+				files, _ := filepath.Glob(filepath.Join(logDir, "*.log"))
+				if len(files) == 0 {
+					return false
+				}
+				f, err := os.Open(files[0])
+				if err != nil {
+					return false
+				}
+				defer f.Close()
+				data, _ := io.ReadAll(encrypt.NewReader(f, keys))
+				plaintext = string(data)
+				return strings.Contains(plaintext, "card issued to alice")
+			})
+			if content := readLogs(t, logDir); strings.Contains(content, "alice") {
+				t.Errorf("the log file contains plaintext: %q", content)
+			}
+			if !strings.Contains(plaintext, `"level"`) {
+				t.Errorf("expected a JSON entry, got %q", plaintext)
+			}
+		})
+	}
+}
+
+// TestEncryptionReinit tests that both backends stop the writers of the encrypted files of the previous logger on re-initialization
+func TestEncryptionReinit(t *testing.T) {
+	keys, err := encrypt.NewStaticKeys("2024-06", map[string][]byte{"2024-06": bytes.Repeat([]byte{7}, 32)})
+	if err != nil {
+		t.Fatalf("failed to create keys: %v", err)
+	}
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			cfg := &logx.LoggerConfig{
+				Type:       typ,
+				Level:      "info",
+				Dir:        logDir,
+				Format:     "json",
+				Encryption: &encrypt.Config{Keys: keys, FlushInterval: 10 * time.Millisecond},
+			}
+			if err := logx.Init(cfg); err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+			before := runtime.NumGoroutine()
+			for i := 0; i < 10; i++ {
+				if err := logx.Init(cfg); err != nil {
+					t.Fatalf("failed to reinitialize logger: %v", err)
+				}
+			}
+			waitFor(t, func() bool {
+				return runtime.NumGoroutine() <= before
+			})
+		})
+	}
+}
+
+// TestEncryptionReinitFailure tests that a failed re-initialization leaves the previous logger and its encrypted files working
+func TestEncryptionReinitFailure(t *testing.T) {
+	keys, err := encrypt.NewStaticKeys("2024-06", map[string][]byte{"2024-06": bytes.Repeat([]byte{7}, 32)})
+	if err != nil {
+		t.Fatalf("failed to create keys: %v", err)
+	}
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			cfg := &logx.LoggerConfig{
+				Type:       typ,
+				Level:      "info",
+				Dir:        logDir,
+				Format:     "json",
+				Encryption: &encrypt.Config{Keys: keys, FlushInterval: 10 * time.Millisecond},
+			}
+			if err := logx.Init(cfg); err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+			bad := *cfg
+			bad.GELF = &gelf.Config{Address: "localhost:12201", Compression: "lz4"}
+			if err := logx.Init(&bad); err == nil {
+				t.Fatal("expected error for an invalid sink")
+			}
+
+			logx.Info("still logging")
+			logx.Sync()
+			files, _ := filepath.Glob(filepath.Join(logDir, "*.log"))
+			var plaintext []byte
+			for _, name := range files {
+				f, err := os.Open(name)
+				if err != nil {
+					t.Fatalf("failed to open log file: %v", err)
+				}
+				data, _ := io.ReadAll(encrypt.NewReader(f, keys))
+				f.Close()
+				plaintext = append(plaintext, data...)
+			}
+			if !strings.Contains(string(plaintext), "still logging") {
+				t.Error("the previous logger should keep logging after a failed re-initialization")
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package logx
+
+import "github.com/go4x/logx/core"
+
+// Field is a key-value pair that can be passed in place of a key and a
+// value to the key-value pairs of Log, Span and the other structured
+// logging functions.
+type Field = core.Field
+
+// Err returns a Field that logs err under the key "error" as a nested
+// object: its message, its Go type, the stack trace it carries if it was
+// created by a package recording them (such as github.com/pkg/errors), and
+// the same for each error it wraps, under "causes", following errors.Unwrap
+// and errors.Join. The object is built only if the entry is written.
+//
+// Example:
+//
+//	logx.Log(ctx, logx.ErrorLevel, "checkout failed", logx.Err(err), "cart", cartID)
+//
+// which the JSON format writes as:
+//
+//	{"msg":"checkout failed","error":{"message":"charge: card declined","type":"*fmt.wrapError",
+//	 "causes":[{"message":"card declined","type":"*errors.errorString"}]},"cart":"c-42"}
+func Err(err error) Field {
+	return NamedErr("error", err)
+}
+
+// NamedErr is like Err with the given key, for entries with several errors.
+func NamedErr(key string, err error) Field {
+	if err == nil {
+		return Field{Key: key}
+	}
+	return Field{Key: key, Value: core.ErrorChain{Err: err}}
+}
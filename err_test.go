@@ -0,0 +1,152 @@
+package logx_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/go4x/logx"
+)
+
+// frame and stackError mimic the errors of github.com/pkg/errors
+type frame uintptr
+
+type stackError struct {
+	msg string
+	pcs []uintptr
+}
+
+func newStackError(msg string) *stackError {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(2, pcs)
+	return &stackError{msg: msg, pcs: pcs[:n]}
+}
+
+func (e *stackError) Error() string { return e.msg }
+
+func (e *stackError) StackTrace() []frame {
+	frames := make([]frame, len(e.pcs))
+	for i, pc := range e.pcs {
+		frames[i] = frame(pc)
+	}
+	return frames
+}
+
+// findEntry returns the JSON entry of content with the given message
+func findEntry(t *testing.T, content, msg string) map[string]any {
+	t.Helper()
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			continue
+		}
+		if entry["msg"] == msg {
+			return entry
+		}
+	}
+	t.Fatalf("no entry %q in %q", msg, content)
+	return nil
+}
+
+// TestErr tests that both backends log the chain, types and stack traces of an error as nested JSON
+func TestErr(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "info",
+				Dir:    logDir,
+				Format: "json",
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			joined := errors.Join(newStackError("card declined"), errors.New("stock exhausted"))
+			wrapped := fmt.Errorf("checkout: %w", joined)
+			logx.Log(context.Background(), logx.ErrorLevel, "checkout failed", logx.Err(wrapped), "cart", 7)
+			logx.Log(context.Background(), logx.InfoLevel, "no error", logx.Err(nil))
+
+			content := readLogs(t, logDir)
+			entry := findEntry(t, content, "checkout failed")
+			if entry["cart"] != float64(7) {
+				t.Errorf("expected the other fields to be kept, got %v", entry)
+			}
+			root, ok := entry["error"].(map[string]any)
+			if !ok {
+				t.Fatalf("expected a nested error object, got %v", entry["error"])
+			}
+			if root["message"] != wrapped.Error() || root["type"] != "*fmt.wrapError" {
+				t.Errorf("unexpected root error: %v", root)
+			}
+			causes, _ := root["causes"].([]any)
+			if len(causes) != 1 {
+				t.Fatalf("expected one cause, got %v", root["causes"])
+			}
+			join, _ := causes[0].(map[string]any)
+			if join["type"] != "*errors.joinError" {
+				t.Errorf("expected the joined error, got %v", join)
+			}
+			leaves, _ := join["causes"].([]any)
+			if len(leaves) != 2 {
+				t.Fatalf("expected two joined causes, got %v", join["causes"])
+			}
+			first, _ := leaves[0].(map[string]any)
+			if first["message"] != "card declined" || first["type"] != "*logx_test.stackError" {
+				t.Errorf("unexpected first cause: %v", first)
+			}
+			stack, _ := first["stack"].([]any)
+			if len(stack) == 0 || !strings.Contains(fmt.Sprint(stack[0]), "TestErr") || !strings.Contains(fmt.Sprint(stack[0]), "err_test.go:") {
+				t.Errorf("expected the stack trace of the error, got %v", first["stack"])
+			}
+			second, _ := leaves[1].(map[string]any)
+			if second["message"] != "stock exhausted" || second["stack"] != nil {
+				t.Errorf("unexpected second cause: %v", second)
+			}
+
+			if entry := findEntry(t, content, "no error"); entry["error"] != nil {
+				t.Errorf("expected a nil error to be logged as null, got %v", entry["error"])
+			}
+		})
+	}
+}
+
+// TestStacktraceLevel tests that both backends add the stack trace of the log site at or above the configured level
+func TestStacktraceLevel(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:            typ,
+				Level:           "info",
+				Dir:             logDir,
+				Format:          "json",
+				StacktraceLevel: "error",
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			logx.Warn("below")
+			logx.Error("above")
+
+			content := readLogs(t, logDir)
+			if entry := findEntry(t, content, "below"); entry["stacktrace"] != nil {
+				t.Errorf("expected no stack trace below error, got %v", entry["stacktrace"])
+			}
+			stack, _ := findEntry(t, content, "above")["stacktrace"].(string)
+			if !strings.Contains(stack, "logx_test.TestStacktraceLevel") || !strings.Contains(stack, "err_test.go:") {
+				t.Errorf("expected the stack trace of the log site, got %q", stack)
+			}
+		})
+	}
+
+	if err := logx.Init(&logx.LoggerConfig{Dir: t.TempDir(), StacktraceLevel: "loud"}); err == nil {
+		t.Error("expected an error for an invalid stack trace level")
+	}
+}
@@ -0,0 +1,205 @@
+// Package flight implements a flight recorder: entries below the level of
+// the logger are kept in memory instead of being discarded, and when an
+// error is logged, the entries recorded before it are written with it. This
+// gives the debug context of an error while running at info level.
+//
+// Entries are recorded in the Buffer carried by the context passed to the
+// logger (see NewContext), typically one per request, or in a global Buffer
+// otherwise. Every Buffer keeps the most recent entries within the limits of
+// the Config.
+//
+// Example usage:
+//
+//	config := &logx.LoggerConfig{
+//	    Level: "info",
+//	    // ...
+//	    FlightRecorder: &flight.Config{Entries: 200},
+//	}
+//
+//	ctx = logx.WithFlightRecorder(ctx)
+//	logx.Log(ctx, logx.DebugLevel, "cache miss", "key", key) // recorded
+//	logx.Log(ctx, logx.ErrorLevel, "query failed", "err", err) // written with the cache miss
+package flight
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go4x/logx/core"
+)
+
+// DumpMessage is the message of the entry written before the recorded
+// entries.
+const DumpMessage = "flight recorder: entries recorded before the error"
+
+// LevelKey is the key of the field holding the original level of the
+// recorded entries, which are written at the level of the error.
+const LevelKey = "flight_level"
+
+// Defaults of Config.
+const (
+	DefaultEntries  = 100
+	DefaultMaxBytes = 64 << 10
+)
+
+// Config holds the configuration of the flight recorder.
+type Config struct {
+	// Level is the lowest level recorded (default debug). Entries at or
+	// above the level of the logger are written as usual.
+	Level string `mapstructure:"level" yaml:"level"`
+
+	// TriggerLevel is the level of the entries that write the recorded
+	// entries (default error).
+	TriggerLevel string `mapstructure:"trigger-level" yaml:"trigger-level"`
+
+	// Entries is the maximum number of entries kept by a buffer (default
+	// 100). The oldest entries are evicted first.
+	Entries int `mapstructure:"entries" yaml:"entries"`
+
+	// MaxBytes is the maximum estimated size of the entries kept by a
+	// buffer (default 64KB).
+	MaxBytes int `mapstructure:"max-bytes" yaml:"max-bytes"`
+}
+
+// Record is a recorded entry.
+type Record struct {
+	Level   core.Level
+	Time    time.Time
+	Message string
+	// Data holds what the backend needs to write the entry later.
+	Data any
+	// Size is the estimated size of the entry in bytes.
+	Size int
+}
+
+// Buffer holds the entries recorded in a scope. The zero value is ready to
+// use, and a Buffer is safe for concurrent use.
+type Buffer struct {
+	mu      sync.Mutex
+	records []Record // ring of at most Config.Entries records
+	head    int      // index of the oldest record
+	n       int
+	bytes   int
+	evicted int
+}
+
+// add records rec, evicting the oldest records beyond the limits.
+func (b *Buffer) add(rec Record, entries, maxBytes int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if rec.Size > maxBytes {
+		b.evicted++
+		return
+	}
+	if b.records == nil {
+		b.records = make([]Record, entries)
+	}
+	for b.n == len(b.records) || b.n > 0 && b.bytes+rec.Size > maxBytes {
+		b.bytes -= b.records[b.head].Size
+		b.records[b.head] = Record{}
+		b.head = (b.head + 1) % len(b.records)
+		b.n--
+		b.evicted++
+	}
+	b.records[(b.head+b.n)%len(b.records)] = rec
+	b.n++
+	b.bytes += rec.Size
+}
+
+// drain removes and returns the records, oldest first, and the number of
+// records evicted since the last drain.
+func (b *Buffer) drain() ([]Record, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := make([]Record, b.n)
+	for i := range out {
+		j := (b.head + i) % len(b.records)
+		out[i] = b.records[j]
+		b.records[j] = Record{}
+	}
+	evicted := b.evicted
+	b.head, b.n, b.bytes, b.evicted = 0, 0, 0, 0
+	return out, evicted
+}
+
+type bufferKey struct{}
+
+// NewContext returns a copy of ctx that carries a new Buffer, so that the
+// entries logged with it are recorded apart from other contexts.
+func NewContext(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bufferKey{}, &Buffer{})
+}
+
+// FromContext returns the Buffer carried by ctx, or nil.
+func FromContext(ctx context.Context) *Buffer {
+	if ctx == nil {
+		return nil
+	}
+	b, _ := ctx.Value(bufferKey{}).(*Buffer)
+	return b
+}
+
+// Recorder records entries into buffers. It is safe for concurrent use.
+type Recorder struct {
+	level    core.Level
+	trigger  core.Level
+	entries  int
+	maxBytes int
+	global   Buffer
+}
+
+// New returns a Recorder with the given configuration.
+func New(c Config) (*Recorder, error) {
+	r := &Recorder{level: core.DebugLevel, trigger: core.ErrorLevel, entries: c.Entries, maxBytes: c.MaxBytes}
+	var err error
+	if c.Level != "" {
+		if r.level, err = core.ParseLevel(c.Level); err != nil {
+			return nil, fmt.Errorf("flight: %w", err)
+		}
+	}
+	if c.TriggerLevel != "" {
+		if r.trigger, err = core.ParseLevel(c.TriggerLevel); err != nil {
+			return nil, fmt.Errorf("flight: %w", err)
+		}
+	}
+	if r.entries < 0 || r.maxBytes < 0 {
+		return nil, fmt.Errorf("flight: entries and max bytes must not be negative")
+	}
+	if r.entries == 0 {
+		r.entries = DefaultEntries
+	}
+	if r.maxBytes == 0 {
+		r.maxBytes = DefaultMaxBytes
+	}
+	return r, nil
+}
+
+// Level returns the lowest level recorded.
+func (r *Recorder) Level() core.Level {
+	return r.level
+}
+
+// Trigger returns the level of the entries that write the recorded entries.
+func (r *Recorder) Trigger() core.Level {
+	return r.trigger
+}
+
+// Record records rec in b, or in the global buffer if b is nil.
+func (r *Recorder) Record(b *Buffer, rec Record) {
+	if b == nil {
+		b = &r.global
+	}
+	b.add(rec, r.entries, r.maxBytes)
+}
+
+// Dump removes and returns the records of b, or of the global buffer if b
+// is nil, oldest first, and the number of records evicted from it since the
+// last dump.
+func (r *Recorder) Dump(b *Buffer) ([]Record, int) {
+	if b == nil {
+		b = &r.global
+	}
+	return b.drain()
+}
@@ -0,0 +1,83 @@
+package flight_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/flight"
+)
+
+func newRecorder(t *testing.T, c flight.Config) *flight.Recorder {
+	t.Helper()
+	r, err := flight.New(c)
+	if err != nil {
+		t.Fatalf("failed to create recorder: %v", err)
+	}
+	return r
+}
+
+func record(r *flight.Recorder, b *flight.Buffer, msg string, size int) {
+	r.Record(b, flight.Record{Level: core.DebugLevel, Message: msg, Size: size})
+}
+
+// TestRecordEntries tests that a buffer keeps the most recent entries up to its entry limit
+func TestRecordEntries(t *testing.T) {
+	r := newRecorder(t, flight.Config{Entries: 3})
+	for i := 0; i < 5; i++ {
+		record(r, nil, fmt.Sprint(i), 10)
+	}
+	records, evicted := r.Dump(nil)
+	if len(records) != 3 || records[0].Message != "2" || records[2].Message != "4" || evicted != 2 {
+		t.Errorf("unexpected dump: %v, %d evicted", records, evicted)
+	}
+	if records, _ := r.Dump(nil); len(records) != 0 {
+		t.Errorf("expected an empty buffer after the dump, got %v", records)
+	}
+}
+
+// TestRecordBytes tests that a buffer keeps its entries within the size limit
+func TestRecordBytes(t *testing.T) {
+	r := newRecorder(t, flight.Config{MaxBytes: 100})
+	for i := 0; i < 5; i++ {
+		record(r, nil, fmt.Sprint(i), 40)
+	}
+	record(r, nil, "huge", 200)
+	records, evicted := r.Dump(nil)
+	if len(records) != 2 || records[0].Message != "3" || evicted != 4 {
+		t.Errorf("unexpected dump: %v, %d evicted", records, evicted)
+	}
+}
+
+// TestContextBuffers tests that the buffers of contexts are separate from the global buffer
+func TestContextBuffers(t *testing.T) {
+	r := newRecorder(t, flight.Config{})
+	ctx := flight.NewContext(context.Background())
+	b := flight.FromContext(ctx)
+	if b == nil || flight.FromContext(context.Background()) != nil {
+		t.Fatal("expected a buffer in the new context only")
+	}
+	record(r, b, "request", 10)
+	record(r, nil, "global", 10)
+	if records, _ := r.Dump(b); len(records) != 1 || records[0].Message != "request" {
+		t.Errorf("unexpected context dump: %v", records)
+	}
+	if records, _ := r.Dump(nil); len(records) != 1 || records[0].Message != "global" {
+		t.Errorf("unexpected global dump: %v", records)
+	}
+}
+
+// TestNewValidation tests configuration validation and defaults
+func TestNewValidation(t *testing.T) {
+	r := newRecorder(t, flight.Config{})
+	if r.Level() != core.DebugLevel || r.Trigger() != core.ErrorLevel {
+		t.Errorf("unexpected defaults: %v, %v", r.Level(), r.Trigger())
+	}
+	if _, err := flight.New(flight.Config{TriggerLevel: "loud"}); err == nil {
+		t.Error("expected error for unknown level")
+	}
+	if _, err := flight.New(flight.Config{Entries: -1}); err == nil {
+		t.Error("expected error for negative entries")
+	}
+}
@@ -0,0 +1,76 @@
+package logx_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go4x/logx"
+	"github.com/go4x/logx/flight"
+)
+
+// TestFlightRecorder tests that both backends write the debug entries of a context when an error is logged with it
+func TestFlightRecorder(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:           typ,
+				Level:          "info",
+				Dir:            logDir,
+				Format:         "json",
+				FlightRecorder: &flight.Config{Entries: 2},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			failing := logx.WithFlightRecorder(context.Background())
+			other := logx.WithFlightRecorder(context.Background())
+			logx.Log(failing, logx.DebugLevel, "evicted step")
+			logx.Log(failing, logx.DebugLevel, "cache miss", "key", "user:42")
+			logx.Log(other, logx.DebugLevel, "unrelated step")
+			logx.Log(failing, logx.DebugLevel, "retrying query")
+			logx.Log(failing, logx.InfoLevel, "request received")
+
+			if content := readLogs(t, logDir); strings.Contains(content, "cache miss") {
+				t.Fatalf("debug entries must not be written before an error: %q", content)
+			}
+
+			logx.Log(failing, logx.ErrorLevel, "query failed")
+			content := readLogs(t, logDir)
+			for _, want := range []string{flight.DumpMessage, `"recorded":2`, `"evicted":1`, "cache miss", `"user:42"`, "retrying query", `"flight_level":"debug"`, "query failed"} {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %s in output, got %q", want, content)
+				}
+			}
+			if strings.Contains(content, "unrelated step") || strings.Contains(content, "evicted step") {
+				t.Errorf("unexpected recorded entries in output: %q", content)
+			}
+			if strings.Index(content, "retrying query") > strings.Index(content, "query failed") {
+				t.Errorf("the recorded entries must precede the error: %q", content)
+			}
+		})
+	}
+}
+
+// TestFlightRecorderGlobal tests that entries logged without a recorder context are recorded globally
+func TestFlightRecorderGlobal(t *testing.T) {
+	logDir := t.TempDir()
+	err := logx.Init(&logx.LoggerConfig{
+		Level:          "warn",
+		Dir:            logDir,
+		Format:         "json",
+		FlightRecorder: &flight.Config{Level: "info"},
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+	logx.Debug("not recorded")
+	logx.Info("connection pool exhausted")
+	logx.Error("request failed")
+	content := readLogs(t, logDir)
+	if !strings.Contains(content, "connection pool exhausted") || strings.Contains(content, "not recorded") {
+		t.Errorf("unexpected output: %q", content)
+	}
+}
@@ -6,7 +6,11 @@ require gopkg.in/natefinch/lumberjack.v2 v2.2.1
 
 require (
 	github.com/stretchr/testify v1.10.0 // indirect
+	go.opentelemetry.io/otel v1.37.0 // indirect
 	go.uber.org/multierr v1.10.0 // indirect
 )
 
-require go.uber.org/zap v1.27.0
+require (
+	go.opentelemetry.io/otel/trace v1.37.0
+	go.uber.org/zap v1.27.0
+)
@@ -0,0 +1,165 @@
+package logx_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go4x/logx"
+)
+
+// TestWithLevel tests that both backends write the debug entries of a context carrying the debug level
+func TestWithLevel(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "info",
+				Dir:    logDir,
+				Format: "json",
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			ctx := logx.WithLevel(context.Background(), logx.DebugLevel)
+			logx.Log(ctx, logx.DebugLevel, "request payload", "size", 42)
+			logx.FromContext(ctx).Debug("cache lookup")
+			logx.Log(context.Background(), logx.DebugLevel, "other request")
+			logx.Debug("global debug")
+			logx.Log(ctx, logx.InfoLevel, "request done")
+
+			content := readLogs(t, logDir)
+			for _, want := range []string{"request payload", `"size":42`, "cache lookup", "request done"} {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %s in output, got %q", want, content)
+				}
+			}
+			if strings.Contains(content, "other request") || strings.Contains(content, "global debug") {
+				t.Errorf("debug entries of other contexts must not be written: %q", content)
+			}
+		})
+	}
+}
+
+// TestWithLevelHigher tests that a context level above the configured level does not filter entries
+func TestWithLevelHigher(t *testing.T) {
+	logDir := t.TempDir()
+	if err := logx.Init(&logx.LoggerConfig{Level: "info", Dir: logDir, Format: "json"}); err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+	ctx := logx.WithLevel(context.Background(), logx.ErrorLevel)
+	logx.Log(ctx, logx.InfoLevel, "still written")
+	if content := readLogs(t, logDir); !strings.Contains(content, "still written") {
+		t.Errorf("expected the entry, got %q", content)
+	}
+}
+
+// TestDebugMiddleware tests that the debug header lowers the level of the request context
+func TestDebugMiddleware(t *testing.T) {
+	logDir := t.TempDir()
+	if err := logx.Init(&logx.LoggerConfig{Type: logx.LoggerTypeSlog, Level: "info", Dir: logDir, Format: "json"}); err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+	handler := logx.DebugMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logx.Log(r.Context(), logx.DebugLevel, "handling "+r.URL.Path)
+	}))
+	for _, path := range []string{"/debug", "/plain"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if path == "/debug" {
+			req.Header.Set(logx.DebugHeader, "1")
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	content := readLogs(t, logDir)
+	if !strings.Contains(content, "handling /debug") || strings.Contains(content, "handling /plain") {
+		t.Errorf("unexpected output: %q", content)
+	}
+}
+
+// TestExtendedLevels tests that both backends write the extended and registered levels with the same names
+func TestExtendedLevels(t *testing.T) {
+	const auditLevel logx.Level = 6
+	if err := logx.RegisterLevel("audit", auditLevel); err != nil {
+		t.Fatalf("failed to register the level: %v", err)
+	}
+	if err := logx.RegisterLevel("audit", logx.WarnLevel); err == nil {
+		t.Error("expected an error for a name registered for another level")
+	}
+
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "trace",
+				Dir:    logDir,
+				Format: "json",
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			logx.Trace("trace entry")
+			logx.Noticef("notice %s", "entry")
+			logx.Critical("critical entry")
+			logx.Log(context.Background(), auditLevel, "audit entry")
+			logx.Log(context.Background(), logx.DebugLevel-1, "verbose entry")
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Error("expected Panic to panic")
+					}
+				}()
+				logx.Panic("panic entry")
+			}()
+
+			content := readLogs(t, logDir)
+			for _, want := range []string{
+				`"level":"TRACE","`, `"level":"NOTICE","`, `"level":"CRITICAL","`,
+				`"level":"AUDIT","`, `"level":"DEBUG-1","`, `"level":"PANIC","`,
+				"trace entry", "notice entry", "critical entry", "audit entry", "verbose entry", "panic entry",
+			} {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %s in output, got %q", want, content)
+				}
+			}
+		})
+	}
+}
+
+// TestExtendedLevelFilter tests that both backends filter the entries below an extended configured level
+func TestExtendedLevelFilter(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "notice",
+				Dir:    logDir,
+				Format: "json",
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			logx.Info("info entry")
+			logx.Notice("notice entry")
+			logx.Error("error entry")
+			logx.Log(logx.WithLevel(context.Background(), logx.InfoLevel), logx.InfoLevel, "request entry")
+
+			content := readLogs(t, logDir)
+			for _, want := range []string{"notice entry", "error entry", "request entry"} {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %s in output, got %q", want, content)
+				}
+			}
+			if strings.Contains(content, "info entry") {
+				t.Errorf("unexpected info entry: %q", content)
+			}
+		})
+	}
+}
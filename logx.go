@@ -19,10 +19,30 @@
 package logx
 
 import (
+	"context"
 	"errors"
 	"fmt"
-
+	"io"
+	"time"
+
+	"github.com/go4x/logx/audit"
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/dedup"
+	"github.com/go4x/logx/encoder"
+	"github.com/go4x/logx/encrypt"
+	"github.com/go4x/logx/flight"
+	"github.com/go4x/logx/redact"
+	"github.com/go4x/logx/sample"
+	"github.com/go4x/logx/sanitize"
+	"github.com/go4x/logx/sink/elasticsearch"
+	"github.com/go4x/logx/sink/fluent"
+	"github.com/go4x/logx/sink/gelf"
+	"github.com/go4x/logx/sink/loki"
+	"github.com/go4x/logx/sink/otlp"
+	"github.com/go4x/logx/sink/splunk"
+	"github.com/go4x/logx/sink/syslog"
 	"github.com/go4x/logx/slog"
+	"github.com/go4x/logx/trace"
 	"github.com/go4x/logx/zap"
 )
 
@@ -49,6 +69,10 @@ const (
 // Logger defines the interface for logging operations.
 // All logger implementations must satisfy this interface.
 type Logger interface {
+	// Trace logs a trace message.
+	Trace(args ...any)
+	// Tracef logs a formatted trace message.
+	Tracef(template string, args ...any)
 	// Debug logs a debug message.
 	Debug(args ...any)
 	// Debugf logs a formatted debug message.
@@ -57,6 +81,10 @@ type Logger interface {
 	Info(args ...any)
 	// Infof logs a formatted informational message.
 	Infof(template string, args ...any)
+	// Notice logs a notice message.
+	Notice(args ...any)
+	// Noticef logs a formatted notice message.
+	Noticef(template string, args ...any)
 	// Warn logs a warning message.
 	Warn(args ...any)
 	// Warnf logs a formatted warning message.
@@ -65,10 +93,64 @@ type Logger interface {
 	Error(args ...any)
 	// Errorf logs a formatted error message.
 	Errorf(template string, args ...any)
+	// Critical logs a critical message.
+	Critical(args ...any)
+	// Criticalf logs a formatted critical message.
+	Criticalf(template string, args ...any)
+	// Panic logs a panic message and panics.
+	Panic(args ...any)
+	// Panicf logs a formatted panic message and panics.
+	Panicf(template string, args ...any)
 	// Fatal logs a fatal message and exits the program.
 	Fatal(args ...any)
 	// Fatalf logs a formatted fatal message and exits the program.
 	Fatalf(template string, args ...any)
+	// Log logs a message at the given level with the fields carried by ctx
+	// followed by the given key-value pairs.
+	Log(ctx context.Context, level Level, msg string, keysAndValues ...any)
+}
+
+// Level is a logging priority. Higher levels are more important.
+type Level = core.Level
+
+const (
+	// TraceLevel logs are finer-grained than debug logs.
+	TraceLevel = core.TraceLevel
+	// DebugLevel logs are typically voluminous and usually disabled in production.
+	DebugLevel = core.DebugLevel
+	// InfoLevel is the default logging priority.
+	InfoLevel = core.InfoLevel
+	// NoticeLevel logs are normal but significant events.
+	NoticeLevel = core.NoticeLevel
+	// WarnLevel logs are more important than Info but don't need individual review.
+	WarnLevel = core.WarnLevel
+	// ErrorLevel logs are high-priority and should be looked at.
+	ErrorLevel = core.ErrorLevel
+	// CriticalLevel logs are errors that need immediate attention.
+	CriticalLevel = core.CriticalLevel
+	// PanicLevel logs a message and then panics.
+	PanicLevel = core.PanicLevel
+	// FatalLevel logs a message and then the program exits.
+	FatalLevel = core.FatalLevel
+)
+
+// ParseLevel converts a level name (trace, debug, info, notice, warn, error,
+// critical, panic, fatal or a name registered with RegisterLevel) to a Level.
+func ParseLevel(s string) (Level, error) {
+	return core.ParseLevel(s)
+}
+
+// RegisterLevel registers a custom level name, which both backends write
+// for the level, and which the level settings of the configuration accept.
+// It is meant to be called at initialization, before Init.
+//
+// Example:
+//
+//	const AuditLevel logx.Level = 6
+//	_ = logx.RegisterLevel("audit", AuditLevel)
+//	logx.Log(ctx, AuditLevel, "user created")
+func RegisterLevel(name string, level Level) error {
+	return core.RegisterLevel(name, level)
 }
 
 // LoggerConfig holds the configuration for the logger.
@@ -76,7 +158,8 @@ type LoggerConfig struct {
 	// Type specifies the logger backend type (slog or zap).
 	Type LoggerType `mapstructure:"type" yaml:"type"`
 
-	// Level specifies the minimum log level (debug, info, warn, error, fatal).
+	// Level specifies the minimum log level (trace, debug, info, notice,
+	// warn, error, critical, panic, fatal or a registered level name).
 	Level string `mapstructure:"level" yaml:"level"`
 
 	// LogInConsole determines whether to output logs to console.
@@ -88,6 +171,20 @@ type LoggerConfig struct {
 	// Format specifies the log output format (text or json).
 	Format string `mapstructure:"format" yaml:"format"`
 
+	// DurationFormat specifies how durations are encoded by both backends:
+	// seconds (default), millis, nanos or string.
+	DurationFormat string `mapstructure:"duration-format" yaml:"duration-format"`
+
+	// Encoder sets the keys, the time format, the level encoding and the
+	// caller format of the entries, with which both backends write the same
+	// JSON entries (nil keeps the defaults of each backend).
+	Encoder *encoder.Config `mapstructure:"encoder" yaml:"encoder"`
+
+	// StacktraceLevel, if set, adds the stack trace of the log site to the
+	// entries at or above this level (such as error), under the key
+	// stacktrace, with both backends.
+	StacktraceLevel string `mapstructure:"stacktrace-level" yaml:"stacktrace-level"`
+
 	// MaxAge specifies the maximum number of days to retain log files (0 means no limit).
 	MaxAge int `mapstructure:"max-age" yaml:"max-age"`
 
@@ -108,11 +205,109 @@ type LoggerConfig struct {
 
 	// FlushInterval specifies the interval in seconds to flush the buffer.
 	FlushInterval int `mapstructure:"flush-interval" yaml:"flush-interval"`
+
+	// Encryption encrypts the log files with AES-GCM, with keys supplied by
+	// a key provider (nil disables). The console output is not encrypted.
+	Encryption *encrypt.Config `mapstructure:"encryption" yaml:"encryption"`
+
+	// Sanitize protects the text format against log injection: control
+	// characters, CR/LF and terminal escapes are escaped, and messages,
+	// fields and entries are truncated with a visible marker. It is on by
+	// default; the JSON format is not affected.
+	Sanitize sanitize.Config `mapstructure:"sanitize" yaml:"sanitize"`
+
+	// Trace configures the trace correlation fields added by the
+	// context-aware API (trace_id, span_id and trace_flags by default).
+	Trace trace.Config `mapstructure:"trace" yaml:"trace"`
+
+	// OTLP enables exporting entries as OpenTelemetry LogRecords (nil disables).
+	OTLP *otlp.Config `mapstructure:"otlp" yaml:"otlp"`
+
+	// Syslog enables sending entries to a syslog server (nil disables).
+	Syslog *syslog.Config `mapstructure:"syslog" yaml:"syslog"`
+
+	// Loki enables pushing entries to Grafana Loki (nil disables).
+	Loki *loki.Config `mapstructure:"loki" yaml:"loki"`
+
+	// Elasticsearch enables indexing entries into Elasticsearch or
+	// OpenSearch with the _bulk API (nil disables).
+	Elasticsearch *elasticsearch.Config `mapstructure:"elasticsearch" yaml:"elasticsearch"`
+
+	// Splunk enables sending entries to the Splunk HTTP Event Collector (nil disables).
+	Splunk *splunk.Config `mapstructure:"splunk" yaml:"splunk"`
+
+	// GELF enables sending entries to Graylog in the GELF format (nil disables).
+	GELF *gelf.Config `mapstructure:"gelf" yaml:"gelf"`
+
+	// Fluent enables forwarding entries to Fluentd or Fluent Bit (nil disables).
+	Fluent *fluent.Config `mapstructure:"fluent" yaml:"fluent"`
+
+	// Redact scrubs passwords, tokens, card numbers and other sensitive data
+	// from messages and fields before they are written to any output (nil
+	// disables).
+	Redact *redact.Config `mapstructure:"redact" yaml:"redact"`
+
+	// Sampling samples and rate limits repetitive entries before they are
+	// written to any output, and reports the entries dropped in a periodic
+	// summary entry (nil disables). Audit entries are never sampled.
+	Sampling *sample.Config `mapstructure:"sampling" yaml:"sampling"`
+
+	// Dedup collapses identical consecutive entries (same level, message
+	// and fields) within a window into the first occurrence and a "last
+	// message repeated N times" summary (nil disables).
+	Dedup *dedup.Config `mapstructure:"dedup" yaml:"dedup"`
+
+	// FlightRecorder keeps the entries below Level in memory, per context
+	// (see WithFlightRecorder) or globally, and writes them when an error
+	// is logged, so that the debug context of errors is available while
+	// running at a higher level (nil disables).
+	FlightRecorder *flight.Config `mapstructure:"flight-recorder" yaml:"flight-recorder"`
+
+	// ModuleLevels sets the level of the call sites in the packages or
+	// files matching glob patterns, such as "github.com/acme/payments/*" or
+	// "*/db/*.go", in place of Level. It applies to the package-level
+	// functions, Log and the loggers returned by FromContext, and can be
+	// changed at runtime with SetModuleLevels.
+	ModuleLevels map[string]string `mapstructure:"module-levels" yaml:"module-levels"`
+
+	// Verbosity is the global verbosity of V: V(n) writes its entries if n
+	// is at most Verbosity. It can be changed at runtime with SetVerbosity.
+	Verbosity int `mapstructure:"verbosity" yaml:"verbosity"`
+
+	// ModuleVerbosity sets the verbosity of V for the call sites in the
+	// packages or files matching glob patterns, with the syntax of
+	// ModuleLevels, in place of Verbosity. It can be changed at runtime with
+	// SetModuleVerbosity.
+	ModuleVerbosity map[string]int `mapstructure:"module-verbosity" yaml:"module-verbosity"`
+
+	// SlowThreshold is the duration above which Span.End logs at warn level
+	// (0 disables). It can be changed per span with Span.Threshold.
+	SlowThreshold time.Duration `mapstructure:"slow-threshold" yaml:"slow-threshold"`
+
+	// Audit enables the tamper-evident audit log written by Audit (nil
+	// disables).
+	Audit *audit.Config `mapstructure:"audit" yaml:"audit"`
+}
+
+// stages holds the processing stages built from the configuration by Init
+// and shared by both backends.
+type stages struct {
+	sinks    []core.Sink
+	redactor *redact.Redactor
+	sampler  *sample.Sampler
+	deduper  *dedup.Deduper
+	recorder *flight.Recorder
 }
 
 // globalLogger is the global logger instance.
 var globalLogger Logger
 
+// globalSampler is the sampler of the global logger, if sampling is enabled.
+var globalSampler *sample.Sampler
+
+// globalDeduper is the deduper of the global logger, if enabled.
+var globalDeduper *dedup.Deduper
+
 // Init initializes the logger according to the configuration.
 // It returns an error if the configuration is invalid or initialization fails.
 func Init(c *LoggerConfig) error {
@@ -129,14 +324,131 @@ func Init(c *LoggerConfig) error {
 		c.Dir = "logs"
 	}
 
-	switch c.Type {
-	case LoggerTypeSlog:
-		return initSlogLogger(c)
-	case LoggerTypeZap:
-		return initZapLogger(c)
-	default:
+	if c.Type != LoggerTypeSlog && c.Type != LoggerTypeZap {
 		return fmt.Errorf("unsupported logger type: %s", c.Type)
 	}
+
+	moduleLevels, err := parseModuleLevels(c.ModuleLevels)
+	if err != nil {
+		return err
+	}
+	if c.Encoder != nil {
+		if err := c.Encoder.Validate(); err != nil {
+			return err
+		}
+	}
+	if c.StacktraceLevel != "" {
+		if _, err := ParseLevel(c.StacktraceLevel); err != nil {
+			return fmt.Errorf("stacktrace level: %w", err)
+		}
+	}
+	switch c.DurationFormat {
+	case "", "seconds", "millis", "nanos", "string":
+	default:
+		return fmt.Errorf("unsupported duration format: %s", c.DurationFormat)
+	}
+
+	var st stages
+	if c.Redact != nil {
+		r, err := redact.New(*c.Redact)
+		if err != nil {
+			return err
+		}
+		st.redactor = r
+	}
+	if c.FlightRecorder != nil {
+		r, err := flight.New(*c.FlightRecorder)
+		if err != nil {
+			return err
+		}
+		st.recorder = r
+	}
+	if c.Sampling != nil {
+		s, err := sample.New(*c.Sampling)
+		if err != nil {
+			return err
+		}
+		st.sampler = s
+	}
+	if c.Dedup != nil {
+		d, err := dedup.New(*c.Dedup)
+		if err != nil {
+			st.close()
+			return err
+		}
+		st.deduper = d
+	}
+
+	// the new logger is built while the previous one keeps running, so that
+	// a failure leaves the previous one in place
+	st.sinks, err = newSinks(c)
+	if err != nil {
+		st.close()
+		return err
+	}
+	var logger Logger
+	if c.Type == LoggerTypeSlog {
+		logger, err = newSlogLogger(c, st)
+	} else {
+		logger, err = newZapLogger(c, st)
+	}
+	if err != nil {
+		st.close()
+		closeSinks(st.sinks)
+		return err
+	}
+
+	// the hash chain of the audit log is continued by one writer at a time,
+	// so the previous audit log is closed before the new one is opened
+	if globalAudit != nil {
+		_ = globalAudit.Close()
+		globalAudit = nil
+	}
+	auditLogger, err := newAudit(c)
+	if err != nil {
+		if cl, ok := logger.(io.Closer); ok {
+			_ = cl.Close()
+		}
+		st.close()
+		closeSinks(st.sinks)
+		return err
+	}
+
+	oldLogger, oldSinks := globalLogger, globalSinks
+	oldSampler, oldDeduper := globalSampler, globalDeduper
+	globalLogger = logger
+	globalSinks = st.sinks
+	globalAudit = auditLogger
+	globalSampler = st.sampler
+	globalDeduper = st.deduper
+	globalModules.Set(moduleLevels)
+	SetVerbosity(c.Verbosity)
+	SetModuleVerbosity(c.ModuleVerbosity)
+	globalSlowThreshold.Store(int64(c.SlowThreshold))
+
+	// the deduper and the sampler of the previous logger write their last
+	// summaries before its files and sinks are flushed and closed
+	if oldDeduper != nil {
+		_ = oldDeduper.Close()
+	}
+	if oldSampler != nil {
+		_ = oldSampler.Close()
+	}
+	if cl, ok := oldLogger.(io.Closer); ok {
+		_ = cl.Close()
+	}
+	closeSinks(oldSinks)
+	return nil
+}
+
+// close closes the deduper and the sampler of a logger that failed to be built.
+func (st stages) close() {
+	if st.deduper != nil {
+		_ = st.deduper.Close()
+	}
+	if st.sampler != nil {
+		_ = st.sampler.Close()
+	}
 }
 
 // GetLogger returns the global logger instance.
@@ -144,73 +456,111 @@ func GetLogger() Logger {
 	return globalLogger
 }
 
-// initSlogLogger initializes the slog logger with the given configuration.
-func initSlogLogger(c *LoggerConfig) error {
+// Sync flushes the buffered output of the global logger and its sinks. It
+// should be called before the program exits.
+func Sync() error {
+	if s, ok := globalLogger.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
+// newSlogLogger creates the slog logger with the given configuration.
+func newSlogLogger(c *LoggerConfig, st stages) (Logger, error) {
 	// convert LoggerConfig to SlogConfig
 	slogConfig := &slog.SlogConfig{
-		Level:         c.Level,
-		LogInFile:     c.Dir != "", // Enable file logging if directory is specified
-		LogInConsole:  c.LogInConsole,
-		Dir:           c.Dir,
-		Format:        c.Format,
-		MaxAge:        c.MaxAge,
-		MaxSize:       c.MaxSize,
-		MaxBackups:    c.MaxBackups,
-		LocalTime:     c.LocalTime,
-		Compress:      c.Compress,
-		BufferSize:    c.BufferSize,    // Use value from configuration
-		FlushInterval: c.FlushInterval, // Use value from configuration
+		Level:           c.Level,
+		LogInFile:       c.Dir != "", // Enable file logging if directory is specified
+		LogInConsole:    c.LogInConsole,
+		Dir:             c.Dir,
+		Format:          c.Format,
+		DurationFormat:  c.DurationFormat,
+		AddSource:       true,
+		Encoder:         c.Encoder,
+		StacktraceLevel: c.StacktraceLevel,
+		StacktraceKey:   "stacktrace",
+		MaxAge:          c.MaxAge,
+		MaxSize:         c.MaxSize,
+		MaxBackups:      c.MaxBackups,
+		LocalTime:       c.LocalTime,
+		Compress:        c.Compress,
+		BufferSize:      c.BufferSize,    // Use value from configuration
+		FlushInterval:   c.FlushInterval, // Use value from configuration
+		ContextFields:   c.Trace.Extractor(),
+		Sinks:           st.sinks,
+		Encryption:      c.Encryption,
+		Sanitize:        c.Sanitize,
+		Redactor:        st.redactor,
+		Sampler:         st.sampler,
+		Deduper:         st.deduper,
+		Recorder:        st.recorder,
 	}
 
 	// create the slog logger
-	logger, err := slog.NewLog(slogConfig)
-	if err != nil {
-		return err
-	}
-	globalLogger = logger
-	return nil
+	return slog.NewLog(slogConfig)
 }
 
-// initZapLogger initializes the zap logger with the given configuration.
-func initZapLogger(c *LoggerConfig) error {
+// newZapLogger creates the zap logger with the given configuration.
+func newZapLogger(c *LoggerConfig, st stages) (Logger, error) {
 	// convert LoggerConfig to ZapConfig
 	zapConfig := &zap.ZapConfig{
-		Level:         c.Level,
-		Format:        c.Format,
-		Director:      c.Dir,
-		MaxAge:        c.MaxAge,
-		MaxSize:       c.MaxSize,
-		MaxBackups:    c.MaxBackups,
-		LogInConsole:  c.LogInConsole,
-		ShowCaller:    true,
-		LocalTime:     c.LocalTime,
-		Compress:      c.Compress,
-		StacktraceKey: "stacktrace",
-		BufferSize:    c.BufferSize,    // Use value from configuration
-		FlushInterval: c.FlushInterval, // Use value from configuration
+		Level:           c.Level,
+		Format:          c.Format,
+		DurationFormat:  c.DurationFormat,
+		Director:        c.Dir,
+		MaxAge:          c.MaxAge,
+		MaxSize:         c.MaxSize,
+		MaxBackups:      c.MaxBackups,
+		LogInConsole:    c.LogInConsole,
+		ShowCaller:      true,
+		LocalTime:       c.LocalTime,
+		Compress:        c.Compress,
+		Encoder:         c.Encoder,
+		StacktraceLevel: c.StacktraceLevel,
+		StacktraceKey:   "stacktrace",
+		BufferSize:      c.BufferSize,    // Use value from configuration
+		FlushInterval:   c.FlushInterval, // Use value from configuration
+		ContextFields:   c.Trace.Extractor(),
+		Sinks:           st.sinks,
+		Encryption:      c.Encryption,
+		Sanitize:        c.Sanitize,
+		Redactor:        st.redactor,
+		Sampler:         st.sampler,
+		Deduper:         st.deduper,
+		Recorder:        st.recorder,
 	}
 
 	// create the zap logger
-	logger, err := zap.NewLog(zapConfig)
-	if err != nil {
-		return err
+	return zap.NewLog(zapConfig)
+}
+
+// Trace logs a trace message using the global logger.
+// If the global logger is not initialized, this function does nothing.
+func Trace(args ...any) {
+	if globalLogger != nil {
+		logCaller(globalLogger, 0, nil, TraceLevel, "", args)
+	}
+}
+
+// Tracef logs a formatted trace message using the global logger.
+func Tracef(template string, args ...any) {
+	if globalLogger != nil {
+		logCaller(globalLogger, 0, nil, TraceLevel, template, args)
 	}
-	globalLogger = logger
-	return nil
 }
 
 // Debug logs a debug message using the global logger.
 // If the global logger is not initialized, this function does nothing.
 func Debug(args ...any) {
 	if globalLogger != nil {
-		globalLogger.Debug(args...)
+		logCaller(globalLogger, 0, nil, DebugLevel, "", args)
 	}
 }
 
 // Debugf logs a formatted debug message using the global logger.
 func Debugf(template string, args ...any) {
 	if globalLogger != nil {
-		globalLogger.Debugf(template, args...)
+		logCaller(globalLogger, 0, nil, DebugLevel, template, args)
 	}
 }
 
@@ -218,14 +568,29 @@ func Debugf(template string, args ...any) {
 // If the global logger is not initialized, this function does nothing.
 func Info(args ...any) {
 	if globalLogger != nil {
-		globalLogger.Info(args...)
+		logCaller(globalLogger, 0, nil, InfoLevel, "", args)
 	}
 }
 
 // Infof logs a formatted informational message using the global logger.
 func Infof(template string, args ...any) {
 	if globalLogger != nil {
-		globalLogger.Infof(template, args...)
+		logCaller(globalLogger, 0, nil, InfoLevel, template, args)
+	}
+}
+
+// Notice logs a notice message using the global logger.
+// If the global logger is not initialized, this function does nothing.
+func Notice(args ...any) {
+	if globalLogger != nil {
+		logCaller(globalLogger, 0, nil, NoticeLevel, "", args)
+	}
+}
+
+// Noticef logs a formatted notice message using the global logger.
+func Noticef(template string, args ...any) {
+	if globalLogger != nil {
+		logCaller(globalLogger, 0, nil, NoticeLevel, template, args)
 	}
 }
 
@@ -233,14 +598,14 @@ func Infof(template string, args ...any) {
 // If the global logger is not initialized, this function does nothing.
 func Warn(args ...any) {
 	if globalLogger != nil {
-		globalLogger.Warn(args...)
+		logCaller(globalLogger, 0, nil, WarnLevel, "", args)
 	}
 }
 
 // Warnf logs a formatted warning message using the global logger.
 func Warnf(template string, args ...any) {
 	if globalLogger != nil {
-		globalLogger.Warnf(template, args...)
+		logCaller(globalLogger, 0, nil, WarnLevel, template, args)
 	}
 }
 
@@ -248,14 +613,44 @@ func Warnf(template string, args ...any) {
 // If the global logger is not initialized, this function does nothing.
 func Error(args ...any) {
 	if globalLogger != nil {
-		globalLogger.Error(args...)
+		logCaller(globalLogger, 0, nil, ErrorLevel, "", args)
 	}
 }
 
 // Errorf logs a formatted error message using the global logger.
 func Errorf(template string, args ...any) {
 	if globalLogger != nil {
-		globalLogger.Errorf(template, args...)
+		logCaller(globalLogger, 0, nil, ErrorLevel, template, args)
+	}
+}
+
+// Critical logs a critical message using the global logger.
+// If the global logger is not initialized, this function does nothing.
+func Critical(args ...any) {
+	if globalLogger != nil {
+		logCaller(globalLogger, 0, nil, CriticalLevel, "", args)
+	}
+}
+
+// Criticalf logs a formatted critical message using the global logger.
+func Criticalf(template string, args ...any) {
+	if globalLogger != nil {
+		logCaller(globalLogger, 0, nil, CriticalLevel, template, args)
+	}
+}
+
+// Panic logs a panic message using the global logger and panics.
+// If the global logger is not initialized, this function does nothing.
+func Panic(args ...any) {
+	if globalLogger != nil {
+		logCaller(globalLogger, 0, nil, PanicLevel, "", args)
+	}
+}
+
+// Panicf logs a formatted panic message using the global logger and panics.
+func Panicf(template string, args ...any) {
+	if globalLogger != nil {
+		logCaller(globalLogger, 0, nil, PanicLevel, template, args)
 	}
 }
 
@@ -263,13 +658,13 @@ func Errorf(template string, args ...any) {
 // If the global logger is not initialized, this function does nothing.
 func Fatal(args ...any) {
 	if globalLogger != nil {
-		globalLogger.Fatal(args...)
+		logCaller(globalLogger, 0, nil, FatalLevel, "", args)
 	}
 }
 
 // Fatalf logs a formatted fatal message using the global logger and exits the program.
 func Fatalf(template string, args ...any) {
 	if globalLogger != nil {
-		globalLogger.Fatalf(template, args...)
+		logCaller(globalLogger, 0, nil, FatalLevel, template, args)
 	}
 }
@@ -0,0 +1,42 @@
+package logx
+
+import "github.com/go4x/logx/core"
+
+// ObjectMarshaler is implemented by the types that log themselves as a
+// nested object with both backends, without reflection. The values of the
+// key-value pairs and Fields implementing it are encoded only if the entry
+// is enabled, to at most 16 levels and 1000 fields and elements; beyond
+// these limits the values are replaced by visible markers.
+//
+// Example:
+//
+//	func (o Order) MarshalLogObject(enc logx.ObjectEncoder) error {
+//	    enc.AddString("id", o.ID)
+//	    enc.AddInt("quantity", o.Quantity)
+//	    enc.AddArray("items", o.Items)
+//	    return nil
+//	}
+type ObjectMarshaler = core.ObjectMarshaler
+
+// ArrayMarshaler is implemented by the types that log themselves as an
+// array with both backends, with the limits of ObjectMarshaler.
+type ArrayMarshaler = core.ArrayMarshaler
+
+// ObjectEncoder is the encoder passed to ObjectMarshaler.
+type ObjectEncoder = core.ObjectEncoder
+
+// ArrayEncoder is the encoder passed to ArrayMarshaler.
+type ArrayEncoder = core.ArrayEncoder
+
+// ObjectMarshalerFunc is a function implementing ObjectMarshaler.
+type ObjectMarshalerFunc = core.ObjectMarshalerFunc
+
+// ArrayMarshalerFunc is a function implementing ArrayMarshaler.
+type ArrayMarshalerFunc = core.ArrayMarshalerFunc
+
+// Lazy is a value computed only if the entry it is logged with is enabled.
+//
+// Example:
+//
+//	logx.Log(ctx, logx.DebugLevel, "cache state", "entries", logx.Lazy(func() any { return cache.Dump() }))
+type Lazy = core.Lazy
@@ -0,0 +1,157 @@
+package logx_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go4x/logx"
+)
+
+type item struct {
+	sku      string
+	quantity int
+}
+
+func (i item) MarshalLogObject(enc logx.ObjectEncoder) error {
+	enc.AddString("sku", i.sku)
+	enc.AddInt("quantity", i.quantity)
+	return nil
+}
+
+type items []item
+
+func (is items) MarshalLogArray(enc logx.ArrayEncoder) error {
+	for _, i := range is {
+		enc.AppendObject(i)
+	}
+	return nil
+}
+
+type order struct {
+	id    string
+	items items
+}
+
+func (o order) MarshalLogObject(enc logx.ObjectEncoder) error {
+	enc.AddString("id", o.id)
+	enc.AddArray("items", o.items)
+	return nil
+}
+
+// node is a cyclic structure
+type node struct {
+	next *node
+}
+
+func (n *node) MarshalLogObject(enc logx.ObjectEncoder) error {
+	enc.AddObject("next", n.next)
+	return nil
+}
+
+// TestObjectMarshaler tests that both backends log marshalers as nested objects and arrays
+func TestObjectMarshaler(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "info",
+				Dir:    logDir,
+				Format: "json",
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			o := order{id: "o-1", items: items{{sku: "a", quantity: 2}, {sku: "b", quantity: 1}}}
+			failing := logx.ObjectMarshalerFunc(func(enc logx.ObjectEncoder) error {
+				enc.AddBool("partial", true)
+				return errors.New("broken")
+			})
+			logx.Log(context.Background(), logx.InfoLevel, "placed", "order", o, logx.Field{Key: "failing", Value: failing})
+
+			content := readLogs(t, logDir)
+			if !strings.Contains(content, `"order":{"id":"o-1","items":[{"sku":"a","quantity":2},{"sku":"b","quantity":1}]}`) {
+				t.Errorf("expected the order as a nested object in order, got %q", content)
+			}
+			entry := findEntry(t, content, "placed")
+			if f, _ := entry["failing"].(map[string]any); f["partial"] != true || entry["failingError"] != "broken" {
+				t.Errorf("expected the partial object and its error, got %v", entry)
+			}
+		})
+	}
+}
+
+// TestMarshalerLimits tests that cyclic and huge values are cut with visible markers
+func TestMarshalerLimits(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "info",
+				Dir:    logDir,
+				Format: "json",
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			cycle := &node{}
+			cycle.next = cycle
+			huge := logx.ArrayMarshalerFunc(func(enc logx.ArrayEncoder) error {
+				for i := 0; i < 5000; i++ {
+					enc.AppendInt(i)
+				}
+				return nil
+			})
+			logx.Log(context.Background(), logx.InfoLevel, "limits", "cycle", cycle, "huge", huge)
+
+			content := readLogs(t, logDir)
+			for _, want := range []string{`"...[max depth]"`, `"...[truncated 4000 elements]"`} {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %s in output, got %.300q", want, content)
+				}
+			}
+		})
+	}
+}
+
+// TestLazy tests that lazy values are computed only for the enabled entries
+func TestLazy(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "info",
+				Dir:    logDir,
+				Format: "json",
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			calls := 0
+			dump := logx.Lazy(func() any {
+				calls++
+				return item{sku: "lazy", quantity: calls}
+			})
+			logx.Log(context.Background(), logx.DebugLevel, "hidden", "dump", dump)
+			if calls != 0 {
+				t.Errorf("expected no evaluation below the level, got %d", calls)
+			}
+			logx.Log(context.Background(), logx.InfoLevel, "shown", "dump", dump)
+			if calls != 1 {
+				t.Errorf("expected one evaluation, got %d", calls)
+			}
+
+			content := readLogs(t, logDir)
+			if !strings.Contains(content, `"dump":{"sku":"lazy","quantity":1}`) {
+				t.Errorf("expected the lazy value, got %q", content)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package logx
+
+import (
+	"fmt"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/module"
+)
+
+// globalModules holds the levels of LoggerConfig.ModuleLevels, as set by
+// Init or SetModuleLevels.
+var globalModules = module.New[Level](nil)
+
+// SetModuleLevels replaces the module levels of the global logger at
+// runtime, with the syntax of LoggerConfig.ModuleLevels. A nil or empty map
+// removes them. The levels are left unchanged if one of them is invalid.
+//
+// Example:
+//
+//	// debug the payments subsystem only
+//	err := logx.SetModuleLevels(map[string]string{"github.com/acme/payments/*": "debug"})
+func SetModuleLevels(levels map[string]string) error {
+	parsed, err := parseModuleLevels(levels)
+	if err != nil {
+		return err
+	}
+	globalModules.Set(parsed)
+	return nil
+}
+
+// parseModuleLevels parses the level names of levels.
+func parseModuleLevels(levels map[string]string) (map[string]Level, error) {
+	if len(levels) == 0 {
+		return nil, nil
+	}
+	parsed := make(map[string]Level, len(levels))
+	for pattern, name := range levels {
+		level, err := core.ParseLevel(name)
+		if err != nil {
+			return nil, fmt.Errorf("module %q: %w", pattern, err)
+		}
+		parsed[pattern] = level
+	}
+	return parsed, nil
+}
+
+// message formats args with template, or as fmt.Sprint does if template is
+// empty.
+func message(template string, args []any) string {
+	switch {
+	case len(args) == 0:
+		return template
+	case template == "":
+		return fmt.Sprint(args...)
+	default:
+		return fmt.Sprintf(template, args...)
+	}
+}
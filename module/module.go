@@ -0,0 +1,168 @@
+// Package module matches call sites against glob patterns of package paths
+// and file paths, such as "github.com/acme/payments/*" or "*/db/*.go", to
+// configure the logging of a subsystem separately from the rest of the
+// program. Lookups are cached per program counter, and the patterns can be
+// replaced at runtime.
+//
+// Example usage:
+//
+//	config := &logx.LoggerConfig{
+//	    Level: "info",
+//	    // ...
+//	    ModuleLevels: map[string]string{"github.com/acme/payments/*": "debug"},
+//	}
+package module
+
+import (
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// rule is a compiled pattern and its value.
+type rule[T any] struct {
+	pattern string
+	re      *regexp.Regexp
+	value   T
+}
+
+// result is a cached lookup.
+type result[T any] struct {
+	value T
+	ok    bool
+}
+
+// state holds the rules set by a call to Set and the lookups made with them.
+type state[T any] struct {
+	rules []rule[T]
+	// cache maps program counters to results
+	cache sync.Map
+}
+
+// Matcher maps call sites to the value of the most specific pattern
+// matching their package or file. It is safe for concurrent use.
+type Matcher[T any] struct {
+	state atomic.Pointer[state[T]]
+}
+
+// New returns a Matcher with the given patterns and values.
+func New[T any](values map[string]T) *Matcher[T] {
+	m := &Matcher[T]{}
+	m.Set(values)
+	return m
+}
+
+// Set replaces the patterns of m and clears the lookups cached with the
+// previous patterns.
+//
+// In a pattern, * matches any sequence of characters, including slashes,
+// and ? matches any single character. A pattern matches a call site if it
+// matches the import path of its package or the path of its source file.
+// When several patterns match, the longest one applies.
+func (m *Matcher[T]) Set(values map[string]T) {
+	if len(values) == 0 {
+		m.state.Store(nil)
+		return
+	}
+	rules := make([]rule[T], 0, len(values))
+	for p, v := range values {
+		rules = append(rules, rule[T]{pattern: p, re: compile(p), value: v})
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		if len(rules[i].pattern) != len(rules[j].pattern) {
+			return len(rules[i].pattern) > len(rules[j].pattern)
+		}
+		return rules[i].pattern < rules[j].pattern
+	})
+	m.state.Store(&state[T]{rules: rules})
+}
+
+// Empty reports whether m has no patterns, in which case lookups can be
+// skipped. It is a single atomic load, cheaper than walking the stack to
+// find a call site.
+func (m *Matcher[T]) Empty() bool {
+	return m.state.Load() == nil
+}
+
+// Lookup returns the value of the call site at pc, a return address as
+// reported by runtime.Callers, and whether a pattern matches it.
+func (m *Matcher[T]) Lookup(pc uintptr) (T, bool) {
+	st := m.state.Load()
+	if st == nil {
+		var zero T
+		return zero, false
+	}
+	if r, ok := st.cache.Load(pc); ok {
+		res := r.(result[T])
+		return res.value, res.ok
+	}
+	var res result[T]
+	pkg, file := site(pc)
+	for _, r := range st.rules {
+		if r.re.MatchString(pkg) || r.re.MatchString(file) {
+			res = result[T]{value: r.value, ok: true}
+			break
+		}
+	}
+	st.cache.Store(pc, res)
+	return res.value, res.ok
+}
+
+// Caller returns the value of the call site skip frames above the caller of
+// Caller, and whether a pattern matches it. With skip 0, it is the call
+// site of the function calling Caller.
+func (m *Matcher[T]) Caller(skip int) (T, bool) {
+	if m.Empty() {
+		var zero T
+		return zero, false
+	}
+	var pcs [1]uintptr
+	if runtime.Callers(skip+3, pcs[:]) == 0 {
+		var zero T
+		return zero, false
+	}
+	return m.Lookup(pcs[0])
+}
+
+// Match reports whether s matches pattern, with the syntax of Set.
+func Match(pattern, s string) bool {
+	return compile(pattern).MatchString(s)
+}
+
+// compile converts a pattern to an anchored regular expression.
+func compile(pattern string) *regexp.Regexp {
+	var sb strings.Builder
+	sb.WriteByte('^')
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteByte('.')
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteByte('$')
+	return regexp.MustCompile(sb.String())
+}
+
+// site returns the import path of the package and the file of the call site
+// at pc.
+func site(pc uintptr) (pkg, file string) {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	return packagePath(frame.Function), frame.File
+}
+
+// packagePath returns the import path of the package of a fully qualified
+// function name, such as "github.com/acme/db.(*Conn).Query".
+func packagePath(function string) string {
+	slash := strings.LastIndexByte(function, '/')
+	if dot := strings.IndexByte(function[slash+1:], '.'); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
@@ -0,0 +1,91 @@
+package module_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/go4x/logx/module"
+)
+
+// pc returns the program counter of its call site.
+func pc() uintptr {
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:])
+	return pcs[0]
+}
+
+// TestMatch tests the glob syntax of the patterns
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		want       bool
+	}{
+		{"github.com/acme/payments/*", "github.com/acme/payments/db", true},
+		{"github.com/acme/payments/*", "github.com/acme/payments/db/pg", true},
+		{"github.com/acme/payments/*", "github.com/acme/payments", false},
+		{"github.com/acme/payments", "github.com/acme/payments", true},
+		{"*/db/*.go", "/src/app/db/conn.go", true},
+		{"*/db/*.go", "/src/app/dbx/conn.go", false},
+		{"*/db/conn?.go", "/src/db/conn2.go", true},
+		{"a.b", "axb", false},
+	}
+	for _, tt := range tests {
+		if got := module.Match(tt.pattern, tt.s); got != tt.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.want)
+		}
+	}
+}
+
+// TestLookup tests that call sites are matched by package or by file, and that the longest pattern applies
+func TestLookup(t *testing.T) {
+	m := module.New(map[string]string{
+		"github.com/go4x/logx/*":  "package",
+		"*/module/module_test.go": "file",
+		"github.com/acme/*":       "other",
+	})
+	if v, ok := m.Lookup(pc()); !ok || v != "file" {
+		t.Errorf("expected the file pattern, got %q, %v", v, ok)
+	}
+
+	m.Set(map[string]string{"github.com/go4x/logx/*": "package", "github.com/acme/*": "other"})
+	if v, ok := m.Lookup(pc()); !ok || v != "package" {
+		t.Errorf("expected the package pattern, got %q, %v", v, ok)
+	}
+
+	m.Set(map[string]string{"github.com/acme/*": "other"})
+	if v, ok := m.Lookup(pc()); ok {
+		t.Errorf("expected no match, got %q", v)
+	}
+}
+
+// TestSet tests that replacing the patterns clears the cached lookups
+func TestSet(t *testing.T) {
+	m := module.New(map[string]int{"*/module_test.go": 1})
+	site := pc()
+	if v, _ := m.Lookup(site); v != 1 {
+		t.Fatalf("expected 1, got %d", v)
+	}
+	m.Set(map[string]int{"*/module_test.go": 2})
+	if v, _ := m.Lookup(site); v != 2 {
+		t.Errorf("expected 2 after Set, got %d", v)
+	}
+	m.Set(nil)
+	if !m.Empty() {
+		t.Error("expected an empty matcher")
+	}
+	if _, ok := m.Lookup(site); ok {
+		t.Error("expected no match after clearing the patterns")
+	}
+}
+
+// TestCaller tests that Caller looks up the call site of its caller
+func TestCaller(t *testing.T) {
+	m := module.New(map[string]bool{"*/module_test.go": true})
+	lookup := func() bool {
+		ok, _ := m.Caller(0)
+		return ok
+	}
+	if !lookup() {
+		t.Error("expected the call site to match")
+	}
+}
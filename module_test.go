@@ -0,0 +1,70 @@
+package logx_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go4x/logx"
+)
+
+// TestModuleLevels tests that both backends apply the level of the module of the call site
+func TestModuleLevels(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:         typ,
+				Level:        "info",
+				Dir:          logDir,
+				Format:       "json",
+				ModuleLevels: map[string]string{"*/module_test.go": "debug"},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+			defer logx.SetModuleLevels(nil)
+
+			ctx := context.Background()
+			logx.Debug("module debug")
+			logx.Debugf("module %s", "debugf")
+			logx.Log(ctx, logx.DebugLevel, "module log", "key", "value")
+			logx.FromContext(ctx).Debug("module context")
+
+			if err := logx.SetModuleLevels(map[string]string{"*/module_test.go": "error"}); err != nil {
+				t.Fatalf("failed to set the module levels: %v", err)
+			}
+			logx.Info("quiet info")
+			logx.Error("loud error")
+
+			if err := logx.SetModuleLevels(nil); err != nil {
+				t.Fatalf("failed to clear the module levels: %v", err)
+			}
+			logx.Debug("plain debug")
+			logx.Info("plain info")
+
+			content := readLogs(t, logDir)
+			for _, want := range []string{"module debug", "module debugf", "module log", `"key":"value"`, "module context", "loud error", "plain info"} {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %s in output, got %q", want, content)
+				}
+			}
+			for _, unwanted := range []string{"quiet info", "plain debug"} {
+				if strings.Contains(content, unwanted) {
+					t.Errorf("unexpected %s in output: %q", unwanted, content)
+				}
+			}
+		})
+	}
+}
+
+// TestModuleLevelsInvalid tests that invalid module levels are rejected
+func TestModuleLevelsInvalid(t *testing.T) {
+	err := logx.Init(&logx.LoggerConfig{Dir: t.TempDir(), ModuleLevels: map[string]string{"*": "loud"}})
+	if err == nil {
+		t.Error("expected an error for an invalid level")
+	}
+	if err := logx.SetModuleLevels(map[string]string{"*": "loud"}); err == nil {
+		t.Error("expected an error for an invalid level")
+	}
+}
@@ -0,0 +1,140 @@
+package logx
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// PanicPolicy is what Recover does after logging a panic.
+type PanicPolicy int
+
+const (
+	// RePanic panics again with the recovered value, so that the program
+	// crashes as it would have without Recover. It is the default.
+	RePanic PanicPolicy = iota
+	// Exit exits the program with status 2, as an unrecovered panic does,
+	// without printing the stack to stderr again.
+	Exit
+	// Continue returns normally from the function deferring Recover.
+	Continue
+)
+
+// RecoverOptions configures Recover and Go.
+type RecoverOptions struct {
+	// Policy is what happens after the panic is logged (RePanic by
+	// default).
+	Policy PanicPolicy
+	// Message is the message of the entry ("panic recovered" by default).
+	Message string
+}
+
+// Recover recovers a panic of the calling goroutine and logs it with the
+// fields carried by ctx, the recovered value under "panic" and the stack of
+// the goroutine under "stack": at PanicLevel if the policy re-panics or
+// exits, or at CriticalLevel if it continues. It then flushes the outputs
+// and the sinks (see Sync) before applying the policy. Recover must be
+// deferred directly.
+//
+// Example:
+//
+//	func (w *Worker) run(ctx context.Context) {
+//	    defer logx.Recover(ctx, logx.RecoverOptions{Policy: logx.Continue})
+//	    w.process(ctx)
+//	}
+func Recover(ctx context.Context, opts RecoverOptions) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	handlePanic(ctx, opts, v)
+}
+
+// Go runs fn in a new goroutine, and recovers and logs its panics as
+// Recover does, with the first of opts or the default options.
+//
+// Example:
+//
+//	logx.Go(ctx, func() { consume(ctx, queue) })
+func Go(ctx context.Context, fn func(), opts ...RecoverOptions) {
+	var o RecoverOptions
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				handlePanic(ctx, o, v)
+			}
+		}()
+		fn()
+	}()
+}
+
+// handlePanic logs the recovered value v, flushes the outputs and applies
+// the policy of opts.
+func handlePanic(ctx context.Context, opts RecoverOptions, v any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	msg := opts.Message
+	if msg == "" {
+		msg = "panic recovered"
+	}
+	level := PanicLevel
+	if opts.Policy == Continue {
+		level = CriticalLevel
+	}
+	stack := string(debug.Stack())
+	kv := []any{"panic", fmt.Sprint(v), "stack", stack}
+	if err, ok := v.(error); ok {
+		kv = []any{NamedErr("panic", err), "stack", stack}
+	}
+	logPanic(ctx, panicSite(), level, msg, kv)
+	_ = Sync()
+
+	switch opts.Policy {
+	case Exit:
+		os.Exit(2)
+	case Continue:
+	default:
+		panic(v)
+	}
+}
+
+// logPanic logs through the global logger with the call site at pc.
+// Logging at PanicLevel makes the backends panic after writing the entry,
+// which is recovered here since the policy decides what happens next.
+func logPanic(ctx context.Context, pc uintptr, level Level, msg string, kv []any) {
+	if globalLogger == nil {
+		return
+	}
+	defer func() { _ = recover() }()
+	logAt(globalLogger, pc, ctx, level, msg, kv...)
+}
+
+// panicSite returns the return address of the frame that panicked, the
+// first frame outside of the runtime below runtime.gopanic, or 0 if the
+// goroutine is not panicking.
+func panicSite() uintptr {
+	var pcs [64]uintptr
+	n := runtime.Callers(2, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	panicking := false
+	for {
+		f, more := frames.Next()
+		switch {
+		case f.Function == "runtime.gopanic":
+			panicking = true
+		case panicking && !strings.HasPrefix(f.Function, "runtime."):
+			// a return address, as core.CallerFrame expects
+			return f.PC + 1
+		}
+		if !more {
+			return 0
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package logx_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx"
+	"github.com/go4x/logx/sink"
+	"github.com/go4x/logx/sink/loki"
+)
+
+// TestRecover tests that both backends log a recovered panic with its stack and flush the buffered output
+func TestRecover(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:          typ,
+				Level:         "info",
+				Dir:           logDir,
+				Format:        "json",
+				BufferSize:    64 * 1024,
+				FlushInterval: 3600,
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			ctx := logx.NewContext(context.Background(), "job", "j1")
+			func() {
+				defer logx.Recover(ctx, logx.RecoverOptions{Policy: logx.Continue})
+				panic("boom")
+			}()
+
+			entry := findEntry(t, readLogs(t, logDir), "panic recovered")
+			if entry["panic"] != "boom" || entry["job"] != "j1" {
+				t.Errorf("expected the panic value and the context fields, got %v", entry)
+			}
+			if level, _ := entry["level"].(string); strings.ToLower(level) != "critical" {
+				t.Errorf("expected critical level when continuing, got %v", entry["level"])
+			}
+			if stack, _ := entry["stack"].(string); !strings.Contains(stack, "TestRecover") {
+				t.Errorf("expected the goroutine stack, got %q", stack)
+			}
+		})
+	}
+}
+
+// TestRecoverRePanic tests that Recover logs at panic level and panics again with the recovered value
+func TestRecoverRePanic(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "info",
+				Dir:    logDir,
+				Format: "json",
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			cause := errors.New("disk full")
+			var recovered any
+			func() {
+				defer func() { recovered = recover() }()
+				defer logx.Recover(context.Background(), logx.RecoverOptions{Message: "worker crashed"})
+				panic(cause)
+			}()
+			if recovered != cause {
+				t.Errorf("expected the original value to be re-panicked, got %v", recovered)
+			}
+
+			entry := findEntry(t, readLogs(t, logDir), "worker crashed")
+			if level, _ := entry["level"].(string); strings.ToLower(level) != "panic" {
+				t.Errorf("expected panic level when re-panicking, got %v", entry["level"])
+			}
+			if p, _ := entry["panic"].(map[string]any); p["message"] != "disk full" {
+				t.Errorf("expected the panic error as a nested object, got %v", entry["panic"])
+			}
+		})
+	}
+}
+
+// TestGo tests that Go recovers and logs the panics of its goroutine
+func TestGo(t *testing.T) {
+	logDir := t.TempDir()
+	err := logx.Init(&logx.LoggerConfig{
+		Type:   logx.LoggerTypeZap,
+		Level:  "info",
+		Dir:    logDir,
+		Format: "json",
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+
+	logx.Go(context.Background(), func() {
+		var m map[string]int
+		m["x"] = 1
+	}, logx.RecoverOptions{Policy: logx.Continue})
+
+	waitFor(t, func() bool {
+		return strings.Contains(readLogs(t, logDir), "assignment to entry in nil map")
+	})
+}
+
+// TestRecoverFlushesSinks tests that Recover flushes the sinks before applying the policy
+func TestRecoverFlushesSinks(t *testing.T) {
+	var mu sync.Mutex
+	var received strings.Builder
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received.Write(data)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			err := logx.Init(&logx.LoggerConfig{
+				Type:  typ,
+				Level: "info",
+				Dir:   t.TempDir(),
+				Loki: &loki.Config{
+					URL:   srv.URL,
+					HTTP:  sink.HTTPConfig{Compression: "none"},
+					Batch: sink.BatchConfig{FlushInterval: time.Hour},
+				},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			func() {
+				defer logx.Recover(context.Background(), logx.RecoverOptions{Policy: logx.Continue, Message: "flushed " + string(typ)})
+				panic("boom")
+			}()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !strings.Contains(received.String(), "flushed "+string(typ)) {
+				t.Errorf("expected the entry to be delivered before Recover returns, got %q", received.String())
+			}
+		})
+	}
+}
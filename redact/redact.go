@@ -0,0 +1,588 @@
+// Package redact scrubs sensitive data such as passwords, tokens and card
+// numbers from log entries before they are encoded.
+//
+// Rules match field names (exact names or glob patterns, case-insensitive)
+// and string values (regular expressions or the built-in patterns "pan",
+// "email" and "jwt"). A matching value is masked, replaced by a salted hash,
+// truncated or dropped.
+//
+// Example usage:
+//
+//	config := &logx.LoggerConfig{
+//	    // ...
+//	    Redact: &redact.Config{
+//	        UseDefaultRules: true,
+//	        Salt:            os.Getenv("LOG_HASH_SALT"),
+//	        Rules: []redact.Rule{
+//	            {Keys: []string{"email"}, Action: redact.ActionHash},
+//	            {Pattern: "pan", Action: redact.ActionTruncate, Keep: 6},
+//	        },
+//	    },
+//	}
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Action is what a rule does with a matching value.
+type Action string
+
+const (
+	// ActionMask replaces the value with the mask (default).
+	ActionMask Action = "mask"
+	// ActionHash replaces the value with a salted HMAC-SHA256, so that equal
+	// values can still be correlated.
+	ActionHash Action = "hash"
+	// ActionTruncate keeps the first Keep characters of the value.
+	ActionTruncate Action = "truncate"
+	// ActionDrop removes the field, or the matching text of a string.
+	ActionDrop Action = "drop"
+)
+
+// DefaultMask is the replacement of masked values.
+const DefaultMask = "[REDACTED]"
+
+// Built-in value patterns.
+var builtinPatterns = map[string]string{
+	// Payment card numbers of 13 to 19 digits, optionally grouped with
+	// spaces or dashes. Matches are confirmed with the Luhn checksum.
+	"pan": `\b\d(?:[ -]?\d){12,18}\b`,
+	// Email addresses.
+	"email": `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	// JSON Web Tokens.
+	"jwt": `\beyJ[A-Za-z0-9_-]*\.eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]*`,
+}
+
+// Rule is a redaction rule.
+type Rule struct {
+	// Keys are the field names the rule applies to: exact names or glob
+	// patterns such as "*token*", matched case-insensitively against the
+	// name of a field or of a nested map key.
+	Keys []string `mapstructure:"keys" yaml:"keys"`
+
+	// Pattern is a regular expression, or one of the built-in patterns
+	// "pan", "email" and "jwt". Only the matching parts of string values
+	// and messages are redacted. With Keys, the pattern only applies to the
+	// values of these fields; without Pattern, the whole value of the
+	// fields is redacted.
+	Pattern string `mapstructure:"pattern" yaml:"pattern"`
+
+	// Action is mask (default), hash, truncate or drop.
+	Action Action `mapstructure:"action" yaml:"action"`
+
+	// Mask replaces masked values (default "[REDACTED]").
+	Mask string `mapstructure:"mask" yaml:"mask"`
+
+	// Keep is the number of characters kept by truncate (default 4).
+	Keep int `mapstructure:"keep" yaml:"keep"`
+}
+
+// Config holds the redaction rules.
+type Config struct {
+	// Rules are applied in order.
+	Rules []Rule `mapstructure:"rules" yaml:"rules"`
+
+	// UseDefaultRules adds DefaultRules after Rules.
+	UseDefaultRules bool `mapstructure:"use-default-rules" yaml:"use-default-rules"`
+
+	// Salt is the HMAC key of the hash action. It is required by hash rules.
+	Salt string `mapstructure:"salt" yaml:"salt"`
+}
+
+// DefaultRules returns rules that mask common secret field names, card
+// numbers and JSON Web Tokens.
+func DefaultRules() []Rule {
+	return []Rule{
+		{Keys: []string{"password", "passwd", "pwd", "*secret*", "*token*", "authorization", "cookie", "set-cookie", "api_key", "apikey", "api-key", "private_key"}},
+		{Pattern: "pan"},
+		{Pattern: "jwt"},
+	}
+}
+
+// rule is a compiled Rule.
+type rule struct {
+	keys    map[string]bool
+	globs   []string
+	pattern *regexp.Regexp
+	luhn    bool
+	action  Action
+	mask    string
+	keep    int
+}
+
+// Redactor applies redaction rules. It is safe for concurrent use.
+type Redactor struct {
+	rules []rule
+	salt  []byte
+	// assignments matches key=value and key: value pairs of the key rules
+	// without pattern in free text, with the key optionally quoted as in
+	// JSON, and the value optionally prefixed with an authorization scheme.
+	assignments *regexp.Regexp
+	assignRule  map[string]int
+}
+
+// New compiles the rules of c.
+func New(c Config) (*Redactor, error) {
+	rules := c.Rules
+	if c.UseDefaultRules {
+		rules = append(append([]Rule(nil), rules...), DefaultRules()...)
+	}
+	r := &Redactor{salt: []byte(c.Salt), assignRule: make(map[string]int)}
+	var assignments []string
+	for i, rc := range rules {
+		if len(rc.Keys) == 0 && rc.Pattern == "" {
+			return nil, fmt.Errorf("redact: rule %d has neither keys nor pattern", i)
+		}
+		cr := rule{action: rc.Action, mask: rc.Mask, keep: rc.Keep, keys: make(map[string]bool)}
+		switch cr.action {
+		case "":
+			cr.action = ActionMask
+		case ActionMask, ActionTruncate, ActionDrop:
+		case ActionHash:
+			if c.Salt == "" {
+				return nil, errors.New("redact: the hash action requires a salt")
+			}
+		default:
+			return nil, fmt.Errorf("redact: unknown action: %s", rc.Action)
+		}
+		if cr.mask == "" {
+			cr.mask = DefaultMask
+		}
+		if cr.keep <= 0 {
+			cr.keep = 4
+		}
+		for _, k := range rc.Keys {
+			k = strings.ToLower(k)
+			if strings.ContainsAny(k, `*?[\`) {
+				if _, err := path.Match(k, ""); err != nil {
+					return nil, fmt.Errorf("redact: invalid key pattern %q: %w", k, err)
+				}
+				cr.globs = append(cr.globs, k)
+			} else {
+				cr.keys[k] = true
+			}
+			if rc.Pattern == "" {
+				assignments = append(assignments, globRegexp(k))
+			}
+		}
+		if rc.Pattern != "" {
+			expr, builtin := builtinPatterns[rc.Pattern]
+			if !builtin {
+				expr = rc.Pattern
+			}
+			re, err := regexp.Compile(expr)
+			if err != nil {
+				return nil, fmt.Errorf("redact: invalid pattern %q: %w", rc.Pattern, err)
+			}
+			cr.pattern = re
+			cr.luhn = rc.Pattern == "pan"
+		}
+		r.rules = append(r.rules, cr)
+		if rc.Pattern == "" {
+			for _, k := range rc.Keys {
+				if _, ok := r.assignRule[strings.ToLower(k)]; !ok {
+					r.assignRule[strings.ToLower(k)] = len(r.rules) - 1
+				}
+			}
+		}
+	}
+	if len(assignments) > 0 {
+		expr := `(?i)(^|[^\w-])"?(` + strings.Join(assignments, "|") + `)"?(\s*[=:]\s*)("[^"]*"|(?:(?:bearer|basic|token)\s+)?[^\s,;&}]+)`
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("redact: %w", err)
+		}
+		r.assignments = re
+	}
+	return r, nil
+}
+
+// globRegexp converts a lowercase key glob to a regular expression matching
+// a key name in free text.
+func globRegexp(glob string) string {
+	var sb strings.Builder
+	for _, c := range glob {
+		switch c {
+		case '*':
+			sb.WriteString(`[\w-]*`)
+		case '?':
+			sb.WriteString(`[\w-]`)
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
+
+// matchKey reports whether the rule applies to the field named key.
+func (cr *rule) matchKey(key string) bool {
+	if len(cr.keys) == 0 && len(cr.globs) == 0 {
+		return true
+	}
+	key = strings.ToLower(key)
+	if cr.keys[key] {
+		return true
+	}
+	for _, g := range cr.globs {
+		if ok, _ := path.Match(g, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// keyRule reports whether the rule redacts the whole value of named fields.
+func (cr *rule) keyRule() bool {
+	return cr.pattern == nil
+}
+
+// Field redacts the value of the field named key. It returns the new value,
+// whether it differs from v and whether the field must be kept. Strings are
+// scrubbed with the pattern rules and the key=value pairs of the key rules;
+// maps, slices, arrays, pointers and structs are redacted recursively, and
+// converted to map[string]any and []any if they change.
+func (r *Redactor) Field(key string, v any) (out any, changed, keep bool) {
+	for i := range r.rules {
+		cr := &r.rules[i]
+		if cr.keyRule() && cr.matchKey(key) {
+			if cr.action == ActionDrop {
+				return nil, true, false
+			}
+			return r.apply(cr, fmt.Sprint(v)), true, true
+		}
+	}
+	out, changed = r.value(key, v, 0)
+	return out, changed, true
+}
+
+// maxDepth bounds the recursion into nested values.
+const maxDepth = 16
+
+// value redacts a field value that no key rule matched as a whole.
+func (r *Redactor) value(key string, v any, depth int) (any, bool) {
+	switch v := v.(type) {
+	case string:
+		s := r.text(key, v)
+		return s, s != v
+	case error:
+		s := v.Error()
+		if out := r.text(key, s); out != s {
+			return out, true
+		}
+		return v, false
+	case fmt.Stringer:
+		s := v.String()
+		if out := r.text(key, s); out != s {
+			return out, true
+		}
+		return v, false
+	case map[string]any:
+		if depth >= maxDepth {
+			return v, false
+		}
+		var out map[string]any
+		for k, e := range v {
+			nv, changed, keep := r.nested(k, e, depth+1)
+			if !changed {
+				continue
+			}
+			if out == nil {
+				out = make(map[string]any, len(v))
+				for k2, e2 := range v {
+					out[k2] = e2
+				}
+			}
+			if keep {
+				out[k] = nv
+			} else {
+				delete(out, k)
+			}
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	case []any:
+		if depth >= maxDepth {
+			return v, false
+		}
+		var out []any
+		for i, e := range v {
+			nv, changed := r.value(key, e, depth+1)
+			if !changed {
+				continue
+			}
+			if out == nil {
+				out = append([]any(nil), v...)
+			}
+			out[i] = nv
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	case []string:
+		var out []string
+		for i, e := range v {
+			s := r.text(key, e)
+			if s == e {
+				continue
+			}
+			if out == nil {
+				out = append([]string(nil), v...)
+			}
+			out[i] = s
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64,
+		float32, float64, []byte:
+		return v, false
+	default:
+		if depth >= maxDepth {
+			return v, false
+		}
+		return r.reflected(key, reflect.ValueOf(v), depth)
+	}
+}
+
+// reflected redacts the maps, slices, arrays, pointers and structs of other
+// types than those of value, such as http.Header or map[string]string.
+func (r *Redactor) reflected(key string, rv reflect.Value, depth int) (any, bool) {
+	v := rv.Interface()
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return v, false
+		}
+		if out, changed := r.value(key, rv.Elem().Interface(), depth+1); changed {
+			return out, true
+		}
+		return v, false
+	case reflect.Map:
+		out := make(map[string]any, rv.Len())
+		changed := false
+		iter := rv.MapRange()
+		for iter.Next() {
+			k := fmt.Sprint(iter.Key().Interface())
+			nv, c, keep := r.nested(k, iter.Value().Interface(), depth+1)
+			changed = changed || c
+			if keep {
+				out[k] = nv
+			}
+		}
+		if !changed {
+			return v, false
+		}
+		return out, true
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return v, false
+		}
+		out := make([]any, rv.Len())
+		changed := false
+		for i := range out {
+			nv, c := r.value(key, rv.Index(i).Interface(), depth+1)
+			changed = changed || c
+			out[i] = nv
+		}
+		if !changed {
+			return v, false
+		}
+		return out, true
+	case reflect.Struct:
+		// the exported fields, named as by encoding/json
+		t := rv.Type()
+		out := make(map[string]any, t.NumField())
+		changed := false
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if !sf.IsExported() {
+				continue
+			}
+			name := sf.Name
+			if tag, _, _ := strings.Cut(sf.Tag.Get("json"), ","); tag == "-" {
+				continue
+			} else if tag != "" {
+				name = tag
+			}
+			nv, c, keep := r.nested(name, rv.Field(i).Interface(), depth+1)
+			changed = changed || c
+			if keep {
+				out[name] = nv
+			}
+		}
+		if !changed {
+			return v, false
+		}
+		return out, true
+	default:
+		return v, false
+	}
+}
+
+// nested redacts a nested map entry, applying the key rules to its key.
+func (r *Redactor) nested(key string, v any, depth int) (any, bool, bool) {
+	for i := range r.rules {
+		cr := &r.rules[i]
+		if cr.keyRule() && cr.matchKey(key) {
+			if cr.action == ActionDrop {
+				return nil, true, false
+			}
+			return r.apply(cr, fmt.Sprint(v)), true, true
+		}
+	}
+	out, changed := r.value(key, v, depth)
+	return out, changed, true
+}
+
+// String scrubs free text such as a log message: the pattern rules without
+// keys are applied, and the values of key=value and key: value pairs whose
+// key matches a key rule are redacted.
+func (r *Redactor) String(s string) string {
+	return r.assign(r.scrub("", s))
+}
+
+// text scrubs the string value of the field named key: the pattern rules
+// that apply to key are applied, and the values of the key=value pairs, as
+// in JSON text, whose key matches a key rule are redacted.
+func (r *Redactor) text(key, s string) string {
+	return r.assign(r.scrub(key, s))
+}
+
+// assign redacts the values of the key=value and key: value pairs of s
+// whose key matches a key rule.
+func (r *Redactor) assign(s string) string {
+	if r.assignments == nil {
+		return s
+	}
+	matches := r.assignments.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		// m[4]:m[5] is the key, m[8]:m[9] the value
+		key := strings.ToLower(s[m[4]:m[5]])
+		cr := r.assignmentRule(key)
+		if cr == nil {
+			continue
+		}
+		sb.WriteString(s[last:m[8]])
+		value := s[m[8]:m[9]]
+		// the quotes of a quoted key and value are kept, so that JSON text
+		// remains valid
+		quoted := m[4] > 0 && s[m[4]-1] == '"' && strings.HasPrefix(value, `"`)
+		if quoted {
+			sb.WriteByte('"')
+		}
+		if cr.action != ActionDrop {
+			sb.WriteString(r.apply(cr, strings.Trim(value, `"`)))
+		}
+		if quoted {
+			sb.WriteByte('"')
+		}
+		last = m[9]
+	}
+	sb.WriteString(s[last:])
+	return sb.String()
+}
+
+// assignmentRule returns the key rule matching a key found in free text.
+func (r *Redactor) assignmentRule(key string) *rule {
+	if i, ok := r.assignRule[key]; ok {
+		return &r.rules[i]
+	}
+	for i := range r.rules {
+		cr := &r.rules[i]
+		if cr.keyRule() && cr.matchKey(key) {
+			return cr
+		}
+	}
+	return nil
+}
+
+// scrub applies the pattern rules that apply to the field named key; an
+// empty key selects the rules without keys.
+func (r *Redactor) scrub(key string, s string) string {
+	for i := range r.rules {
+		cr := &r.rules[i]
+		if cr.pattern == nil {
+			continue
+		}
+		if key == "" && (len(cr.keys) > 0 || len(cr.globs) > 0) {
+			continue
+		}
+		if key != "" && !cr.matchKey(key) {
+			continue
+		}
+		s = cr.pattern.ReplaceAllStringFunc(s, func(m string) string {
+			if cr.luhn && !luhnValid(m) {
+				return m
+			}
+			if cr.action == ActionDrop {
+				return ""
+			}
+			return r.apply(cr, m)
+		})
+	}
+	return s
+}
+
+// apply returns the replacement of a matching value.
+func (r *Redactor) apply(cr *rule, s string) string {
+	switch cr.action {
+	case ActionHash:
+		mac := hmac.New(sha256.New, r.salt)
+		mac.Write([]byte(s))
+		return "sha256:" + hex.EncodeToString(mac.Sum(nil))[:16]
+	case ActionTruncate:
+		if utf8.RuneCountInString(s) <= cr.keep {
+			return s
+		}
+		i := 0
+		for n := 0; n < cr.keep; n++ {
+			_, size := utf8.DecodeRuneInString(s[i:])
+			i += size
+		}
+		return s[:i] + "..."
+	case ActionDrop:
+		return ""
+	default:
+		return cr.mask
+	}
+}
+
+// luhnValid reports whether the digits of s pass the Luhn checksum.
+func luhnValid(s string) bool {
+	sum, n := 0, 0
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return n >= 13 && sum%10 == 0
+}
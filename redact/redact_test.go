@@ -0,0 +1,213 @@
+package redact_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go4x/logx/redact"
+)
+
+func newRedactor(t *testing.T, c redact.Config) *redact.Redactor {
+	t.Helper()
+	r, err := redact.New(c)
+	if err != nil {
+		t.Fatalf("failed to create redactor: %v", err)
+	}
+	return r
+}
+
+// TestKeyRules tests exact and glob key rules with each action
+func TestKeyRules(t *testing.T) {
+	r := newRedactor(t, redact.Config{
+		Salt: "pepper",
+		Rules: []redact.Rule{
+			{Keys: []string{"Password"}},
+			{Keys: []string{"*token*"}, Mask: "***"},
+			{Keys: []string{"email"}, Action: redact.ActionHash},
+			{Keys: []string{"account"}, Action: redact.ActionTruncate, Keep: 3},
+			{Keys: []string{"internal_*"}, Action: redact.ActionDrop},
+		},
+	})
+	testCases := []struct {
+		key   string
+		value any
+		want  any
+		keep  bool
+	}{
+		{"password", "hunter2", "[REDACTED]", true},
+		{"PASSWORD", 1234, "[REDACTED]", true},
+		{"refresh_token", "abc", "***", true},
+		{"account", "ACME-42", "ACM...", true},
+		{"internal_state", "x", nil, false},
+		{"user", "alice", "alice", true},
+	}
+	for _, tc := range testCases {
+		out, _, keep := r.Field(tc.key, tc.value)
+		if keep != tc.keep || (keep && out != tc.want) {
+			t.Errorf("Field(%q, %v) = %v, %v; want %v, %v", tc.key, tc.value, out, keep, tc.want, tc.keep)
+		}
+	}
+
+	h1, _, _ := r.Field("email", "alice@example.com")
+	h2, _, _ := r.Field("email", "alice@example.com")
+	h3, _, _ := r.Field("email", "bob@example.com")
+	if h1 != h2 || h1 == h3 || !strings.HasPrefix(h1.(string), "sha256:") {
+		t.Errorf("unexpected hashes: %v %v %v", h1, h2, h3)
+	}
+	other := newRedactor(t, redact.Config{Salt: "salt", Rules: []redact.Rule{{Keys: []string{"email"}, Action: redact.ActionHash}}})
+	if h4, _, _ := other.Field("email", "alice@example.com"); h4 == h1 {
+		t.Error("hashes should depend on the salt")
+	}
+}
+
+// TestPatterns tests the built-in value patterns
+func TestPatterns(t *testing.T) {
+	r := newRedactor(t, redact.Config{Rules: []redact.Rule{
+		{Pattern: "pan", Action: redact.ActionTruncate, Keep: 6},
+		{Pattern: "email"},
+		{Pattern: "jwt", Mask: "<jwt>"},
+	}})
+	testCases := []struct {
+		in, want string
+	}{
+		{"card 4111-1111-1111-1111 declined", "card 4111-1... declined"},
+		{"order 1234567890123 shipped", "order 1234567890123 shipped"},
+		{"mail bob@example.org now", "mail [REDACTED] now"},
+		{"token eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig-_1 ok", "token <jwt> ok"},
+	}
+	for _, tc := range testCases {
+		if got := r.String(tc.in); got != tc.want {
+			t.Errorf("String(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+	if out, changed, _ := r.Field("note", "to bob@example.org"); !changed || out != "to [REDACTED]" {
+		t.Errorf("unexpected field value: %v", out)
+	}
+	if out, _, _ := r.Field("err", errors.New("no mailbox bob@example.org")); out != "no mailbox [REDACTED]" {
+		t.Errorf("unexpected error value: %v", out)
+	}
+}
+
+// TestScopedPattern tests a pattern that only applies to the values of some keys
+func TestScopedPattern(t *testing.T) {
+	r := newRedactor(t, redact.Config{Rules: []redact.Rule{{Keys: []string{"query"}, Pattern: `secret=\w+`}}})
+	if out, _, _ := r.Field("query", "a=1&secret=xyz"); out != "a=1&[REDACTED]" {
+		t.Errorf("unexpected value: %v", out)
+	}
+	if out, changed, _ := r.Field("body", "secret=xyz"); changed || out != "secret=xyz" {
+		t.Errorf("the pattern should not apply to other keys: %v", out)
+	}
+	if got := r.String("secret=xyz"); got != "secret=xyz" {
+		t.Errorf("the pattern should not apply to messages: %q", got)
+	}
+}
+
+// TestNested tests that maps and slices are redacted recursively without modifying the input
+func TestNested(t *testing.T) {
+	r := newRedactor(t, redact.Config{UseDefaultRules: true})
+	in := map[string]any{
+		"user":    map[string]any{"name": "alice", "password": "p"},
+		"cookies": []any{"a", "eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.sig"},
+	}
+	out, changed, _ := r.Field("request", in)
+	if !changed {
+		t.Fatal("expected the value to change")
+	}
+	m := out.(map[string]any)
+	if m["user"].(map[string]any)["password"] != "[REDACTED]" || m["user"].(map[string]any)["name"] != "alice" {
+		t.Errorf("unexpected user: %v", m["user"])
+	}
+	if m["cookies"].([]any)[1] != "[REDACTED]" {
+		t.Errorf("unexpected cookies: %v", m["cookies"])
+	}
+	if in["user"].(map[string]any)["password"] != "p" {
+		t.Error("the input should not be modified")
+	}
+}
+
+// TestShapes tests that the maps, slices and structs of any type and the JSON text of string values are redacted
+func TestShapes(t *testing.T) {
+	r := newRedactor(t, redact.Config{UseDefaultRules: true})
+	type credentials struct {
+		User     string
+		Password string
+		APIKey   string `json:"api_key"`
+		Ignored  string `json:"-"`
+		secret   string
+	}
+	testCases := []struct {
+		name  string
+		value any
+		leak  string
+	}{
+		{"header", http.Header{"Authorization": {"Bearer tok-1"}, "Accept": {"*/*"}}, "tok-1"},
+		{"map of slices", map[string][]string{"token": {"tok-2"}}, "tok-2"},
+		{"map of strings", map[string]string{"password": "pw-3", "user": "bob"}, "pw-3"},
+		{"struct", credentials{User: "bob", Password: "pw-4", APIKey: "key-4", secret: "s"}, "pw-4"},
+		{"struct pointer", &credentials{User: "bob", Password: "pw-5"}, "pw-5"},
+		{"slice of structs", []credentials{{User: "bob", Password: "pw-6"}}, "pw-6"},
+		{"json text", `{"password":"pw-7","user":"bob"}`, "pw-7"},
+	}
+	for _, tc := range testCases {
+		out, changed, keep := r.Field("request", tc.value)
+		if !changed || !keep {
+			t.Errorf("%s: expected the value to change, got %v", tc.name, out)
+			continue
+		}
+		if got := fmt.Sprintf("%v", out); strings.Contains(got, tc.leak) || !strings.Contains(got, "[REDACTED]") {
+			t.Errorf("%s: %q leaked: %s", tc.name, tc.leak, got)
+		}
+	}
+
+	out, _, _ := r.Field("request", credentials{User: "bob", Password: "pw", APIKey: "key", Ignored: "x"})
+	m := out.(map[string]any)
+	if m["User"] != "bob" || m["api_key"] != "[REDACTED]" || len(m) != 3 {
+		t.Errorf("unexpected struct: %v", m)
+	}
+	in := map[string]string{"user": "bob"}
+	if out, changed, _ := r.Field("request", in); changed || out.(map[string]string)["user"] != "bob" {
+		t.Errorf("expected the value to be left as is, got %v", out)
+	}
+}
+
+// TestMessageAssignments tests that key rules redact key=value pairs in messages
+func TestMessageAssignments(t *testing.T) {
+	r := newRedactor(t, redact.Config{UseDefaultRules: true})
+	testCases := []struct {
+		in, want string
+	}{
+		{"login password=hunter2 ok", "login password=[REDACTED] ok"},
+		{`Authorization: "Bearer x"`, "Authorization: [REDACTED]"},
+		{"access_token=abc&user=1", "access_token=[REDACTED]&user=1"},
+		{"Authorization: Bearer abc.def", "Authorization: [REDACTED]"},
+		{"authorization=Basic dXNlcjpwdw== user=1", "authorization=[REDACTED] user=1"},
+		{`body {"password":"x","user":"bob"}`, `body {"password":"[REDACTED]","user":"bob"}`},
+		{`{"api_key": 42}`, `{"api_key": [REDACTED]}`},
+		{"password_hint=blue", "password_hint=blue"},
+		{"no secrets here", "no secrets here"},
+	}
+	for _, tc := range testCases {
+		if got := r.String(tc.in); got != tc.want {
+			t.Errorf("String(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+// TestNewValidation tests configuration validation
+func TestNewValidation(t *testing.T) {
+	testCases := []redact.Config{
+		{Rules: []redact.Rule{{}}},
+		{Rules: []redact.Rule{{Keys: []string{"a"}, Action: "encrypt"}}},
+		{Rules: []redact.Rule{{Keys: []string{"a"}, Action: redact.ActionHash}}},
+		{Rules: []redact.Rule{{Pattern: "("}}},
+		{Rules: []redact.Rule{{Keys: []string{"[a"}}}},
+	}
+	for _, c := range testCases {
+		if _, err := redact.New(c); err == nil {
+			t.Errorf("expected error for %+v", c)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package logx_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go4x/logx"
+	"github.com/go4x/logx/redact"
+)
+
+// TestRedact tests that both backends redact fields and formatted messages before writing them
+func TestRedact(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "info",
+				Dir:    logDir,
+				Format: "json",
+				Redact: &redact.Config{
+					UseDefaultRules: true,
+					Salt:            "pepper",
+					Rules: []redact.Rule{
+						{Keys: []string{"email"}, Action: redact.ActionHash},
+						{Keys: []string{"debug_*"}, Action: redact.ActionDrop},
+					},
+				},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			ctx := logx.NewContext(context.Background(), "Authorization", "Bearer abc")
+			logx.Log(ctx, logx.InfoLevel, "signed in",
+				"password", "hunter2",
+				"email", "alice@example.com",
+				"debug_dump", "internal",
+				"user", map[string]any{"name": "alice", "api_key": "k-123"},
+				"header", http.Header{"Authorization": {"Bearer hdr-tok"}},
+				"labels", map[string]string{"secret": "lbl-secret"},
+				"creds", struct{ User, Password string }{"bob", "struct-pw"},
+				"body", `{"password":"json-pw"}`)
+			logx.Infof("charging card %s with password=%s", "4111 1111 1111 1111", "s3cret")
+
+			content := readLogs(t, logDir)
+			for _, leaked := range []string{"hunter2", "alice@example.com", "debug_dump", "k-123", "Bearer abc", "4111 1111 1111 1111", "s3cret", "hdr-tok", "lbl-secret", "struct-pw", "json-pw"} {
+				if strings.Contains(content, leaked) {
+					t.Errorf("%q leaked into the output: %q", leaked, content)
+				}
+			}
+			for _, want := range []string{`"password":"[REDACTED]"`, `"email":"sha256:`, `"name":"alice"`, "charging card [REDACTED] with password=[REDACTED]"} {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %s in output, got %q", want, content)
+				}
+			}
+		})
+	}
+}
+
+// TestRedactInvalidConfig tests that Init rejects invalid redaction rules
+func TestRedactInvalidConfig(t *testing.T) {
+	err := logx.Init(&logx.LoggerConfig{
+		Dir:    t.TempDir(),
+		Redact: &redact.Config{Rules: []redact.Rule{{Keys: []string{"email"}, Action: redact.ActionHash}}},
+	})
+	if err == nil {
+		t.Error("expected error for a hash rule without salt")
+	}
+}
@@ -0,0 +1,323 @@
+// Package sample reduces the volume of repetitive log entries. Entries with
+// the same level and message can be sampled, logging the first N of every
+// tick and then every Mth, and the levels and messages can be limited with
+// token buckets. The number of entries dropped is reported periodically in
+// a summary entry, so that dropping is never silent.
+//
+// Example usage:
+//
+//	config := &logx.LoggerConfig{
+//	    // ...
+//	    Sampling: &sample.Config{
+//	        First:      100,
+//	        Thereafter: 100,
+//	        Levels: map[string]sample.Limit{
+//	            "debug": {Rate: 1000},
+//	        },
+//	        PerMessage: sample.Limit{Rate: 50, Burst: 100},
+//	    },
+//	}
+package sample
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go4x/logx/core"
+)
+
+// SummaryMessage is the message of the summary entries.
+const SummaryMessage = "log entries dropped by sampling"
+
+// Defaults of Config.
+const (
+	DefaultTick            = time.Second
+	DefaultSummaryInterval = time.Minute
+)
+
+// maxMessageBuckets bounds the number of per-message token buckets; idle
+// buckets are discarded beyond it.
+const maxMessageBuckets = 10000
+
+// Limit is a token bucket limit.
+type Limit struct {
+	// Rate is the sustained number of entries per second (0 disables).
+	Rate float64 `mapstructure:"rate" yaml:"rate"`
+
+	// Burst is the number of entries allowed at once (default Rate,
+	// rounded up).
+	Burst int `mapstructure:"burst" yaml:"burst"`
+}
+
+// Config holds the configuration of sampling and rate limiting.
+type Config struct {
+	// Tick is the period of the sampling counters (default 1s).
+	Tick time.Duration `mapstructure:"tick" yaml:"tick"`
+
+	// First is the number of entries with the same level and message logged
+	// every tick before sampling starts (0 disables sampling).
+	First int `mapstructure:"first" yaml:"first"`
+
+	// Thereafter logs every Thereafter-th entry after the first ones in the
+	// same tick (0 drops them all).
+	Thereafter int `mapstructure:"thereafter" yaml:"thereafter"`
+
+	// Levels limits the entries of each level, by level name.
+	Levels map[string]Limit `mapstructure:"levels" yaml:"levels"`
+
+	// PerMessage limits the entries of every level and message.
+	PerMessage Limit `mapstructure:"per-message" yaml:"per-message"`
+
+	// SummaryInterval is the period of the summary entry (default 1m,
+	// negative disables).
+	SummaryInterval time.Duration `mapstructure:"summary-interval" yaml:"summary-interval"`
+}
+
+// Summary holds the number of entries dropped since the previous summary.
+type Summary struct {
+	// Sampled is the number of entries dropped by sampling.
+	Sampled uint64
+	// Limited is the number of entries dropped by the rate limits.
+	Limited uint64
+}
+
+// key identifies the entries sampled and limited together.
+type key struct {
+	level core.Level
+	msg   string
+}
+
+// bucket is the state of a token bucket.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// refill adds the tokens earned since the last call.
+func (b *bucket) refill(l Limit, now time.Time) {
+	b.tokens = math.Min(float64(l.Burst), b.tokens+now.Sub(b.last).Seconds()*l.Rate)
+	b.last = now
+}
+
+// Sampler decides which entries are logged. It is safe for concurrent use.
+type Sampler struct {
+	cfg    Config
+	levels map[core.Level]Limit
+
+	mu       sync.Mutex
+	tick     int64
+	counts   map[key]uint64
+	levelBkt map[core.Level]*bucket
+	msgBkt   map[key]*bucket
+
+	sampled atomic.Uint64
+	limited atomic.Uint64
+
+	start sync.Once
+	stop  chan struct{}
+	done  chan struct{}
+	close sync.Once
+}
+
+// New returns a Sampler with the given configuration.
+func New(c Config) (*Sampler, error) {
+	if c.Tick <= 0 {
+		c.Tick = DefaultTick
+	}
+	if c.SummaryInterval == 0 {
+		c.SummaryInterval = DefaultSummaryInterval
+	}
+	if c.First < 0 || c.Thereafter < 0 {
+		return nil, fmt.Errorf("sample: first and thereafter must not be negative")
+	}
+	s := &Sampler{
+		levels:   make(map[core.Level]Limit),
+		counts:   make(map[key]uint64),
+		levelBkt: make(map[core.Level]*bucket),
+		msgBkt:   make(map[key]*bucket),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	for name, l := range c.Levels {
+		level, err := core.ParseLevel(name)
+		if err != nil || name == "" {
+			return nil, fmt.Errorf("sample: unknown level: %q", name)
+		}
+		if l, err = normalize(l); err != nil {
+			return nil, err
+		}
+		if l.Rate > 0 {
+			s.levels[level] = l
+		}
+	}
+	l, err := normalize(c.PerMessage)
+	if err != nil {
+		return nil, err
+	}
+	c.PerMessage = l
+	s.cfg = c
+	return s, nil
+}
+
+// normalize validates l and sets its default burst.
+func normalize(l Limit) (Limit, error) {
+	if l.Rate < 0 || l.Burst < 0 {
+		return l, fmt.Errorf("sample: rate and burst must not be negative")
+	}
+	if l.Burst == 0 {
+		l.Burst = int(math.Ceil(l.Rate))
+	}
+	return l, nil
+}
+
+// Config returns the configuration of s, with the defaults applied.
+func (s *Sampler) Config() Config {
+	return s.cfg
+}
+
+// Sampling reports whether entries are sampled.
+func (s *Sampler) Sampling() bool {
+	return s.cfg.First > 0
+}
+
+// Limiting reports whether entries are rate limited.
+func (s *Sampler) Limiting() bool {
+	return len(s.levels) > 0 || s.cfg.PerMessage.Rate > 0
+}
+
+// Sample reports whether an entry is kept by sampling, and counts it
+// otherwise.
+func (s *Sampler) Sample(level core.Level, msg string) bool {
+	if !s.Sampling() {
+		return true
+	}
+	tick := time.Now().UnixNano() / int64(s.cfg.Tick)
+	s.mu.Lock()
+	if tick != s.tick {
+		clear(s.counts)
+		s.tick = tick
+	}
+	k := key{level, msg}
+	s.counts[k]++
+	n := s.counts[k]
+	s.mu.Unlock()
+
+	first := uint64(s.cfg.First)
+	if n <= first || s.cfg.Thereafter > 0 && (n-first)%uint64(s.cfg.Thereafter) == 0 {
+		return true
+	}
+	s.CountSampled()
+	return false
+}
+
+// CountSampled counts an entry dropped by sampling, for backends that sample
+// entries themselves.
+func (s *Sampler) CountSampled() {
+	s.sampled.Add(1)
+}
+
+// Allow reports whether an entry is within the rate limits, and counts it
+// otherwise. An allowed entry takes a token from the bucket of its level and
+// from the bucket of its message.
+func (s *Sampler) Allow(level core.Level, msg string) bool {
+	if !s.Limiting() {
+		return true
+	}
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var lb, mb *bucket
+	if l, ok := s.levels[level]; ok {
+		lb = bucketOf(s.levelBkt, level, l, now)
+	}
+	if s.cfg.PerMessage.Rate > 0 {
+		if len(s.msgBkt) >= maxMessageBuckets {
+			s.prune(now)
+		}
+		mb = bucketOf(s.msgBkt, key{level, msg}, s.cfg.PerMessage, now)
+	}
+	if lb != nil && lb.tokens < 1 || mb != nil && mb.tokens < 1 {
+		s.limited.Add(1)
+		return false
+	}
+	if lb != nil {
+		lb.tokens--
+	}
+	if mb != nil {
+		mb.tokens--
+	}
+	return true
+}
+
+// bucketOf returns the refilled bucket of k in m, creating a full one.
+func bucketOf[K comparable](m map[K]*bucket, k K, l Limit, now time.Time) *bucket {
+	b, ok := m[k]
+	if !ok {
+		b = &bucket{tokens: float64(l.Burst), last: now}
+		m[k] = b
+		return b
+	}
+	b.refill(l, now)
+	return b
+}
+
+// prune discards the message buckets that have refilled, which behave as
+// new buckets.
+func (s *Sampler) prune(now time.Time) {
+	for k, b := range s.msgBkt {
+		if b.refill(s.cfg.PerMessage, now); b.tokens >= float64(s.cfg.PerMessage.Burst) {
+			delete(s.msgBkt, k)
+		}
+	}
+}
+
+// Start calls report with the entries dropped every SummaryInterval, if any,
+// until s is closed. Only the first call has an effect.
+func (s *Sampler) Start(report func(Summary)) {
+	s.start.Do(func() {
+		if s.cfg.SummaryInterval < 0 {
+			close(s.done)
+			return
+		}
+		go s.run(report)
+	})
+}
+
+// run is the summary loop.
+func (s *Sampler) run(report func(Summary)) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.cfg.SummaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.report(report)
+		case <-s.stop:
+			s.report(report)
+			return
+		}
+	}
+}
+
+// report calls report with the counts, which are reset.
+func (s *Sampler) report(report func(Summary)) {
+	sum := Summary{Sampled: s.sampled.Swap(0), Limited: s.limited.Swap(0)}
+	if sum.Sampled > 0 || sum.Limited > 0 {
+		report(sum)
+	}
+}
+
+// Close stops the summary loop, reporting the entries dropped since the
+// last summary.
+func (s *Sampler) Close() error {
+	s.close.Do(func() {
+		s.Start(func(Summary) {})
+		close(s.stop)
+		<-s.done
+	})
+	return nil
+}
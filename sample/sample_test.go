@@ -0,0 +1,127 @@
+package sample_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sample"
+)
+
+func newSampler(t *testing.T, c sample.Config) *sample.Sampler {
+	t.Helper()
+	s, err := sample.New(c)
+	if err != nil {
+		t.Fatalf("failed to create sampler: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// TestSample tests that the first entries and then every Mth are kept per level and message
+func TestSample(t *testing.T) {
+	s := newSampler(t, sample.Config{Tick: time.Hour, First: 2, Thereafter: 3})
+	var kept []int
+	for i := 1; i <= 10; i++ {
+		if s.Sample(core.ErrorLevel, "db timeout") {
+			kept = append(kept, i)
+		}
+	}
+	if len(kept) != 4 || kept[2] != 5 || kept[3] != 8 {
+		t.Errorf("expected entries 1, 2, 5 and 8, got %v", kept)
+	}
+	if !s.Sample(core.WarnLevel, "db timeout") || !s.Sample(core.ErrorLevel, "other") {
+		t.Error("other levels and messages must be sampled separately")
+	}
+}
+
+// TestSampleThereafterZero tests that all entries after the first ones are dropped
+func TestSampleThereafterZero(t *testing.T) {
+	s := newSampler(t, sample.Config{Tick: time.Hour, First: 1})
+	s.Sample(core.InfoLevel, "m")
+	for i := 0; i < 5; i++ {
+		if s.Sample(core.InfoLevel, "m") {
+			t.Fatal("expected the entry to be dropped")
+		}
+	}
+}
+
+// TestLimit tests the token buckets of the levels and the messages
+func TestLimit(t *testing.T) {
+	s := newSampler(t, sample.Config{
+		Levels:     map[string]sample.Limit{"info": {Rate: 0.001, Burst: 3}},
+		PerMessage: sample.Limit{Rate: 0.001, Burst: 2},
+	})
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if s.Allow(core.InfoLevel, "same") {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("expected the message limit to allow 2 entries, got %d", allowed)
+	}
+	if !s.Allow(core.InfoLevel, "other") {
+		t.Error("expected the last token of the level to be available")
+	}
+	if s.Allow(core.InfoLevel, "third") {
+		t.Error("expected the level limit to be exhausted")
+	}
+	if !s.Allow(core.ErrorLevel, "same") {
+		t.Error("expected levels without a limit to only use the message limit")
+	}
+}
+
+// TestLimitRefill tests that tokens are earned at the configured rate
+func TestLimitRefill(t *testing.T) {
+	s := newSampler(t, sample.Config{PerMessage: sample.Limit{Rate: 50, Burst: 1}})
+	if !s.Allow(core.InfoLevel, "m") || s.Allow(core.InfoLevel, "m") {
+		t.Fatal("expected a burst of 1")
+	}
+	time.Sleep(40 * time.Millisecond)
+	if !s.Allow(core.InfoLevel, "m") {
+		t.Error("expected a token after the refill")
+	}
+}
+
+// TestSummary tests that the dropped entries are reported periodically and on close
+func TestSummary(t *testing.T) {
+	s := newSampler(t, sample.Config{Tick: time.Hour, First: 1, PerMessage: sample.Limit{Rate: 0.001, Burst: 1}, SummaryInterval: 20 * time.Millisecond})
+	var mu sync.Mutex
+	var total sample.Summary
+	s.Start(func(sum sample.Summary) {
+		mu.Lock()
+		defer mu.Unlock()
+		total.Sampled += sum.Sampled
+		total.Limited += sum.Limited
+	})
+	for i := 0; i < 4; i++ {
+		if s.Sample(core.InfoLevel, "a") {
+			s.Allow(core.InfoLevel, "a")
+		}
+		s.Allow(core.InfoLevel, "b")
+	}
+	time.Sleep(50 * time.Millisecond)
+	s.Sample(core.InfoLevel, "a")
+	s.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if total.Sampled != 4 || total.Limited != 3 {
+		t.Errorf("expected 4 sampled and 3 limited entries, got %+v", total)
+	}
+}
+
+// TestNewValidation tests configuration validation
+func TestNewValidation(t *testing.T) {
+	if _, err := sample.New(sample.Config{Levels: map[string]sample.Limit{"loud": {Rate: 1}}}); err == nil {
+		t.Error("expected error for unknown level")
+	}
+	if _, err := sample.New(sample.Config{PerMessage: sample.Limit{Rate: -1}}); err == nil {
+		t.Error("expected error for negative rate")
+	}
+	if _, err := sample.New(sample.Config{First: -1}); err == nil {
+		t.Error("expected error for negative first")
+	}
+}
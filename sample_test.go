@@ -0,0 +1,142 @@
+package logx_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx"
+	"github.com/go4x/logx/audit"
+	"github.com/go4x/logx/sample"
+)
+
+// TestSampling tests that both backends sample repeated entries and report the drops in a summary
+func TestSampling(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "info",
+				Dir:    logDir,
+				Format: "json",
+				Sampling: &sample.Config{
+					Tick:            time.Hour,
+					First:           2,
+					Thereafter:      3,
+					SummaryInterval: 20 * time.Millisecond,
+				},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			for i := 0; i < 10; i++ {
+				logx.Error("db timeout")
+			}
+			waitFor(t, func() bool {
+				return strings.Contains(readLogs(t, logDir), sample.SummaryMessage)
+			})
+			content := readLogs(t, logDir)
+			if n := strings.Count(content, "db timeout"); n != 4 {
+				t.Errorf("expected 4 sampled entries, got %d: %q", n, content)
+			}
+			if !strings.Contains(content, `"sampled":6`) || !strings.Contains(content, `"rate_limited":0`) {
+				t.Errorf("expected the drops in the summary, got %q", content)
+			}
+		})
+	}
+}
+
+// TestRateLimit tests that both backends limit the entries of a level
+func TestRateLimit(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "info",
+				Dir:    logDir,
+				Format: "json",
+				Sampling: &sample.Config{
+					Levels:          map[string]sample.Limit{"info": {Rate: 0.001, Burst: 3}},
+					SummaryInterval: 20 * time.Millisecond,
+				},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			for i := 0; i < 10; i++ {
+				logx.Infof("request %d", i)
+			}
+			logx.Warn("not limited")
+			waitFor(t, func() bool {
+				return strings.Contains(readLogs(t, logDir), sample.SummaryMessage)
+			})
+			content := readLogs(t, logDir)
+			if n := strings.Count(content, "request "); n != 3 {
+				t.Errorf("expected 3 entries, got %d: %q", n, content)
+			}
+			if !strings.Contains(content, "not limited") || !strings.Contains(content, `"rate_limited":7`) {
+				t.Errorf("unexpected output: %q", content)
+			}
+		})
+	}
+}
+
+// TestSamplingCustomLevels tests that both backends sample the levels zap does not define
+func TestSamplingCustomLevels(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:     typ,
+				Level:    "info",
+				Dir:      logDir,
+				Format:   "json",
+				Sampling: &sample.Config{Tick: time.Hour, First: 1, Thereafter: 0},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			for i := 0; i < 10; i++ {
+				logx.Critical("disk full")
+				logx.Notice("config reloaded")
+			}
+			content := readLogs(t, logDir)
+			if n := strings.Count(content, "disk full"); n != 1 {
+				t.Errorf("expected 1 critical entry, got %d: %q", n, content)
+			}
+			if n := strings.Count(content, "config reloaded"); n != 1 {
+				t.Errorf("expected 1 notice entry, got %d: %q", n, content)
+			}
+		})
+	}
+}
+
+// TestSamplingAudit tests that audit entries are never sampled
+func TestSamplingAudit(t *testing.T) {
+	logDir := t.TempDir()
+	err := logx.Init(&logx.LoggerConfig{
+		Dir:      logDir,
+		Format:   "json",
+		Sampling: &sample.Config{First: 1, PerMessage: sample.Limit{Rate: 0.001, Burst: 1}},
+		Audit:    &audit.Config{Key: "secret"},
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := logx.Audit(context.Background(), "payment approved", "index", i); err != nil {
+			t.Fatalf("failed to write audit entry: %v", err)
+		}
+	}
+	content := readLogs(t, filepath.Join(logDir, "audit"))
+	if !strings.Contains(content, `"seq":5`) {
+		t.Errorf("expected 5 audit entries, got %q", content)
+	}
+}
@@ -0,0 +1,211 @@
+// Package sanitize protects text log output against log injection: it
+// escapes the characters that could forge log lines or drive a terminal,
+// and it bounds the size of messages, field values and entries with visible
+// truncation markers.
+//
+// Sanitizing is on by default for the text format. The JSON format already
+// escapes control characters and is left untouched.
+package sanitize
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Default limits in bytes.
+const (
+	DefaultMaxMessageSize = 32 << 10
+	DefaultMaxFieldSize   = 8 << 10
+	DefaultMaxEntrySize   = 128 << 10
+)
+
+// Config configures the sanitizing of text output.
+type Config struct {
+	// Disabled turns sanitizing off.
+	Disabled bool `mapstructure:"disabled" yaml:"disabled"`
+
+	// MaxMessageSize is the maximum size of a message in bytes (default
+	// 32KB, negative for no limit).
+	MaxMessageSize int `mapstructure:"max-message-size" yaml:"max-message-size"`
+
+	// MaxFieldSize is the maximum size of a string field value in bytes
+	// (default 8KB, negative for no limit).
+	MaxFieldSize int `mapstructure:"max-field-size" yaml:"max-field-size"`
+
+	// MaxEntrySize is the maximum size of an encoded entry in bytes
+	// (default 128KB, negative for no limit).
+	MaxEntrySize int `mapstructure:"max-entry-size" yaml:"max-entry-size"`
+}
+
+// limit returns the effective value of a size limit.
+func limit(n, def int) int {
+	switch {
+	case n == 0:
+		return def
+	case n < 0:
+		return -1
+	default:
+		return n
+	}
+}
+
+// Message escapes and truncates a message.
+func (c Config) Message(s string) string {
+	return c.TruncateMessage(Escape(s))
+}
+
+// TruncateMessage truncates a message to MaxMessageSize.
+func (c Config) TruncateMessage(s string) string {
+	return Truncate(s, limit(c.MaxMessageSize, DefaultMaxMessageSize))
+}
+
+// TruncateField truncates a string field value to MaxFieldSize.
+func (c Config) TruncateField(s string) string {
+	return Truncate(s, limit(c.MaxFieldSize, DefaultMaxFieldSize))
+}
+
+// Writer returns w limited to entries of MaxEntrySize bytes.
+func (c Config) Writer(w io.Writer) io.Writer {
+	max := limit(c.MaxEntrySize, DefaultMaxEntrySize)
+	if max < 0 {
+		return w
+	}
+	return &Writer{w: w, max: max}
+}
+
+// Escape escapes the C0 and C1 control characters (including CR, LF, tab
+// and the ESC of terminal escape sequences), DEL, the Unicode line and
+// paragraph separators, the bidirectional overrides and invalid UTF-8, so
+// that s is rendered on a single line exactly as written.
+func Escape(s string) string {
+	i := 0
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if needsEscape(r, size) {
+			break
+		}
+		i += size
+	}
+	if i == len(s) {
+		return s
+	}
+
+	buf := make([]byte, 0, len(s)+16)
+	buf = append(buf, s[:i]...)
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !needsEscape(r, size) {
+			buf = append(buf, s[i:i+size]...)
+			i += size
+			continue
+		}
+		switch {
+		case r == '\n':
+			buf = append(buf, `\n`...)
+		case r == '\r':
+			buf = append(buf, `\r`...)
+		case r == '\t':
+			buf = append(buf, `\t`...)
+		case r == utf8.RuneError && size == 1, r < 0x80:
+			buf = append(buf, `\x`...)
+			buf = appendHex(buf, uint64(s[i]), 2)
+		default:
+			buf = append(buf, `\u`...)
+			buf = appendHex(buf, uint64(r), 4)
+		}
+		i += size
+	}
+	return string(buf)
+}
+
+// needsEscape reports whether the rune r, encoded in size bytes, must be
+// escaped.
+func needsEscape(r rune, size int) bool {
+	switch {
+	case r < 0x20, r == 0x7f:
+		return true
+	case r < 0x80:
+		return false
+	case r == utf8.RuneError && size == 1:
+		return true
+	case r >= 0x80 && r <= 0x9f:
+		return true
+	case r == 0x2028, r == 0x2029:
+		return true
+	case r >= 0x202a && r <= 0x202e, r >= 0x2066 && r <= 0x2069:
+		return true
+	default:
+		return false
+	}
+}
+
+// appendHex appends n as a lowercase hexadecimal number of width digits.
+func appendHex(buf []byte, n uint64, width int) []byte {
+	h := strconv.FormatUint(n, 16)
+	for i := len(h); i < width; i++ {
+		buf = append(buf, '0')
+	}
+	return append(buf, h...)
+}
+
+// Truncate shortens s to at most max bytes, cutting at a rune boundary,
+// and appends a marker with the number of bytes removed. A negative max
+// disables the limit.
+func Truncate(s string, max int) string {
+	if max < 0 || len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + marker(len(s)-cut)
+}
+
+// marker returns the truncation marker for n removed bytes.
+func marker(n int) string {
+	return fmt.Sprintf("...[truncated %d bytes]", n)
+}
+
+// Writer truncates the entries written to an io.Writer. Every call to Write
+// must be one encoded entry, ending with a newline, as done by the zap and
+// slog encoders.
+type Writer struct {
+	w   io.Writer
+	max int
+}
+
+// Write implements the io.Writer interface. An entry longer than the limit,
+// not counting its newline, is cut and ended with a truncation marker and a newline.
+func (w *Writer) Write(p []byte) (int, error) {
+	n := len(p)
+	if n > 0 && p[n-1] == '\n' {
+		n--
+	}
+	if n <= w.max {
+		return w.w.Write(p)
+	}
+	cut := w.max
+	for cut > 0 && !utf8.RuneStart(p[cut]) {
+		cut--
+	}
+	removed := n - cut
+	line := make([]byte, 0, cut+32)
+	line = append(line, p[:cut]...)
+	line = append(line, marker(removed)...)
+	line = append(line, '\n')
+	if _, err := w.w.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Sync flushes the underlying writer if it supports it.
+func (w *Writer) Sync() error {
+	if s, ok := w.w.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
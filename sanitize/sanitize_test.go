@@ -0,0 +1,77 @@
+package sanitize_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/go4x/logx/sanitize"
+)
+
+// TestEscape tests the escaping of control characters, terminal escapes and invalid UTF-8
+func TestEscape(t *testing.T) {
+	testCases := []struct {
+		in, want string
+	}{
+		{"plain text ✓", "plain text ✓"},
+		{"a\nINFO forged", `a\nINFO forged`},
+		{"a\r\nb\tc", `a\r\nb\tc`},
+		{"\x1b[31mred\x1b[0m", `\x1b[31mred\x1b[0m`},
+		{"nul\x00del\x7f", `nul\x00del\x7f`},
+		{"c1\u0085next", `c1\u0085next`},
+		{"line\u2028sep", `line\u2028sep`},
+		{"bidi\u202eevil", `bidi\u202eevil`},
+		{"bad\xffutf8", `bad\xffutf8`},
+	}
+	for _, tc := range testCases {
+		if got := sanitize.Escape(tc.in); got != tc.want {
+			t.Errorf("Escape(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+// TestTruncate tests truncation at rune boundaries with a marker
+func TestTruncate(t *testing.T) {
+	if got := sanitize.Truncate("short", 10); got != "short" {
+		t.Errorf("unexpected result: %q", got)
+	}
+	if got := sanitize.Truncate("abcdefghij", 4); got != "abcd...[truncated 6 bytes]" {
+		t.Errorf("unexpected result: %q", got)
+	}
+	// "é" is two bytes and must not be split
+	if got := sanitize.Truncate("aéb", 2); got != "a...[truncated 3 bytes]" {
+		t.Errorf("unexpected result: %q", got)
+	}
+	if got := sanitize.Truncate("abc", -1); got != "abc" {
+		t.Errorf("a negative limit should disable truncation: %q", got)
+	}
+
+	c := sanitize.Config{MaxMessageSize: 3, MaxFieldSize: -1}
+	if got := c.Message("a\nbc"); got != `a\n...[truncated 2 bytes]` {
+		t.Errorf("unexpected message: %q", got)
+	}
+	long := strings.Repeat("x", 1<<20)
+	if got := c.TruncateField(long); got != long {
+		t.Error("a negative field limit should disable truncation")
+	}
+	if got := (sanitize.Config{}).TruncateField(long); len(got) > sanitize.DefaultMaxFieldSize+32 {
+		t.Errorf("expected the default field limit, got %d bytes", len(got))
+	}
+}
+
+// TestWriter tests that long entries are cut and still end with a newline
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := sanitize.Config{MaxEntrySize: 8}.Writer(&buf)
+
+	for _, line := range []string{"12345678\n", "1234567890\n"} {
+		n, err := w.Write([]byte(line))
+		if err != nil || n != len(line) {
+			t.Fatalf("Write = %d, %v", n, err)
+		}
+	}
+	want := "12345678\n12345678...[truncated 2 bytes]\n"
+	if buf.String() != want {
+		t.Errorf("expected %q, got %q", want, buf.String())
+	}
+}
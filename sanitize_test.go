@@ -0,0 +1,71 @@
+package logx_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/go4x/logx"
+	"github.com/go4x/logx/sanitize"
+)
+
+// TestSanitize tests that the text format of both backends cannot be used to forge log lines
+func TestSanitize(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:     typ,
+				Level:    "info",
+				Dir:      logDir,
+				Format:   "text",
+				Sanitize: sanitize.Config{MaxMessageSize: 64, MaxFieldSize: 16},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			user := "mallory\nINFO admin logged in \x1b[2J"
+			logx.Infof("login failed for %s", user)
+			logx.Log(context.Background(), logx.WarnLevel, "bad input", "user", user, "err", errors.New(strings.Repeat("e", 40)))
+			logx.Info(strings.Repeat("m", 100))
+
+			content := readLogs(t, logDir)
+			lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+			if len(lines) != 3 {
+				t.Fatalf("expected 3 lines, got %d: %q", len(lines), content)
+			}
+			if strings.Contains(content, "\x1b") {
+				t.Errorf("terminal escape written to the file: %q", content)
+			}
+			if !strings.Contains(lines[0], `mallory\nINFO admin`) {
+				t.Errorf("expected the newline to be escaped: %q", lines[0])
+			}
+			for _, want := range []string{"...[truncated 24 bytes]", "...[truncated 36 bytes]"} {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %s in output, got %q", want, content)
+				}
+			}
+		})
+	}
+}
+
+// TestSanitizeDisabled tests that sanitizing can be turned off
+func TestSanitizeDisabled(t *testing.T) {
+	logDir := t.TempDir()
+	err := logx.Init(&logx.LoggerConfig{
+		Type:     logx.LoggerTypeZap,
+		Level:    "info",
+		Dir:      logDir,
+		Format:   "text",
+		Sanitize: sanitize.Config{Disabled: true},
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+	logx.Info("first\nsecond")
+	if content := readLogs(t, logDir); !strings.Contains(content, "first\nsecond") {
+		t.Errorf("expected the raw message, got %q", content)
+	}
+}
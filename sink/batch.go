@@ -0,0 +1,434 @@
+// Package sink provides the building blocks shared by the network sinks: a
+// bounded in-memory queue that groups entries into batches, retries with
+// exponential backoff and jitter, and compressed HTTP delivery.
+package sink
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go4x/logx/core"
+)
+
+// BatchConfig configures the queue and batching of a network sink.
+type BatchConfig struct {
+	// BatchSize is the maximum number of entries sent in one request (default 512).
+	BatchSize int `mapstructure:"batch-size" yaml:"batch-size"`
+
+	// FlushInterval is how often a partial batch is sent (default 1s).
+	FlushInterval time.Duration `mapstructure:"flush-interval" yaml:"flush-interval"`
+
+	// QueueSize is the maximum number of entries waiting to be sent. Entries
+	// logged while the queue is full are dropped and counted (default 8192).
+	QueueSize int `mapstructure:"queue-size" yaml:"queue-size"`
+
+	// Spool configures the disk spool that keeps failed batches until the
+	// endpoint recovers. It is disabled by default.
+	Spool SpoolConfig `mapstructure:"spool" yaml:"spool"`
+
+	// ErrorHandler replaces the default ErrorHandler of the Batcher.
+	ErrorHandler func(err error) `mapstructure:"-" yaml:"-"`
+}
+
+// withDefaults returns a copy of c with zero values replaced by defaults.
+func (c BatchConfig) withDefaults() BatchConfig {
+	if c.BatchSize <= 0 {
+		c.BatchSize = 512
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = time.Second
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 8192
+	}
+	return c
+}
+
+// SendFunc delivers a batch of entries. The batch must not be retained
+// after the function returns.
+type SendFunc func(ctx context.Context, batch []*core.Entry) error
+
+// PartialError is returned by a SendFunc that delivered part of a batch, so
+// that the delivered entries are not sent again. Failed entries were rejected
+// for good; Retry holds those that may be sent again, which are spooled if
+// the spool is enabled.
+type PartialError struct {
+	// Failed is the number of entries rejected permanently.
+	Failed int
+	// Retry holds the entries that were not delivered and may be sent again.
+	Retry []*core.Entry
+	// Err describes the failures.
+	Err error
+}
+
+func (e *PartialError) Error() string { return e.Err.Error() }
+func (e *PartialError) Unwrap() error { return e.Err }
+
+// Stats holds the counters of a Batcher.
+type Stats struct {
+	// Sent is the number of entries delivered successfully.
+	Sent uint64
+	// Dropped is the number of entries discarded because the queue was full
+	// or the batcher was closed.
+	Dropped uint64
+	// Failed is the number of entries whose delivery failed.
+	Failed uint64
+	// Spooled is the number of entries written to the disk spool. They are
+	// counted as Sent once replayed.
+	Spooled uint64
+}
+
+// maxReplayBackoff caps the delay between attempts to replay the spool.
+const maxReplayBackoff = time.Minute
+
+// Batcher queues entries in memory and hands them to a SendFunc in batches
+// from a single background goroutine.
+type Batcher struct {
+	cfg     BatchConfig
+	send    SendFunc
+	queue   chan *core.Entry
+	flush   chan chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	closed  atomic.Bool
+	once    sync.Once
+	// mu orders the sends of Add before the final drain of Close: Add
+	// holds it for reading, Close for writing while it closes the queue
+	mu sync.RWMutex
+
+	sent     atomic.Uint64
+	dropped  atomic.Uint64
+	failed   atomic.Uint64
+	spooled  atomic.Uint64
+	reported uint64 // dropped count at the last report, owned by run
+
+	// spool and the replay state are owned by run.
+	spool          *Spool
+	replayFailures int
+	nextReplay     time.Time
+
+	// ErrorHandler is called with delivery errors, and at most once per
+	// FlushInterval with the number of entries dropped since the last call.
+	// It defaults to writing the error to stderr and must be set before the
+	// first Add.
+	ErrorHandler func(err error)
+}
+
+// NewBatcher starts a Batcher that delivers entries with send. If the spool
+// is enabled, it is opened and the batches left by a previous process are
+// replayed in the background. If its directory is still used by the spool of
+// another Batcher, such as the one of a logger being replaced, the entries
+// are delivered once that spool is closed.
+func NewBatcher(c BatchConfig, send SendFunc) (*Batcher, error) {
+	c = c.withDefaults()
+	var spool *Spool
+	var released <-chan struct{}
+	if c.Spool.Enabled {
+		var err error
+		var inUse *inUseError
+		spool, err = OpenSpool(c.Spool)
+		if errors.As(err, &inUse) {
+			released = inUse.released
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	b := &Batcher{
+		cfg:     c,
+		send:    send,
+		queue:   make(chan *core.Entry, c.QueueSize),
+		flush:   make(chan chan struct{}),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		spool:   spool,
+		ErrorHandler: func(err error) {
+			fmt.Fprintf(os.Stderr, "logx: sink: %v\n", err)
+		},
+	}
+	if c.ErrorHandler != nil {
+		b.ErrorHandler = c.ErrorHandler
+	}
+	go b.run(released)
+	return b, nil
+}
+
+// Add queues an entry without blocking. It reports false if the entry was
+// dropped because the queue is full or the batcher is closed.
+func (b *Batcher) Add(e *core.Entry) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed.Load() {
+		b.dropped.Add(1)
+		return false
+	}
+	select {
+	case b.queue <- e:
+		return true
+	default:
+		b.dropped.Add(1)
+		return false
+	}
+}
+
+// Flush sends the queued entries and waits until they are delivered or
+// spooled. It also tries to replay the spool.
+func (b *Batcher) Flush() error {
+	if b.closed.Load() {
+		return nil
+	}
+	ack := make(chan struct{})
+	select {
+	case b.flush <- ack:
+		<-ack
+	case <-b.stopped:
+	}
+	return nil
+}
+
+// Close sends the queued entries and stops the background goroutine.
+func (b *Batcher) Close() error {
+	b.once.Do(func() {
+		// the entries added before are drained by run, and those added
+		// after are dropped
+		b.mu.Lock()
+		b.closed.Store(true)
+		close(b.done)
+		b.mu.Unlock()
+	})
+	<-b.stopped
+	return nil
+}
+
+// Stats returns the current counters.
+func (b *Batcher) Stats() Stats {
+	return Stats{
+		Sent:    b.sent.Load(),
+		Dropped: b.dropped.Load(),
+		Failed:  b.failed.Load(),
+		Spooled: b.spooled.Load(),
+	}
+}
+
+func (b *Batcher) run(released <-chan struct{}) {
+	defer close(b.stopped)
+	if released != nil {
+		b.openSpool(released)
+	}
+	if b.spool != nil {
+		defer b.spool.Close()
+	}
+	ticker := time.NewTicker(b.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*core.Entry, 0, b.cfg.BatchSize)
+	add := func(e *core.Entry) {
+		batch = append(batch, e)
+		if len(batch) >= b.cfg.BatchSize {
+			b.deliver(batch)
+			batch = make([]*core.Entry, 0, b.cfg.BatchSize)
+		}
+	}
+	drain := func() {
+		for {
+			select {
+			case e := <-b.queue:
+				add(e)
+			default:
+				if len(batch) > 0 {
+					b.deliver(batch)
+					batch = make([]*core.Entry, 0, b.cfg.BatchSize)
+				}
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case e := <-b.queue:
+			add(e)
+		case <-ticker.C:
+			drain()
+			b.replay(false)
+			b.reportDropped()
+		case ack := <-b.flush:
+			drain()
+			b.replay(true)
+			close(ack)
+		case <-b.done:
+			drain()
+			b.reportDropped()
+			return
+		}
+	}
+}
+
+// openSpool opens the spool once released is closed by the spool that used
+// its directory. If the Batcher is closed first, it runs without a spool.
+func (b *Batcher) openSpool(released <-chan struct{}) {
+	for {
+		select {
+		case <-released:
+		case <-b.done:
+			return
+		}
+		spool, err := OpenSpool(b.cfg.Spool)
+		var inUse *inUseError
+		if errors.As(err, &inUse) {
+			released = inUse.released
+			continue
+		}
+		if err != nil {
+			if b.ErrorHandler != nil {
+				b.ErrorHandler(err)
+			}
+			return
+		}
+		b.spool = spool
+		return
+	}
+}
+
+// deliver sends one batch and records the outcome. With a spool, a batch is
+// spooled if it fails to be delivered, or without trying if older batches are
+// still waiting in the spool, so that the order is preserved.
+func (b *Batcher) deliver(batch []*core.Entry) {
+	if b.spool != nil && b.spool.Len() > 0 {
+		b.spoolBatch(batch, nil)
+		return
+	}
+	err := b.send(context.Background(), batch)
+	if err == nil {
+		b.sent.Add(uint64(len(batch)))
+		return
+	}
+	var pe *PartialError
+	if errors.As(err, &pe) {
+		b.partial(len(batch), pe)
+		if len(pe.Retry) == 0 {
+			return
+		}
+		if b.spool != nil {
+			b.spoolBatch(pe.Retry, pe.Err)
+			return
+		}
+		b.fail(len(pe.Retry), pe.Err)
+		return
+	}
+	if b.spool != nil && !IsPermanent(err) {
+		b.spoolBatch(batch, err)
+		return
+	}
+	b.fail(len(batch), err)
+}
+
+// fail counts entries whose delivery failed and reports err.
+func (b *Batcher) fail(n int, err error) {
+	b.failed.Add(uint64(n))
+	if b.ErrorHandler != nil {
+		b.ErrorHandler(fmt.Errorf("failed to deliver %d entries: %w", n, err))
+	}
+}
+
+// partial counts the entries of a batch of n entries that were delivered or
+// rejected for good according to pe.
+func (b *Batcher) partial(n int, pe *PartialError) {
+	b.sent.Add(uint64(n - pe.Failed - len(pe.Retry)))
+	if pe.Failed > 0 {
+		b.fail(pe.Failed, pe.Err)
+	}
+}
+
+// spoolBatch writes a batch to the spool after the delivery error cause, if any.
+func (b *Batcher) spoolBatch(batch []*core.Entry, cause error) {
+	dropped, err := b.spool.Append(batch)
+	b.dropped.Add(uint64(dropped))
+	if err != nil {
+		b.fail(len(batch), errors.Join(cause, err))
+		return
+	}
+	b.spooled.Add(uint64(len(batch)))
+	if cause != nil && b.ErrorHandler != nil {
+		b.ErrorHandler(fmt.Errorf("spooled %d entries after failed delivery: %w", len(batch), cause))
+	}
+}
+
+// replay sends the spooled batches in order until the spool is empty or a
+// delivery fails. After a failure, replay backs off exponentially unless force
+// is set.
+func (b *Batcher) replay(force bool) {
+	if b.spool == nil || b.spool.Len() == 0 || !force && time.Now().Before(b.nextReplay) {
+		return
+	}
+	for {
+		batch, err := b.spool.Peek()
+		if err != nil {
+			if b.ErrorHandler != nil {
+				b.ErrorHandler(err)
+			}
+			return
+		}
+		if batch == nil {
+			return
+		}
+		err = b.send(context.Background(), batch)
+		var pe *PartialError
+		if errors.As(err, &pe) {
+			// the entries left are spooled again, after the other batches,
+			// so that the delivered ones are not sent twice
+			if perr := b.spool.Pop(); perr != nil && b.ErrorHandler != nil {
+				b.ErrorHandler(perr)
+			}
+			b.partial(len(batch), pe)
+			if len(pe.Retry) == 0 {
+				continue
+			}
+			dropped, aerr := b.spool.Append(pe.Retry)
+			b.dropped.Add(uint64(dropped))
+			if aerr != nil {
+				b.fail(len(pe.Retry), errors.Join(pe.Err, aerr))
+			}
+			b.replayFailures++
+			b.nextReplay = time.Now().Add(Backoff(b.replayFailures, b.cfg.FlushInterval, maxReplayBackoff))
+			if b.ErrorHandler != nil {
+				b.ErrorHandler(fmt.Errorf("failed to replay %d spooled entries: %w", len(pe.Retry), pe.Err))
+			}
+			return
+		}
+		if err != nil && !IsPermanent(err) {
+			b.replayFailures++
+			b.nextReplay = time.Now().Add(Backoff(b.replayFailures, b.cfg.FlushInterval, maxReplayBackoff))
+			if b.ErrorHandler != nil {
+				b.ErrorHandler(fmt.Errorf("failed to replay %d spooled entries: %w", len(batch), err))
+			}
+			return
+		}
+		if perr := b.spool.Pop(); perr != nil && b.ErrorHandler != nil {
+			b.ErrorHandler(perr)
+		}
+		if err != nil {
+			b.fail(len(batch), err)
+			continue
+		}
+		b.replayFailures = 0
+		b.sent.Add(uint64(len(batch)))
+	}
+}
+
+// reportDropped reports the entries dropped since the last report.
+func (b *Batcher) reportDropped() {
+	dropped := b.dropped.Load()
+	if dropped == b.reported {
+		return
+	}
+	n := dropped - b.reported
+	b.reported = dropped
+	if b.ErrorHandler != nil {
+		b.ErrorHandler(fmt.Errorf("dropped %d entries because the queue was full", n))
+	}
+}
@@ -0,0 +1,100 @@
+package sink
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// Redialer holds the connection of a stream or datagram sink. It connects
+// lazily and, after a failure, waits with exponential backoff and jitter
+// before dialing again, so that logging does not stall on a down server.
+// A Redialer is not safe for concurrent use.
+type Redialer struct {
+	// Network and Address are passed to net.Dial.
+	Network string
+	Address string
+
+	// TLS enables TLS over the connection if not nil.
+	TLS *tls.Config
+
+	// Timeout bounds dialing (default 5s).
+	Timeout time.Duration
+
+	// MinBackoff and MaxBackoff bound the delay between failed attempts
+	// (default 100ms and 30s).
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	conn      net.Conn
+	failures  int
+	nextRetry time.Time
+}
+
+// Conn returns the current connection, dialing if there is none. It fails
+// without dialing while the backoff after the last failure has not elapsed.
+func (r *Redialer) Conn() (net.Conn, error) {
+	if r.conn != nil {
+		return r.conn, nil
+	}
+	if time.Now().Before(r.nextRetry) {
+		return nil, errors.New("not connected, waiting to reconnect")
+	}
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	var err error
+	if r.TLS != nil {
+		conn, err = tls.DialWithDialer(dialer, r.Network, r.Address, r.TLS)
+	} else {
+		conn, err = dialer.Dial(r.Network, r.Address)
+	}
+	if err != nil {
+		r.backoff()
+		return nil, fmt.Errorf("dial: %w", err)
+	}
+	r.conn = conn
+	r.failures = 0
+	r.nextRetry = time.Time{}
+	return conn, nil
+}
+
+// Fail closes the current connection after an I/O error and delays the next
+// dial.
+func (r *Redialer) Fail() {
+	if r.conn != nil {
+		r.conn.Close()
+		r.conn = nil
+	}
+	r.backoff()
+}
+
+// Close closes the current connection.
+func (r *Redialer) Close() error {
+	if r.conn == nil {
+		return nil
+	}
+	err := r.conn.Close()
+	r.conn = nil
+	return err
+}
+
+func (r *Redialer) backoff() {
+	minDelay, maxDelay := r.MinBackoff, r.MaxBackoff
+	if minDelay <= 0 {
+		minDelay = 100 * time.Millisecond
+	}
+	if maxDelay <= 0 {
+		maxDelay = 30 * time.Second
+	}
+	if maxDelay < minDelay {
+		maxDelay = minDelay
+	}
+	r.nextRetry = time.Now().Add(Backoff(r.failures, minDelay, maxDelay))
+	r.failures++
+}
@@ -0,0 +1,216 @@
+// Package elasticsearch provides a sink that indexes logx entries into
+// Elasticsearch or OpenSearch with the _bulk API.
+//
+// Example usage:
+//
+//	config := &logx.LoggerConfig{
+//	    // ...
+//	    Elasticsearch: &elasticsearch.Config{
+//	        URL:   "http://elasticsearch:9200",
+//	        Index: "logs-2006.01.02",
+//	        HTTP: sink.HTTPConfig{
+//	            Headers: map[string]string{"Authorization": "ApiKey " + apiKey},
+//	        },
+//	    },
+//	}
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+)
+
+// Config holds the configuration of the Elasticsearch sink.
+type Config struct {
+	// URL is the base URL of the cluster, e.g. http://localhost:9200.
+	URL string `mapstructure:"url" yaml:"url"`
+
+	// Index is the target index or data stream (default "logx"). A name
+	// containing the year 2006 is a Go time layout applied to the entry time
+	// in UTC, so "logs-2006.01.02" writes to daily indices.
+	Index string `mapstructure:"index" yaml:"index"`
+
+	// Pipeline is an optional ingest pipeline.
+	Pipeline string `mapstructure:"pipeline" yaml:"pipeline"`
+
+	// HTTP configures headers, compression and timeout of the requests.
+	HTTP sink.HTTPConfig `mapstructure:"http" yaml:"http"`
+
+	// Batch configures the bounded queue and batching.
+	Batch sink.BatchConfig `mapstructure:"batch" yaml:"batch"`
+
+	// Retry configures the retries of failed requests and of documents
+	// rejected with a retryable status.
+	Retry sink.RetryConfig `mapstructure:"retry" yaml:"retry"`
+}
+
+// Client is a core.Sink that indexes entries with the _bulk API.
+type Client struct {
+	cfg     Config
+	url     string
+	batcher *sink.Batcher
+}
+
+// New creates a Client and starts its background delivery.
+func New(c Config) (*Client, error) {
+	if c.URL == "" {
+		return nil, errors.New("elasticsearch: url is required")
+	}
+	if c.Index == "" {
+		c.Index = "logx"
+	}
+	es := &Client{cfg: c, url: strings.TrimSuffix(c.URL, "/") + "/_bulk"}
+	if c.Pipeline != "" {
+		es.url += "?pipeline=" + url.QueryEscape(c.Pipeline)
+	}
+	client := c.HTTP.Client()
+	batcher, err := sink.NewBatcher(c.Batch, func(ctx context.Context, batch []*core.Entry) error {
+		return es.bulk(ctx, client, batch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	es.batcher = batcher
+	return es, nil
+}
+
+// Write implements the core.Sink interface. The entry is queued and sent in
+// the background; it is dropped if the queue is full.
+func (es *Client) Write(e *core.Entry) error {
+	es.batcher.Add(e)
+	return nil
+}
+
+// Sync implements the core.Sink interface.
+func (es *Client) Sync() error {
+	return es.batcher.Flush()
+}
+
+// Close implements the core.Sink interface.
+func (es *Client) Close() error {
+	return es.batcher.Close()
+}
+
+// Stats returns the delivery counters of the client.
+func (es *Client) Stats() sink.Stats {
+	return es.batcher.Stats()
+}
+
+// bulkResponse is the subset of the _bulk response used to find rejected documents.
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int `json:"status"`
+		Error  *struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		} `json:"error"`
+	} `json:"items"`
+}
+
+// bulk indexes a batch. Documents rejected with 429 or 5xx are sent again on
+// the next attempt; other rejections are reported without retrying. Only the
+// documents that were not indexed are reported as failed or left to retry, so
+// that the others are not indexed twice.
+func (es *Client) bulk(ctx context.Context, client *http.Client, batch []*core.Entry) error {
+	pending := make([][]byte, 0, len(batch))
+	for _, e := range batch {
+		action, err := es.encode(e)
+		if err != nil {
+			// sending the batch again cannot succeed
+			return sink.Permanent(fmt.Errorf("elasticsearch: %w", err))
+		}
+		pending = append(pending, action)
+	}
+
+	entries := batch
+	var rejected []string
+	err := sink.Retry(ctx, es.cfg.Retry, func(ctx context.Context) error {
+		body := bytes.Join(pending, nil)
+		data, err := sink.PostResponse(ctx, client, es.cfg.HTTP, es.url, "application/x-ndjson", body)
+		if err != nil {
+			return err
+		}
+		var resp bulkResponse
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return sink.Permanent(fmt.Errorf("elasticsearch: invalid bulk response: %w", err))
+		}
+		if !resp.Errors {
+			pending, entries = nil, nil
+			return nil
+		}
+		var retry [][]byte
+		var retryEntries []*core.Entry
+		for i, item := range resp.Items {
+			if i >= len(pending) {
+				break
+			}
+			for _, result := range item {
+				if result.Error == nil {
+					continue
+				}
+				if result.Status == http.StatusTooManyRequests || result.Status >= 500 {
+					retry = append(retry, pending[i])
+					retryEntries = append(retryEntries, entries[i])
+				} else {
+					rejected = append(rejected, fmt.Sprintf("%d %s: %s", result.Status, result.Error.Type, result.Error.Reason))
+				}
+			}
+		}
+		pending, entries = retry, retryEntries
+		if len(retry) > 0 {
+			return fmt.Errorf("elasticsearch: %d documents rejected with a retryable status", len(retry))
+		}
+		return nil
+	})
+	if len(rejected) == 0 && (err == nil || len(entries) == len(batch)) {
+		return err
+	}
+	if len(rejected) > 0 {
+		rerr := sink.Permanent(fmt.Errorf("elasticsearch: %d documents rejected, first: %s", len(rejected), rejected[0]))
+		if len(entries) == 0 {
+			err = rerr
+		} else {
+			err = errors.Join(err, rerr)
+		}
+	}
+	return &sink.PartialError{Failed: len(rejected), Retry: entries, Err: err}
+}
+
+// encode returns the bulk action and document lines of e.
+func (es *Client) encode(e *core.Entry) ([]byte, error) {
+	action, err := json.Marshal(map[string]any{
+		"create": map[string]string{"_index": es.index(e)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	doc := sink.Document(e, "message")
+	doc["@timestamp"] = e.Time.UTC().Format("2006-01-02T15:04:05.000000000Z07:00")
+	source, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	line := make([]byte, 0, len(action)+len(source)+2)
+	line = append(line, action...)
+	line = append(line, '\n')
+	line = append(line, source...)
+	return append(line, '\n'), nil
+}
+
+// index returns the index of e.
+func (es *Client) index(e *core.Entry) string {
+	if !strings.Contains(es.cfg.Index, "2006") {
+		return es.cfg.Index
+	}
+	return e.Time.UTC().Format(es.cfg.Index)
+}
@@ -0,0 +1,211 @@
+package elasticsearch_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+	"github.com/go4x/logx/sink/elasticsearch"
+)
+
+// TestBulk tests the bulk request format, index templates and the retry of rejected documents
+func TestBulk(t *testing.T) {
+	var mu sync.Mutex
+	var indices, messages []string
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		calls++
+
+		var items []string
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			var action struct {
+				Create struct {
+					Index string `json:"_index"`
+				} `json:"create"`
+			}
+			json.Unmarshal(scanner.Bytes(), &action)
+			scanner.Scan()
+			var doc map[string]any
+			json.Unmarshal(scanner.Bytes(), &doc)
+			msg, _ := doc["message"].(string)
+
+			status := 201
+			switch {
+			case msg == "rejected":
+				status = 400
+			case msg == "throttled" && calls == 1:
+				status = 429
+			}
+			if status == 201 {
+				indices = append(indices, action.Create.Index)
+				messages = append(messages, msg)
+				items = append(items, `{"create":{"status":201}}`)
+			} else {
+				items = append(items, fmt.Sprintf(`{"create":{"status":%d,"error":{"type":"t","reason":"r"}}}`, status))
+			}
+		}
+		fmt.Fprintf(w, `{"errors":%t,"items":[%s]}`, len(items) != len(messages), strings.Join(items, ","))
+	}))
+	defer srv.Close()
+
+	var errs []error
+	es, err := elasticsearch.New(elasticsearch.Config{
+		URL:   srv.URL,
+		Index: "logs-2006.01.02",
+		HTTP:  sink.HTTPConfig{Compression: "none"},
+		Batch: sink.BatchConfig{FlushInterval: time.Hour, ErrorHandler: func(err error) { errs = append(errs, err) }},
+		Retry: sink.RetryConfig{MaxRetries: 2, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer es.Close()
+
+	ts := time.Date(2024, 3, 1, 23, 0, 0, 0, time.UTC)
+	for _, msg := range []string{"ok", "throttled", "rejected"} {
+		es.Write(&core.Entry{Time: ts, Level: core.InfoLevel, Message: msg})
+	}
+	es.Sync()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 2 {
+		t.Errorf("expected the throttled document to be retried once, got %d calls", calls)
+	}
+	if strings.Join(messages, ",") != "ok,throttled" || indices[0] != "logs-2024.03.01" {
+		t.Errorf("unexpected indexed documents: %v %v", messages, indices)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "1 documents rejected") {
+		t.Errorf("expected the rejected document to be reported, got %v", errs)
+	}
+}
+
+// TestNewValidation tests configuration validation
+func TestNewValidation(t *testing.T) {
+	if _, err := elasticsearch.New(elasticsearch.Config{}); err == nil {
+		t.Error("expected error without url")
+	}
+}
+
+// TestBulkNonFinite tests that NaN and infinite floats are indexed as strings
+func TestBulkNonFinite(t *testing.T) {
+	docs := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		docs <- string(data)
+		fmt.Fprint(w, `{"errors":false,"items":[{"create":{"status":201}}]}`)
+	}))
+	defer srv.Close()
+
+	es, err := elasticsearch.New(elasticsearch.Config{
+		URL:   srv.URL,
+		HTTP:  sink.HTTPConfig{Compression: "none"},
+		Batch: sink.BatchConfig{FlushInterval: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer es.Close()
+
+	es.Write(&core.Entry{Time: time.Now(), Level: core.InfoLevel, Message: "ratio", Fields: []core.Field{{Key: "ratio", Value: math.NaN()}, {Key: "min", Value: math.Inf(-1)}}})
+	es.Sync()
+
+	select {
+	case body := <-docs:
+		if !strings.Contains(body, `"ratio":"NaN"`) || !strings.Contains(body, `"min":"-Inf"`) {
+			t.Errorf("unexpected documents: %s", body)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no bulk request received")
+	}
+}
+
+// TestBulkPartial tests that only the documents still throttled after the retries are spooled and sent again
+func TestBulkPartial(t *testing.T) {
+	var mu sync.Mutex
+	var messages []string
+	throttle := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		var items []string
+		errs := false
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			scanner.Scan()
+			var doc map[string]any
+			json.Unmarshal(scanner.Bytes(), &doc)
+			msg, _ := doc["message"].(string)
+			switch {
+			case msg == "rejected":
+				errs = true
+				items = append(items, `{"create":{"status":400,"error":{"type":"t","reason":"r"}}}`)
+			case msg == "throttled" && throttle:
+				errs = true
+				items = append(items, `{"create":{"status":429,"error":{"type":"t","reason":"r"}}}`)
+			default:
+				messages = append(messages, msg)
+				items = append(items, `{"create":{"status":201}}`)
+			}
+		}
+		fmt.Fprintf(w, `{"errors":%t,"items":[%s]}`, errs, strings.Join(items, ","))
+	}))
+	defer srv.Close()
+
+	es, err := elasticsearch.New(elasticsearch.Config{
+		URL:  srv.URL,
+		HTTP: sink.HTTPConfig{Compression: "none"},
+		Batch: sink.BatchConfig{
+			FlushInterval: time.Hour,
+			Spool:         sink.SpoolConfig{Enabled: true, Dir: t.TempDir()},
+			ErrorHandler:  func(err error) {},
+		},
+		Retry: sink.RetryConfig{MaxRetries: 1, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer es.Close()
+
+	for _, msg := range []string{"ok", "throttled", "rejected"} {
+		es.Write(&core.Entry{Time: time.Now(), Level: core.InfoLevel, Message: msg})
+	}
+	es.Sync()
+	if s := es.Stats(); s.Sent != 1 || s.Failed != 1 || s.Spooled != 1 {
+		t.Errorf("unexpected stats while throttled: %+v", s)
+	}
+
+	mu.Lock()
+	throttle = false
+	mu.Unlock()
+	es.Sync()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if strings.Join(messages, ",") != "ok,throttled" {
+		t.Errorf("expected each document to be indexed once, got %v", messages)
+	}
+	if s := es.Stats(); s.Sent != 2 || s.Failed != 1 {
+		t.Errorf("unexpected stats after recovery: %+v", s)
+	}
+}
@@ -0,0 +1,266 @@
+// Package fluent provides a sink that sends logx entries to Fluentd or Fluent
+// Bit with the forward protocol: batches of MessagePack events over TCP or a
+// unix socket, optionally acknowledged by the server.
+//
+// Example usage:
+//
+//	config := &logx.LoggerConfig{
+//	    // ...
+//	    Fluent: &fluent.Config{
+//	        Address:    "localhost:24224",
+//	        Tag:        "app.checkout",
+//	        RequireAck: true,
+//	    },
+//	}
+package fluent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+)
+
+// Config holds the configuration of the forward sink.
+type Config struct {
+	// Network is tcp (default) or unix.
+	Network string `mapstructure:"network" yaml:"network"`
+
+	// Address is the host:port of the forward input (default
+	// localhost:24224), or the path of the socket.
+	Address string `mapstructure:"address" yaml:"address"`
+
+	// Tag is the tag of the events (default "logx").
+	Tag string `mapstructure:"tag" yaml:"tag"`
+
+	// TagField is an optional field whose value, if present, is appended to
+	// Tag with a dot to form the tag of the entry. The field is removed from
+	// the record.
+	TagField string `mapstructure:"tag-field" yaml:"tag-field"`
+
+	// MessageKey is the record key of the message (default "message").
+	MessageKey string `mapstructure:"message-key" yaml:"message-key"`
+
+	// RequireAck makes the server acknowledge every batch, which is then
+	// retried until acknowledged (at-least-once delivery).
+	RequireAck bool `mapstructure:"require-ack" yaml:"require-ack"`
+
+	// Timeout bounds dialing, writing a batch and waiting for its ack (default 5s).
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+
+	// Batch configures the bounded queue and batching.
+	Batch sink.BatchConfig `mapstructure:"batch" yaml:"batch"`
+
+	// Retry configures the retries of failed batches.
+	Retry sink.RetryConfig `mapstructure:"retry" yaml:"retry"`
+}
+
+// Client is a core.Sink that forwards entries to Fluentd or Fluent Bit.
+type Client struct {
+	cfg     Config
+	conn    sink.Redialer // owned by the batcher goroutine
+	batcher *sink.Batcher
+}
+
+// New creates a Client and starts its background delivery. The connection
+// is established when the first batch is sent.
+func New(c Config) (*Client, error) {
+	if c.Network == "" {
+		c.Network = "tcp"
+	}
+	if c.Network != "tcp" && c.Network != "tcp4" && c.Network != "tcp6" && c.Network != "unix" {
+		return nil, fmt.Errorf("fluent: unsupported network: %s", c.Network)
+	}
+	if c.Address == "" {
+		c.Address = "localhost:24224"
+	}
+	if c.Tag == "" {
+		c.Tag = "logx"
+	}
+	if c.MessageKey == "" {
+		c.MessageKey = "message"
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+	f := &Client{
+		cfg: c,
+		conn: sink.Redialer{
+			Network: c.Network,
+			Address: c.Address,
+			Timeout: c.Timeout,
+			// Retry already backs off between attempts.
+			MinBackoff: time.Nanosecond,
+			MaxBackoff: time.Nanosecond,
+		},
+	}
+	batcher, err := sink.NewBatcher(c.Batch, f.send)
+	if err != nil {
+		return nil, err
+	}
+	f.batcher = batcher
+	return f, nil
+}
+
+// Write implements the core.Sink interface. The entry is queued and sent in
+// the background; it is dropped if the queue is full.
+func (f *Client) Write(e *core.Entry) error {
+	f.batcher.Add(e)
+	return nil
+}
+
+// Sync implements the core.Sink interface.
+func (f *Client) Sync() error {
+	return f.batcher.Flush()
+}
+
+// Close implements the core.Sink interface.
+func (f *Client) Close() error {
+	err := f.batcher.Close()
+	return errors.Join(err, f.conn.Close())
+}
+
+// Stats returns the delivery counters of the client.
+func (f *Client) Stats() sink.Stats {
+	return f.batcher.Stats()
+}
+
+// send forwards a batch as one message per tag, in Forward mode:
+// [tag, [[time, record], ...], {"size": n, "chunk": id}]. If a message fails
+// after others were sent, only the entries of the unsent messages are left to
+// retry.
+func (f *Client) send(ctx context.Context, batch []*core.Entry) error {
+	var tags []string
+	groups := make(map[string][]*core.Entry)
+	for _, e := range batch {
+		tag := f.tag(e)
+		if _, ok := groups[tag]; !ok {
+			tags = append(tags, tag)
+		}
+		groups[tag] = append(groups[tag], e)
+	}
+	for i, tag := range tags {
+		msg, chunk, err := f.encode(tag, groups[tag])
+		if err == nil {
+			err = sink.Retry(ctx, f.cfg.Retry, func(ctx context.Context) error {
+				return f.write(msg, chunk)
+			})
+		}
+		if err != nil {
+			if i == 0 {
+				return err
+			}
+			var retry []*core.Entry
+			for _, tag := range tags[i:] {
+				retry = append(retry, groups[tag]...)
+			}
+			return &sink.PartialError{Retry: retry, Err: err}
+		}
+	}
+	return nil
+}
+
+// write sends one message and waits for its ack if required.
+func (f *Client) write(msg []byte, chunk string) error {
+	conn, err := f.conn.Conn()
+	if err != nil {
+		return fmt.Errorf("fluent: %w", err)
+	}
+	_ = conn.SetDeadline(time.Now().Add(f.cfg.Timeout))
+	if _, err := conn.Write(msg); err != nil {
+		f.conn.Fail()
+		return fmt.Errorf("fluent: write: %w", err)
+	}
+	if !f.cfg.RequireAck {
+		return nil
+	}
+	ack, err := readAck(conn)
+	if err != nil {
+		f.conn.Fail()
+		return fmt.Errorf("fluent: ack: %w", err)
+	}
+	if ack != chunk {
+		f.conn.Fail()
+		return fmt.Errorf("fluent: ack %q does not match chunk %q", ack, chunk)
+	}
+	return nil
+}
+
+// tag returns the tag of e.
+func (f *Client) tag(e *core.Entry) string {
+	if f.cfg.TagField == "" {
+		return f.cfg.Tag
+	}
+	for _, field := range e.Fields {
+		if field.Key == f.cfg.TagField {
+			return f.cfg.Tag + "." + fmt.Sprint(field.Value)
+		}
+	}
+	return f.cfg.Tag
+}
+
+// encode returns the Forward mode message of entries and its chunk id.
+func (f *Client) encode(tag string, entries []*core.Entry) ([]byte, string, error) {
+	var id [16]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, "", err
+	}
+	chunk := base64.StdEncoding.EncodeToString(id[:])
+
+	b := appendArrayHeader(nil, 3)
+	b = appendString(b, tag)
+	b = appendArrayHeader(b, len(entries))
+	for _, e := range entries {
+		b = appendArrayHeader(b, 2)
+		b = appendEventTime(b, e.Time)
+		b = f.appendRecord(b, e)
+	}
+	options := 1
+	if f.cfg.RequireAck {
+		options = 2
+	}
+	b = appendMapHeader(b, options)
+	b = appendString(b, "size")
+	b = appendInt(b, int64(len(entries)))
+	if f.cfg.RequireAck {
+		b = appendString(b, "chunk")
+		b = appendString(b, chunk)
+	}
+	return b, chunk, nil
+}
+
+// appendRecord appends the record of e: the message, the level, the caller
+// and the fields except the tag field.
+func (f *Client) appendRecord(b []byte, e *core.Entry) []byte {
+	n := 2
+	if e.Caller != "" {
+		n++
+	}
+	for _, field := range e.Fields {
+		if field.Key != f.cfg.TagField || f.cfg.TagField == "" {
+			n++
+		}
+	}
+	b = appendMapHeader(b, n)
+	b = appendString(b, f.cfg.MessageKey)
+	b = appendString(b, e.Message)
+	b = appendString(b, "level")
+	b = appendString(b, e.Level.String())
+	if e.Caller != "" {
+		b = appendString(b, "caller")
+		b = appendString(b, e.Caller)
+	}
+	for _, field := range e.Fields {
+		if field.Key == f.cfg.TagField && f.cfg.TagField != "" {
+			continue
+		}
+		b = appendString(b, field.Key)
+		b = appendValue(b, field.Value)
+	}
+	return b
+}
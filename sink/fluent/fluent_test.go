@@ -0,0 +1,333 @@
+package fluent_test
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+	"github.com/go4x/logx/sink/fluent"
+)
+
+// forwarder is a local forward input that decodes the received messages.
+type forwarder struct {
+	ln net.Listener
+
+	mu       sync.Mutex
+	messages [][]any
+	// dropAcks is the number of messages to receive without acknowledging
+	// them, closing the connection instead.
+	dropAcks int
+	// dropTag is a tag whose messages are not acknowledged.
+	dropTag string
+}
+
+func newForwarder(t *testing.T, dropAcks int) *forwarder {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	f := &forwarder{ln: ln, dropAcks: dropAcks}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+func (f *forwarder) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		v, err := decode(r)
+		if err != nil {
+			return
+		}
+		msg := v.([]any)
+		f.mu.Lock()
+		f.messages = append(f.messages, msg)
+		drop := f.dropAcks > 0 || f.dropTag != "" && msg[0] == f.dropTag
+		if f.dropAcks > 0 {
+			f.dropAcks--
+		}
+		f.mu.Unlock()
+		if drop {
+			return
+		}
+		if chunk, ok := msg[2].(map[string]any)["chunk"].(string); ok {
+			ack := []byte{0x81, 0xa3, 'a', 'c', 'k', 0xa0 | byte(len(chunk))}
+			conn.Write(append(ack, chunk...))
+		}
+	}
+}
+
+func (f *forwarder) received() [][]any {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]any(nil), f.messages...)
+}
+
+// TestForward tests the Forward mode messages, tags and records
+func TestForward(t *testing.T) {
+	fw := newForwarder(t, 0)
+	c, err := fluent.New(fluent.Config{
+		Address:    fw.ln.Addr().String(),
+		Tag:        "app",
+		TagField:   "component",
+		RequireAck: true,
+		Batch:      sink.BatchConfig{FlushInterval: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	ts := time.Unix(1700000000, 123456789)
+	c.Write(&core.Entry{Time: ts, Level: core.ErrorLevel, Message: "payment failed", Fields: []core.Field{
+		{Key: "component", Value: "billing"},
+		{Key: "attempt", Value: 2},
+		{Key: "amount", Value: 9.5},
+		{Key: "tags", Value: []string{"a", "b"}},
+	}})
+	c.Write(&core.Entry{Time: ts, Level: core.InfoLevel, Message: "started"})
+	c.Sync()
+
+	messages := fw.received()
+	if len(messages) != 2 {
+		t.Fatalf("expected one message per tag, got %d", len(messages))
+	}
+	if messages[0][0] != "app.billing" || messages[1][0] != "app" {
+		t.Errorf("unexpected tags: %v %v", messages[0][0], messages[1][0])
+	}
+	events := messages[0][1].([]any)
+	event := events[0].([]any)
+	if tm, ok := event[0].(time.Time); !ok || !tm.Equal(ts) {
+		t.Errorf("expected EventTime %v, got %v", ts, event[0])
+	}
+	record := event[1].(map[string]any)
+	want := map[string]any{"message": "payment failed", "level": "error", "attempt": int64(2), "amount": 9.5}
+	for k, v := range want {
+		if record[k] != v {
+			t.Errorf("expected %s = %v, got %v", k, v, record[k])
+		}
+	}
+	if fmt.Sprint(record["tags"]) != "[a b]" {
+		t.Errorf("unexpected tags field: %v", record["tags"])
+	}
+	if _, ok := record["component"]; ok {
+		t.Error("the tag field should be removed from the record")
+	}
+	if s := c.Stats(); s.Sent != 2 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+// TestForwardResendsUnacknowledged tests that a batch is sent again when its ack does not arrive
+func TestForwardResendsUnacknowledged(t *testing.T) {
+	fw := newForwarder(t, 1)
+	c, err := fluent.New(fluent.Config{
+		Address:    fw.ln.Addr().String(),
+		RequireAck: true,
+		Timeout:    time.Second,
+		Batch:      sink.BatchConfig{FlushInterval: time.Hour},
+		Retry:      sink.RetryConfig{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	c.Write(&core.Entry{Time: time.Now(), Level: core.InfoLevel, Message: "must arrive"})
+	c.Sync()
+
+	messages := fw.received()
+	if len(messages) != 2 {
+		t.Fatalf("expected the batch to be sent twice, got %d", len(messages))
+	}
+	if s := c.Stats(); s.Sent != 1 || s.Failed != 0 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+// TestForwardPartial tests that the messages acknowledged before a failed one are not sent again
+func TestForwardPartial(t *testing.T) {
+	fw := newForwarder(t, 0)
+	fw.dropTag = "app"
+	c, err := fluent.New(fluent.Config{
+		Address:    fw.ln.Addr().String(),
+		Tag:        "app",
+		TagField:   "component",
+		RequireAck: true,
+		Timeout:    time.Second,
+		Batch: sink.BatchConfig{
+			FlushInterval: time.Hour,
+			Spool:         sink.SpoolConfig{Enabled: true, Dir: t.TempDir()},
+			ErrorHandler:  func(err error) {},
+		},
+		Retry: sink.RetryConfig{MaxRetries: -1},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	c.Write(&core.Entry{Time: time.Now(), Level: core.InfoLevel, Message: "billed", Fields: []core.Field{{Key: "component", Value: "billing"}}})
+	c.Write(&core.Entry{Time: time.Now(), Level: core.InfoLevel, Message: "started"})
+	c.Sync()
+	if s := c.Stats(); s.Sent != 1 || s.Spooled != 1 {
+		t.Errorf("unexpected stats while unacknowledged: %+v", s)
+	}
+
+	fw.mu.Lock()
+	fw.dropTag = ""
+	fw.mu.Unlock()
+	c.Sync()
+
+	billing := 0
+	for _, msg := range fw.received() {
+		if msg[0] == "app.billing" {
+			billing++
+		}
+	}
+	if billing != 1 {
+		t.Errorf("expected the acknowledged message to be sent once, got %d", billing)
+	}
+	if s := c.Stats(); s.Sent != 2 || s.Failed != 0 {
+		t.Errorf("unexpected stats after recovery: %+v", s)
+	}
+}
+
+// TestNewValidation tests configuration validation
+func TestNewValidation(t *testing.T) {
+	if _, err := fluent.New(fluent.Config{Network: "udp"}); err == nil {
+		t.Error("expected error for unsupported network")
+	}
+}
+
+// decode reads one MessagePack value. Maps are decoded as map[string]any,
+// integers as int64 and the EventTime extension as time.Time.
+func decode(r *bufio.Reader) (any, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case b <= 0x7f:
+		return int64(b), nil
+	case b >= 0xe0:
+		return int64(int8(b)), nil
+	case b&0xf0 == 0x80:
+		return decodeMap(r, int(b&0x0f))
+	case b&0xf0 == 0x90:
+		return decodeArray(r, int(b&0x0f))
+	case b&0xe0 == 0xa0:
+		return readString(r, int(b&0x1f))
+	}
+	switch b {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xc4, 0xd9:
+		n, err := readUint(r, 1)
+		if err != nil {
+			return nil, err
+		}
+		return readString(r, int(n))
+	case 0xcb:
+		n, err := readUint(r, 8)
+		return math.Float64frombits(n), err
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := readUint(r, 1<<(b-0xcc))
+		return int64(n), err
+	case 0xd0, 0xd1, 0xd2, 0xd3:
+		size := 1 << (b - 0xd0)
+		n, err := readUint(r, size)
+		shift := 64 - 8*size
+		return int64(n<<shift) >> shift, err
+	case 0xd7:
+		typ, err := r.ReadByte()
+		if err != nil || typ != 0 {
+			return nil, fmt.Errorf("unexpected extension %d", typ)
+		}
+		sec, _ := readUint(r, 4)
+		nsec, err := readUint(r, 4)
+		return time.Unix(int64(sec), int64(nsec)), err
+	case 0xda:
+		n, err := readUint(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		return readString(r, int(n))
+	case 0xdc:
+		n, err := readUint(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		return decodeArray(r, int(n))
+	case 0xde:
+		n, err := readUint(r, 2)
+		if err != nil {
+			return nil, err
+		}
+		return decodeMap(r, int(n))
+	}
+	return nil, fmt.Errorf("unsupported type 0x%02x", b)
+}
+
+func decodeArray(r *bufio.Reader, n int) ([]any, error) {
+	values := make([]any, n)
+	for i := range values {
+		v, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+	}
+	return values, nil
+}
+
+func decodeMap(r *bufio.Reader, n int) (map[string]any, error) {
+	m := make(map[string]any, n)
+	for i := 0; i < n; i++ {
+		k, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+		v, err := decode(r)
+		if err != nil {
+			return nil, err
+		}
+		m[fmt.Sprint(k)] = v
+	}
+	return m, nil
+}
+
+func readUint(r *bufio.Reader, size int) (uint64, error) {
+	buf := make([]byte, 8)
+	if _, err := io.ReadFull(r, buf[8-size:]); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+func readString(r *bufio.Reader, n int) (string, error) {
+	buf := make([]byte, n)
+	_, err := io.ReadFull(r, buf)
+	return string(buf), err
+}
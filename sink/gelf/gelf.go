@@ -0,0 +1,341 @@
+// Package gelf provides a sink that sends logx entries to Graylog in the
+// Graylog Extended Log Format (GELF 1.1) over UDP or TCP.
+//
+// Over UDP, messages are compressed and split into GELF chunks when they do
+// not fit in one datagram. Over TCP, messages are null-byte delimited and
+// not compressed, as required by Graylog.
+//
+// Example usage:
+//
+//	config := &logx.LoggerConfig{
+//	    // ...
+//	    GELF: &gelf.Config{
+//	        Address: "graylog:12201",
+//	    },
+//	}
+package gelf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+	"github.com/go4x/logx/sink/syslog"
+)
+
+const (
+	// CompressionGzip compresses UDP messages with gzip (default).
+	CompressionGzip = "gzip"
+	// CompressionZlib compresses UDP messages with zlib.
+	CompressionZlib = "zlib"
+	// CompressionNone sends UDP messages uncompressed.
+	CompressionNone = "none"
+
+	// maxChunks is the maximum number of chunks of a GELF message.
+	maxChunks = 128
+	// chunkHeaderSize is the size of the chunk header: magic bytes, message
+	// id, sequence number and sequence count.
+	chunkHeaderSize = 12
+)
+
+// Config holds the configuration of the GELF sink.
+type Config struct {
+	// Network is udp (default) or tcp.
+	Network string `mapstructure:"network" yaml:"network"`
+
+	// Address is the host:port of the GELF input.
+	Address string `mapstructure:"address" yaml:"address"`
+
+	// Host is the host field. It defaults to os.Hostname.
+	Host string `mapstructure:"host" yaml:"host"`
+
+	// Compression is the compression of UDP messages: gzip (default), zlib or none.
+	Compression string `mapstructure:"compression" yaml:"compression"`
+
+	// ChunkSize is the maximum size of a UDP datagram (default 1420, which
+	// fits the usual MTU of a WAN).
+	ChunkSize int `mapstructure:"chunk-size" yaml:"chunk-size"`
+
+	// Timeout bounds dialing and each write (default 5s).
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+
+	// MinBackoff is the delay before the first reconnection attempt (default 100ms).
+	MinBackoff time.Duration `mapstructure:"min-backoff" yaml:"min-backoff"`
+
+	// MaxBackoff caps the exponential delay between reconnection attempts (default 30s).
+	MaxBackoff time.Duration `mapstructure:"max-backoff" yaml:"max-backoff"`
+
+	// Batch configures the bounded queue and batching.
+	Batch sink.BatchConfig `mapstructure:"batch" yaml:"batch"`
+}
+
+// Writer is a core.Sink that sends entries as GELF messages. Entries are
+// queued and sent in the background.
+type Writer struct {
+	cfg  Config
+	host string
+
+	conn    sink.Redialer // owned by the batcher goroutine
+	batcher *sink.Batcher
+}
+
+// New creates a Writer from c and starts its background delivery. The
+// connection is established when the first batch is sent.
+func New(c Config) (*Writer, error) {
+	if c.Network == "" {
+		c.Network = "udp"
+	}
+	switch c.Network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("gelf: unsupported network: %s", c.Network)
+	}
+	if c.Address == "" {
+		return nil, errors.New("gelf: address is required")
+	}
+	switch c.Compression {
+	case "":
+		c.Compression = CompressionGzip
+	case CompressionGzip, CompressionZlib, CompressionNone:
+	default:
+		return nil, fmt.Errorf("gelf: unsupported compression: %s", c.Compression)
+	}
+	if c.ChunkSize <= 0 {
+		c.ChunkSize = 1420
+	}
+	if c.ChunkSize <= chunkHeaderSize {
+		return nil, fmt.Errorf("gelf: chunk size %d is too small", c.ChunkSize)
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+
+	w := &Writer{
+		cfg:  c,
+		host: c.Host,
+		conn: sink.Redialer{
+			Network:    c.Network,
+			Address:    c.Address,
+			Timeout:    c.Timeout,
+			MinBackoff: c.MinBackoff,
+			MaxBackoff: c.MaxBackoff,
+		},
+	}
+	if w.host == "" {
+		w.host, _ = os.Hostname()
+	}
+	batcher, err := sink.NewBatcher(c.Batch, w.send)
+	if err != nil {
+		return nil, err
+	}
+	w.batcher = batcher
+	return w, nil
+}
+
+// Write implements the core.Sink interface. The entry is queued and sent in
+// the background; it is dropped if the queue is full.
+func (w *Writer) Write(e *core.Entry) error {
+	w.batcher.Add(e)
+	return nil
+}
+
+// Sync implements the core.Sink interface.
+func (w *Writer) Sync() error {
+	return w.batcher.Flush()
+}
+
+// Close implements the core.Sink interface.
+func (w *Writer) Close() error {
+	err := w.batcher.Close()
+	return errors.Join(err, w.conn.Close())
+}
+
+// Stats returns the delivery counters of the writer.
+func (w *Writer) Stats() sink.Stats {
+	return w.batcher.Stats()
+}
+
+// send writes a batch as one message per entry, reconnecting if necessary.
+// Entries that cannot be encoded are reported as failed. If a write fails
+// after others succeeded, only the entries left are reported, so that the
+// others are not sent again.
+func (w *Writer) send(ctx context.Context, batch []*core.Entry) error {
+	var errs []error
+	failed := 0
+	for i, e := range batch {
+		packets, err := w.packets(e)
+		if err != nil {
+			// sending the entry again cannot succeed
+			failed++
+			errs = append(errs, err)
+			continue
+		}
+		if err := w.write(packets); err != nil {
+			if i == 0 {
+				return err
+			}
+			return &sink.PartialError{Failed: failed, Retry: batch[i:], Err: errors.Join(append(errs, err)...)}
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	return &sink.PartialError{Failed: failed, Err: errors.Join(errs...)}
+}
+
+// packets returns the datagrams of the message of e, or the null-terminated
+// message over TCP.
+func (w *Writer) packets(e *core.Entry) ([][]byte, error) {
+	msg, err := json.Marshal(w.message(e))
+	if err != nil {
+		return nil, fmt.Errorf("gelf: %w", err)
+	}
+	if w.stream() {
+		return [][]byte{append(msg, 0)}, nil
+	}
+	if msg, err = w.compress(msg); err != nil {
+		return nil, err
+	}
+	return w.chunk(msg)
+}
+
+// write writes the packets of one message.
+func (w *Writer) write(packets [][]byte) error {
+	conn, err := w.conn.Conn()
+	if err != nil {
+		return fmt.Errorf("gelf: %w", err)
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(w.cfg.Timeout))
+	for _, p := range packets {
+		if _, err := conn.Write(p); err != nil {
+			w.conn.Fail()
+			return fmt.Errorf("gelf: write: %w", err)
+		}
+	}
+	return nil
+}
+
+// stream reports whether the transport is TCP.
+func (w *Writer) stream() bool {
+	return w.cfg.Network[:3] == "tcp"
+}
+
+// message returns the GELF message of e. Fields become additional fields
+// prefixed with an underscore.
+func (w *Writer) message(e *core.Entry) map[string]any {
+	msg := make(map[string]any, len(e.Fields)+6)
+	msg["version"] = "1.1"
+	msg["host"] = w.host
+	msg["short_message"] = e.Message
+	msg["timestamp"] = math.Round(float64(e.Time.UnixNano())/1e6) / 1e3
+	msg["level"] = syslog.Severity(e.Level)
+	if e.Caller != "" {
+		msg["_caller"] = e.Caller
+	}
+	for _, f := range e.Fields {
+		msg[fieldName(f.Key)] = fieldValue(f.Value)
+	}
+	return msg
+}
+
+// compress compresses a UDP message.
+func (w *Writer) compress(msg []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	switch w.cfg.Compression {
+	case CompressionGzip:
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(msg); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	case CompressionZlib:
+		zw := zlib.NewWriter(&buf)
+		if _, err := zw.Write(msg); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+	default:
+		return msg, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// chunk splits a UDP message into GELF chunks if it does not fit in one datagram.
+func (w *Writer) chunk(msg []byte) ([][]byte, error) {
+	if len(msg) <= w.cfg.ChunkSize {
+		return [][]byte{msg}, nil
+	}
+	size := w.cfg.ChunkSize - chunkHeaderSize
+	count := (len(msg) + size - 1) / size
+	if count > maxChunks {
+		return nil, fmt.Errorf("gelf: message of %d bytes needs more than %d chunks", len(msg), maxChunks)
+	}
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return nil, err
+	}
+	chunks := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		part := msg[i*size : min((i+1)*size, len(msg))]
+		c := make([]byte, 0, chunkHeaderSize+len(part))
+		c = append(c, 0x1e, 0x0f)
+		c = append(c, id[:]...)
+		c = append(c, byte(i), byte(count))
+		chunks = append(chunks, append(c, part...))
+	}
+	return chunks, nil
+}
+
+// fieldName returns the additional field name of a key: an underscore
+// followed by the key, with invalid characters replaced. The reserved _id
+// becomes __id.
+func fieldName(key string) string {
+	b := make([]byte, 0, len(key)+1)
+	b = append(b, '_')
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		valid := c == '_' || c == '.' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+		if !valid {
+			c = '_'
+		}
+		b = append(b, c)
+	}
+	if string(b) == "_id" {
+		return "__id"
+	}
+	return string(b)
+}
+
+// fieldValue returns a GELF additional field value, which must be a string
+// or a number.
+func fieldValue(v any) any {
+	switch v := sink.JSONValue(v).(type) {
+	case string, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case nil:
+		return ""
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
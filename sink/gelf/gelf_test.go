@@ -0,0 +1,275 @@
+package gelf_test
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"math"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink/gelf"
+)
+
+func testEntry(message string) *core.Entry {
+	return &core.Entry{
+		Time:    time.UnixMilli(1700000000123),
+		Level:   core.WarnLevel,
+		Message: message,
+		Caller:  "app/main.go:42",
+		Fields: []core.Field{
+			{Key: "free_mb", Value: 12},
+			{Key: "id", Value: "abc"},
+			{Key: "ok", Value: true},
+			{Key: "disk name", Value: map[string]any{"dev": "sda"}},
+		},
+	}
+}
+
+// readDatagram reads one datagram from conn.
+func readDatagram(t *testing.T, conn net.PacketConn) []byte {
+	t.Helper()
+	buf := make([]byte, 64*1024)
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed to read datagram: %v", err)
+	}
+	return buf[:n]
+}
+
+// TestUDPChunking tests that large messages are compressed and split into GELF chunks
+func TestUDPChunking(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	w, err := gelf.New(gelf.Config{Address: conn.LocalAddr().String(), Host: "web-1", ChunkSize: 100})
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer w.Close()
+
+	// Random-looking content that does not compress into a single chunk.
+	var sb strings.Builder
+	for i := 0; i < 300; i++ {
+		sb.WriteString(string(rune('a' + i*7%26)))
+		sb.WriteString(string(rune('A' + i*11%26)))
+	}
+	if err := w.Write(testEntry(sb.String())); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	w.Sync()
+
+	first := readDatagram(t, conn)
+	if first[0] != 0x1e || first[1] != 0x0f {
+		t.Fatalf("expected a chunked message, got %x", first[:2])
+	}
+	count := int(first[11])
+	parts := make([][]byte, count)
+	parts[first[10]] = first[12:]
+	for i := 1; i < count; i++ {
+		chunk := readDatagram(t, conn)
+		if !bytes.Equal(chunk[2:10], first[2:10]) {
+			t.Fatal("chunks have different message ids")
+		}
+		if len(chunk) > 100 {
+			t.Errorf("chunk of %d bytes exceeds the chunk size", len(chunk))
+		}
+		parts[chunk[10]] = chunk[12:]
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(bytes.Join(parts, nil)))
+	if err != nil {
+		t.Fatalf("invalid gzip message: %v", err)
+	}
+	data, _ := io.ReadAll(zr)
+
+	var msg map[string]any
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("invalid GELF JSON: %v", err)
+	}
+	if msg["version"] != "1.1" || msg["host"] != "web-1" || msg["short_message"] != sb.String() {
+		t.Errorf("unexpected message: %v", msg)
+	}
+	if msg["level"] != float64(4) || msg["timestamp"] != 1700000000.123 {
+		t.Errorf("unexpected level or timestamp: %v %v", msg["level"], msg["timestamp"])
+	}
+	for key, want := range map[string]any{"_free_mb": float64(12), "__id": "abc", "_ok": "true", "_disk_name": `{"dev":"sda"}`, "_caller": "app/main.go:42"} {
+		if msg[key] != want {
+			t.Errorf("expected %s = %v, got %v", key, want, msg[key])
+		}
+	}
+}
+
+// TestUDPZlib tests a small zlib-compressed message sent in one datagram
+func TestUDPZlib(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	w, err := gelf.New(gelf.Config{Address: conn.LocalAddr().String(), Compression: gelf.CompressionZlib})
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer w.Close()
+	w.Write(testEntry("short"))
+	w.Sync()
+
+	zr, err := zlib.NewReader(bytes.NewReader(readDatagram(t, conn)))
+	if err != nil {
+		t.Fatalf("invalid zlib message: %v", err)
+	}
+	var msg map[string]any
+	if err := json.NewDecoder(zr).Decode(&msg); err != nil {
+		t.Fatalf("invalid GELF JSON: %v", err)
+	}
+	if msg["short_message"] != "short" {
+		t.Errorf("unexpected message: %v", msg)
+	}
+}
+
+// TestTCP tests null-byte delimited messages over TCP
+func TestTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	frames := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			frame, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			frames <- strings.TrimSuffix(frame, "\x00")
+		}
+	}()
+
+	w, err := gelf.New(gelf.Config{Network: "tcp", Address: ln.Addr().String()})
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer w.Close()
+	w.Write(testEntry("first"))
+	w.Write(testEntry("second"))
+	w.Sync()
+
+	for _, want := range []string{"first", "second"} {
+		select {
+		case frame := <-frames:
+			var msg map[string]any
+			if err := json.Unmarshal([]byte(frame), &msg); err != nil {
+				t.Fatalf("invalid GELF JSON %q: %v", frame, err)
+			}
+			if msg["short_message"] != want {
+				t.Errorf("expected %q, got %v", want, msg["short_message"])
+			}
+		case <-time.After(3 * time.Second):
+			t.Fatal("no message received")
+		}
+	}
+}
+
+// TestLazyDial tests that the writer connects when the first batch is sent rather than in New
+func TestLazyDial(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	w, err := gelf.New(gelf.Config{Network: "tcp", Address: ln.Addr().String()})
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer w.Close()
+	select {
+	case conn := <-accepted:
+		conn.Close()
+		t.Fatal("the writer connected before sending")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	w.Write(testEntry("first"))
+	w.Sync()
+	select {
+	case conn := <-accepted:
+		defer conn.Close()
+		frame, err := bufio.NewReader(conn).ReadString(0)
+		if err != nil || !strings.Contains(frame, `"short_message":"first"`) {
+			t.Errorf("unexpected frame: %q %v", frame, err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no connection")
+	}
+	if s := w.Stats(); s.Sent != 1 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+// TestNewValidation tests configuration validation
+func TestNewValidation(t *testing.T) {
+	testCases := []gelf.Config{
+		{},
+		{Address: "localhost:12201", Network: "unix"},
+		{Address: "localhost:12201", Compression: "lz4"},
+		{Address: "localhost:12201", ChunkSize: 8},
+	}
+	for _, c := range testCases {
+		if _, err := gelf.New(c); err == nil {
+			t.Errorf("expected error for %+v", c)
+		}
+	}
+}
+
+// TestNonFinite tests that NaN and infinite floats are sent as strings
+func TestNonFinite(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	w, err := gelf.New(gelf.Config{Address: conn.LocalAddr().String(), Compression: gelf.CompressionNone})
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer w.Close()
+	entry := testEntry("ratio")
+	entry.Fields = append(entry.Fields, core.Field{Key: "ratio", Value: math.NaN()}, core.Field{Key: "max", Value: float32(math.Inf(1))})
+	if err := w.Write(entry); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	w.Sync()
+
+	var msg map[string]any
+	if err := json.Unmarshal(readDatagram(t, conn), &msg); err != nil {
+		t.Fatalf("invalid GELF JSON: %v", err)
+	}
+	if msg["_ratio"] != "NaN" || msg["_max"] != "+Inf" {
+		t.Errorf("unexpected fields: %v %v", msg["_ratio"], msg["_max"])
+	}
+}
@@ -0,0 +1,97 @@
+package sink
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxResponseSize caps the successful response bodies read by PostResponse.
+const maxResponseSize = 16 << 20
+
+// HTTPConfig configures the HTTP delivery of a push sink.
+type HTTPConfig struct {
+	// Headers are added to every request, e.g. for authentication.
+	Headers map[string]string `mapstructure:"headers" yaml:"headers"`
+
+	// Compression is the request body encoding, "gzip" (default) or "none".
+	Compression string `mapstructure:"compression" yaml:"compression"`
+
+	// Timeout bounds each request (default 10s).
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+}
+
+// Client returns an http.Client using the timeout of c.
+func (c HTTPConfig) Client() *http.Client {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// Post sends body to url with the given content type. The body is gzip
+// compressed unless c.Compression is "none". Network errors, 429 and 5xx
+// responses are returned as retryable errors; other non-2xx responses are
+// returned as permanent errors.
+func Post(ctx context.Context, client *http.Client, c HTTPConfig, url, contentType string, body []byte) error {
+	_, err := PostResponse(ctx, client, c, url, contentType, body)
+	return err
+}
+
+// PostResponse is like Post but also returns the body of a successful
+// response, for APIs that report partial failures in it.
+func PostResponse(ctx context.Context, client *http.Client, c HTTPConfig, url, contentType string, body []byte) ([]byte, error) {
+	compress := c.Compression != "none"
+	if compress {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return nil, Permanent(err)
+		}
+		if err := zw.Close(); err != nil {
+			return nil, Permanent(err)
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, Permanent(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+		if err != nil {
+			return nil, err
+		}
+		return respBody, nil
+	}
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	err = fmt.Errorf("%s: unexpected status %s: %s", url, resp.Status, bytes.TrimSpace(respBody))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		if secs, perr := strconv.Atoi(resp.Header.Get("Retry-After")); perr == nil && secs > 0 {
+			return nil, &retryAfterError{err: err, delay: time.Duration(secs) * time.Second}
+		}
+		return nil, err
+	}
+	return nil, Permanent(err)
+}
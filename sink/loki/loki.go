@@ -0,0 +1,245 @@
+// Package loki provides a sink that pushes logx entries to Grafana Loki.
+//
+// Entries are grouped into streams by their labels: the static Labels, the
+// level and the fields listed in LabelFields. The remaining fields are sent
+// in the log line, which is a JSON object.
+//
+// Example usage:
+//
+//	config := &logx.LoggerConfig{
+//	    // ...
+//	    Loki: &loki.Config{
+//	        URL:         "http://loki:3100",
+//	        Labels:      map[string]string{"app": "checkout"},
+//	        LabelFields: []string{"region"},
+//	    },
+//	}
+package loki
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+)
+
+// pushPath is the path of the push API.
+const pushPath = "/loki/api/v1/push"
+
+// Config holds the configuration of the Loki sink.
+type Config struct {
+	// URL is the base URL of Loki, e.g. http://localhost:3100. A URL that
+	// already ends with the push path is used as is.
+	URL string `mapstructure:"url" yaml:"url"`
+
+	// TenantID is sent as X-Scope-OrgID in multi-tenant setups.
+	TenantID string `mapstructure:"tenant-id" yaml:"tenant-id"`
+
+	// Labels are added to every stream.
+	Labels map[string]string `mapstructure:"labels" yaml:"labels"`
+
+	// LabelFields are fields promoted to stream labels. Keep them to a few
+	// low-cardinality fields; they are removed from the log line.
+	LabelFields []string `mapstructure:"label-fields" yaml:"label-fields"`
+
+	// LevelLabel is the label holding the level (default "level", "-" omits it).
+	LevelLabel string `mapstructure:"level-label" yaml:"level-label"`
+
+	// HTTP configures headers, compression and timeout of the requests.
+	HTTP sink.HTTPConfig `mapstructure:"http" yaml:"http"`
+
+	// Batch configures the bounded queue and batching.
+	Batch sink.BatchConfig `mapstructure:"batch" yaml:"batch"`
+
+	// Retry configures the retries of failed requests.
+	Retry sink.RetryConfig `mapstructure:"retry" yaml:"retry"`
+}
+
+// Client is a core.Sink that pushes entries to Loki.
+type Client struct {
+	cfg         Config
+	url         string
+	labelFields map[string]bool
+	batcher     *sink.Batcher
+}
+
+// New creates a Client and starts its background delivery.
+func New(c Config) (*Client, error) {
+	if c.URL == "" {
+		return nil, errors.New("loki: url is required")
+	}
+	if c.LevelLabel == "" {
+		c.LevelLabel = "level"
+	}
+	l := &Client{
+		cfg:         c,
+		url:         strings.TrimSuffix(c.URL, "/"),
+		labelFields: make(map[string]bool, len(c.LabelFields)),
+	}
+	if !strings.HasSuffix(l.url, pushPath) {
+		l.url += pushPath
+	}
+	for _, f := range c.LabelFields {
+		l.labelFields[f] = true
+	}
+	if c.TenantID != "" {
+		headers := make(map[string]string, len(c.HTTP.Headers)+1)
+		for k, v := range c.HTTP.Headers {
+			headers[k] = v
+		}
+		headers["X-Scope-OrgID"] = c.TenantID
+		l.cfg.HTTP.Headers = headers
+	}
+	client := l.cfg.HTTP.Client()
+	batcher, err := sink.NewBatcher(c.Batch, func(ctx context.Context, batch []*core.Entry) error {
+		return l.push(ctx, client, batch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	l.batcher = batcher
+	return l, nil
+}
+
+// Write implements the core.Sink interface. The entry is queued and sent in
+// the background; it is dropped if the queue is full.
+func (l *Client) Write(e *core.Entry) error {
+	l.batcher.Add(e)
+	return nil
+}
+
+// Sync implements the core.Sink interface.
+func (l *Client) Sync() error {
+	return l.batcher.Flush()
+}
+
+// Close implements the core.Sink interface.
+func (l *Client) Close() error {
+	return l.batcher.Close()
+}
+
+// Stats returns the delivery counters of the client.
+func (l *Client) Stats() sink.Stats {
+	return l.batcher.Stats()
+}
+
+// stream is one stream of a push request.
+type stream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// push sends a batch as one push request.
+func (l *Client) push(ctx context.Context, client *http.Client, batch []*core.Entry) error {
+	body, err := l.encode(batch)
+	if err != nil {
+		// sending the batch again cannot succeed
+		return sink.Permanent(fmt.Errorf("loki: %w", err))
+	}
+	return sink.Retry(ctx, l.cfg.Retry, func(ctx context.Context) error {
+		return sink.Post(ctx, client, l.cfg.HTTP, l.url, "application/json", body)
+	})
+}
+
+// encode groups the entries into streams, keeping their order within each stream.
+func (l *Client) encode(batch []*core.Entry) ([]byte, error) {
+	var streams []*stream
+	index := make(map[string]*stream)
+	for _, e := range batch {
+		labels := l.labels(e)
+		key := labelsKey(labels)
+		s, ok := index[key]
+		if !ok {
+			s = &stream{Stream: labels}
+			index[key] = s
+			streams = append(streams, s)
+		}
+		line, err := l.line(e)
+		if err != nil {
+			return nil, err
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(e.Time.UnixNano(), 10), line})
+	}
+	return json.Marshal(map[string]any{"streams": streams})
+}
+
+// labels returns the stream labels of e.
+func (l *Client) labels(e *core.Entry) map[string]string {
+	labels := make(map[string]string, len(l.cfg.Labels)+len(l.labelFields)+1)
+	for k, v := range l.cfg.Labels {
+		labels[k] = v
+	}
+	if l.cfg.LevelLabel != "-" {
+		labels[l.cfg.LevelLabel] = e.Level.String()
+	}
+	for _, f := range e.Fields {
+		if l.labelFields[f.Key] {
+			labels[labelName(f.Key)] = labelValue(f.Value)
+		}
+	}
+	return labels
+}
+
+// line returns the log line of e: a JSON object with the message, the caller
+// and the fields that are not labels.
+func (l *Client) line(e *core.Entry) (string, error) {
+	doc := make(map[string]any, len(e.Fields)+2)
+	doc["msg"] = e.Message
+	if e.Caller != "" {
+		doc["caller"] = e.Caller
+	}
+	for _, f := range e.Fields {
+		if !l.labelFields[f.Key] {
+			doc[f.Key] = sink.JSONValue(f.Value)
+		}
+	}
+	data, err := json.Marshal(doc)
+	return string(data), err
+}
+
+// labelsKey returns a canonical key identifying a label set.
+func labelsKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(k)
+		sb.WriteByte(0)
+		sb.WriteString(labels[k])
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
+
+// labelName replaces the characters that are not valid in a label name.
+func labelName(s string) string {
+	b := []byte(s)
+	for i, c := range b {
+		valid := c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 0 && c >= '0' && c <= '9'
+		if !valid {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
+
+// labelValue renders a field value as a label value.
+func labelValue(v any) string {
+	switch v := sink.JSONValue(v).(type) {
+	case string:
+		return v
+	default:
+		data, _ := json.Marshal(v)
+		return string(data)
+	}
+}
@@ -0,0 +1,149 @@
+package loki_test
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+	"github.com/go4x/logx/sink/loki"
+)
+
+type pushRequest struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+// TestPush tests that entries are grouped into streams by labels derived from fields
+func TestPush(t *testing.T) {
+	var mu sync.Mutex
+	var reqs []pushRequest
+	var paths, tenants []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(zr)
+		var req pushRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		reqs = append(reqs, req)
+		paths = append(paths, r.URL.Path)
+		tenants = append(tenants, r.Header.Get("X-Scope-OrgID"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c, err := loki.New(loki.Config{
+		URL:         srv.URL,
+		TenantID:    "team-a",
+		Labels:      map[string]string{"app": "checkout"},
+		LabelFields: []string{"region"},
+		Batch:       sink.BatchConfig{FlushInterval: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	ts := time.Unix(1700000000, 5)
+	c.Write(&core.Entry{Time: ts, Level: core.InfoLevel, Message: "first", Fields: []core.Field{{Key: "region", Value: "eu"}, {Key: "order", Value: 1}}})
+	c.Write(&core.Entry{Time: ts, Level: core.InfoLevel, Message: "other region", Fields: []core.Field{{Key: "region", Value: "us"}}})
+	c.Write(&core.Entry{Time: ts, Level: core.InfoLevel, Message: "second", Fields: []core.Field{{Key: "region", Value: "eu"}, {Key: "order", Value: 2}}})
+	c.Sync()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reqs) != 1 || paths[0] != "/loki/api/v1/push" || tenants[0] != "team-a" {
+		t.Fatalf("unexpected requests: %d %v %v", len(reqs), paths, tenants)
+	}
+	streams := reqs[0].Streams
+	if len(streams) != 2 {
+		t.Fatalf("expected 2 streams, got %+v", streams)
+	}
+	eu := streams[0]
+	if eu.Stream["app"] != "checkout" || eu.Stream["region"] != "eu" || eu.Stream["level"] != "info" {
+		t.Errorf("unexpected labels: %v", eu.Stream)
+	}
+	if len(eu.Values) != 2 || eu.Values[0][0] != "1700000000000000005" {
+		t.Fatalf("unexpected values: %v", eu.Values)
+	}
+	var line map[string]any
+	if err := json.Unmarshal([]byte(eu.Values[1][1]), &line); err != nil {
+		t.Fatalf("invalid log line: %v", err)
+	}
+	if line["msg"] != "second" || line["order"] != float64(2) {
+		t.Errorf("unexpected line: %v", line)
+	}
+	if _, ok := line["region"]; ok {
+		t.Error("label fields should be removed from the line")
+	}
+}
+
+// TestNewValidation tests configuration validation
+func TestNewValidation(t *testing.T) {
+	if _, err := loki.New(loki.Config{}); err == nil {
+		t.Error("expected error without url")
+	}
+}
+
+// TestPushNonFinite tests that NaN and infinite floats are delivered as strings, and that a batch that cannot be encoded is not retried
+func TestPushNonFinite(t *testing.T) {
+	var mu sync.Mutex
+	var lines []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zr, _ := gzip.NewReader(r.Body)
+		data, _ := io.ReadAll(zr)
+		var req pushRequest
+		json.Unmarshal(data, &req)
+		mu.Lock()
+		for _, s := range req.Streams {
+			for _, v := range s.Values {
+				lines = append(lines, v[1])
+			}
+		}
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c, err := loki.New(loki.Config{
+		URL:   srv.URL,
+		Batch: sink.BatchConfig{FlushInterval: time.Hour},
+		Retry: sink.RetryConfig{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer c.Close()
+
+	c.Write(&core.Entry{Time: time.Now(), Level: core.InfoLevel, Message: "ratio", Fields: []core.Field{{Key: "ratio", Value: math.NaN()}, {Key: "max", Value: math.Inf(1)}}})
+	c.Sync()
+	c.Write(&core.Entry{Time: time.Now(), Level: core.InfoLevel, Message: "struct", Fields: []core.Field{{Key: "point", Value: struct{ X float64 }{math.NaN()}}}})
+	c.Sync()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(lines) != 1 || !strings.Contains(lines[0], `"ratio":"NaN"`) || !strings.Contains(lines[0], `"max":"+Inf"`) {
+		t.Errorf("unexpected lines: %q", lines)
+	}
+	if stats := c.Stats(); stats.Sent != 1 || stats.Failed != 1 {
+		t.Errorf("expected the batch that cannot be encoded to fail once, got %+v", stats)
+	}
+}
@@ -0,0 +1,484 @@
+package otlp
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go4x/logx/core"
+)
+
+// scopeName is the instrumentation scope of every exported record.
+const scopeName = "github.com/go4x/logx"
+
+// Severity maps a logx level to an OpenTelemetry severity number. Each OTel
+// severity has a range of four numbers; the standard logx levels map to the
+// first number of their range:
+//
+//	logx level  SeverityNumber          SeverityText
+//	trace       1  SEVERITY_NUMBER_TRACE  TRACE
+//	debug       5  SEVERITY_NUMBER_DEBUG  DEBUG
+//	info        9  SEVERITY_NUMBER_INFO   INFO
+//	notice      11 SEVERITY_NUMBER_INFO3  NOTICE
+//	warn        13 SEVERITY_NUMBER_WARN   WARN
+//	error       17 SEVERITY_NUMBER_ERROR  ERROR
+//	critical    18 SEVERITY_NUMBER_ERROR2 CRITICAL
+//	panic       19 SEVERITY_NUMBER_ERROR3 PANIC
+//	fatal       21 SEVERITY_NUMBER_FATAL  FATAL
+//
+// Levels between two standard levels take the following numbers of the lower
+// level's range, so their relative order is preserved; levels below debug map
+// to the TRACE range (1-4).
+func Severity(level core.Level) int {
+	switch {
+	case level < core.DebugLevel:
+		return max(1, 4-int(core.DebugLevel-level-1))
+	case level < core.InfoLevel:
+		return 5 + int(level-core.DebugLevel)
+	case level < core.WarnLevel:
+		return 9 + int(level-core.InfoLevel)
+	case level < core.ErrorLevel:
+		return 13 + int(level-core.WarnLevel)
+	case level < core.FatalLevel:
+		return 17 + min(3, int(level-core.ErrorLevel)/2)
+	default:
+		return 21 + min(3, int(level-core.FatalLevel))
+	}
+}
+
+// keyValue is an attribute with a normalized value: string, bool, int64,
+// float64, []byte, []any or []keyValue.
+type keyValue struct {
+	key   string
+	value any
+}
+
+// logRecord is the backend-independent form of an OTLP LogRecord.
+type logRecord struct {
+	timeUnixNano     uint64
+	observedUnixNano uint64
+	severityNumber   int
+	severityText     string
+	body             string
+	attributes       []keyValue
+	traceID          []byte
+	spanID           []byte
+	flags            uint32
+}
+
+// newLogRecord converts an entry. The trace_id, span_id and trace_flags
+// fields added by trace correlation become the record's trace context.
+func newLogRecord(e *core.Entry) logRecord {
+	r := logRecord{
+		timeUnixNano:     uint64(e.Time.UnixNano()),
+		observedUnixNano: uint64(time.Now().UnixNano()),
+		severityNumber:   Severity(e.Level),
+		severityText:     strings.ToUpper(e.Level.String()),
+		body:             e.Message,
+		attributes:       make([]keyValue, 0, len(e.Fields)+2),
+	}
+	if e.Caller != "" {
+		file, line := e.Caller, ""
+		if i := strings.LastIndexByte(e.Caller, ':'); i > 0 {
+			file, line = e.Caller[:i], e.Caller[i+1:]
+		}
+		r.attributes = append(r.attributes, keyValue{"code.filepath", file})
+		if n, err := strconv.ParseInt(line, 10, 64); err == nil {
+			r.attributes = append(r.attributes, keyValue{"code.lineno", n})
+		}
+	}
+	for _, f := range e.Fields {
+		switch s, _ := f.Value.(string); {
+		case f.Key == "trace_id" && len(s) == 32 && r.traceID == nil:
+			if b, err := hex.DecodeString(s); err == nil {
+				r.traceID = b
+				continue
+			}
+		case f.Key == "span_id" && len(s) == 16 && r.spanID == nil:
+			if b, err := hex.DecodeString(s); err == nil {
+				r.spanID = b
+				continue
+			}
+		case f.Key == "trace_flags" && len(s) == 2:
+			if b, err := hex.DecodeString(s); err == nil {
+				r.flags = uint32(b[0])
+				continue
+			}
+		}
+		r.attributes = append(r.attributes, keyValue{f.Key, normalize(f.Value, 0)})
+	}
+	return r
+}
+
+// resourceAttributes returns the resource attributes of c.
+func resourceAttributes(c Config) []keyValue {
+	name := c.ServiceName
+	if name == "" {
+		name = os.Getenv("OTEL_SERVICE_NAME")
+	}
+	if name == "" {
+		name = filepath.Base(os.Args[0])
+	}
+	attrs := []keyValue{{"service.name", name}}
+	if host, err := os.Hostname(); err == nil {
+		attrs = append(attrs, keyValue{"host.name", host})
+	}
+	attrs = append(attrs, keyValue{"process.pid", int64(os.Getpid())})
+
+	keys := make([]string, 0, len(c.ResourceAttributes))
+	for k := range c.ResourceAttributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		attrs = append(attrs, keyValue{k, c.ResourceAttributes[k]})
+	}
+	return attrs
+}
+
+// maxDepth bounds the nesting of converted values.
+const maxDepth = 8
+
+// normalize converts a field value to one of the types of an OTLP AnyValue.
+func normalize(v any, depth int) any {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return v
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case uint:
+		return uintValue(uint64(v))
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		return uintValue(v)
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	case []byte:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return v.String()
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	if depth >= maxDepth {
+		return fmt.Sprintf("%+v", v)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		values := make([]any, rv.Len())
+		for i := range values {
+			values[i] = normalize(rv.Index(i).Interface(), depth+1)
+		}
+		return values
+	case reflect.Map:
+		keys := rv.MapKeys()
+		kvs := make([]keyValue, 0, len(keys))
+		for _, k := range keys {
+			kvs = append(kvs, keyValue{fmt.Sprint(k.Interface()), normalize(rv.MapIndex(k).Interface(), depth+1)})
+		}
+		sort.Slice(kvs, func(i, j int) bool { return kvs[i].key < kvs[j].key })
+		return kvs
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return ""
+		}
+		return normalize(rv.Elem().Interface(), depth+1)
+	default:
+		return fmt.Sprintf("%+v", v)
+	}
+}
+
+func uintValue(v uint64) any {
+	if v > math.MaxInt64 {
+		return strconv.FormatUint(v, 10)
+	}
+	return int64(v)
+}
+
+// JSON encoding, following the OTLP/JSON mapping of the protobuf messages:
+// lowerCamelCase names, 64-bit integers as strings and hex trace ids.
+
+type jsonExportRequest struct {
+	ResourceLogs []jsonResourceLogs `json:"resourceLogs"`
+}
+
+type jsonResourceLogs struct {
+	Resource  jsonResource    `json:"resource"`
+	ScopeLogs []jsonScopeLogs `json:"scopeLogs"`
+}
+
+type jsonResource struct {
+	Attributes []jsonKeyValue `json:"attributes"`
+}
+
+type jsonScopeLogs struct {
+	Scope      jsonScope       `json:"scope"`
+	LogRecords []jsonLogRecord `json:"logRecords"`
+}
+
+type jsonScope struct {
+	Name string `json:"name"`
+}
+
+type jsonLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 jsonAnyValue   `json:"body"`
+	Attributes           []jsonKeyValue `json:"attributes,omitempty"`
+	Flags                uint32         `json:"flags,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+type jsonKeyValue struct {
+	Key   string       `json:"key"`
+	Value jsonAnyValue `json:"value"`
+}
+
+type jsonAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"`
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	BytesValue  []byte          `json:"bytesValue,omitempty"`
+	ArrayValue  *jsonArrayValue `json:"arrayValue,omitempty"`
+	KvlistValue *jsonKvList     `json:"kvlistValue,omitempty"`
+}
+
+type jsonArrayValue struct {
+	Values []jsonAnyValue `json:"values"`
+}
+
+type jsonKvList struct {
+	Values []jsonKeyValue `json:"values"`
+}
+
+// encodeJSON encodes an ExportLogsServiceRequest as OTLP JSON.
+func encodeJSON(resource []keyValue, records []logRecord) ([]byte, error) {
+	jrs := make([]jsonLogRecord, len(records))
+	for i, r := range records {
+		jrs[i] = jsonLogRecord{
+			TimeUnixNano:         strconv.FormatUint(r.timeUnixNano, 10),
+			ObservedTimeUnixNano: strconv.FormatUint(r.observedUnixNano, 10),
+			SeverityNumber:       r.severityNumber,
+			SeverityText:         r.severityText,
+			Body:                 jsonValue(r.body),
+			Attributes:           jsonKeyValues(r.attributes),
+			Flags:                r.flags,
+			TraceID:              hex.EncodeToString(r.traceID),
+			SpanID:               hex.EncodeToString(r.spanID),
+		}
+	}
+	req := jsonExportRequest{ResourceLogs: []jsonResourceLogs{{
+		Resource:  jsonResource{Attributes: jsonKeyValues(resource)},
+		ScopeLogs: []jsonScopeLogs{{Scope: jsonScope{Name: scopeName}, LogRecords: jrs}},
+	}}}
+	return json.Marshal(req)
+}
+
+func jsonKeyValues(kvs []keyValue) []jsonKeyValue {
+	if len(kvs) == 0 {
+		return nil
+	}
+	out := make([]jsonKeyValue, len(kvs))
+	for i, kv := range kvs {
+		out[i] = jsonKeyValue{Key: kv.key, Value: jsonValue(kv.value)}
+	}
+	return out
+}
+
+func jsonValue(v any) jsonAnyValue {
+	switch v := v.(type) {
+	case string:
+		return jsonAnyValue{StringValue: &v}
+	case bool:
+		return jsonAnyValue{BoolValue: &v}
+	case int64:
+		s := strconv.FormatInt(v, 10)
+		return jsonAnyValue{IntValue: &s}
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			s := strconv.FormatFloat(v, 'g', -1, 64)
+			return jsonAnyValue{StringValue: &s}
+		}
+		return jsonAnyValue{DoubleValue: &v}
+	case []byte:
+		return jsonAnyValue{BytesValue: v}
+	case []any:
+		values := make([]jsonAnyValue, len(v))
+		for i, e := range v {
+			values[i] = jsonValue(e)
+		}
+		return jsonAnyValue{ArrayValue: &jsonArrayValue{Values: values}}
+	case []keyValue:
+		return jsonAnyValue{KvlistValue: &jsonKvList{Values: jsonKeyValues(v)}}
+	default:
+		s := fmt.Sprint(v)
+		return jsonAnyValue{StringValue: &s}
+	}
+}
+
+// Protobuf encoding of opentelemetry.proto.collector.logs.v1.ExportLogsServiceRequest.
+// Only the wire types needed by the messages below are implemented.
+
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+func appendTag(b []byte, field, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wire))
+}
+
+func appendBytesField(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendStringField(b []byte, field int, v string) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendVarintField(b []byte, field int, v uint64) []byte {
+	b = appendTag(b, field, wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendFixed64Field(b []byte, field int, v uint64) []byte {
+	b = appendTag(b, field, wireFixed64)
+	return binary.LittleEndian.AppendUint64(b, v)
+}
+
+func appendFixed32Field(b []byte, field int, v uint32) []byte {
+	b = appendTag(b, field, wireFixed32)
+	return binary.LittleEndian.AppendUint32(b, v)
+}
+
+// encodeProto encodes an ExportLogsServiceRequest as protobuf.
+func encodeProto(resource []keyValue, records []logRecord) []byte {
+	// ScopeLogs: scope = 1, log_records = 2
+	var scopeLogs []byte
+	scopeLogs = appendBytesField(scopeLogs, 1, appendStringField(nil, 1, scopeName))
+	for _, r := range records {
+		scopeLogs = appendBytesField(scopeLogs, 2, protoLogRecord(r))
+	}
+
+	// Resource: attributes = 1
+	var res []byte
+	for _, kv := range resource {
+		res = appendBytesField(res, 1, protoKeyValue(kv))
+	}
+
+	// ResourceLogs: resource = 1, scope_logs = 2
+	var resourceLogs []byte
+	resourceLogs = appendBytesField(resourceLogs, 1, res)
+	resourceLogs = appendBytesField(resourceLogs, 2, scopeLogs)
+
+	// ExportLogsServiceRequest: resource_logs = 1
+	return appendBytesField(nil, 1, resourceLogs)
+}
+
+// protoLogRecord encodes a LogRecord.
+func protoLogRecord(r logRecord) []byte {
+	var b []byte
+	b = appendFixed64Field(b, 1, r.timeUnixNano)
+	b = appendVarintField(b, 2, uint64(r.severityNumber))
+	b = appendStringField(b, 3, r.severityText)
+	b = appendBytesField(b, 5, protoAnyValue(r.body))
+	for _, kv := range r.attributes {
+		b = appendBytesField(b, 6, protoKeyValue(kv))
+	}
+	if r.flags != 0 {
+		b = appendFixed32Field(b, 8, r.flags)
+	}
+	if len(r.traceID) > 0 {
+		b = appendBytesField(b, 9, r.traceID)
+	}
+	if len(r.spanID) > 0 {
+		b = appendBytesField(b, 10, r.spanID)
+	}
+	return appendFixed64Field(b, 11, r.observedUnixNano)
+}
+
+// protoKeyValue encodes a KeyValue: key = 1, value = 2.
+func protoKeyValue(kv keyValue) []byte {
+	b := appendStringField(nil, 1, kv.key)
+	return appendBytesField(b, 2, protoAnyValue(kv.value))
+}
+
+// protoAnyValue encodes an AnyValue.
+func protoAnyValue(v any) []byte {
+	switch v := v.(type) {
+	case string:
+		return appendStringField(nil, 1, v)
+	case bool:
+		var n uint64
+		if v {
+			n = 1
+		}
+		return appendVarintField(nil, 2, n)
+	case int64:
+		return appendVarintField(nil, 3, uint64(v))
+	case float64:
+		return appendFixed64Field(nil, 4, math.Float64bits(v))
+	case []any:
+		// ArrayValue: values = 1
+		var arr []byte
+		for _, e := range v {
+			arr = appendBytesField(arr, 1, protoAnyValue(e))
+		}
+		return appendBytesField(nil, 5, arr)
+	case []keyValue:
+		// KeyValueList: values = 1
+		var list []byte
+		for _, kv := range v {
+			list = appendBytesField(list, 1, protoKeyValue(kv))
+		}
+		return appendBytesField(nil, 6, list)
+	case []byte:
+		return appendBytesField(nil, 7, v)
+	default:
+		return appendStringField(nil, 1, fmt.Sprint(v))
+	}
+}
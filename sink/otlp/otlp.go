@@ -0,0 +1,183 @@
+// Package otlp provides a sink that exports logx entries as OpenTelemetry
+// LogRecords to an OTLP/HTTP endpoint, and optionally to a file in the OTLP
+// JSON format.
+//
+// Example usage:
+//
+//	config := &logx.LoggerConfig{
+//	    // ...
+//	    OTLP: &otlp.Config{
+//	        Endpoint:    "http://otel-collector:4318/v1/logs",
+//	        ServiceName: "checkout",
+//	    },
+//	}
+package otlp
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+)
+
+const (
+	// ProtocolProtobuf sends binary protobuf requests (application/x-protobuf).
+	ProtocolProtobuf = "http/protobuf"
+	// ProtocolJSON sends OTLP JSON requests (application/json).
+	ProtocolJSON = "http/json"
+)
+
+// Config holds the configuration of the OTLP exporter.
+type Config struct {
+	// Endpoint is the OTLP/HTTP logs URL, e.g. http://localhost:4318/v1/logs.
+	// It may be empty if File is set.
+	Endpoint string `mapstructure:"endpoint" yaml:"endpoint"`
+
+	// Protocol is http/protobuf (default) or http/json.
+	Protocol string `mapstructure:"protocol" yaml:"protocol"`
+
+	// File is an optional path that receives every batch as one line of
+	// OTLP JSON, the format read by the collector's otlpjsonfile receiver.
+	File string `mapstructure:"file" yaml:"file"`
+
+	// ServiceName is the service.name resource attribute. It defaults to
+	// $OTEL_SERVICE_NAME or the executable name.
+	ServiceName string `mapstructure:"service-name" yaml:"service-name"`
+
+	// ResourceAttributes are added to the resource of every batch, next to
+	// service.name, host.name and process.pid.
+	ResourceAttributes map[string]string `mapstructure:"resource-attributes" yaml:"resource-attributes"`
+
+	// HTTP configures headers, compression and timeout of the requests.
+	HTTP sink.HTTPConfig `mapstructure:"http" yaml:"http"`
+
+	// Batch configures the bounded queue and batching.
+	Batch sink.BatchConfig `mapstructure:"batch" yaml:"batch"`
+
+	// Retry configures the retries of failed requests.
+	Retry sink.RetryConfig `mapstructure:"retry" yaml:"retry"`
+}
+
+// Exporter is a core.Sink that exports entries as OTLP LogRecords.
+type Exporter struct {
+	cfg      Config
+	resource []keyValue
+	batcher  *sink.Batcher
+
+	mu   sync.Mutex
+	file *os.File
+}
+
+// New creates an Exporter and starts its background delivery.
+func New(c Config) (*Exporter, error) {
+	if c.Endpoint == "" && c.File == "" {
+		return nil, errors.New("otlp: endpoint or file is required")
+	}
+	switch c.Protocol {
+	case "":
+		c.Protocol = ProtocolProtobuf
+	case ProtocolProtobuf, ProtocolJSON:
+	default:
+		return nil, errors.New("otlp: unsupported protocol: " + c.Protocol)
+	}
+
+	x := &Exporter{cfg: c, resource: resourceAttributes(c)}
+	if c.File != "" {
+		if err := os.MkdirAll(filepath.Dir(c.File), os.ModePerm); err != nil {
+			return nil, err
+		}
+		f, err := os.OpenFile(c.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		x.file = f
+	}
+	client := c.HTTP.Client()
+	batcher, err := sink.NewBatcher(c.Batch, func(ctx context.Context, batch []*core.Entry) error {
+		return x.export(ctx, client, batch)
+	})
+	if err != nil {
+		if x.file != nil {
+			x.file.Close()
+		}
+		return nil, err
+	}
+	x.batcher = batcher
+	return x, nil
+}
+
+// Write implements the core.Sink interface. The entry is queued and sent in
+// the background; it is dropped if the queue is full.
+func (x *Exporter) Write(e *core.Entry) error {
+	x.batcher.Add(e)
+	return nil
+}
+
+// Sync implements the core.Sink interface.
+func (x *Exporter) Sync() error {
+	return x.batcher.Flush()
+}
+
+// Close implements the core.Sink interface.
+func (x *Exporter) Close() error {
+	err := x.batcher.Close()
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.file != nil {
+		if cerr := x.file.Close(); err == nil {
+			err = cerr
+		}
+		x.file = nil
+	}
+	return err
+}
+
+// Stats returns the delivery counters of the exporter.
+func (x *Exporter) Stats() sink.Stats {
+	return x.batcher.Stats()
+}
+
+// export writes a batch to the file and sends it to the endpoint.
+func (x *Exporter) export(ctx context.Context, client *http.Client, batch []*core.Entry) error {
+	records := make([]logRecord, len(batch))
+	for i, e := range batch {
+		records[i] = newLogRecord(e)
+	}
+
+	var fileErr error
+	if x.file != nil {
+		line, err := encodeJSON(x.resource, records)
+		if err == nil {
+			x.mu.Lock()
+			if x.file != nil {
+				_, err = x.file.Write(append(line, '\n'))
+			}
+			x.mu.Unlock()
+		}
+		fileErr = err
+	}
+	if x.cfg.Endpoint == "" {
+		return fileErr
+	}
+
+	var body []byte
+	contentType := "application/x-protobuf"
+	if x.cfg.Protocol == ProtocolJSON {
+		var err error
+		if body, err = encodeJSON(x.resource, records); err != nil {
+			return err
+		}
+		contentType = "application/json"
+	} else {
+		body = encodeProto(x.resource, records)
+	}
+	err := sink.Retry(ctx, x.cfg.Retry, func(ctx context.Context) error {
+		return sink.Post(ctx, client, x.cfg.HTTP, x.cfg.Endpoint, contentType, body)
+	})
+	return errors.Join(err, fileErr)
+}
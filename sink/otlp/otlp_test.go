@@ -0,0 +1,340 @@
+package otlp_test
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+	"github.com/go4x/logx/sink/otlp"
+)
+
+// collector is an httptest stand-in for an OTLP/HTTP collector.
+type collector struct {
+	*httptest.Server
+	mu       sync.Mutex
+	bodies   [][]byte
+	types    []string
+	failures int
+}
+
+func newCollector(t *testing.T, failures int) *collector {
+	c := &collector{failures: failures}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if c.failures > 0 {
+			c.failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var body io.Reader = r.Body
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		data, _ := io.ReadAll(body)
+		c.bodies = append(c.bodies, data)
+		c.types = append(c.types, r.Header.Get("Content-Type"))
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+func (c *collector) requests() ([][]byte, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]byte(nil), c.bodies...), append([]string(nil), c.types...)
+}
+
+// exportRequest is the subset of the OTLP JSON request checked by the tests.
+type exportRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []keyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			LogRecords []struct {
+				SeverityNumber int        `json:"severityNumber"`
+				SeverityText   string     `json:"severityText"`
+				Body           anyValue   `json:"body"`
+				Attributes     []keyValue `json:"attributes"`
+				TraceID        string     `json:"traceId"`
+				SpanID         string     `json:"spanId"`
+				Flags          int        `json:"flags"`
+			} `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+	IntValue    string `json:"intValue"`
+	BoolValue   bool   `json:"boolValue"`
+	KvlistValue *struct {
+		Values []keyValue `json:"values"`
+	} `json:"kvlistValue"`
+}
+
+func attr(kvs []keyValue, key string) (anyValue, bool) {
+	for _, kv := range kvs {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return anyValue{}, false
+}
+
+func testEntry() *core.Entry {
+	return &core.Entry{
+		Time:    time.Unix(1700000000, 0),
+		Level:   core.WarnLevel,
+		Message: "disk almost full",
+		Caller:  "app/main.go:42",
+		Fields: []core.Field{
+			{Key: "free_mb", Value: int64(12)},
+			{Key: "trace_id", Value: "4bf92f3577b34da6a3ce929d0e0e4736"},
+			{Key: "span_id", Value: "00f067aa0ba902b7"},
+			{Key: "trace_flags", Value: "01"},
+			{Key: "disk", Value: map[string]any{"name": "sda", "ssd": true}},
+		},
+	}
+}
+
+// TestExportJSON tests the OTLP JSON mapping of an entry and its resource
+func TestExportJSON(t *testing.T) {
+	c := newCollector(t, 0)
+	x, err := otlp.New(otlp.Config{
+		Endpoint:           c.URL,
+		Protocol:           otlp.ProtocolJSON,
+		ServiceName:        "checkout",
+		ResourceAttributes: map[string]string{"deployment.environment": "test"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	defer x.Close()
+
+	x.Write(testEntry())
+	x.Sync()
+
+	bodies, types := c.requests()
+	if len(bodies) != 1 || types[0] != "application/json" {
+		t.Fatalf("expected one JSON request, got %d %v", len(bodies), types)
+	}
+	var req exportRequest
+	if err := json.Unmarshal(bodies[0], &req); err != nil {
+		t.Fatalf("invalid OTLP JSON: %v", err)
+	}
+
+	res := req.ResourceLogs[0].Resource.Attributes
+	if v, _ := attr(res, "service.name"); v.StringValue != "checkout" {
+		t.Errorf("unexpected service.name: %+v", v)
+	}
+	if v, _ := attr(res, "deployment.environment"); v.StringValue != "test" {
+		t.Errorf("unexpected deployment.environment: %+v", v)
+	}
+	if _, ok := attr(res, "process.pid"); !ok {
+		t.Error("expected process.pid resource attribute")
+	}
+	if _, ok := attr(res, "host.name"); !ok {
+		t.Error("expected host.name resource attribute")
+	}
+
+	r := req.ResourceLogs[0].ScopeLogs[0].LogRecords[0]
+	if r.SeverityNumber != 13 || r.SeverityText != "WARN" || r.Body.StringValue != "disk almost full" {
+		t.Errorf("unexpected record: %+v", r)
+	}
+	if r.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || r.SpanID != "00f067aa0ba902b7" || r.Flags != 1 {
+		t.Errorf("expected trace context to be lifted, got %+v", r)
+	}
+	if _, ok := attr(r.Attributes, "trace_id"); ok {
+		t.Error("trace_id should not remain an attribute")
+	}
+	if v, _ := attr(r.Attributes, "free_mb"); v.IntValue != "12" {
+		t.Errorf("unexpected free_mb: %+v", v)
+	}
+	if v, _ := attr(r.Attributes, "code.lineno"); v.IntValue != "42" {
+		t.Errorf("unexpected code.lineno: %+v", v)
+	}
+	if v, _ := attr(r.Attributes, "disk"); v.KvlistValue == nil || len(v.KvlistValue.Values) != 2 {
+		t.Errorf("expected nested disk attribute, got %+v", v)
+	}
+}
+
+// TestExportProtobufWithRetry tests protobuf requests and retries of an unavailable collector
+func TestExportProtobufWithRetry(t *testing.T) {
+	c := newCollector(t, 2)
+	x, err := otlp.New(otlp.Config{
+		Endpoint: c.URL,
+		Retry:    sink.RetryConfig{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond},
+	})
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	defer x.Close()
+
+	x.Write(testEntry())
+	x.Sync()
+
+	bodies, types := c.requests()
+	if len(bodies) != 1 || types[0] != "application/x-protobuf" {
+		t.Fatalf("expected one protobuf request after retries, got %d %v", len(bodies), types)
+	}
+
+	// ExportLogsServiceRequest.resource_logs[0].scope_logs[0].log_records[0]
+	resourceLogs := protoField(t, bodies[0], 1)
+	scopeLogs := protoField(t, resourceLogs, 2)
+	record := protoField(t, scopeLogs, 2)
+	if sev := protoVarint(t, record, 2); sev != 13 {
+		t.Errorf("expected severity 13, got %d", sev)
+	}
+	body := protoField(t, record, 5)
+	if msg := string(protoField(t, body, 1)); msg != "disk almost full" {
+		t.Errorf("unexpected body: %q", msg)
+	}
+	if traceID := protoField(t, record, 9); len(traceID) != 16 {
+		t.Errorf("expected 16-byte trace id, got %x", traceID)
+	}
+	if s := x.Stats(); s.Sent != 1 || s.Failed != 0 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+// TestExportFile tests that batches are written to the file as OTLP JSON lines
+func TestExportFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "otlp", "logs.json")
+	x, err := otlp.New(otlp.Config{File: path})
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	x.Write(testEntry())
+	x.Sync()
+	x.Write(testEntry())
+	x.Close()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("failed to open file: %v", err)
+	}
+	defer f.Close()
+	lines := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var req exportRequest
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			t.Fatalf("invalid OTLP JSON line: %v", err)
+		}
+		lines++
+	}
+	if lines != 2 {
+		t.Errorf("expected 2 lines, got %d", lines)
+	}
+}
+
+// TestSeverity tests the mapping of logx levels to OTel severity numbers
+func TestSeverity(t *testing.T) {
+	testCases := map[core.Level]int{
+		core.DebugLevel - 4: 1,
+		core.DebugLevel - 1: 4,
+		core.DebugLevel:     5,
+		core.InfoLevel:      9,
+		core.InfoLevel + 2:  11,
+		core.WarnLevel:      13,
+		core.ErrorLevel:     17,
+		core.ErrorLevel + 2: 18,
+		core.FatalLevel:     21,
+		core.PanicLevel:     19,
+	}
+	for level, want := range testCases {
+		if got := otlp.Severity(level); got != want {
+			t.Errorf("Severity(%d) = %d, want %d", level, got, want)
+		}
+	}
+}
+
+// TestNewValidation tests configuration validation
+func TestNewValidation(t *testing.T) {
+	if _, err := otlp.New(otlp.Config{}); err == nil {
+		t.Error("expected error without endpoint or file")
+	}
+	if _, err := otlp.New(otlp.Config{Endpoint: "http://localhost", Protocol: "grpc"}); err == nil {
+		t.Error("expected error for unsupported protocol")
+	}
+}
+
+// protoField returns the first length-delimited field with the given number.
+func protoField(t *testing.T, b []byte, field int) []byte {
+	t.Helper()
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		b = b[n:]
+		num, wire := int(tag>>3), int(tag&7)
+		switch wire {
+		case 0:
+			_, n = binary.Uvarint(b)
+			b = b[n:]
+		case 1:
+			b = b[8:]
+		case 5:
+			b = b[4:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			v := b[n : n+int(l)]
+			b = b[n+int(l):]
+			if num == field {
+				return v
+			}
+		default:
+			t.Fatalf("unexpected wire type %d", wire)
+		}
+	}
+	t.Fatalf("field %d not found", field)
+	return nil
+}
+
+// protoVarint returns the first varint field with the given number.
+func protoVarint(t *testing.T, b []byte, field int) uint64 {
+	t.Helper()
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		b = b[n:]
+		num, wire := int(tag>>3), int(tag&7)
+		switch wire {
+		case 0:
+			v, n := binary.Uvarint(b)
+			b = b[n:]
+			if num == field {
+				return v
+			}
+		case 1:
+			b = b[8:]
+		case 5:
+			b = b[4:]
+		case 2:
+			l, n := binary.Uvarint(b)
+			b = b[n+int(l):]
+		}
+	}
+	t.Fatalf("field %d not found", field)
+	return 0
+}
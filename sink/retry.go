@@ -0,0 +1,106 @@
+package sink
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryConfig configures the retries of a failed delivery.
+type RetryConfig struct {
+	// MaxRetries is the number of retries after the first attempt (default 5).
+	// A negative value disables retries.
+	MaxRetries int `mapstructure:"max-retries" yaml:"max-retries"`
+
+	// MinBackoff is the backoff before the first retry (default 100ms).
+	MinBackoff time.Duration `mapstructure:"min-backoff" yaml:"min-backoff"`
+
+	// MaxBackoff caps the exponential backoff (default 10s).
+	MaxBackoff time.Duration `mapstructure:"max-backoff" yaml:"max-backoff"`
+}
+
+// withDefaults returns a copy of c with zero values replaced by defaults.
+func (c RetryConfig) withDefaults() RetryConfig {
+	if c.MaxRetries == 0 {
+		c.MaxRetries = 5
+	}
+	if c.MinBackoff <= 0 {
+		c.MinBackoff = 100 * time.Millisecond
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 10 * time.Second
+	}
+	if c.MaxBackoff < c.MinBackoff {
+		c.MaxBackoff = c.MinBackoff
+	}
+	return c
+}
+
+// permanentError marks an error that must not be retried.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent wraps err so that Retry gives up immediately.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was wrapped with Permanent.
+func IsPermanent(err error) bool {
+	var pe *permanentError
+	return errors.As(err, &pe)
+}
+
+// retryAfterError carries the delay requested by the server.
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+func (e *retryAfterError) Error() string { return e.err.Error() }
+func (e *retryAfterError) Unwrap() error { return e.err }
+
+// Retry calls fn until it succeeds, returns a permanent error, ctx is done or
+// the retries configured in c are exhausted. The delay between attempts grows
+// exponentially with full jitter, unless the server asked for a specific delay.
+func Retry(ctx context.Context, c RetryConfig, fn func(ctx context.Context) error) error {
+	c = c.withDefaults()
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || IsPermanent(err) || c.MaxRetries < 0 || attempt >= c.MaxRetries {
+			return err
+		}
+		delay := Backoff(attempt, c.MinBackoff, c.MaxBackoff)
+		var ra *retryAfterError
+		if errors.As(err, &ra) && ra.delay > 0 {
+			delay = min(ra.delay, c.MaxBackoff)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+// Backoff returns a random delay in [0, min(maxDelay, minDelay*2^attempt)],
+// the "full jitter" strategy that spreads the retries of many clients.
+func Backoff(attempt int, minDelay, maxDelay time.Duration) time.Duration {
+	ceiling := maxDelay
+	if attempt < 32 {
+		if d := minDelay << attempt; d > 0 && d < maxDelay {
+			ceiling = d
+		}
+	}
+	return time.Duration(rand.Int64N(int64(ceiling) + 1))
+}
@@ -0,0 +1,215 @@
+package sink_test
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+)
+
+// TestBatcherBatchSize tests that full batches are sent immediately and partial ones on flush
+func TestBatcherBatchSize(t *testing.T) {
+	var mu sync.Mutex
+	var sizes []int
+	b, _ := sink.NewBatcher(sink.BatchConfig{BatchSize: 3, FlushInterval: time.Hour}, func(ctx context.Context, batch []*core.Entry) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sizes = append(sizes, len(batch))
+		return nil
+	})
+	defer b.Close()
+
+	for i := 0; i < 7; i++ {
+		b.Add(&core.Entry{Message: "m"})
+	}
+	b.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sizes) != 3 || sizes[0] != 3 || sizes[1] != 3 || sizes[2] != 1 {
+		t.Errorf("unexpected batch sizes: %v", sizes)
+	}
+	if s := b.Stats(); s.Sent != 7 || s.Dropped != 0 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+// TestBatcherFlushInterval tests that partial batches are sent periodically
+func TestBatcherFlushInterval(t *testing.T) {
+	sent := make(chan int, 1)
+	b, _ := sink.NewBatcher(sink.BatchConfig{BatchSize: 100, FlushInterval: 10 * time.Millisecond}, func(ctx context.Context, batch []*core.Entry) error {
+		sent <- len(batch)
+		return nil
+	})
+	defer b.Close()
+
+	b.Add(&core.Entry{Message: "m"})
+	select {
+	case n := <-sent:
+		if n != 1 {
+			t.Errorf("expected 1 entry, got %d", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("partial batch was not sent")
+	}
+}
+
+// TestBatcherDropsWhenFull tests that the queue is bounded and drops are counted and reported
+func TestBatcherDropsWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	var reports []error
+	c := sink.BatchConfig{BatchSize: 1, QueueSize: 2, FlushInterval: time.Hour, ErrorHandler: func(err error) {
+		reports = append(reports, err)
+	}}
+	b, _ := sink.NewBatcher(c, func(ctx context.Context, batch []*core.Entry) error {
+		<-release
+		return nil
+	})
+
+	accepted := 0
+	for i := 0; i < 10; i++ {
+		if b.Add(&core.Entry{Message: "m"}) {
+			accepted++
+		}
+	}
+	close(release)
+	b.Close()
+
+	s := b.Stats()
+	if s.Dropped == 0 || s.Dropped+uint64(accepted) != 10 {
+		t.Errorf("unexpected stats: %+v (accepted %d)", s, accepted)
+	}
+	want := fmt.Sprintf("dropped %d entries because the queue was full", s.Dropped)
+	if len(reports) != 1 || reports[0].Error() != want {
+		t.Errorf("expected drop report %q, got %v", want, reports)
+	}
+	if b.Add(&core.Entry{}) {
+		t.Error("expected Add to fail after Close")
+	}
+}
+
+// TestBatcherAddClose tests that the entries added concurrently with Close are either sent or dropped
+func TestBatcherAddClose(t *testing.T) {
+	for round := 0; round < 200; round++ {
+		b, _ := sink.NewBatcher(sink.BatchConfig{FlushInterval: time.Hour}, func(ctx context.Context, batch []*core.Entry) error {
+			return nil
+		})
+		const adders, perAdder = 32, 50
+		var wg sync.WaitGroup
+		for i := 0; i < adders; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < perAdder; j++ {
+					b.Add(&core.Entry{Message: "m"})
+				}
+			}()
+		}
+		b.Close()
+		wg.Wait()
+
+		if s := b.Stats(); s.Sent+s.Dropped != adders*perAdder {
+			t.Fatalf("round %d: %d entries lost: %+v", round, adders*perAdder-int(s.Sent+s.Dropped), s)
+		}
+	}
+}
+
+// TestRetry tests retries of transient errors and immediate failure of permanent errors
+func TestRetry(t *testing.T) {
+	cfg := sink.RetryConfig{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+	calls := 0
+	err := sink.Retry(context.Background(), cfg, func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("expected success after 3 calls, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	err = sink.Retry(context.Background(), cfg, func(ctx context.Context) error {
+		calls++
+		return sink.Permanent(errors.New("bad request"))
+	})
+	if !sink.IsPermanent(err) || calls != 1 {
+		t.Errorf("expected permanent error after 1 call, got %v after %d", err, calls)
+	}
+
+	calls = 0
+	err = sink.Retry(context.Background(), cfg, func(ctx context.Context) error {
+		calls++
+		return errors.New("down")
+	})
+	if err == nil || calls != 4 {
+		t.Errorf("expected failure after 4 calls, got %v after %d", err, calls)
+	}
+}
+
+// TestBackoffBounds tests that the jittered backoff stays within its ceiling
+func TestBackoffBounds(t *testing.T) {
+	for attempt := 0; attempt < 40; attempt++ {
+		d := sink.Backoff(attempt, 10*time.Millisecond, time.Second)
+		if d < 0 || d > time.Second {
+			t.Fatalf("backoff %v out of bounds at attempt %d", d, attempt)
+		}
+		if attempt == 0 && d > 10*time.Millisecond {
+			t.Fatalf("first backoff %v exceeds minimum", d)
+		}
+	}
+}
+
+// TestPostCompressesAndClassifiesErrors tests gzip bodies and retryable status codes
+func TestPostCompressesAndClassifiesErrors(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusServiceUnavailable)
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "gzip" || r.Header.Get("X-Token") != "secret" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(zr)
+		body = string(data)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer srv.Close()
+
+	cfg := sink.HTTPConfig{Headers: map[string]string{"X-Token": "secret"}}
+	err := sink.Post(context.Background(), srv.Client(), cfg, srv.URL, "text/plain", []byte("hello"))
+	if err == nil || sink.IsPermanent(err) {
+		t.Errorf("expected retryable error for 503, got %v", err)
+	}
+
+	status.Store(http.StatusBadRequest)
+	err = sink.Post(context.Background(), srv.Client(), cfg, srv.URL, "text/plain", []byte("hello"))
+	if !sink.IsPermanent(err) {
+		t.Errorf("expected permanent error for 400, got %v", err)
+	}
+
+	status.Store(http.StatusNoContent)
+	if err := sink.Post(context.Background(), srv.Client(), cfg, srv.URL, "text/plain", []byte("hello")); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if body != "hello" {
+		t.Errorf("expected decompressed body, got %q", body)
+	}
+}
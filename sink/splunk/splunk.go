@@ -0,0 +1,162 @@
+// Package splunk provides a sink that sends logx entries to the Splunk HTTP
+// Event Collector (HEC).
+//
+// Example usage:
+//
+//	config := &logx.LoggerConfig{
+//	    // ...
+//	    Splunk: &splunk.Config{
+//	        URL:        "https://splunk:8088",
+//	        Token:      hecToken,
+//	        Index:      "main",
+//	        SourceType: "_json",
+//	    },
+//	}
+package splunk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+)
+
+// eventPath is the path of the JSON event endpoint.
+const eventPath = "/services/collector/event"
+
+// Config holds the configuration of the Splunk HEC sink.
+type Config struct {
+	// URL is the base URL of the collector, e.g. https://localhost:8088. A
+	// URL that already ends with the event path is used as is.
+	URL string `mapstructure:"url" yaml:"url"`
+
+	// Token is the HEC token.
+	Token string `mapstructure:"token" yaml:"token"`
+
+	// Index is the target index; empty uses the default index of the token.
+	Index string `mapstructure:"index" yaml:"index"`
+
+	// Source is the source of the events.
+	Source string `mapstructure:"source" yaml:"source"`
+
+	// SourceType is the sourcetype of the events (default "_json").
+	SourceType string `mapstructure:"source-type" yaml:"source-type"`
+
+	// Host is the host of the events. It defaults to os.Hostname.
+	Host string `mapstructure:"host" yaml:"host"`
+
+	// HTTP configures headers, compression and timeout of the requests.
+	HTTP sink.HTTPConfig `mapstructure:"http" yaml:"http"`
+
+	// Batch configures the bounded queue and batching.
+	Batch sink.BatchConfig `mapstructure:"batch" yaml:"batch"`
+
+	// Retry configures the retries of failed requests.
+	Retry sink.RetryConfig `mapstructure:"retry" yaml:"retry"`
+}
+
+// Client is a core.Sink that sends entries to the HTTP Event Collector.
+type Client struct {
+	cfg     Config
+	url     string
+	batcher *sink.Batcher
+}
+
+// New creates a Client and starts its background delivery.
+func New(c Config) (*Client, error) {
+	if c.URL == "" {
+		return nil, errors.New("splunk: url is required")
+	}
+	if c.Token == "" {
+		return nil, errors.New("splunk: token is required")
+	}
+	if c.SourceType == "" {
+		c.SourceType = "_json"
+	}
+	if c.Host == "" {
+		c.Host, _ = os.Hostname()
+	}
+	headers := make(map[string]string, len(c.HTTP.Headers)+1)
+	for k, v := range c.HTTP.Headers {
+		headers[k] = v
+	}
+	headers["Authorization"] = "Splunk " + c.Token
+	c.HTTP.Headers = headers
+
+	s := &Client{cfg: c, url: strings.TrimSuffix(c.URL, "/")}
+	if !strings.HasSuffix(s.url, eventPath) {
+		s.url += eventPath
+	}
+	client := c.HTTP.Client()
+	batcher, err := sink.NewBatcher(c.Batch, func(ctx context.Context, batch []*core.Entry) error {
+		return s.send(ctx, client, batch)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.batcher = batcher
+	return s, nil
+}
+
+// Write implements the core.Sink interface. The entry is queued and sent in
+// the background; it is dropped if the queue is full.
+func (s *Client) Write(e *core.Entry) error {
+	s.batcher.Add(e)
+	return nil
+}
+
+// Sync implements the core.Sink interface.
+func (s *Client) Sync() error {
+	return s.batcher.Flush()
+}
+
+// Close implements the core.Sink interface.
+func (s *Client) Close() error {
+	return s.batcher.Close()
+}
+
+// Stats returns the delivery counters of the client.
+func (s *Client) Stats() sink.Stats {
+	return s.batcher.Stats()
+}
+
+// event is one HEC event.
+type event struct {
+	Time       float64        `json:"time"`
+	Host       string         `json:"host,omitempty"`
+	Source     string         `json:"source,omitempty"`
+	SourceType string         `json:"sourcetype,omitempty"`
+	Index      string         `json:"index,omitempty"`
+	Event      map[string]any `json:"event"`
+}
+
+// send posts a batch as concatenated JSON events.
+func (s *Client) send(ctx context.Context, client *http.Client, batch []*core.Entry) error {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, e := range batch {
+		err := enc.Encode(event{
+			Time:       float64(e.Time.UnixMicro()) / 1e6,
+			Host:       s.cfg.Host,
+			Source:     s.cfg.Source,
+			SourceType: s.cfg.SourceType,
+			Index:      s.cfg.Index,
+			Event:      sink.Document(e, "message"),
+		})
+		if err != nil {
+			// sending the batch again cannot succeed
+			return sink.Permanent(fmt.Errorf("splunk: %w", err))
+		}
+	}
+	body := buf.Bytes()
+	return sink.Retry(ctx, s.cfg.Retry, func(ctx context.Context) error {
+		return sink.Post(ctx, client, s.cfg.HTTP, s.url, "application/json", body)
+	})
+}
@@ -0,0 +1,119 @@
+package splunk_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+	"github.com/go4x/logx/sink/splunk"
+)
+
+type hecEvent struct {
+	Time       float64        `json:"time"`
+	Host       string         `json:"host"`
+	Index      string         `json:"index"`
+	SourceType string         `json:"sourcetype"`
+	Event      map[string]any `json:"event"`
+}
+
+// TestSend tests the HEC event format and token authentication
+func TestSend(t *testing.T) {
+	events := make(chan []hecEvent, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/services/collector/event" || r.Header.Get("Authorization") != "Splunk secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		var batch []hecEvent
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for dec.More() {
+			var e hecEvent
+			if err := dec.Decode(&e); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			batch = append(batch, e)
+		}
+		events <- batch
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer srv.Close()
+
+	s, err := splunk.New(splunk.Config{
+		URL:   srv.URL,
+		Token: "secret",
+		Index: "main",
+		Host:  "web-1",
+		HTTP:  sink.HTTPConfig{Compression: "none"},
+		Batch: sink.BatchConfig{FlushInterval: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer s.Close()
+
+	s.Write(&core.Entry{Time: time.UnixMilli(1700000000250), Level: core.ErrorLevel, Message: "payment failed", Fields: []core.Field{{Key: "attempt", Value: 2}}})
+	s.Write(&core.Entry{Time: time.UnixMilli(1700000000500), Level: core.InfoLevel, Message: "retrying"})
+	s.Sync()
+
+	batch := <-events
+	if len(batch) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(batch))
+	}
+	e := batch[0]
+	if e.Time != 1700000000.25 || e.Host != "web-1" || e.Index != "main" || e.SourceType != "_json" {
+		t.Errorf("unexpected event metadata: %+v", e)
+	}
+	if e.Event["message"] != "payment failed" || e.Event["level"] != "error" || e.Event["attempt"] != float64(2) {
+		t.Errorf("unexpected event: %v", e.Event)
+	}
+}
+
+// TestNewValidation tests configuration validation
+func TestNewValidation(t *testing.T) {
+	if _, err := splunk.New(splunk.Config{URL: "http://localhost:8088"}); err == nil {
+		t.Error("expected error without token")
+	}
+}
+
+// TestSendNonFinite tests that NaN and infinite floats are sent as strings
+func TestSendNonFinite(t *testing.T) {
+	events := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		events <- data
+		w.Write([]byte(`{"text":"Success","code":0}`))
+	}))
+	defer srv.Close()
+
+	s, err := splunk.New(splunk.Config{
+		URL:   srv.URL,
+		Token: "secret",
+		HTTP:  sink.HTTPConfig{Compression: "none"},
+		Batch: sink.BatchConfig{FlushInterval: time.Hour},
+	})
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	defer s.Close()
+
+	s.Write(&core.Entry{Time: time.Now(), Level: core.InfoLevel, Message: "ratio", Fields: []core.Field{{Key: "ratio", Value: math.NaN()}}})
+	s.Sync()
+
+	select {
+	case data := <-events:
+		if !bytes.Contains(data, []byte(`"ratio":"NaN"`)) {
+			t.Errorf("unexpected event: %s", data)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no event received")
+	}
+}
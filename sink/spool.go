@@ -0,0 +1,540 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go4x/logx/core"
+)
+
+// SpoolConfig configures the disk spool of a Batcher.
+type SpoolConfig struct {
+	// Enabled turns the spool on. Batches that fail to be delivered are then
+	// written to disk and replayed in order once the endpoint recovers,
+	// including after a restart.
+	Enabled bool `mapstructure:"enabled" yaml:"enabled"`
+
+	// Dir is the spool directory. It must not be shared between sinks.
+	// Sinks configured through logx.LoggerConfig default to a directory
+	// under the log Dir.
+	Dir string `mapstructure:"dir" yaml:"dir"`
+
+	// MaxSize caps the size of the spool in bytes (default 256MB). When it
+	// is exceeded the oldest segments are discarded and counted as dropped.
+	MaxSize int64 `mapstructure:"max-size" yaml:"max-size"`
+
+	// SegmentSize is the size at which a new segment file is started (default 8MB).
+	SegmentSize int64 `mapstructure:"segment-size" yaml:"segment-size"`
+}
+
+// withDefaults returns a copy of c with zero values replaced by defaults.
+func (c SpoolConfig) withDefaults() SpoolConfig {
+	if c.MaxSize <= 0 {
+		c.MaxSize = 256 << 20
+	}
+	if c.SegmentSize <= 0 {
+		c.SegmentSize = 8 << 20
+	}
+	if c.SegmentSize > c.MaxSize {
+		c.SegmentSize = c.MaxSize
+	}
+	return c
+}
+
+const (
+	// segmentExt is the extension of the segment files.
+	segmentExt = ".seg"
+	// cursorFile records the read position of the oldest segment.
+	cursorFile = "cursor"
+	// recordHeaderSize is the size of the record header: payload length,
+	// entry count and CRC-32C of the payload, all little endian uint32.
+	recordHeaderSize = 12
+	// maxRecordSize bounds the payload length accepted when reading.
+	maxRecordSize = 1 << 30
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// segment is a spool file holding records of batches.
+type segment struct {
+	seq     uint64
+	size    int64
+	entries int
+}
+
+// Spool is a size-capped queue of batches on disk. Each batch is one record
+// of a segment file, protected by a checksum. Records are read in the order
+// they were appended, and the read position is persisted so that a reopened
+// spool resumes where it stopped. A Spool is not safe for concurrent use.
+type Spool struct {
+	cfg      SpoolConfig
+	segments []*segment // oldest first
+	offset   int64      // read position in segments[0]
+	read     *os.File
+	write    *os.File
+	// peekedSize and peekedEntries describe the record returned by Peek.
+	peekedSize    int64
+	peekedEntries int
+	closed        bool
+}
+
+// openDirs holds the directories of the spools open in this process, which
+// are closed when the spool is closed.
+var (
+	openMu   sync.Mutex
+	openDirs = make(map[string]chan struct{})
+)
+
+// inUseError is returned by OpenSpool when the directory is already used by
+// another spool of this process.
+type inUseError struct {
+	dir      string
+	released <-chan struct{} // closed when the other spool is closed
+}
+
+func (e *inUseError) Error() string { return "spool: " + e.dir + " is already in use" }
+
+// claimDir reserves dir for one spool.
+func claimDir(dir string) error {
+	openMu.Lock()
+	defer openMu.Unlock()
+	if released, ok := openDirs[dir]; ok {
+		return &inUseError{dir: dir, released: released}
+	}
+	openDirs[dir] = make(chan struct{})
+	return nil
+}
+
+// releaseDir releases dir for the next spool.
+func releaseDir(dir string) {
+	openMu.Lock()
+	defer openMu.Unlock()
+	if released, ok := openDirs[dir]; ok {
+		close(released)
+		delete(openDirs, dir)
+	}
+}
+
+// OpenSpool opens the spool in c.Dir, creating the directory if needed, and
+// restores the segments and the read position left by a previous process.
+// A directory can be used by one open spool at a time.
+func OpenSpool(c SpoolConfig) (s *Spool, err error) {
+	if c.Dir == "" {
+		return nil, errors.New("spool: dir is required")
+	}
+	c = c.withDefaults()
+	if c.Dir, err = filepath.Abs(c.Dir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(c.Dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := claimDir(c.Dir); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err != nil {
+			releaseDir(c.Dir)
+		}
+	}()
+	s = &Spool{cfg: c}
+
+	names, err := filepath.Glob(filepath.Join(c.Dir, "*"+segmentExt))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(name), segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		s.segments = append(s.segments, &segment{seq: seq})
+	}
+	sort.Slice(s.segments, func(i, j int) bool { return s.segments[i].seq < s.segments[j].seq })
+
+	if len(s.segments) > 0 {
+		if data, err := os.ReadFile(filepath.Join(c.Dir, cursorFile)); err == nil {
+			var seq uint64
+			var offset int64
+			if _, err := fmt.Sscanf(string(data), "%d %d", &seq, &offset); err == nil && seq == s.segments[0].seq {
+				s.offset = offset
+			}
+		}
+	}
+	for i, seg := range s.segments {
+		start := int64(0)
+		if i == 0 {
+			start = s.offset
+		}
+		if err := s.scan(seg, start); err != nil {
+			return nil, err
+		}
+	}
+	s.removeEmpty()
+	return s, nil
+}
+
+// scan counts the valid records of seg from start. A torn or corrupt record,
+// typically left by a crash during a write, ends the segment: the file is
+// truncated to the last valid record.
+func (s *Spool) scan(seg *segment, start int64) error {
+	f, err := os.OpenFile(s.path(seg.seq), os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(f)
+	end := start
+	for {
+		n, count, err := readRecord(r, nil)
+		if err != nil {
+			break
+		}
+		end += n
+		seg.entries += count
+	}
+	seg.size = end
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Size() > end {
+		return f.Truncate(end)
+	}
+	return nil
+}
+
+// Len returns the number of entries waiting in the spool.
+func (s *Spool) Len() int {
+	n := 0
+	for _, seg := range s.segments {
+		n += seg.entries
+	}
+	return n
+}
+
+// Size returns the number of bytes waiting in the spool.
+func (s *Spool) Size() int64 {
+	var n int64
+	for _, seg := range s.segments {
+		n += seg.size
+	}
+	return n - s.offset
+}
+
+// Append writes batch as one record. If the spool would exceed its maximum
+// size, the oldest segments are discarded first; the number of entries lost
+// is returned.
+func (s *Spool) Append(batch []*core.Entry) (dropped int, err error) {
+	payload, err := encodeBatch(batch)
+	if err != nil {
+		return 0, err
+	}
+	size := int64(recordHeaderSize + len(payload))
+	if size > s.cfg.MaxSize {
+		return len(batch), fmt.Errorf("spool: batch of %d bytes exceeds the maximum size", size)
+	}
+	for len(s.segments) > 0 && s.Size()+size > s.cfg.MaxSize {
+		dropped += s.segments[0].entries
+		if err := s.removeOldest(); err != nil {
+			return dropped, err
+		}
+	}
+
+	last := s.last()
+	if last == nil || last.size+size > s.cfg.SegmentSize && last.size > 0 {
+		if err := s.startSegment(); err != nil {
+			return dropped, err
+		}
+		last = s.last()
+	}
+	if s.write == nil {
+		f, err := os.OpenFile(s.path(last.seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return dropped, err
+		}
+		s.write = f
+	}
+
+	record := make([]byte, recordHeaderSize, size)
+	binary.LittleEndian.PutUint32(record[0:], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:], uint32(len(batch)))
+	binary.LittleEndian.PutUint32(record[8:], crc32.Checksum(payload, crcTable))
+	record = append(record, payload...)
+	if _, err := s.write.Write(record); err != nil {
+		// Drop the partial record so that the next append starts cleanly.
+		_ = s.write.Truncate(last.size)
+		return dropped, err
+	}
+	if err := s.write.Sync(); err != nil {
+		return dropped, err
+	}
+	last.size += size
+	last.entries += len(batch)
+	return dropped, nil
+}
+
+// Peek returns the oldest batch without removing it, or nil if the spool is
+// empty. Call Pop to remove it once it has been delivered.
+func (s *Spool) Peek() ([]*core.Entry, error) {
+	s.removeEmpty()
+	if len(s.segments) == 0 {
+		return nil, nil
+	}
+	seg := s.segments[0]
+	if s.offset >= seg.size {
+		return nil, nil
+	}
+	if s.read == nil {
+		f, err := os.Open(s.path(seg.seq))
+		if err != nil {
+			return nil, err
+		}
+		s.read = f
+	}
+	if _, err := s.read.Seek(s.offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var payload []byte
+	n, count, err := readRecord(s.read, &payload)
+	if err != nil {
+		// The checksums were verified when the spool was opened or the
+		// record was appended, so this is an I/O error or external damage:
+		// skip the rest of the segment rather than blocking the spool.
+		lost := seg.entries
+		_ = s.removeOldest()
+		return nil, fmt.Errorf("spool: discarded %d entries of a damaged segment: %w", lost, err)
+	}
+	batch, err := decodeBatch(payload)
+	if err != nil {
+		s.peekedSize, s.peekedEntries = n, count
+		_ = s.Pop()
+		return nil, fmt.Errorf("spool: discarded %d undecodable entries: %w", count, err)
+	}
+	s.peekedSize, s.peekedEntries = n, count
+	return batch, nil
+}
+
+// Pop removes the batch returned by the last Peek.
+func (s *Spool) Pop() error {
+	if s.peekedSize == 0 || len(s.segments) == 0 {
+		return nil
+	}
+	seg := s.segments[0]
+	s.offset += s.peekedSize
+	seg.entries -= s.peekedEntries
+	s.peekedSize, s.peekedEntries = 0, 0
+	if s.offset >= seg.size {
+		return s.removeOldest()
+	}
+	return s.saveCursor()
+}
+
+// Close closes the open files. The spooled batches stay on disk.
+func (s *Spool) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	defer releaseDir(s.cfg.Dir)
+	var errs []error
+	if s.read != nil {
+		errs = append(errs, s.read.Close())
+		s.read = nil
+	}
+	if s.write != nil {
+		errs = append(errs, s.write.Close())
+		s.write = nil
+	}
+	return errors.Join(errs...)
+}
+
+func (s *Spool) path(seq uint64) string {
+	return filepath.Join(s.cfg.Dir, fmt.Sprintf("%016d%s", seq, segmentExt))
+}
+
+func (s *Spool) last() *segment {
+	if len(s.segments) == 0 {
+		return nil
+	}
+	return s.segments[len(s.segments)-1]
+}
+
+// startSegment appends a new empty segment and makes it the write segment.
+func (s *Spool) startSegment() error {
+	seq := uint64(1)
+	if last := s.last(); last != nil {
+		seq = last.seq + 1
+	}
+	if s.write != nil {
+		if err := s.write.Close(); err != nil {
+			return err
+		}
+		s.write = nil
+	}
+	f, err := os.OpenFile(s.path(seq), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	s.write = f
+	s.segments = append(s.segments, &segment{seq: seq})
+	return nil
+}
+
+// removeOldest deletes the oldest segment and resets the read position.
+func (s *Spool) removeOldest() error {
+	seg := s.segments[0]
+	if s.read != nil {
+		s.read.Close()
+		s.read = nil
+	}
+	if len(s.segments) == 1 && s.write != nil {
+		s.write.Close()
+		s.write = nil
+	}
+	s.segments = s.segments[1:]
+	s.offset = 0
+	s.peekedSize, s.peekedEntries = 0, 0
+	if err := os.Remove(s.path(seg.seq)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return s.saveCursor()
+}
+
+// removeEmpty deletes fully read segments that are not being written.
+func (s *Spool) removeEmpty() {
+	for len(s.segments) > 1 && s.offset >= s.segments[0].size {
+		_ = s.removeOldest()
+	}
+}
+
+// saveCursor persists the read position.
+func (s *Spool) saveCursor() error {
+	path := filepath.Join(s.cfg.Dir, cursorFile)
+	if len(s.segments) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	tmp := path + ".tmp"
+	data := fmt.Sprintf("%d %d\n", s.segments[0].seq, s.offset)
+	if err := os.WriteFile(tmp, []byte(data), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readRecord reads one record from r and verifies its checksum. It returns
+// the size of the record and its entry count; the payload is stored in
+// *payload unless payload is nil.
+func readRecord(r io.Reader, payload *[]byte) (int64, int, error) {
+	var header [recordHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return 0, 0, err
+	}
+	length := binary.LittleEndian.Uint32(header[0:])
+	count := int(binary.LittleEndian.Uint32(header[4:]))
+	sum := binary.LittleEndian.Uint32(header[8:])
+	if length > maxRecordSize {
+		return 0, 0, errors.New("spool: invalid record length")
+	}
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, 0, err
+	}
+	if crc32.Checksum(data, crcTable) != sum {
+		return 0, 0, errors.New("spool: checksum mismatch")
+	}
+	if payload != nil {
+		*payload = data
+	}
+	return int64(recordHeaderSize + len(data)), count, nil
+}
+
+// spooledEntry is the JSON form of an entry in the spool.
+type spooledEntry struct {
+	Time    int64          `json:"t"`
+	Level   core.Level     `json:"l"`
+	Message string         `json:"m"`
+	Caller  string         `json:"c,omitempty"`
+	Fields  []spooledField `json:"f,omitempty"`
+}
+
+type spooledField struct {
+	Key   string `json:"k"`
+	Value any    `json:"v"`
+}
+
+func encodeBatch(batch []*core.Entry) ([]byte, error) {
+	entries := make([]spooledEntry, len(batch))
+	for i, e := range batch {
+		se := spooledEntry{Time: e.Time.UnixNano(), Level: e.Level, Message: e.Message, Caller: e.Caller}
+		if len(e.Fields) > 0 {
+			se.Fields = make([]spooledField, len(e.Fields))
+			for j, f := range e.Fields {
+				se.Fields[j] = spooledField{Key: f.Key, Value: JSONValue(f.Value)}
+			}
+		}
+		entries[i] = se
+	}
+	return json.Marshal(entries)
+}
+
+func decodeBatch(data []byte) ([]*core.Entry, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var entries []spooledEntry
+	if err := dec.Decode(&entries); err != nil {
+		return nil, err
+	}
+	batch := make([]*core.Entry, len(entries))
+	for i, se := range entries {
+		e := &core.Entry{Time: time.Unix(0, se.Time), Level: se.Level, Message: se.Message, Caller: se.Caller}
+		if len(se.Fields) > 0 {
+			e.Fields = make([]core.Field, len(se.Fields))
+			for j, f := range se.Fields {
+				e.Fields[j] = core.Field{Key: f.Key, Value: numbers(f.Value)}
+			}
+		}
+		batch[i] = e
+	}
+	return batch, nil
+}
+
+// numbers replaces the json.Numbers of a decoded value with int64 or float64.
+func numbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i := range v {
+			v[i] = numbers(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = numbers(v[k])
+		}
+	}
+	return v
+}
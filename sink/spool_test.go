@@ -0,0 +1,325 @@
+package sink_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+)
+
+func spoolBatch(msgs ...string) []*core.Entry {
+	batch := make([]*core.Entry, len(msgs))
+	for i, msg := range msgs {
+		batch[i] = &core.Entry{
+			Time:    time.Unix(1700000000, int64(i)),
+			Level:   core.WarnLevel,
+			Message: msg,
+			Fields:  []core.Field{{Key: "n", Value: i}, {Key: "ratio", Value: 0.5}},
+		}
+	}
+	return batch
+}
+
+// pop reads and removes the oldest batch of s.
+func pop(t *testing.T, s *sink.Spool) []*core.Entry {
+	t.Helper()
+	batch, err := s.Peek()
+	if err != nil {
+		t.Fatalf("failed to peek: %v", err)
+	}
+	if err := s.Pop(); err != nil {
+		t.Fatalf("failed to pop: %v", err)
+	}
+	return batch
+}
+
+// TestSpoolOrderAndRestart tests that batches are read in order and that the read position survives a reopen
+func TestSpoolOrderAndRestart(t *testing.T) {
+	cfg := sink.SpoolConfig{Dir: t.TempDir(), SegmentSize: 200}
+	s, err := sink.OpenSpool(cfg)
+	if err != nil {
+		t.Fatalf("failed to open spool: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err := s.Append(spoolBatch(fmt.Sprintf("batch %d", i), "second")); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+	segments, _ := filepath.Glob(filepath.Join(cfg.Dir, "*.seg"))
+	if len(segments) < 2 {
+		t.Errorf("expected several segments, got %d", len(segments))
+	}
+
+	first := pop(t, s)
+	if first[0].Message != "batch 0" || first[0].Level != core.WarnLevel || !first[0].Time.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("unexpected first entry: %+v", first[0])
+	}
+	if v := first[1].Fields[0].Value; v != int64(1) {
+		t.Errorf("expected int64 field, got %T %v", v, v)
+	}
+	if v := first[1].Fields[1].Value; v != 0.5 {
+		t.Errorf("expected float field, got %T %v", v, v)
+	}
+	pop(t, s)
+	s.Close()
+
+	s, err = sink.OpenSpool(cfg)
+	if err != nil {
+		t.Fatalf("failed to reopen spool: %v", err)
+	}
+	defer s.Close()
+	if s.Len() != 6 {
+		t.Errorf("expected 6 pending entries after reopen, got %d", s.Len())
+	}
+	for i := 2; i < 5; i++ {
+		if batch := pop(t, s); batch[0].Message != fmt.Sprintf("batch %d", i) {
+			t.Errorf("expected batch %d, got %q", i, batch[0].Message)
+		}
+	}
+	if batch, err := s.Peek(); batch != nil || err != nil || s.Len() != 0 {
+		t.Errorf("expected empty spool, got %v %v %d", batch, err, s.Len())
+	}
+}
+
+// TestSpoolMaxSize tests that the oldest segments are discarded when the spool is full
+func TestSpoolMaxSize(t *testing.T) {
+	s, err := sink.OpenSpool(sink.SpoolConfig{Dir: t.TempDir(), MaxSize: 1000, SegmentSize: 250})
+	if err != nil {
+		t.Fatalf("failed to open spool: %v", err)
+	}
+	defer s.Close()
+
+	dropped := 0
+	for i := 0; i < 20; i++ {
+		n, err := s.Append(spoolBatch(fmt.Sprintf("batch %02d", i)))
+		if err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+		dropped += n
+	}
+	if dropped == 0 || s.Size() > 1000 || s.Len()+dropped != 20 {
+		t.Errorf("unexpected spool state: dropped %d, size %d, len %d", dropped, s.Size(), s.Len())
+	}
+	if batch := pop(t, s); batch[0].Message == "batch 00" {
+		t.Error("expected the oldest batches to be discarded")
+	}
+}
+
+// TestSpoolTornWrite tests that a partial record left by a crash is discarded on open
+func TestSpoolTornWrite(t *testing.T) {
+	cfg := sink.SpoolConfig{Dir: t.TempDir()}
+	s, err := sink.OpenSpool(cfg)
+	if err != nil {
+		t.Fatalf("failed to open spool: %v", err)
+	}
+	s.Append(spoolBatch("intact"))
+	s.Close()
+
+	segments, _ := filepath.Glob(filepath.Join(cfg.Dir, "*.seg"))
+	f, err := os.OpenFile(segments[0], os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatalf("failed to open segment: %v", err)
+	}
+	f.Write([]byte{200, 0, 0, 0, 1, 0, 0, 0, 1, 2, 3, 4, '['})
+	f.Close()
+
+	s, err = sink.OpenSpool(cfg)
+	if err != nil {
+		t.Fatalf("failed to reopen spool: %v", err)
+	}
+	defer s.Close()
+	if s.Len() != 1 {
+		t.Errorf("expected the torn record to be ignored, got %d entries", s.Len())
+	}
+	s.Append(spoolBatch("after restart"))
+	if batch := pop(t, s); batch[0].Message != "intact" {
+		t.Errorf("unexpected first batch: %q", batch[0].Message)
+	}
+	if batch := pop(t, s); batch[0].Message != "after restart" {
+		t.Errorf("unexpected second batch: %q", batch[0].Message)
+	}
+}
+
+// TestBatcherSpool tests that failed batches are spooled and replayed in order, including after a restart
+func TestBatcherSpool(t *testing.T) {
+	var down atomic.Bool
+	var mu sync.Mutex
+	var delivered []string
+	send := func(ctx context.Context, batch []*core.Entry) error {
+		if down.Load() {
+			return errors.New("connection refused")
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		for _, e := range batch {
+			delivered = append(delivered, e.Message)
+		}
+		return nil
+	}
+	cfg := sink.BatchConfig{
+		BatchSize:     2,
+		FlushInterval: time.Hour,
+		Spool:         sink.SpoolConfig{Enabled: true, Dir: t.TempDir()},
+		ErrorHandler:  func(err error) {},
+	}
+
+	down.Store(true)
+	b, err := sink.NewBatcher(cfg, send)
+	if err != nil {
+		t.Fatalf("failed to create batcher: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		b.Add(&core.Entry{Message: fmt.Sprintf("m%d", i)})
+	}
+	b.Flush()
+	b.Add(&core.Entry{Message: "m3"})
+	b.Close()
+	if s := b.Stats(); s.Spooled != 4 || s.Sent != 0 || s.Failed != 0 {
+		t.Errorf("unexpected stats while down: %+v", s)
+	}
+
+	// The endpoint recovers after a restart.
+	down.Store(false)
+	b, err = sink.NewBatcher(cfg, send)
+	if err != nil {
+		t.Fatalf("failed to reopen batcher: %v", err)
+	}
+	defer b.Close()
+	b.Add(&core.Entry{Message: "m4"})
+	b.Flush()
+	b.Flush()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(delivered) != "[m0 m1 m2 m3 m4]" {
+		t.Errorf("expected in-order delivery, got %v", delivered)
+	}
+	if s := b.Stats(); s.Sent != 5 {
+		t.Errorf("unexpected stats after recovery: %+v", s)
+	}
+}
+
+// TestSpoolNonFinite tests that batches with NaN and infinite floats are spooled with the floats as strings
+func TestSpoolNonFinite(t *testing.T) {
+	s, err := sink.OpenSpool(sink.SpoolConfig{Dir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("failed to open spool: %v", err)
+	}
+	defer s.Close()
+	batch := spoolBatch("ratio")
+	batch[0].Fields = append(batch[0].Fields, core.Field{Key: "nan", Value: math.NaN()}, core.Field{Key: "inf", Value: math.Inf(1)})
+	if _, err := s.Append(batch); err != nil {
+		t.Fatalf("failed to append: %v", err)
+	}
+	fields := pop(t, s)[0].Fields
+	if fields[2].Value != "NaN" || fields[3].Value != "+Inf" {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+}
+
+// TestBatcherPartial tests that only the entries left by a partial delivery are failed or spooled and replayed
+func TestBatcherPartial(t *testing.T) {
+	var down atomic.Bool
+	var delivered []string
+	send := func(ctx context.Context, batch []*core.Entry) error {
+		var retry []*core.Entry
+		failed := 0
+		for _, e := range batch {
+			switch {
+			case e.Message == "bad":
+				failed++
+			case e.Message == "slow" && down.Load():
+				retry = append(retry, e)
+			default:
+				delivered = append(delivered, e.Message)
+			}
+		}
+		if failed == 0 && len(retry) == 0 {
+			return nil
+		}
+		return &sink.PartialError{Failed: failed, Retry: retry, Err: errors.New("rejected")}
+	}
+	b, err := sink.NewBatcher(sink.BatchConfig{
+		BatchSize:     3,
+		FlushInterval: time.Hour,
+		Spool:         sink.SpoolConfig{Enabled: true, Dir: t.TempDir()},
+		ErrorHandler:  func(err error) {},
+	}, send)
+	if err != nil {
+		t.Fatalf("failed to create batcher: %v", err)
+	}
+	defer b.Close()
+
+	down.Store(true)
+	for _, msg := range []string{"ok", "bad", "slow"} {
+		b.Add(&core.Entry{Message: msg})
+	}
+	b.Flush()
+	if s := b.Stats(); s.Sent != 1 || s.Failed != 1 || s.Spooled != 1 {
+		t.Errorf("unexpected stats after a partial delivery: %+v", s)
+	}
+
+	down.Store(false)
+	b.Flush()
+	if fmt.Sprint(delivered) != "[ok slow]" {
+		t.Errorf("expected each entry to be delivered once, got %v", delivered)
+	}
+	if s := b.Stats(); s.Sent != 2 || s.Failed != 1 {
+		t.Errorf("unexpected stats after replay: %+v", s)
+	}
+}
+
+// TestBatcherSpoolHandover tests that a batcher whose spool directory is still in use spools once the previous batcher is closed
+func TestBatcherSpoolHandover(t *testing.T) {
+	dir := t.TempDir()
+	s, err := sink.OpenSpool(sink.SpoolConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("failed to open spool: %v", err)
+	}
+	if _, err := sink.OpenSpool(sink.SpoolConfig{Dir: dir}); err == nil {
+		t.Error("expected error when opening a spool in use")
+	}
+	s.Close()
+	if s, err = sink.OpenSpool(sink.SpoolConfig{Dir: dir}); err != nil {
+		t.Fatalf("failed to reopen a closed spool: %v", err)
+	}
+	s.Close()
+
+	cfg := sink.BatchConfig{
+		FlushInterval: time.Hour,
+		Spool:         sink.SpoolConfig{Enabled: true, Dir: t.TempDir()},
+		ErrorHandler:  func(err error) {},
+	}
+	down := func(ctx context.Context, batch []*core.Entry) error {
+		return errors.New("connection refused")
+	}
+	old, err := sink.NewBatcher(cfg, down)
+	if err != nil {
+		t.Fatalf("failed to create batcher: %v", err)
+	}
+	b, err := sink.NewBatcher(cfg, down)
+	if err != nil {
+		t.Fatalf("failed to create batcher on a spool in use: %v", err)
+	}
+	defer b.Close()
+	old.Add(&core.Entry{Message: "old"})
+	b.Add(&core.Entry{Message: "new"})
+	old.Close()
+	b.Flush()
+
+	if s := old.Stats(); s.Spooled != 1 {
+		t.Errorf("unexpected stats of the previous batcher: %+v", s)
+	}
+	if s := b.Stats(); s.Spooled != 1 || s.Failed != 0 {
+		t.Errorf("unexpected stats of the new batcher: %+v", s)
+	}
+}
@@ -0,0 +1,494 @@
+// Package syslog provides a sink that sends logx entries to a syslog server
+// in the RFC 5424 or RFC 3164 format over UDP, TCP, TLS or a unix socket.
+//
+// Example usage:
+//
+//	config := &logx.LoggerConfig{
+//	    // ...
+//	    Syslog: &syslog.Config{
+//	        Network:          "tcp",
+//	        Address:          "localhost:514",
+//	        Facility:         "local0",
+//	        StructuredDataID: "logx@32473",
+//	    },
+//	}
+package syslog
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+)
+
+const (
+	// FormatRFC5424 is the structured syslog protocol.
+	FormatRFC5424 = "rfc5424"
+	// FormatRFC3164 is the legacy BSD syslog format.
+	FormatRFC3164 = "rfc3164"
+
+	// FramingOctetCounting prefixes each message with its length (RFC 6587).
+	FramingOctetCounting = "octet-counting"
+	// FramingNonTransparent terminates each message with a newline.
+	FramingNonTransparent = "non-transparent"
+
+	// NetworkTLS is TCP with TLS (RFC 5425).
+	NetworkTLS = "tcp+tls"
+)
+
+// Severity values defined by RFC 5424.
+const (
+	SeverityEmergency = 0
+	SeverityAlert     = 1
+	SeverityCritical  = 2
+	SeverityError     = 3
+	SeverityWarning   = 4
+	SeverityNotice    = 5
+	SeverityInfo      = 6
+	SeverityDebug     = 7
+)
+
+// facilities maps facility names to their RFC 5424 codes.
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// Config holds the configuration of the syslog sink.
+type Config struct {
+	// Network is udp (default), tcp, tcp+tls, unix or unixgram.
+	Network string `mapstructure:"network" yaml:"network"`
+
+	// Address is the host:port of the server or the path of the socket.
+	Address string `mapstructure:"address" yaml:"address"`
+
+	// Format is rfc5424 (default) or rfc3164.
+	Format string `mapstructure:"format" yaml:"format"`
+
+	// Facility is the facility name, e.g. user (default), daemon or local0.
+	Facility string `mapstructure:"facility" yaml:"facility"`
+
+	// AppName is the APP-NAME (TAG in RFC 3164). It defaults to the executable name.
+	AppName string `mapstructure:"app-name" yaml:"app-name"`
+
+	// Hostname is the HOSTNAME field. It defaults to os.Hostname.
+	Hostname string `mapstructure:"hostname" yaml:"hostname"`
+
+	// StructuredDataID is the SD-ID, e.g. logx@32473, under which the entry
+	// fields are sent as RFC 5424 structured data. If empty, or with the
+	// RFC 3164 format, the fields are appended to the message as key=value.
+	StructuredDataID string `mapstructure:"structured-data-id" yaml:"structured-data-id"`
+
+	// Framing is the framing of stream transports, octet-counting (default)
+	// or non-transparent.
+	Framing string `mapstructure:"framing" yaml:"framing"`
+
+	// CAFile is a PEM file with the CA certificates that verify a tcp+tls server.
+	CAFile string `mapstructure:"ca-file" yaml:"ca-file"`
+
+	// TLS overrides the TLS configuration of tcp+tls.
+	TLS *tls.Config `mapstructure:"-" yaml:"-"`
+
+	// Timeout bounds dialing and each write (default 5s).
+	Timeout time.Duration `mapstructure:"timeout" yaml:"timeout"`
+
+	// MinBackoff is the delay before the first reconnection attempt (default 100ms).
+	MinBackoff time.Duration `mapstructure:"min-backoff" yaml:"min-backoff"`
+
+	// MaxBackoff caps the exponential delay between reconnection attempts (default 30s).
+	MaxBackoff time.Duration `mapstructure:"max-backoff" yaml:"max-backoff"`
+
+	// Batch configures the bounded queue and batching.
+	Batch sink.BatchConfig `mapstructure:"batch" yaml:"batch"`
+}
+
+// Writer is a core.Sink that sends entries to a syslog server. Entries are
+// queued and sent in the background; the Writer connects lazily and
+// reconnects with backoff after a failure.
+type Writer struct {
+	cfg      Config
+	facility int
+	hostname string
+	appName  string
+	pid      string
+
+	conn    sink.Redialer // owned by the batcher goroutine
+	batcher *sink.Batcher
+}
+
+// New creates a Writer from c and starts its background delivery. The
+// connection is established when the first batch is sent.
+func New(c Config) (*Writer, error) {
+	if c.Network == "" {
+		c.Network = "udp"
+	}
+	switch c.Network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6", NetworkTLS, "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("syslog: unsupported network: %s", c.Network)
+	}
+	if c.Address == "" {
+		return nil, errors.New("syslog: address is required")
+	}
+	if c.Format == "" {
+		c.Format = FormatRFC5424
+	}
+	if c.Format != FormatRFC5424 && c.Format != FormatRFC3164 {
+		return nil, fmt.Errorf("syslog: unsupported format: %s", c.Format)
+	}
+	if c.Framing == "" {
+		c.Framing = FramingOctetCounting
+	}
+	if c.Framing != FramingOctetCounting && c.Framing != FramingNonTransparent {
+		return nil, fmt.Errorf("syslog: unsupported framing: %s", c.Framing)
+	}
+	if c.Facility == "" {
+		c.Facility = "user"
+	}
+	facility, ok := facilities[strings.ToLower(c.Facility)]
+	if !ok {
+		return nil, fmt.Errorf("syslog: unknown facility: %s", c.Facility)
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+
+	w := &Writer{
+		cfg:      c,
+		facility: facility,
+		hostname: c.Hostname,
+		appName:  c.AppName,
+		pid:      strconv.Itoa(os.Getpid()),
+	}
+	if w.hostname == "" {
+		w.hostname, _ = os.Hostname()
+	}
+	if w.appName == "" {
+		w.appName = filepath.Base(os.Args[0])
+	}
+	w.conn = sink.Redialer{
+		Network:    c.Network,
+		Address:    c.Address,
+		Timeout:    c.Timeout,
+		MinBackoff: c.MinBackoff,
+		MaxBackoff: c.MaxBackoff,
+	}
+	if c.Network == NetworkTLS {
+		cfg, err := tlsConfig(c)
+		if err != nil {
+			return nil, err
+		}
+		w.conn.Network = "tcp"
+		w.conn.TLS = cfg
+	}
+	batcher, err := sink.NewBatcher(c.Batch, w.send)
+	if err != nil {
+		return nil, err
+	}
+	w.batcher = batcher
+	return w, nil
+}
+
+// tlsConfig builds the TLS configuration of c.
+func tlsConfig(c Config) (*tls.Config, error) {
+	if c.TLS != nil {
+		return c.TLS.Clone(), nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if host, _, err := net.SplitHostPort(c.Address); err == nil {
+		cfg.ServerName = host
+	}
+	if c.CAFile != "" {
+		pem, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("syslog: no certificates in %s", c.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// Write implements the core.Sink interface. The entry is queued and sent in
+// the background; it is dropped if the queue is full.
+func (w *Writer) Write(e *core.Entry) error {
+	w.batcher.Add(e)
+	return nil
+}
+
+// Sync implements the core.Sink interface.
+func (w *Writer) Sync() error {
+	return w.batcher.Flush()
+}
+
+// Close implements the core.Sink interface.
+func (w *Writer) Close() error {
+	err := w.batcher.Close()
+	return errors.Join(err, w.conn.Close())
+}
+
+// Stats returns the delivery counters of the writer.
+func (w *Writer) Stats() sink.Stats {
+	return w.batcher.Stats()
+}
+
+// LevelWriter returns an io.Writer that queues each write as one message at
+// the severity of level. It also implements zapcore.WriteSyncer, so it can be
+// used as the output of a zap core or of a slog handler; trailing newlines
+// of the encoded entries are removed.
+func (w *Writer) LevelWriter(level core.Level) *LevelWriter {
+	return &LevelWriter{w: w, level: level}
+}
+
+// LevelWriter sends written bytes as syslog messages of a fixed severity.
+type LevelWriter struct {
+	w     *Writer
+	level core.Level
+}
+
+// Write implements the io.Writer interface.
+func (lw *LevelWriter) Write(p []byte) (int, error) {
+	msg := strings.TrimRight(string(p), "\r\n")
+	lw.w.batcher.Add(&core.Entry{Time: time.Now(), Level: lw.level, Message: msg})
+	return len(p), nil
+}
+
+// Sync implements the zapcore.WriteSyncer interface.
+func (lw *LevelWriter) Sync() error {
+	return lw.w.Sync()
+}
+
+// Severity maps a logx level to a syslog severity:
+//
+//	logx level                                 syslog severity
+//	below info (trace, debug)                  7 debug
+//	info                                       6 informational
+//	above info, below warn (notice)            5 notice
+//	warn                                       4 warning
+//	error                                      3 error
+//	above error, below fatal (critical, panic) 2 critical
+//	fatal                                      1 alert
+func Severity(level core.Level) int {
+	switch {
+	case level < core.InfoLevel:
+		return SeverityDebug
+	case level == core.InfoLevel:
+		return SeverityInfo
+	case level < core.WarnLevel:
+		return SeverityNotice
+	case level < core.ErrorLevel:
+		return SeverityWarning
+	case level == core.ErrorLevel:
+		return SeverityError
+	case level < core.FatalLevel:
+		return SeverityCritical
+	default:
+		return SeverityAlert
+	}
+}
+
+// send writes a batch as one message per entry, reconnecting if necessary.
+// If a write fails after others succeeded, only the entries left are
+// reported, so that the others are not sent again.
+func (w *Writer) send(ctx context.Context, batch []*core.Entry) error {
+	for i, e := range batch {
+		if err := w.write(w.frame(e)); err != nil {
+			if i == 0 {
+				return err
+			}
+			return &sink.PartialError{Retry: batch[i:], Err: err}
+		}
+	}
+	return nil
+}
+
+// frame returns the formatted and framed message of e.
+func (w *Writer) frame(e *core.Entry) []byte {
+	severity := Severity(e.Level)
+	var frame []byte
+	if w.cfg.Format == FormatRFC3164 {
+		frame = w.format3164(severity, e.Time, e.Message, e.Fields)
+	} else {
+		frame = w.format5424(severity, e.Time, e.Message, e.Fields)
+	}
+	if w.stream() {
+		if w.cfg.Framing == FramingOctetCounting {
+			frame = append([]byte(strconv.Itoa(len(frame))+" "), frame...)
+		} else {
+			frame = append(frame, '\n')
+		}
+	}
+	return frame
+}
+
+// write writes one message.
+func (w *Writer) write(frame []byte) error {
+	conn, err := w.conn.Conn()
+	if err != nil {
+		return fmt.Errorf("syslog: %w", err)
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(w.cfg.Timeout))
+	if _, err := conn.Write(frame); err != nil {
+		w.conn.Fail()
+		return fmt.Errorf("syslog: write: %w", err)
+	}
+	return nil
+}
+
+// stream reports whether the transport is a byte stream that needs framing.
+func (w *Writer) stream() bool {
+	switch w.cfg.Network {
+	case "tcp", "tcp4", "tcp6", NetworkTLS, "unix":
+		return true
+	default:
+		return false
+	}
+}
+
+// format5424 formats an RFC 5424 message:
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (w *Writer) format5424(severity int, t time.Time, msg string, fields []core.Field) []byte {
+	b := make([]byte, 0, 128+len(msg))
+	b = append(b, '<')
+	b = strconv.AppendInt(b, int64(w.facility*8+severity), 10)
+	b = append(b, ">1 "...)
+	b = t.AppendFormat(b, "2006-01-02T15:04:05.000000Z07:00")
+	b = append(b, ' ')
+	b = append(b, headerField(w.hostname, 255)...)
+	b = append(b, ' ')
+	b = append(b, headerField(w.appName, 48)...)
+	b = append(b, ' ')
+	b = append(b, w.pid...)
+	b = append(b, " - "...)
+	if w.cfg.StructuredDataID != "" && len(fields) > 0 {
+		b = appendStructuredData(b, w.cfg.StructuredDataID, fields)
+	} else {
+		b = append(b, '-')
+		msg = appendKeyValues(msg, fields)
+	}
+	if msg != "" {
+		b = append(b, ' ')
+		b = append(b, msg...)
+	}
+	return b
+}
+
+// format3164 formats an RFC 3164 message: <PRI>TIMESTAMP HOSTNAME TAG[PID]: MSG
+func (w *Writer) format3164(severity int, t time.Time, msg string, fields []core.Field) []byte {
+	msg = appendKeyValues(msg, fields)
+	b := make([]byte, 0, 64+len(msg))
+	b = append(b, '<')
+	b = strconv.AppendInt(b, int64(w.facility*8+severity), 10)
+	b = append(b, '>')
+	b = t.AppendFormat(b, time.Stamp)
+	b = append(b, ' ')
+	b = append(b, headerField(w.hostname, 255)...)
+	b = append(b, ' ')
+	b = append(b, headerField(w.appName, 32)...)
+	b = append(b, '[')
+	b = append(b, w.pid...)
+	b = append(b, "]: "...)
+	return append(b, msg...)
+}
+
+// headerField returns s restricted to printable US-ASCII without spaces, as
+// required for header fields, or "-" if s is empty.
+func headerField(s string, max int) string {
+	if s == "" {
+		return "-"
+	}
+	out := []byte(s)
+	for i, c := range out {
+		if c < 33 || c > 126 {
+			out[i] = '_'
+		}
+	}
+	if len(out) > max {
+		out = out[:max]
+	}
+	return string(out)
+}
+
+// appendStructuredData appends one SD-ELEMENT holding the fields as params.
+func appendStructuredData(b []byte, id string, fields []core.Field) []byte {
+	b = append(b, '[')
+	b = append(b, sdName(id)...)
+	for _, f := range fields {
+		b = append(b, ' ')
+		b = append(b, sdName(f.Key)...)
+		b = append(b, `="`...)
+		for _, r := range formatValue(f.Value) {
+			if r == '"' || r == '\\' || r == ']' {
+				b = append(b, '\\')
+			}
+			b = append(b, string(r)...)
+		}
+		b = append(b, '"')
+	}
+	return append(b, ']')
+}
+
+// sdName restricts s to the characters allowed in SD-ID and PARAM-NAME.
+func sdName(s string) string {
+	out := make([]byte, 0, len(s))
+	for i := 0; i < len(s) && len(out) < 32; i++ {
+		c := s[i]
+		if c < 33 || c > 126 || c == '=' || c == ']' || c == '"' {
+			c = '_'
+		}
+		out = append(out, c)
+	}
+	if len(out) == 0 {
+		return "_"
+	}
+	return string(out)
+}
+
+// appendKeyValues appends the fields to msg as key=value pairs.
+func appendKeyValues(msg string, fields []core.Field) string {
+	if len(fields) == 0 {
+		return msg
+	}
+	var sb strings.Builder
+	sb.WriteString(msg)
+	for _, f := range fields {
+		sb.WriteByte(' ')
+		sb.WriteString(f.Key)
+		sb.WriteByte('=')
+		v := formatValue(f.Value)
+		if strings.ContainsAny(v, " \"=") {
+			v = strconv.Quote(v)
+		}
+		sb.WriteString(v)
+	}
+	return sb.String()
+}
+
+// formatValue renders a field value as text.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case error:
+		return v.Error()
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,370 @@
+package syslog_test
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink/syslog"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func testEntry(level core.Level) *core.Entry {
+	return &core.Entry{
+		Time:    time.Date(2024, 3, 1, 12, 30, 45, 0, time.UTC),
+		Level:   level,
+		Message: "disk almost full",
+		Fields: []core.Field{
+			{Key: "free_mb", Value: 12},
+			{Key: "path", Value: `C:\data "main"`},
+		},
+	}
+}
+
+// readPacket reads one datagram from conn.
+func readPacket(t *testing.T, conn net.PacketConn) string {
+	t.Helper()
+	buf := make([]byte, 64*1024)
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed to read packet: %v", err)
+	}
+	return string(buf[:n])
+}
+
+// readFrame reads one octet-counted frame from r.
+func readFrame(r *bufio.Reader) (string, error) {
+	length, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(length, " "))
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// TestRFC5424UDP tests the RFC 5424 format with structured data over UDP
+func TestRFC5424UDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+
+	w, err := syslog.New(syslog.Config{
+		Address:          conn.LocalAddr().String(),
+		Facility:         "local0",
+		AppName:          "checkout",
+		Hostname:         "web 1",
+		StructuredDataID: "logx@32473",
+	})
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer w.Close()
+
+	if err := w.Write(testEntry(core.WarnLevel)); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	w.Sync()
+	msg := readPacket(t, conn)
+	// local0 (16) * 8 + warning (4) = 132
+	prefix := "<132>1 2024-03-01T12:30:45.000000Z web_1 checkout "
+	if !strings.HasPrefix(msg, prefix) {
+		t.Errorf("expected prefix %q, got %q", prefix, msg)
+	}
+	want := ` - [logx@32473 free_mb="12" path="C:\\data \"main\""] disk almost full`
+	if !strings.HasSuffix(msg, want) {
+		t.Errorf("expected suffix %q, got %q", want, msg)
+	}
+}
+
+// TestRFC3164Unixgram tests the RFC 3164 format over a unix datagram socket
+func TestRFC3164Unixgram(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Skipf("unixgram not supported: %v", err)
+	}
+	defer conn.Close()
+
+	w, err := syslog.New(syslog.Config{
+		Network:  "unixgram",
+		Address:  path,
+		Format:   syslog.FormatRFC3164,
+		Facility: "daemon",
+		AppName:  "worker",
+		Hostname: "host1",
+	})
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer w.Close()
+
+	if err := w.Write(testEntry(core.ErrorLevel)); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	w.Sync()
+	msg := readPacket(t, conn)
+	// daemon (3) * 8 + error (3) = 27
+	if !strings.HasPrefix(msg, "<27>Mar  1 12:30:45 host1 worker[") {
+		t.Errorf("unexpected header: %q", msg)
+	}
+	if !strings.HasSuffix(msg, `]: disk almost full free_mb=12 path="C:\\data \"main\""`) {
+		t.Errorf("unexpected message: %q", msg)
+	}
+}
+
+// TestTCPReconnect tests octet-counting framing and reconnection after the server drops the connection
+func TestTCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	frames := make(chan string, 16)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			if msg, err := readFrame(bufio.NewReader(conn)); err == nil {
+				frames <- msg
+			}
+			conn.Close()
+		}
+	}()
+
+	w, err := syslog.New(syslog.Config{
+		Network:    "tcp",
+		Address:    ln.Addr().String(),
+		MinBackoff: time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer w.Close()
+
+	if err := w.Write(testEntry(core.InfoLevel)); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	w.Sync()
+	if msg := <-frames; !strings.HasPrefix(msg, "<14>1 ") {
+		t.Errorf("unexpected first frame: %q", msg)
+	}
+
+	// The server closed the connection: writes fail until the writer reconnects.
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		_ = w.Write(testEntry(core.ErrorLevel))
+		w.Sync()
+		select {
+		case msg := <-frames:
+			if !strings.HasPrefix(msg, "<11>1 ") {
+				t.Errorf("unexpected frame after reconnect: %q", msg)
+			}
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("writer did not reconnect")
+		}
+	}
+}
+
+// TestLazyDial tests that the writer connects when the first batch is sent rather than in New
+func TestLazyDial(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	w, err := syslog.New(syslog.Config{Network: "tcp", Address: ln.Addr().String()})
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer w.Close()
+	select {
+	case conn := <-accepted:
+		conn.Close()
+		t.Fatal("the writer connected before sending")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	w.Write(testEntry(core.InfoLevel))
+	w.Sync()
+	select {
+	case conn := <-accepted:
+		defer conn.Close()
+		if msg, err := readFrame(bufio.NewReader(conn)); err != nil || !strings.HasPrefix(msg, "<14>1 ") {
+			t.Errorf("unexpected frame: %q %v", msg, err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no connection")
+	}
+	if s := w.Stats(); s.Sent != 1 {
+		t.Errorf("unexpected stats: %+v", s)
+	}
+}
+
+// TestTLS tests sending messages over TLS with non-transparent framing
+func TestTLS(t *testing.T) {
+	cert, pool := selfSignedCert(t)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	lines := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('\n')
+		lines <- line
+	}()
+
+	w, err := syslog.New(syslog.Config{
+		Network: syslog.NetworkTLS,
+		Address: ln.Addr().String(),
+		Framing: syslog.FramingNonTransparent,
+		TLS:     &tls.Config{RootCAs: pool, ServerName: "127.0.0.1"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer w.Close()
+
+	if err := w.Write(testEntry(core.DebugLevel)); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+	w.Sync()
+	select {
+	case line := <-lines:
+		if !strings.HasPrefix(line, "<15>1 ") || !strings.HasSuffix(line, "disk almost full free_mb=12 path=\"C:\\\\data \\\"main\\\"\"\n") {
+			t.Errorf("unexpected line: %q", line)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no message received")
+	}
+}
+
+// TestLevelWriter tests the writer as the output of a zap core
+func TestLevelWriter(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer conn.Close()
+	w, err := syslog.New(syslog.Config{Address: conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatalf("failed to create writer: %v", err)
+	}
+	defer w.Close()
+
+	var ws zapcore.WriteSyncer = w.LevelWriter(core.ErrorLevel)
+	logger := zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), ws, zapcore.ErrorLevel))
+	logger.Error("boom", zap.Int("code", 7))
+	logger.Sync()
+
+	msg := readPacket(t, conn)
+	if !strings.HasPrefix(msg, "<11>1 ") || !strings.HasSuffix(msg, `"code":7}`) {
+		t.Errorf("unexpected message: %q", msg)
+	}
+}
+
+// TestSeverity tests the mapping of logx levels to syslog severities
+func TestSeverity(t *testing.T) {
+	testCases := map[core.Level]int{
+		core.DebugLevel:     syslog.SeverityDebug,
+		core.InfoLevel:      syslog.SeverityInfo,
+		core.InfoLevel + 2:  syslog.SeverityNotice,
+		core.WarnLevel:      syslog.SeverityWarning,
+		core.ErrorLevel:     syslog.SeverityError,
+		core.ErrorLevel + 2: syslog.SeverityCritical,
+		core.FatalLevel:     syslog.SeverityAlert,
+		core.PanicLevel:     syslog.SeverityCritical,
+	}
+	for level, want := range testCases {
+		if got := syslog.Severity(level); got != want {
+			t.Errorf("Severity(%d) = %d, want %d", level, got, want)
+		}
+	}
+}
+
+// TestNewValidation tests configuration validation
+func TestNewValidation(t *testing.T) {
+	testCases := []syslog.Config{
+		{},
+		{Address: "localhost:514", Network: "sctp"},
+		{Address: "localhost:514", Format: "json"},
+		{Address: "localhost:514", Facility: "local9"},
+		{Address: "localhost:514", Framing: "chunked"},
+	}
+	for _, c := range testCases {
+		if _, err := syslog.New(c); err == nil {
+			t.Errorf("expected error for %+v", c)
+		}
+	}
+}
+
+// selfSignedCert returns a certificate for 127.0.0.1 and a pool trusting it.
+func selfSignedCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "logx test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}, pool
+}
@@ -0,0 +1,99 @@
+package sink
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/go4x/logx/core"
+)
+
+// maxDepth bounds the recursion into nested field values.
+const maxDepth = 8
+
+// JSONValue converts a field value to a value that encoding/json renders
+// faithfully: errors, durations and fmt.Stringers become strings, times are
+// formatted as RFC 3339, NaN and infinite floats, which JSON cannot
+// represent, become "NaN", "+Inf" and "-Inf", and maps and slices are
+// converted recursively.
+func JSONValue(v any) any {
+	return jsonValue(v, 0)
+}
+
+func jsonValue(v any, depth int) any {
+	switch v := v.(type) {
+	case float64:
+		return jsonFloat(v)
+	case float32:
+		if f := float64(v); math.IsNaN(f) || math.IsInf(f, 0) {
+			return jsonFloat(f)
+		}
+		return v
+	case nil, string, bool, int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64, []byte:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case time.Duration:
+		return v.String()
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	if depth >= maxDepth {
+		return fmt.Sprintf("%+v", v)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		values := make([]any, rv.Len())
+		for i := range values {
+			values[i] = jsonValue(rv.Index(i).Interface(), depth+1)
+		}
+		return values
+	case reflect.Map:
+		m := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			m[fmt.Sprint(iter.Key().Interface())] = jsonValue(iter.Value().Interface(), depth+1)
+		}
+		return m
+	case reflect.Pointer:
+		if rv.IsNil() {
+			return nil
+		}
+		return jsonValue(rv.Elem().Interface(), depth+1)
+	case reflect.Struct:
+		return v
+	default:
+		return fmt.Sprintf("%+v", v)
+	}
+}
+
+// jsonFloat returns f, or its string form if it is NaN or infinite.
+func jsonFloat(f float64) any {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return f
+}
+
+// Document returns e as a map for JSON encoding, with the message under
+// messageKey, the level, the caller if known and the fields converted with
+// JSONValue. Fields named like these keys overwrite them.
+func Document(e *core.Entry, messageKey string) map[string]any {
+	doc := make(map[string]any, len(e.Fields)+3)
+	doc[messageKey] = e.Message
+	doc["level"] = e.Level.String()
+	if e.Caller != "" {
+		doc["caller"] = e.Caller
+	}
+	for _, f := range e.Fields {
+		doc[f.Key] = JSONValue(f.Value)
+	}
+	return doc
+}
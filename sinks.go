@@ -0,0 +1,88 @@
+package logx
+
+import (
+	"path/filepath"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sink"
+	"github.com/go4x/logx/sink/elasticsearch"
+	"github.com/go4x/logx/sink/fluent"
+	"github.com/go4x/logx/sink/gelf"
+	"github.com/go4x/logx/sink/loki"
+	"github.com/go4x/logx/sink/otlp"
+	"github.com/go4x/logx/sink/splunk"
+	"github.com/go4x/logx/sink/syslog"
+)
+
+// globalSinks are the sinks created by the last successful call to Init.
+var globalSinks []core.Sink
+
+// newSinks creates the sinks enabled in the configuration.
+func newSinks(c *LoggerConfig) ([]core.Sink, error) {
+	var sinks []core.Sink
+	var err error
+	add := func(s core.Sink, serr error) {
+		if err == nil && serr == nil {
+			sinks = append(sinks, s)
+		}
+		if err == nil {
+			err = serr
+		}
+	}
+	if c.OTLP != nil {
+		cfg := *c.OTLP
+		cfg.Batch.Spool = spoolConfig(c, cfg.Batch.Spool, "otlp")
+		add(otlp.New(cfg))
+	}
+	if c.Syslog != nil {
+		cfg := *c.Syslog
+		cfg.Batch.Spool = spoolConfig(c, cfg.Batch.Spool, "syslog")
+		add(syslog.New(cfg))
+	}
+	if c.Loki != nil {
+		cfg := *c.Loki
+		cfg.Batch.Spool = spoolConfig(c, cfg.Batch.Spool, "loki")
+		add(loki.New(cfg))
+	}
+	if c.Elasticsearch != nil {
+		cfg := *c.Elasticsearch
+		cfg.Batch.Spool = spoolConfig(c, cfg.Batch.Spool, "elasticsearch")
+		add(elasticsearch.New(cfg))
+	}
+	if c.Splunk != nil {
+		cfg := *c.Splunk
+		cfg.Batch.Spool = spoolConfig(c, cfg.Batch.Spool, "splunk")
+		add(splunk.New(cfg))
+	}
+	if c.GELF != nil {
+		cfg := *c.GELF
+		cfg.Batch.Spool = spoolConfig(c, cfg.Batch.Spool, "gelf")
+		add(gelf.New(cfg))
+	}
+	if c.Fluent != nil {
+		cfg := *c.Fluent
+		cfg.Batch.Spool = spoolConfig(c, cfg.Batch.Spool, "fluent")
+		add(fluent.New(cfg))
+	}
+	if err != nil {
+		closeSinks(sinks)
+		return nil, err
+	}
+	return sinks, nil
+}
+
+// spoolConfig places an enabled spool without a directory under the log
+// directory, in a subdirectory named after the sink.
+func spoolConfig(c *LoggerConfig, s sink.SpoolConfig, name string) sink.SpoolConfig {
+	if s.Enabled && s.Dir == "" {
+		s.Dir = filepath.Join(c.Dir, "spool", name)
+	}
+	return s
+}
+
+// closeSinks flushes and closes sinks.
+func closeSinks(sinks []core.Sink) {
+	for _, s := range sinks {
+		_ = s.Close()
+	}
+}
@@ -0,0 +1,375 @@
+package logx_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx"
+	"github.com/go4x/logx/sink"
+	"github.com/go4x/logx/sink/elasticsearch"
+	"github.com/go4x/logx/sink/fluent"
+	"github.com/go4x/logx/sink/gelf"
+	"github.com/go4x/logx/sink/loki"
+	"github.com/go4x/logx/sink/otlp"
+	"github.com/go4x/logx/sink/splunk"
+	"github.com/go4x/logx/sink/syslog"
+)
+
+// waitFor polls cond until it returns true or the deadline passes.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met before deadline")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestOTLPSink tests that both backends export entries to an OTLP collector
+func TestOTLPSink(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			var mu sync.Mutex
+			var received strings.Builder
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				received.Write(data)
+				mu.Unlock()
+			}))
+			defer srv.Close()
+
+			err := logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "info",
+				Dir:    t.TempDir(),
+				Format: "json",
+				OTLP: &otlp.Config{
+					Endpoint:    srv.URL,
+					Protocol:    otlp.ProtocolJSON,
+					ServiceName: "logx-test",
+					HTTP:        sink.HTTPConfig{Compression: "none"},
+					Batch:       sink.BatchConfig{FlushInterval: 10 * time.Millisecond},
+				},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			ctx := logx.NewContext(context.Background(), "order_id", "o-1")
+			logx.Log(ctx, logx.ErrorLevel, "payment failed", "attempt", 2)
+			logx.Debug("filtered by level")
+
+			waitFor(t, func() bool {
+				mu.Lock()
+				defer mu.Unlock()
+				return strings.Contains(received.String(), "payment failed")
+			})
+			mu.Lock()
+			content := received.String()
+			mu.Unlock()
+			for _, want := range []string{`"severityNumber":17`, `"key":"order_id"`, `"key":"attempt"`, `"stringValue":"logx-test"`} {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %s in export, got %s", want, content)
+				}
+			}
+			if strings.Contains(content, "filtered by level") {
+				t.Error("debug entry should not be exported")
+			}
+		})
+	}
+}
+
+// TestSyslogSink tests that both backends send entries to a syslog server
+func TestSyslogSink(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatalf("failed to listen: %v", err)
+			}
+			defer conn.Close()
+
+			err = logx.Init(&logx.LoggerConfig{
+				Type:   typ,
+				Level:  "info",
+				Dir:    t.TempDir(),
+				Format: "json",
+				Syslog: &syslog.Config{
+					Address:          conn.LocalAddr().String(),
+					Facility:         "local3",
+					AppName:          "logx-test",
+					StructuredDataID: "logx@32473",
+				},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			logx.Debug("filtered by level")
+			logx.Log(context.Background(), logx.WarnLevel, "queue is backing up", "depth", 900)
+
+			buf := make([]byte, 4096)
+			_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				t.Fatalf("failed to read message: %v", err)
+			}
+			msg := string(buf[:n])
+			// local3 (19) * 8 + warning (4) = 156
+			if !strings.HasPrefix(msg, "<156>1 ") || !strings.Contains(msg, " logx-test ") {
+				t.Errorf("unexpected header: %q", msg)
+			}
+			if !strings.HasSuffix(msg, `[logx@32473 depth="900"] queue is backing up`) {
+				t.Errorf("unexpected message: %q", msg)
+			}
+		})
+	}
+}
+
+// TestHTTPSinks tests that the Loki, Elasticsearch and Splunk sinks are enabled from the configuration
+func TestHTTPSinks(t *testing.T) {
+	var mu sync.Mutex
+	received := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received[r.URL.Path] += string(data)
+		mu.Unlock()
+		if r.URL.Path == "/_bulk" {
+			w.Write([]byte(`{"errors":false,"items":[]}`))
+		}
+	}))
+	defer srv.Close()
+
+	httpCfg := sink.HTTPConfig{Compression: "none"}
+	batch := sink.BatchConfig{FlushInterval: 10 * time.Millisecond}
+	err := logx.Init(&logx.LoggerConfig{
+		Type:          logx.LoggerTypeSlog,
+		Level:         "info",
+		Dir:           t.TempDir(),
+		Format:        "json",
+		Loki:          &loki.Config{URL: srv.URL, HTTP: httpCfg, Batch: batch},
+		Elasticsearch: &elasticsearch.Config{URL: srv.URL, HTTP: httpCfg, Batch: batch},
+		Splunk:        &splunk.Config{URL: srv.URL, Token: "t", HTTP: httpCfg, Batch: batch},
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+
+	logx.Info("shipped everywhere")
+	for _, path := range []string{"/loki/api/v1/push", "/_bulk", "/services/collector/event"} {
+		waitFor(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return strings.Contains(received[path], "shipped everywhere")
+		})
+	}
+}
+
+// TestSinkSpool tests that entries spooled in the log directory during an outage are replayed after a restart
+func TestSinkSpool(t *testing.T) {
+	var up atomic.Bool
+	var mu sync.Mutex
+	var received strings.Builder
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received.Write(data)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	logDir := t.TempDir()
+	config := &logx.LoggerConfig{
+		Type:   logx.LoggerTypeZap,
+		Level:  "info",
+		Dir:    logDir,
+		Format: "json",
+		Loki: &loki.Config{
+			URL:   srv.URL,
+			HTTP:  sink.HTTPConfig{Compression: "none"},
+			Batch: sink.BatchConfig{FlushInterval: 10 * time.Millisecond, Spool: sink.SpoolConfig{Enabled: true}, ErrorHandler: func(error) {}},
+			Retry: sink.RetryConfig{MaxRetries: -1},
+		},
+	}
+	if err := logx.Init(config); err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+	logx.Info("logged during the outage")
+	waitFor(t, func() bool {
+		segments, _ := filepath.Glob(filepath.Join(logDir, "spool", "loki", "*.seg"))
+		return len(segments) > 0
+	})
+
+	// Reinitializing closes the sinks; the new ones replay the spool.
+	up.Store(true)
+	if err := logx.Init(config); err != nil {
+		t.Fatalf("failed to reinitialize logger: %v", err)
+	}
+	waitFor(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return strings.Contains(received.String(), "logged during the outage")
+	})
+}
+
+// TestGELFAndFluentSinks tests that the GELF and forward sinks are enabled from the configuration
+func TestGELFAndFluentSinks(t *testing.T) {
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer udp.Close()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer ln.Close()
+	forwarded := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 4096)
+		n, _ := conn.Read(buf)
+		forwarded <- buf[:n]
+	}()
+
+	err = logx.Init(&logx.LoggerConfig{
+		Type:   logx.LoggerTypeZap,
+		Level:  "info",
+		Dir:    t.TempDir(),
+		Format: "json",
+		GELF:   &gelf.Config{Address: udp.LocalAddr().String(), Compression: gelf.CompressionNone},
+		Fluent: &fluent.Config{Address: ln.Addr().String(), Tag: "logx.test", Batch: sink.BatchConfig{FlushInterval: 10 * time.Millisecond}},
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+	logx.Log(context.Background(), logx.WarnLevel, "disk almost full", "free_mb", 12)
+
+	buf := make([]byte, 4096)
+	_ = udp.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, _, err := udp.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("failed to read GELF message: %v", err)
+	}
+	if msg := string(buf[:n]); !strings.Contains(msg, `"short_message":"disk almost full"`) || !strings.Contains(msg, `"_free_mb":12`) {
+		t.Errorf("unexpected GELF message: %s", msg)
+	}
+	select {
+	case data := <-forwarded:
+		if !strings.Contains(string(data), "logx.test") || !strings.Contains(string(data), "disk almost full") {
+			t.Errorf("unexpected forward message: %q", data)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("no forward message received")
+	}
+}
+
+// TestSinkPanicDelivered tests that both backends deliver the panic entries to the sinks before panicking
+func TestSinkPanicDelivered(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			var mu sync.Mutex
+			var received strings.Builder
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				received.Write(data)
+				mu.Unlock()
+			}))
+			defer srv.Close()
+
+			err := logx.Init(&logx.LoggerConfig{
+				Type:  typ,
+				Level: "info",
+				Dir:   t.TempDir(),
+				OTLP: &otlp.Config{
+					Endpoint: srv.URL,
+					Protocol: otlp.ProtocolJSON,
+					HTTP:     sink.HTTPConfig{Compression: "none"},
+					Batch:    sink.BatchConfig{FlushInterval: time.Hour},
+				},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			func() {
+				defer func() { _ = recover() }()
+				logx.Panic("boom " + string(typ))
+			}()
+
+			mu.Lock()
+			defer mu.Unlock()
+			if !strings.Contains(received.String(), "boom "+string(typ)) {
+				t.Errorf("expected the panic entry to be delivered before panicking, got %q", received.String())
+			}
+		})
+	}
+}
+
+// TestSinkCaller tests that both backends send the caller to the sinks trimmed to its directory and file
+func TestSinkCaller(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			var mu sync.Mutex
+			var received strings.Builder
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				data, _ := io.ReadAll(r.Body)
+				mu.Lock()
+				received.Write(data)
+				mu.Unlock()
+			}))
+			defer srv.Close()
+
+			err := logx.Init(&logx.LoggerConfig{
+				Type:  typ,
+				Level: "info",
+				Dir:   t.TempDir(),
+				Loki: &loki.Config{
+					URL:   srv.URL,
+					HTTP:  sink.HTTPConfig{Compression: "none"},
+					Batch: sink.BatchConfig{FlushInterval: time.Hour},
+				},
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			_, file, line, _ := runtime.Caller(0)
+			logx.Info("sink caller")
+			if err := logx.Sync(); err != nil {
+				t.Fatalf("failed to sync: %v", err)
+			}
+
+			want := fmt.Sprintf("%s/%s:%d", filepath.Base(filepath.Dir(file)), filepath.Base(file), line+1)
+			mu.Lock()
+			defer mu.Unlock()
+			if !strings.Contains(received.String(), `\"caller\":\"`+want+`\"`) {
+				t.Errorf("expected the caller %s, got %q", want, received.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package slog
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/go4x/logx/dedup"
+)
+
+// dedupHandler is a slog.Handler that suppresses the records repeating the
+// previous record.
+type dedupHandler struct {
+	next slog.Handler
+	d    *dedup.Deduper
+	// context is the fingerprint of the groups and attributes added with
+	// WithGroup and WithAttrs
+	context string
+}
+
+// NewDedupHandler returns a slog.Handler that suppresses the identical
+// consecutive records handled by h according to d.
+func NewDedupHandler(h slog.Handler, d *dedup.Deduper) slog.Handler {
+	return &dedupHandler{next: h, d: d}
+}
+
+// Enabled implements the slog.Handler interface.
+func (h *dedupHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements the slog.Handler interface.
+func (h *dedupHandler) Handle(ctx context.Context, r slog.Record) error {
+	var sb strings.Builder
+	sb.WriteString(r.Level.String())
+	sb.WriteByte(0)
+	sb.WriteString(r.Message)
+	sb.WriteByte(0)
+	sb.WriteString(h.context)
+	r.Attrs(func(a slog.Attr) bool {
+		sb.WriteByte(0)
+		sb.WriteString(a.String())
+		return true
+	})
+	if h.d.Check(sb.String(), r.Time, h.summary(r.Level)) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// summary returns the function writing the summary of the records
+// repeating a record of the given level.
+func (h *dedupHandler) summary(level slog.Level) func(dedup.Repeat) {
+	return func(rep dedup.Repeat) {
+		r := slog.NewRecord(rep.Last, level, rep.Message(), 0)
+		r.AddAttrs(slog.Int("repeated", rep.Count), slog.Time("first", rep.First), slog.Time("last", rep.Last))
+		_ = h.next.Handle(context.Background(), r)
+	}
+}
+
+// WithAttrs implements the slog.Handler interface.
+func (h *dedupHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var sb strings.Builder
+	sb.WriteString(h.context)
+	for _, a := range attrs {
+		sb.WriteByte(0)
+		sb.WriteString(a.String())
+	}
+	return &dedupHandler{next: h.next.WithAttrs(attrs), d: h.d, context: sb.String()}
+}
+
+// WithGroup implements the slog.Handler interface.
+func (h *dedupHandler) WithGroup(name string) slog.Handler {
+	return &dedupHandler{next: h.next.WithGroup(name), d: h.d, context: h.context + "\x00" + name + "."}
+}
@@ -0,0 +1,75 @@
+package slog
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/encoder"
+)
+
+// replaceDuration returns a slog.HandlerOptions.ReplaceAttr function that
+// encodes the durations as the zap encoder of format does: as seconds
+// (default), millis or nanos, or as a string such as "1.5s".
+func replaceDuration(format string) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if a.Value.Kind() != slog.KindDuration {
+			return a
+		}
+		d := a.Value.Duration()
+		switch format {
+		case "millis":
+			a.Value = slog.Float64Value(float64(d) / float64(time.Millisecond))
+		case "nanos":
+			a.Value = slog.Int64Value(int64(d))
+		case "string":
+			a.Value = slog.StringValue(d.String())
+		default:
+			a.Value = slog.Float64Value(d.Seconds())
+		}
+		return a
+	}
+}
+
+// replaceEncoder returns a slog.HandlerOptions.ReplaceAttr function that
+// renames the time, level, message and source keys and encodes their values
+// as e does, as the zap backend does with the same configuration.
+func replaceEncoder(e encoder.Config) func(groups []string, a slog.Attr) slog.Attr {
+	e = e.Keys()
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) > 0 {
+			return a
+		}
+		switch a.Key {
+		case slog.TimeKey:
+			if a.Value.Kind() != slog.KindTime {
+				return a
+			}
+			if e.TimeKey == "" {
+				return slog.Attr{}
+			}
+			return slog.Any(e.TimeKey, e.Time(a.Value.Time()))
+		case slog.LevelKey:
+			l, ok := a.Value.Any().(slog.Level)
+			if !ok {
+				return a
+			}
+			if e.LevelKey == "" {
+				return slog.Attr{}
+			}
+			return slog.String(e.LevelKey, e.Level(core.Level(l)))
+		case slog.MessageKey:
+			if e.MessageKey == "" {
+				return slog.Attr{}
+			}
+			a.Key = e.MessageKey
+		case slog.SourceKey:
+			src, ok := a.Value.Any().(*slog.Source)
+			if !ok {
+				return a
+			}
+			return slog.String(e.CallerKey, e.Caller(src.Function, src.File, src.Line))
+		}
+		return a
+	}
+}
@@ -2,6 +2,7 @@ package slog
 
 import (
 	"bufio"
+	"errors"
 	"io"
 	"log/slog"
 	"os"
@@ -9,11 +10,20 @@ import (
 	"sync"
 	"time"
 
+	"github.com/go4x/logx/encrypt"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // GetHandler get slog.Handler
 func GetHandler(c *SlogConfig) (slog.Handler, error) {
+	h, _, _, err := newHandler(c)
+	return h, err
+}
+
+// newHandler returns the handler of GetHandler, a function that flushes
+// the buffered output of the handler, and the file output of the handler,
+// which must be closed to release it.
+func newHandler(c *SlogConfig) (slog.Handler, func() error, io.Closer, error) {
 	now := time.Now().Format("2006-01-02")
 	filename := path.Join(c.Dir, now+"-"+c.Level+".log")
 	lumberjackLogger := &lumberjack.Logger{
@@ -35,13 +45,23 @@ func GetHandler(c *SlogConfig) (slog.Handler, error) {
 		flushInterval = 5 // Default to flush every 5 seconds
 	}
 
+	// Encrypt the file output if configured
+	var file io.WriteCloser = lumberjackLogger
+	if c.Encryption != nil && c.LogInFile {
+		w, err := encrypt.NewWriter(lumberjackLogger, *c.Encryption)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		file = w
+	}
+
 	var writer io.Writer
 	if c.LogInConsole && c.LogInFile {
-		writer = io.MultiWriter(os.Stdout, lumberjackLogger)
+		writer = io.MultiWriter(os.Stdout, file)
 	} else if c.LogInConsole {
 		writer = os.Stdout
 	} else if c.LogInFile {
-		writer = lumberjackLogger
+		writer = file
 	} else {
 		// If neither is enabled, use standard output as default
 		writer = os.Stdout
@@ -56,15 +76,42 @@ func GetHandler(c *SlogConfig) (slog.Handler, error) {
 		writer = bufferedWriter
 		go bufferedWriter.startFlushTicker(flushInterval)
 	}
-	switch c.Format {
-	case "json":
-		return slog.NewJSONHandler(writer, &slog.HandlerOptions{
-			Level: getSlogLevel(c.Level),
-		}), nil
-	default:
-		return slog.NewTextHandler(writer, &slog.HandlerOptions{
-			Level: getSlogLevel(c.Level),
-		}), nil
+	opts := &slog.HandlerOptions{
+		Level:       getSlogLevel(c.Level),
+		AddSource:   c.AddSource,
+		ReplaceAttr: chainReplace(replaceLevel, replaceDuration(c.DurationFormat)),
+	}
+	if c.Encoder != nil {
+		// the level is encoded by replaceEncoder
+		opts.ReplaceAttr = replaceDuration(c.DurationFormat)
+		opts.AddSource = c.AddSource && c.Encoder.Keys().CallerKey != ""
+	}
+	jsonFormat := c.Format == "json"
+	if !jsonFormat && !c.Sanitize.Disabled {
+		writer = c.Sanitize.Writer(writer)
+		opts.ReplaceAttr = chainReplace(opts.ReplaceAttr, sanitizeAttr(c.Sanitize))
+	}
+	if c.Encoder != nil {
+		// last, since the others expect the keys of slog
+		opts.ReplaceAttr = chainReplace(opts.ReplaceAttr, replaceEncoder(*c.Encoder))
+	}
+	if jsonFormat {
+		return slog.NewJSONHandler(writer, opts), syncWriters(writer, file), file, nil
+	}
+	return slog.NewTextHandler(writer, opts), syncWriters(writer, file), file, nil
+}
+
+// syncWriters returns a function that syncs the writers supporting it, in
+// order.
+func syncWriters(writers ...io.Writer) func() error {
+	return func() error {
+		var errs []error
+		for _, w := range writers {
+			if s, ok := w.(interface{ Sync() error }); ok {
+				errs = append(errs, s.Sync())
+			}
+		}
+		return errors.Join(errs...)
 	}
 }
 
@@ -88,6 +135,23 @@ func (bw *BufferedWriter) Write(p []byte) (n int, err error) {
 	return bw.buf.Write(p)
 }
 
+// Sync flushes the buffer, and syncs the underlying writer if it supports
+// it.
+func (bw *BufferedWriter) Sync() error {
+	bw.mu.Lock()
+	defer bw.mu.Unlock()
+
+	if bw.buf != nil {
+		if err := bw.buf.Flush(); err != nil {
+			return err
+		}
+	}
+	if s, ok := bw.Writer.(interface{ Sync() error }); ok {
+		return s.Sync()
+	}
+	return nil
+}
+
 // startFlushTicker starts a periodic flush goroutine
 func (bw *BufferedWriter) startFlushTicker(interval int) {
 	ticker := time.NewTicker(time.Duration(interval) * time.Second)
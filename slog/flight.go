@@ -0,0 +1,93 @@
+package slog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/flight"
+)
+
+// recorded is the data of a recorded record.
+type recorded struct {
+	next slog.Handler
+	r    slog.Record
+}
+
+// flightHandler is a slog.Handler that records the records below the level
+// of the wrapped handler, and writes them when a record at the trigger level
+// of the recorder is handled.
+type flightHandler struct {
+	next slog.Handler
+	fr   *flight.Recorder
+}
+
+// NewFlightHandler returns a slog.Handler that records the records not
+// enabled by h with fr.
+func NewFlightHandler(h slog.Handler, fr *flight.Recorder) slog.Handler {
+	return &flightHandler{next: h, fr: fr}
+}
+
+// Enabled implements the slog.Handler interface.
+func (h *flightHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level) || core.Level(level) >= h.fr.Level()
+}
+
+// Handle implements the slog.Handler interface.
+func (h *flightHandler) Handle(ctx context.Context, r slog.Record) error {
+	b := flight.FromContext(ctx)
+	if !h.next.Enabled(ctx, r.Level) {
+		size := len(r.Message)
+		r.Attrs(func(a slog.Attr) bool {
+			size += len(a.Key) + 16
+			if a.Value.Kind() == slog.KindString {
+				size += len(a.Value.String())
+			}
+			return true
+		})
+		h.fr.Record(b, flight.Record{
+			Level:   core.Level(r.Level),
+			Time:    r.Time,
+			Message: r.Message,
+			Data:    recorded{next: h.next, r: r.Clone()},
+			Size:    size,
+		})
+		return nil
+	}
+	if core.Level(r.Level) >= h.fr.Trigger() {
+		h.dump(ctx, b, r)
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// dump writes the records recorded in b at the level of the record r, after
+// a marker record.
+func (h *flightHandler) dump(ctx context.Context, b *flight.Buffer, r slog.Record) {
+	records, evicted := h.fr.Dump(b)
+	if len(records) == 0 {
+		return
+	}
+	marker := slog.NewRecord(r.Time, r.Level, flight.DumpMessage, r.PC)
+	marker.AddAttrs(slog.Int("recorded", len(records)), slog.Int("evicted", evicted))
+	_ = h.next.Handle(ctx, marker)
+	for _, rec := range records {
+		data := rec.Data.(recorded)
+		out := slog.NewRecord(data.r.Time, r.Level, data.r.Message, data.r.PC)
+		data.r.Attrs(func(a slog.Attr) bool {
+			out.AddAttrs(a)
+			return true
+		})
+		out.AddAttrs(slog.String(flight.LevelKey, rec.Level.String()))
+		_ = data.next.Handle(ctx, out)
+	}
+}
+
+// WithAttrs implements the slog.Handler interface.
+func (h *flightHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &flightHandler{next: h.next.WithAttrs(attrs), fr: h.fr}
+}
+
+// WithGroup implements the slog.Handler interface.
+func (h *flightHandler) WithGroup(name string) slog.Handler {
+	return &flightHandler{next: h.next.WithGroup(name), fr: h.fr}
+}
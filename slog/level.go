@@ -0,0 +1,65 @@
+package slog
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/go4x/logx/core"
+)
+
+// contextLevelHandler is a slog.Handler that also enables the records at or
+// above the level carried by the context (see core.WithLevel).
+type contextLevelHandler struct {
+	next slog.Handler
+}
+
+// NewContextLevelHandler returns a slog.Handler that handles the records
+// enabled by h, and the records logged with a context carrying a lower
+// level.
+func NewContextLevelHandler(h slog.Handler) slog.Handler {
+	return &contextLevelHandler{next: h}
+}
+
+// Enabled implements the slog.Handler interface.
+func (h *contextLevelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if min, ok := core.LevelFrom(ctx); ok && core.Level(level) >= min {
+		return true
+	}
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements the slog.Handler interface.
+func (h *contextLevelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs implements the slog.Handler interface.
+func (h *contextLevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextLevelHandler{next: h.next.WithAttrs(attrs)}
+}
+
+// WithGroup implements the slog.Handler interface.
+func (h *contextLevelHandler) WithGroup(name string) slog.Handler {
+	return &contextLevelHandler{next: h.next.WithGroup(name)}
+}
+
+// replaceLevel is a slog.HandlerOptions.ReplaceAttr function that writes the
+// level of the records with its core name in upper case, such as NOTICE
+// where slog writes INFO+2, so that both backends name the levels alike.
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.LevelKey {
+		if l, ok := a.Value.Any().(slog.Level); ok {
+			a.Value = slog.StringValue(strings.ToUpper(core.Level(l).String()))
+		}
+	}
+	return a
+}
+
+// chainReplace returns a slog.HandlerOptions.ReplaceAttr function that
+// applies f and then g.
+func chainReplace(f, g func([]string, slog.Attr) slog.Attr) func([]string, slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		return g(groups, f(groups, a))
+	}
+}
@@ -0,0 +1,72 @@
+package slog
+
+import (
+	"log/slog"
+
+	"github.com/go4x/logx/core"
+)
+
+// needsArgs reports whether slogArgs converts some of keysAndValues.
+func needsArgs(keysAndValues []any) bool {
+	for _, v := range keysAndValues {
+		if _, ok := v.(core.Field); ok || core.IsMarshaler(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// slogArgs returns keysAndValues with its core.Field elements converted to
+// slog.Attr, which slog takes as they are, and the values encoded by
+// core.MarshalValue converted to groups and slices. keysAndValues is not
+// modified.
+func slogArgs(keysAndValues []any) []any {
+	out := make([]any, 0, len(keysAndValues))
+	for i := 0; i < len(keysAndValues); i++ {
+		switch v := keysAndValues[i].(type) {
+		case slog.Attr:
+			out = append(out, v)
+		case core.Field:
+			out = appendAttrs(out, v.Key, v.Value)
+		case string:
+			if i+1 == len(keysAndValues) {
+				return append(out, v)
+			}
+			i++
+			out = appendAttrs(out, v, keysAndValues[i])
+		default:
+			// left to slog, which reports the invalid keys
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// appendAttrs appends the attribute of key and value to out, followed by
+// the error of the marshaler of value if any.
+func appendAttrs(out []any, key string, value any) []any {
+	v, err := core.MarshalValue(value)
+	out = append(out, slog.Attr{Key: key, Value: slogValue(v)})
+	if err != nil {
+		out = append(out, slog.String(key+"Error", err.Error()))
+	}
+	return out
+}
+
+// slogValue returns the slog.Value of a value encoded by core.MarshalValue.
+// The objects are groups, except in arrays, which are left as core.Array
+// since slog has no arrays of groups: core.Array encodes its objects in
+// order as JSON, in both formats.
+func slogValue(value any) slog.Value {
+	switch v := value.(type) {
+	case core.Object:
+		attrs := make([]slog.Attr, len(v))
+		for i, f := range v {
+			attrs[i] = slog.Attr{Key: f.Key, Value: slogValue(f.Value)}
+		}
+		return slog.GroupValue(attrs...)
+	case core.Array:
+		return slog.AnyValue(v)
+	}
+	return slog.AnyValue(value)
+}
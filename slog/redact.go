@@ -0,0 +1,79 @@
+package slog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/redact"
+)
+
+// redactHandler is a slog.Handler that redacts the message and the
+// attributes of the records before handing them to the wrapped handler.
+type redactHandler struct {
+	next slog.Handler
+	r    *redact.Redactor
+}
+
+// NewRedactHandler returns a slog.Handler that applies r to the records
+// handled by h.
+func NewRedactHandler(h slog.Handler, r *redact.Redactor) slog.Handler {
+	return &redactHandler{next: h, r: r}
+}
+
+// Enabled implements the slog.Handler interface.
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements the slog.Handler interface.
+func (h *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	out := slog.NewRecord(r.Time, r.Level, h.r.String(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		if a, keep := redactAttr(h.r, a); keep {
+			out.AddAttrs(a)
+		}
+		return true
+	})
+	return h.next.Handle(ctx, out)
+}
+
+// WithAttrs implements the slog.Handler interface.
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		if a, keep := redactAttr(h.r, a); keep {
+			redacted = append(redacted, a)
+		}
+	}
+	return &redactHandler{next: h.next.WithAttrs(redacted), r: h.r}
+}
+
+// WithGroup implements the slog.Handler interface.
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{next: h.next.WithGroup(name), r: h.r}
+}
+
+// redactAttr applies r to a, recursing into groups. It reports whether the
+// attribute must be kept.
+func redactAttr(r *redact.Redactor, a slog.Attr) (slog.Attr, bool) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		if out, changed, keep := r.Field(a.Key, nil); changed {
+			return slog.Any(a.Key, out), keep
+		}
+		group := a.Value.Group()
+		attrs := make([]slog.Attr, 0, len(group))
+		for _, ga := range group {
+			if ga, keep := redactAttr(r, ga); keep {
+				attrs = append(attrs, ga)
+			}
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(attrs...)}, true
+	}
+	out, changed, keep := r.Field(a.Key, core.PlainValue(a.Value.Any()))
+	if !changed {
+		return a, true
+	}
+	return slog.Any(a.Key, out), keep
+}
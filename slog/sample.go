@@ -0,0 +1,61 @@
+package slog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/sample"
+)
+
+// sampleHandler is a slog.Handler that samples and rate limits the records
+// before handing them to the wrapped handler.
+type sampleHandler struct {
+	next slog.Handler
+	s    *sample.Sampler
+}
+
+// NewSampleHandler returns a slog.Handler that samples and rate limits the
+// records handled by h according to s.
+func NewSampleHandler(h slog.Handler, s *sample.Sampler) slog.Handler {
+	return &sampleHandler{next: h, s: s}
+}
+
+// Enabled implements the slog.Handler interface.
+func (h *sampleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements the slog.Handler interface.
+func (h *sampleHandler) Handle(ctx context.Context, r slog.Record) error {
+	level := core.Level(r.Level)
+	if !h.s.Sample(level, r.Message) || !h.s.Allow(level, r.Message) {
+		return nil
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs implements the slog.Handler interface.
+func (h *sampleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &sampleHandler{next: h.next.WithAttrs(attrs), s: h.s}
+}
+
+// WithGroup implements the slog.Handler interface.
+func (h *sampleHandler) WithGroup(name string) slog.Handler {
+	return &sampleHandler{next: h.next.WithGroup(name), s: h.s}
+}
+
+// summaryReporter returns the function writing the summaries of a sampler
+// to h, which must not be sampled.
+func summaryReporter(h slog.Handler) func(sample.Summary) {
+	return func(sum sample.Summary) {
+		ctx := context.Background()
+		if !h.Enabled(ctx, slog.LevelWarn) {
+			return
+		}
+		r := slog.NewRecord(time.Now(), slog.LevelWarn, sample.SummaryMessage, 0)
+		r.AddAttrs(slog.Uint64("sampled", sum.Sampled), slog.Uint64("rate_limited", sum.Limited))
+		_ = h.Handle(ctx, r)
+	}
+}
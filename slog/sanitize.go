@@ -0,0 +1,31 @@
+package slog
+
+import (
+	"log/slog"
+
+	"github.com/go4x/logx/sanitize"
+)
+
+// sanitizeAttr returns a slog.HandlerOptions.ReplaceAttr function that
+// truncates the message and the long string values according to c. The
+// text handler already quotes and escapes the strings that contain control
+// characters.
+func sanitizeAttr(c sanitize.Config) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.MessageKey {
+			a.Value = slog.StringValue(c.TruncateMessage(a.Value.String()))
+			return a
+		}
+		switch a.Value.Kind() {
+		case slog.KindString:
+			a.Value = slog.StringValue(c.TruncateField(a.Value.String()))
+		case slog.KindAny:
+			if err, ok := a.Value.Any().(error); ok {
+				if s := err.Error(); c.TruncateField(s) != s {
+					a.Value = slog.StringValue(c.TruncateField(s))
+				}
+			}
+		}
+		return a
+	}
+}
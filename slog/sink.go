@@ -0,0 +1,220 @@
+package slog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/go4x/logx/core"
+)
+
+// sinkHandler is a slog.Handler that converts records to core.Entry and
+// hands them to a core.Sink.
+type sinkHandler struct {
+	sink  core.Sink
+	level slog.Leveler
+	goas  []groupOrAttrs
+}
+
+// groupOrAttrs holds either a group name or a list of attributes added with
+// WithGroup or WithAttrs.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// NewSinkHandler returns a slog.Handler that writes the records at or above
+// level to s.
+func NewSinkHandler(s core.Sink, level slog.Leveler) slog.Handler {
+	return &sinkHandler{sink: s, level: level}
+}
+
+// Enabled implements the slog.Handler interface.
+func (h *sinkHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements the slog.Handler interface.
+func (h *sinkHandler) Handle(_ context.Context, r slog.Record) error {
+	e := &core.Entry{
+		Time:    r.Time,
+		Level:   core.Level(r.Level),
+		Message: r.Message,
+		Fields:  h.fields(r),
+	}
+	if f, ok := core.CallerFrame(r.PC); ok {
+		// trimmed, as the zap backend does
+		e.Caller = core.TrimmedCaller(f.File, f.Line)
+	}
+	if err := h.sink.Write(e); err != nil {
+		return err
+	}
+	// the records above error may be followed by a panic or an exit, so
+	// they are flushed as the zap backend does
+	if e.Level > core.ErrorLevel {
+		return h.sink.Sync()
+	}
+	return nil
+}
+
+// fields converts the handler's attributes and groups and the record's
+// attributes to core fields. Groups become nested maps and empty groups are
+// omitted, as in the standard handlers.
+func (h *sinkHandler) fields(r slog.Record) []core.Field {
+	goas := h.goas
+	if r.NumAttrs() == 0 {
+		for len(goas) > 0 && goas[len(goas)-1].group != "" {
+			goas = goas[:len(goas)-1]
+		}
+	}
+
+	type group struct {
+		name   string
+		fields []core.Field
+	}
+	stack := []group{{}}
+	for _, goa := range goas {
+		if goa.group != "" {
+			stack = append(stack, group{name: goa.group})
+			continue
+		}
+		top := &stack[len(stack)-1]
+		for _, a := range goa.attrs {
+			top.fields = appendAttr(top.fields, a)
+		}
+	}
+	top := &stack[len(stack)-1]
+	r.Attrs(func(a slog.Attr) bool {
+		top.fields = appendAttr(top.fields, a)
+		return true
+	})
+	for i := len(stack) - 1; i > 0; i-- {
+		if len(stack[i].fields) > 0 {
+			stack[i-1].fields = append(stack[i-1].fields, core.Field{Key: stack[i].name, Value: fieldsToMap(stack[i].fields)})
+		}
+	}
+	return stack[0].fields
+}
+
+// WithAttrs implements the slog.Handler interface.
+func (h *sinkHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	return h.withGroupOrAttrs(groupOrAttrs{attrs: attrs})
+}
+
+// WithGroup implements the slog.Handler interface.
+func (h *sinkHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	return h.withGroupOrAttrs(groupOrAttrs{group: name})
+}
+
+func (h *sinkHandler) withGroupOrAttrs(goa groupOrAttrs) *sinkHandler {
+	clone := *h
+	clone.goas = make([]groupOrAttrs, len(h.goas)+1)
+	copy(clone.goas, h.goas)
+	clone.goas[len(h.goas)] = goa
+	return &clone
+}
+
+// appendAttr converts a slog.Attr to core fields and appends them to dst.
+func appendAttr(dst []core.Field, a slog.Attr) []core.Field {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return dst
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		var fields []core.Field
+		for _, ga := range a.Value.Group() {
+			fields = appendAttr(fields, ga)
+		}
+		if len(fields) == 0 {
+			return dst
+		}
+		if a.Key == "" {
+			return append(dst, fields...)
+		}
+		return append(dst, core.Field{Key: a.Key, Value: fieldsToMap(fields)})
+	}
+	return append(dst, core.Field{Key: a.Key, Value: attrValue(a.Value)})
+}
+
+// attrValue converts a resolved slog.Value to a plain Go value.
+func attrValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration()
+	case slog.KindTime:
+		return v.Time()
+	default:
+		return core.PlainValue(v.Any())
+	}
+}
+
+// fieldsToMap converts fields to a map for use as a nested object.
+func fieldsToMap(fields []core.Field) map[string]any {
+	m := make(map[string]any, len(fields))
+	for _, f := range fields {
+		m[f.Key] = f.Value
+	}
+	return m
+}
+
+// fanoutHandler is a slog.Handler that dispatches records to several handlers.
+type fanoutHandler struct {
+	handlers []slog.Handler
+}
+
+// Enabled implements the slog.Handler interface.
+func (h *fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, hh := range h.handlers {
+		if hh.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle implements the slog.Handler interface.
+func (h *fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var firstErr error
+	for _, hh := range h.handlers {
+		if !hh.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := hh.Handle(ctx, r.Clone()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// WithAttrs implements the slog.Handler interface.
+func (h *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, hh := range h.handlers {
+		handlers[i] = hh.WithAttrs(attrs)
+	}
+	return &fanoutHandler{handlers: handlers}
+}
+
+// WithGroup implements the slog.Handler interface.
+func (h *fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, hh := range h.handlers {
+		handlers[i] = hh.WithGroup(name)
+	}
+	return &fanoutHandler{handlers: handlers}
+}
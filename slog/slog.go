@@ -5,14 +5,24 @@ package slog
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"time"
+
+	"github.com/go4x/logx/core"
 )
 
 // Logger wraps slog.Logger to implement the logx.Logger interface.
 type Logger struct {
 	*slog.Logger
+	contextFields func(ctx context.Context) []any
+	// sync flushes the outputs and the sinks
+	sync func() error
+	// file is the file output, closed by Close
+	file io.Closer
 }
 
 // pathExists checks if the given path exists.
@@ -41,31 +51,102 @@ func NewLog(c *SlogConfig) (*Logger, error) {
 		_ = os.MkdirAll(c.Dir, os.ModePerm)
 	}
 
-	handler, err := GetHandler(c)
+	handler, syncOutput, file, err := newHandler(c)
 	if err != nil {
 		return nil, err
 	}
+	if c.StacktraceLevel != "" {
+		key := c.StacktraceKey
+		if key == "" {
+			key = DefaultStacktraceKey
+		}
+		if c.Encoder != nil {
+			// empty if omitted
+			key = c.Encoder.Keys().StacktraceKey
+		}
+		if l, err := core.ParseLevel(c.StacktraceLevel); err == nil && key != "" {
+			handler = NewStackHandler(handler, slog.Level(l), key)
+		}
+	}
+	// the outputs consult the level carried by the context, so that the
+	// wrappers delegating Enabled to them do too
+	handler = NewContextLevelHandler(handler)
+	if len(c.Sinks) > 0 {
+		handlers := []slog.Handler{handler}
+		for _, s := range c.Sinks {
+			handlers = append(handlers, NewContextLevelHandler(NewSinkHandler(s, getSlogLevel(c.Level))))
+		}
+		handler = &fanoutHandler{handlers: handlers}
+	}
+	if c.Redactor != nil {
+		handler = NewRedactHandler(handler, c.Redactor)
+	}
+	if c.Deduper != nil {
+		handler = NewDedupHandler(handler, c.Deduper)
+	}
+	if c.Recorder != nil {
+		handler = NewFlightHandler(handler, c.Recorder)
+	}
+	if c.Sampler != nil {
+		c.Sampler.Start(summaryReporter(handler))
+		handler = NewSampleHandler(handler, c.Sampler)
+	}
 
+	sinks := c.Sinks
+	sync := func() error {
+		errs := []error{syncOutput()}
+		for _, s := range sinks {
+			errs = append(errs, s.Sync())
+		}
+		return errors.Join(errs...)
+	}
 	logger := slog.New(handler)
-	return &Logger{Logger: logger}, nil
+	return &Logger{Logger: logger, contextFields: c.ContextFields, sync: sync, file: file}, nil
+}
+
+// Close flushes the buffered output and closes the file, stopping the
+// goroutines of its writer. The logger must not be used afterwards.
+func (l *Logger) Close() error {
+	err := l.Sync()
+	if l.file != nil {
+		err = errors.Join(err, l.file.Close())
+	}
+	return err
 }
 
-// getSlogLevel converts the string level to slog.Level
+// Sync flushes the buffered output and the sinks.
+func (l *Logger) Sync() error {
+	if l.sync == nil {
+		return nil
+	}
+	return l.sync()
+}
+
+// getSlogLevel converts the string level to slog.Level, which is info if
+// the level is invalid
 func getSlogLevel(level string) slog.Level {
-	switch level {
-	case "debug":
-		return slog.LevelDebug
-	case "info":
-		return slog.LevelInfo
-	case "warn":
-		return slog.LevelWarn
-	case "error":
-		return slog.LevelError
-	case "fatal":
-		return slog.LevelError
-	default:
+	l, err := core.ParseLevel(level)
+	if err != nil {
 		return slog.LevelInfo
 	}
+	return slog.Level(l)
+}
+
+// Trace implements the Trace method of the Logger interface
+func (l *Logger) Trace(args ...any) {
+	if len(args) == 0 {
+		return
+	}
+	if l.LevelEnabled(context.Background(), core.TraceLevel) {
+		l.log(core.TraceLevel, fmt.Sprint(args...))
+	}
+}
+
+// Tracef implements the Tracef method of the Logger interface
+func (l *Logger) Tracef(template string, args ...any) {
+	if l.LevelEnabled(context.Background(), core.TraceLevel) {
+		l.log(core.TraceLevel, fmt.Sprintf(template, args...))
+	}
 }
 
 // Debug implements the Debug method of the Logger interface
@@ -73,14 +154,16 @@ func (l *Logger) Debug(args ...any) {
 	if len(args) == 0 {
 		return
 	}
-	msg := fmt.Sprint(args...)
-	l.Logger.Debug(msg)
+	if l.LevelEnabled(context.Background(), core.DebugLevel) {
+		l.log(core.DebugLevel, fmt.Sprint(args...))
+	}
 }
 
 // Debugf implements the Debugf method of the Logger interface
 func (l *Logger) Debugf(template string, args ...any) {
-	msg := fmt.Sprintf(template, args...)
-	l.Logger.Debug(msg)
+	if l.LevelEnabled(context.Background(), core.DebugLevel) {
+		l.log(core.DebugLevel, fmt.Sprintf(template, args...))
+	}
 }
 
 // Info implements the Info method of the Logger interface
@@ -88,14 +171,33 @@ func (l *Logger) Info(args ...any) {
 	if len(args) == 0 {
 		return
 	}
-	msg := fmt.Sprint(args...)
-	l.Logger.Info(msg)
+	if l.LevelEnabled(context.Background(), core.InfoLevel) {
+		l.log(core.InfoLevel, fmt.Sprint(args...))
+	}
 }
 
 // Infof implements the Infof method of the Logger interface
 func (l *Logger) Infof(template string, args ...any) {
-	msg := fmt.Sprintf(template, args...)
-	l.Logger.Info(msg)
+	if l.LevelEnabled(context.Background(), core.InfoLevel) {
+		l.log(core.InfoLevel, fmt.Sprintf(template, args...))
+	}
+}
+
+// Notice implements the Notice method of the Logger interface
+func (l *Logger) Notice(args ...any) {
+	if len(args) == 0 {
+		return
+	}
+	if l.LevelEnabled(context.Background(), core.NoticeLevel) {
+		l.log(core.NoticeLevel, fmt.Sprint(args...))
+	}
+}
+
+// Noticef implements the Noticef method of the Logger interface
+func (l *Logger) Noticef(template string, args ...any) {
+	if l.LevelEnabled(context.Background(), core.NoticeLevel) {
+		l.log(core.NoticeLevel, fmt.Sprintf(template, args...))
+	}
 }
 
 // Warn implements the Warn method of the Logger interface
@@ -103,14 +205,16 @@ func (l *Logger) Warn(args ...any) {
 	if len(args) == 0 {
 		return
 	}
-	msg := fmt.Sprint(args...)
-	l.Logger.Warn(msg)
+	if l.LevelEnabled(context.Background(), core.WarnLevel) {
+		l.log(core.WarnLevel, fmt.Sprint(args...))
+	}
 }
 
 // Warnf implements the Warnf method of the Logger interface
 func (l *Logger) Warnf(template string, args ...any) {
-	msg := fmt.Sprintf(template, args...)
-	l.Logger.Warn(msg)
+	if l.LevelEnabled(context.Background(), core.WarnLevel) {
+		l.log(core.WarnLevel, fmt.Sprintf(template, args...))
+	}
 }
 
 // Error implements the Error method of the Logger interface
@@ -118,38 +222,130 @@ func (l *Logger) Error(args ...any) {
 	if len(args) == 0 {
 		return
 	}
-	msg := fmt.Sprint(args...)
-	l.Logger.Error(msg)
+	if l.LevelEnabled(context.Background(), core.ErrorLevel) {
+		l.log(core.ErrorLevel, fmt.Sprint(args...))
+	}
 }
 
 // Errorf implements the Errorf method of the Logger interface
 func (l *Logger) Errorf(template string, args ...any) {
-	msg := fmt.Sprintf(template, args...)
-	l.Logger.Error(msg)
+	if l.LevelEnabled(context.Background(), core.ErrorLevel) {
+		l.log(core.ErrorLevel, fmt.Sprintf(template, args...))
+	}
 }
 
-// Fatal implements the Fatal method of the Logger interface
-// Note: This implementation logs the message as error and exits.
-// Consider using a different approach for production code.
-func (l *Logger) Fatal(args ...any) {
+// Critical implements the Critical method of the Logger interface
+func (l *Logger) Critical(args ...any) {
 	if len(args) == 0 {
-		l.Logger.Error("Fatal error occurred")
-		os.Exit(1)
 		return
 	}
-	msg := fmt.Sprint(args...)
-	l.Logger.Error(msg)
-	os.Exit(1)
+	if l.LevelEnabled(context.Background(), core.CriticalLevel) {
+		l.log(core.CriticalLevel, fmt.Sprint(args...))
+	}
+}
+
+// Criticalf implements the Criticalf method of the Logger interface
+func (l *Logger) Criticalf(template string, args ...any) {
+	if l.LevelEnabled(context.Background(), core.CriticalLevel) {
+		l.log(core.CriticalLevel, fmt.Sprintf(template, args...))
+	}
+}
+
+// Panic implements the Panic method of the Logger interface: it logs the
+// message and panics with it.
+func (l *Logger) Panic(args ...any) {
+	l.log(core.PanicLevel, fmt.Sprint(args...))
+}
+
+// Panicf implements the Panicf method of the Logger interface
+func (l *Logger) Panicf(template string, args ...any) {
+	l.log(core.PanicLevel, fmt.Sprintf(template, args...))
+}
+
+// Fatal implements the Fatal method of the Logger interface: it logs the
+// message and exits.
+func (l *Logger) Fatal(args ...any) {
+	msg := "Fatal error occurred"
+	if len(args) > 0 {
+		msg = fmt.Sprint(args...)
+	}
+	l.log(core.FatalLevel, msg)
 }
 
 // Fatalf implements the Fatalf method of the Logger interface
 func (l *Logger) Fatalf(template string, args ...any) {
-	msg := fmt.Sprintf(template, args...)
-	l.Logger.Error(msg)
-	os.Exit(1)
+	l.log(core.FatalLevel, fmt.Sprintf(template, args...))
+}
+
+// log logs msg at level with the call site of the caller of the calling
+// method.
+func (l *Logger) log(level core.Level, msg string) {
+	l.LogCaller(context.Background(), core.CallerPC(1), level, msg)
+}
+
+// Log logs a message at the given level with the key-value pairs carried by
+// ctx followed by the given key-value pairs. A level carried by ctx (see
+// core.WithLevel) lowers the configured level. Like Panic and Fatal,
+// logging at core.PanicLevel panics, and at core.FatalLevel or above exits.
+func (l *Logger) Log(ctx context.Context, level core.Level, msg string, keysAndValues ...any) {
+	l.LogCaller(ctx, core.CallerPC(0), level, msg, keysAndValues...)
+}
+
+// LogCaller implements the core.CallerLogger interface: it logs as Log
+// does, with the source of the record at pc.
+func (l *Logger) LogCaller(ctx context.Context, pc uintptr, level core.Level, msg string, keysAndValues ...any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	// the record is built here, as slog.Logger does, since slog would take
+	// this method as the source
+	if l.Logger.Enabled(ctx, slog.Level(level)) {
+		keysAndValues = contextKeysAndValues(ctx, l.contextFields, keysAndValues)
+		// the values are only marshaled for the enabled records
+		if needsArgs(keysAndValues) {
+			keysAndValues = slogArgs(keysAndValues)
+		}
+		r := slog.NewRecord(time.Now(), slog.Level(level), msg, pc)
+		r.Add(keysAndValues...)
+		_ = l.Logger.Handler().Handle(ctx, r)
+	}
+	switch {
+	case level >= core.FatalLevel:
+		// the buffered output and the sinks are flushed before exiting
+		_ = l.Sync()
+		os.Exit(1)
+	case level == core.PanicLevel:
+		_ = l.Sync()
+		panic(msg)
+	}
+}
+
+// LevelEnabled implements the core.CallerLogger interface.
+func (l *Logger) LevelEnabled(ctx context.Context, level core.Level) bool {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return l.Logger.Enabled(ctx, slog.Level(level))
+}
+
+// contextKeysAndValues prepends the key-value pairs carried by ctx and those
+// returned by extract to keysAndValues.
+func contextKeysAndValues(ctx context.Context, extract func(context.Context) []any, keysAndValues []any) []any {
+	fields := core.Fields(ctx)
+	var extra []any
+	if extract != nil {
+		extra = extract(ctx)
+	}
+	if len(fields) == 0 && len(extra) == 0 {
+		return keysAndValues
+	}
+	out := make([]any, 0, len(fields)+len(extra)+len(keysAndValues))
+	out = append(out, extra...)
+	out = append(out, fields...)
+	return append(out, keysAndValues...)
 }
 
 // WithContext returns a logger with context
 func (l *Logger) WithContext(ctx context.Context) *Logger {
-	return &Logger{Logger: l.Logger}
+	return &Logger{Logger: l.Logger, contextFields: l.contextFields, sync: l.sync, file: l.file}
 }
@@ -1,8 +1,22 @@
 package slog
 
+import (
+	"context"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/dedup"
+	"github.com/go4x/logx/encoder"
+	"github.com/go4x/logx/encrypt"
+	"github.com/go4x/logx/flight"
+	"github.com/go4x/logx/redact"
+	"github.com/go4x/logx/sample"
+	"github.com/go4x/logx/sanitize"
+)
+
 // SlogConfig holds the configuration for the slog logger.
 type SlogConfig struct {
-	// Level specifies the minimum log level (debug, info, warn, error, fatal).
+	// Level specifies the minimum log level (trace, debug, info, notice,
+	// warn, error, critical, panic, fatal or a registered level name).
 	Level string `mapstructure:"level" yaml:"level"`
 
 	// LogInFile determines whether to write logs to file.
@@ -17,6 +31,27 @@ type SlogConfig struct {
 	// Format specifies the log output format (text or json).
 	Format string `mapstructure:"format" yaml:"format"`
 
+	// DurationFormat specifies how to encode durations: seconds (default),
+	// millis, nanos or string, as the zap backend does.
+	DurationFormat string `mapstructure:"duration-format" yaml:"duration-format"`
+
+	// AddSource adds the source of the log site to the records, as the
+	// ShowCaller option of the zap backend does.
+	AddSource bool `mapstructure:"add-source" yaml:"add-source"`
+
+	// Encoder, if set, replaces the keys, the time format, the level
+	// encoding and the caller format of the records, and the
+	// StacktraceKey, as the zap backend does.
+	Encoder *encoder.Config `mapstructure:"encoder" yaml:"encoder"`
+
+	// StacktraceLevel, if set, adds the stack trace of the log site to the
+	// records at or above this level, under StacktraceKey.
+	StacktraceLevel string `mapstructure:"stacktrace-level" yaml:"stacktrace-level"`
+
+	// StacktraceKey specifies the key of the stack traces (stacktrace by
+	// default).
+	StacktraceKey string `mapstructure:"stacktrace-key" yaml:"stacktrace-key"`
+
 	// MaxAge specifies the maximum number of days to retain log files (0 means no limit).
 	MaxAge int `mapstructure:"max-age" yaml:"max-age"`
 
@@ -37,4 +72,35 @@ type SlogConfig struct {
 
 	// FlushInterval specifies the interval in seconds to flush the buffer.
 	FlushInterval int `mapstructure:"flush-interval" yaml:"flush-interval"`
+
+	// ContextFields returns extra key-value pairs derived from the context
+	// passed to Log, such as trace correlation ids.
+	ContextFields func(ctx context.Context) []any `mapstructure:"-" yaml:"-"`
+
+	// Sinks receive every entry that passes the level filter, in addition
+	// to the console and file outputs.
+	Sinks []core.Sink `mapstructure:"-" yaml:"-"`
+
+	// Encryption, if set, encrypts the log files with AES-GCM.
+	Encryption *encrypt.Config `mapstructure:"encryption" yaml:"encryption"`
+
+	// Sanitize configures the escaping of control characters and the size
+	// limits of the text format, which are on by default.
+	Sanitize sanitize.Config `mapstructure:"sanitize" yaml:"sanitize"`
+
+	// Redactor, if set, scrubs sensitive data from the messages and
+	// attributes of every record before it is encoded.
+	Redactor *redact.Redactor `mapstructure:"-" yaml:"-"`
+
+	// Sampler, if set, samples and rate limits the records written to all
+	// outputs, and reports the records it drops in a summary record.
+	Sampler *sample.Sampler `mapstructure:"-" yaml:"-"`
+
+	// Deduper, if set, suppresses the identical consecutive records and
+	// writes a summary of the suppressed records instead.
+	Deduper *dedup.Deduper `mapstructure:"-" yaml:"-"`
+
+	// Recorder, if set, records the records below Level and writes them
+	// when an error is logged.
+	Recorder *flight.Recorder `mapstructure:"-" yaml:"-"`
 }
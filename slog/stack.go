@@ -0,0 +1,110 @@
+package slog
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/go4x/logx/core"
+)
+
+// DefaultStacktraceKey is the key of the stack traces added by the handler
+// of NewStackHandler when none is configured.
+const DefaultStacktraceKey = "stacktrace"
+
+// maxStackDepth bounds the number of frames of a stack trace.
+const maxStackDepth = 64
+
+// stackHandler is a slog.Handler that adds the stack trace of the log site
+// to the records at or above a level.
+type stackHandler struct {
+	next  slog.Handler
+	level slog.Leveler
+	key   string
+}
+
+// NewStackHandler returns a slog.Handler that adds the stack trace of the
+// log site under key to the records at or above level, in the format of the
+// stack traces of zap, and passes the records to h. The stack trace starts
+// at the source of the record, or else at the first frame outside of slog
+// and of this module.
+func NewStackHandler(h slog.Handler, level slog.Leveler, key string) slog.Handler {
+	if key == "" {
+		key = DefaultStacktraceKey
+	}
+	return &stackHandler{next: h, level: level, key: key}
+}
+
+// Enabled implements the slog.Handler interface.
+func (h *stackHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle implements the slog.Handler interface.
+func (h *stackHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level >= h.level.Level() {
+		r = r.Clone()
+		stack := core.Stacktrace(r.PC)
+		if stack == "" {
+			stack = logSiteStack()
+		}
+		r.AddAttrs(slog.String(h.key, stack))
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs implements the slog.Handler interface.
+func (h *stackHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &stackHandler{next: h.next.WithAttrs(attrs), level: h.level, key: h.key}
+}
+
+// WithGroup implements the slog.Handler interface.
+func (h *stackHandler) WithGroup(name string) slog.Handler {
+	return &stackHandler{next: h.next.WithGroup(name), level: h.level, key: h.key}
+}
+
+// logSiteStack returns the stack trace of the goroutine, starting at the
+// first frame outside of slog and of this module, formatted as zap does.
+func logSiteStack() string {
+	var pcs [maxStackDepth]uintptr
+	n := runtime.Callers(3, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+	var b strings.Builder
+	inLogger := true
+	for {
+		f, more := frames.Next()
+		if f.Function == "runtime.goexit" {
+			// zap leaves out the frame starting the goroutines
+			return b.String()
+		}
+		if inLogger && !loggerFrame(f) {
+			inLogger = false
+		}
+		if !inLogger {
+			if b.Len() > 0 {
+				b.WriteByte('\n')
+			}
+			b.WriteString(f.Function)
+			b.WriteString("\n\t")
+			b.WriteString(f.File)
+			b.WriteByte(':')
+			b.WriteString(strconv.Itoa(f.Line))
+		}
+		if !more {
+			return b.String()
+		}
+	}
+}
+
+// loggerFrame reports whether f belongs to slog or to this module, other
+// than its tests.
+func loggerFrame(f runtime.Frame) bool {
+	if strings.HasSuffix(f.File, "_test.go") {
+		return false
+	}
+	return strings.HasPrefix(f.Function, "log/slog.") ||
+		strings.HasPrefix(f.Function, "github.com/go4x/logx.") ||
+		strings.HasPrefix(f.Function, "github.com/go4x/logx/")
+}
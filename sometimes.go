@@ -0,0 +1,196 @@
+package logx
+
+import (
+	"context"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sometimes is a Logger that writes through the global logger only some of
+// the times it is called, according to the helper that returned it: Every,
+// EveryN, FirstN or Once. Its state is kept per call site of that helper,
+// or per key with Key, so the helpers can be called in place in a loop:
+//
+//	for _, item := range items {
+//	    logx.EveryN(1000).Infof("processing %s", item.ID)
+//	    if err := process(item); err != nil {
+//	        logx.Every(time.Minute).Warn("processing failed: ", err)
+//	    }
+//	}
+//
+// The decision is made when a log method is called, before the level is
+// checked.
+type Sometimes struct {
+	pc    uintptr
+	key   string
+	kind  sometimesKind
+	every time.Duration
+	n     uint64
+}
+
+// sometimesKind is the helper that returned a Sometimes.
+type sometimesKind uint8
+
+const (
+	kindEvery sometimesKind = iota
+	kindEveryN
+	kindFirstN
+)
+
+// sometimesKey identifies the state of a Sometimes.
+type sometimesKey struct {
+	pc   uintptr
+	key  string
+	kind sometimesKind
+}
+
+// sometimesState is the state of the Sometimes of a call site or a key.
+type sometimesState struct {
+	// last is the time of the last entry written by Every, in nanoseconds
+	last atomic.Int64
+	// count is the number of calls of EveryN and FirstN
+	count atomic.Uint64
+}
+
+// sometimesStates maps sometimesKey to *sometimesState. The states are
+// kept for the lifetime of the program, so keys should not be unbounded.
+var sometimesStates sync.Map
+
+// Every returns a Logger that writes at most one entry per period d.
+func Every(d time.Duration) Sometimes {
+	return Sometimes{pc: callerPC(), kind: kindEvery, every: d}
+}
+
+// EveryN returns a Logger that writes the first entry and then every n-th
+// entry.
+func EveryN(n int) Sometimes {
+	return Sometimes{pc: callerPC(), kind: kindEveryN, n: uint64(max(n, 1))}
+}
+
+// FirstN returns a Logger that writes the first n entries only.
+func FirstN(n int) Sometimes {
+	return Sometimes{pc: callerPC(), kind: kindFirstN, n: uint64(max(n, 0))}
+}
+
+// Once returns a Logger that writes the first entry only.
+func Once() Sometimes {
+	return Sometimes{pc: callerPC(), kind: kindFirstN, n: 1}
+}
+
+// callerPC returns the program counter of the call site of the function
+// calling callerPC.
+func callerPC() uintptr {
+	var pcs [1]uintptr
+	runtime.Callers(3, pcs[:])
+	return pcs[0]
+}
+
+// Key returns a copy of s whose state is kept per key instead of per call
+// site, so that several call sites can share it.
+//
+// Example:
+//
+//	logx.Every(time.Minute).Key("db-" + shard).Warn("replica lag")
+func (s Sometimes) Key(key string) Sometimes {
+	s.pc, s.key = 0, key
+	return s
+}
+
+// allow reports whether the entry of this call is written.
+func (s Sometimes) allow() bool {
+	k := sometimesKey{pc: s.pc, key: s.key, kind: s.kind}
+	v, ok := sometimesStates.Load(k)
+	if !ok {
+		v, _ = sometimesStates.LoadOrStore(k, &sometimesState{})
+	}
+	st := v.(*sometimesState)
+	switch s.kind {
+	case kindEvery:
+		now := time.Now().UnixNano()
+		last := st.last.Load()
+		if last != 0 && now-last < int64(s.every) {
+			return false
+		}
+		return st.last.CompareAndSwap(last, now)
+	case kindEveryN:
+		return (st.count.Add(1)-1)%s.n == 0
+	default:
+		// the count stops increasing once n entries are written
+		if st.count.Load() >= s.n {
+			return false
+		}
+		return st.count.Add(1) <= s.n
+	}
+}
+
+// log logs at level through the global logger if the entry is allowed.
+func (s Sometimes) log(level Level, template string, args []any) {
+	if globalLogger != nil && s.allow() {
+		logCaller(globalLogger, 1, context.Background(), level, template, args)
+	}
+}
+
+// Trace implements the Trace method of the Logger interface.
+func (s Sometimes) Trace(args ...any) { s.log(TraceLevel, "", args) }
+
+// Tracef implements the Tracef method of the Logger interface.
+func (s Sometimes) Tracef(template string, args ...any) { s.log(TraceLevel, template, args) }
+
+// Debug implements the Debug method of the Logger interface.
+func (s Sometimes) Debug(args ...any) { s.log(DebugLevel, "", args) }
+
+// Debugf implements the Debugf method of the Logger interface.
+func (s Sometimes) Debugf(template string, args ...any) { s.log(DebugLevel, template, args) }
+
+// Info implements the Info method of the Logger interface.
+func (s Sometimes) Info(args ...any) { s.log(InfoLevel, "", args) }
+
+// Infof implements the Infof method of the Logger interface.
+func (s Sometimes) Infof(template string, args ...any) { s.log(InfoLevel, template, args) }
+
+// Notice implements the Notice method of the Logger interface.
+func (s Sometimes) Notice(args ...any) { s.log(NoticeLevel, "", args) }
+
+// Noticef implements the Noticef method of the Logger interface.
+func (s Sometimes) Noticef(template string, args ...any) { s.log(NoticeLevel, template, args) }
+
+// Warn implements the Warn method of the Logger interface.
+func (s Sometimes) Warn(args ...any) { s.log(WarnLevel, "", args) }
+
+// Warnf implements the Warnf method of the Logger interface.
+func (s Sometimes) Warnf(template string, args ...any) { s.log(WarnLevel, template, args) }
+
+// Error implements the Error method of the Logger interface.
+func (s Sometimes) Error(args ...any) { s.log(ErrorLevel, "", args) }
+
+// Errorf implements the Errorf method of the Logger interface.
+func (s Sometimes) Errorf(template string, args ...any) { s.log(ErrorLevel, template, args) }
+
+// Critical implements the Critical method of the Logger interface.
+func (s Sometimes) Critical(args ...any) { s.log(CriticalLevel, "", args) }
+
+// Criticalf implements the Criticalf method of the Logger interface.
+func (s Sometimes) Criticalf(template string, args ...any) { s.log(CriticalLevel, template, args) }
+
+// Panic implements the Panic method of the Logger interface. Only the
+// entries written panic.
+func (s Sometimes) Panic(args ...any) { s.log(PanicLevel, "", args) }
+
+// Panicf implements the Panicf method of the Logger interface.
+func (s Sometimes) Panicf(template string, args ...any) { s.log(PanicLevel, template, args) }
+
+// Fatal implements the Fatal method of the Logger interface. Only the
+// entries written exit.
+func (s Sometimes) Fatal(args ...any) { s.log(FatalLevel, "", args) }
+
+// Fatalf implements the Fatalf method of the Logger interface.
+func (s Sometimes) Fatalf(template string, args ...any) { s.log(FatalLevel, template, args) }
+
+// Log implements the Log method of the Logger interface.
+func (s Sometimes) Log(ctx context.Context, level Level, msg string, keysAndValues ...any) {
+	if globalLogger != nil && s.allow() {
+		logCaller(globalLogger, 0, ctx, level, msg, nil, keysAndValues...)
+	}
+}
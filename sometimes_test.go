@@ -0,0 +1,86 @@
+package logx_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx"
+)
+
+// TestSometimes tests that the rate helpers write the expected number of entries per call site
+func TestSometimes(t *testing.T) {
+	logDir := t.TempDir()
+	if err := logx.Init(&logx.LoggerConfig{Level: "info", Dir: logDir, Format: "json"}); err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		logx.EveryN(3).Infof("every n %d", i)
+		logx.FirstN(2).Warn("first n")
+		logx.Once().Error("once")
+		logx.Every(time.Hour).Log(context.Background(), logx.InfoLevel, "every", "i", i)
+	}
+	// a second call site has its own state
+	logx.Once().Error("once")
+
+	content := readLogs(t, logDir)
+	testCases := map[string]int{
+		`"msg":"every n `: 4,
+		`"msg":"first n"`: 2,
+		`"msg":"once"`:    2,
+		`"msg":"every"`:   1,
+	}
+	for msg, want := range testCases {
+		if got := strings.Count(content, msg); got != want {
+			t.Errorf("expected %d entries %s, got %d", want, msg, got)
+		}
+	}
+	for _, want := range []string{"every n 0", "every n 3", "every n 6", "every n 9"} {
+		if !strings.Contains(content, want) {
+			t.Errorf("expected %s in output, got %q", want, content)
+		}
+	}
+}
+
+// TestSometimesKey tests that call sites with the same key share their state
+func TestSometimesKey(t *testing.T) {
+	logDir := t.TempDir()
+	if err := logx.Init(&logx.LoggerConfig{Level: "info", Dir: logDir, Format: "json"}); err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+
+	logx.Once().Key("shared-key-test").Info("keyed")
+	logx.Once().Key("shared-key-test").Info("keyed")
+	logx.Once().Key("other-key-test").Info("keyed")
+
+	if got := strings.Count(readLogs(t, logDir), `"msg":"keyed"`); got != 2 {
+		t.Errorf("expected 2 keyed entries, got %d", got)
+	}
+}
+
+// TestSometimesConcurrent tests that FirstN writes exactly n entries under concurrent calls
+func TestSometimesConcurrent(t *testing.T) {
+	logDir := t.TempDir()
+	if err := logx.Init(&logx.LoggerConfig{Type: logx.LoggerTypeSlog, Level: "info", Dir: logDir, Format: "json"}); err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				logx.FirstN(10).Key("concurrent-test").Info("concurrent")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := strings.Count(readLogs(t, logDir), `"msg":"concurrent"`); got != 10 {
+		t.Errorf("expected 10 entries, got %d", got)
+	}
+}
@@ -0,0 +1,109 @@
+package logx
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// globalSlowThreshold is LoggerConfig.SlowThreshold, in nanoseconds.
+var globalSlowThreshold atomic.Int64
+
+type spanKey struct{}
+
+// Span times an operation started with Start, and logs its duration and
+// outcome when End is called. It is safe for concurrent use.
+type Span struct {
+	ctx           context.Context
+	name          string
+	path          string
+	start         time.Time
+	keysAndValues []any
+	threshold     time.Duration
+	ended         atomic.Bool
+}
+
+// Start starts timing the operation name, and returns its Span. The span
+// of an operation started with the context returned by Span.Context is
+// nested: its path is the path of its parent followed by its name, as in
+// "checkout/charge".
+//
+// Example:
+//
+//	func Checkout(ctx context.Context) (err error) {
+//	    span := logx.Start(ctx, "checkout", "cart", cartID)
+//	    defer func() { span.End(err) }()
+//	    return charge(span.Context())
+//	}
+func Start(ctx context.Context, name string, keysAndValues ...any) *Span {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	s := &Span{
+		name:          name,
+		path:          name,
+		start:         time.Now(),
+		keysAndValues: keysAndValues,
+		threshold:     time.Duration(globalSlowThreshold.Load()),
+	}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok {
+		s.path = parent.path + "/" + name
+	}
+	s.ctx = context.WithValue(ctx, spanKey{}, s)
+	return s
+}
+
+// Timed starts timing the operation name, and returns a function that logs
+// its duration when called.
+//
+// Example:
+//
+//	defer logx.Timed("rebuild index")()
+func Timed(name string, keysAndValues ...any) func() {
+	s := Start(context.Background(), name, keysAndValues...)
+	return func() { s.end(1, nil) }
+}
+
+// Context returns the context of the span, which carries the fields of the
+// context it was started with. The spans started with it are nested.
+func (s *Span) Context() context.Context {
+	return s.ctx
+}
+
+// Threshold sets the duration above which End logs at warn level, in place
+// of LoggerConfig.SlowThreshold (0 disables), and returns s.
+func (s *Span) Threshold(d time.Duration) *Span {
+	s.threshold = d
+	return s
+}
+
+// End logs the operation with the key-value pairs given to Start, its path,
+// its duration and its outcome: at error level with the error if err is not
+// nil, at warn level if the duration is above the threshold, and at info
+// level otherwise. Only the first call logs.
+func (s *Span) End(err error) {
+	s.end(1, err)
+}
+
+// end ends the span called skip frames above the caller of end.
+func (s *Span) end(skip int, err error) {
+	if !s.ended.CompareAndSwap(false, true) || globalLogger == nil {
+		return
+	}
+	d := time.Since(s.start)
+	level := InfoLevel
+	kv := make([]any, 0, len(s.keysAndValues)+8)
+	kv = append(kv, s.keysAndValues...)
+	kv = append(kv, "path", s.path, "duration", d)
+	switch {
+	case err != nil:
+		level = ErrorLevel
+		kv = append(kv, "outcome", "error", "error", err.Error())
+	case s.threshold > 0 && d > s.threshold:
+		level = WarnLevel
+		kv = append(kv, "outcome", "slow", "threshold", s.threshold)
+	default:
+		kv = append(kv, "outcome", "ok")
+	}
+	logCaller(globalLogger, skip, s.ctx, level, s.name, nil, kv...)
+}
@@ -0,0 +1,110 @@
+package logx_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx"
+)
+
+// TestSpan tests that both backends log the path, duration and outcome of nested spans
+func TestSpan(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:           typ,
+				Level:          "info",
+				Dir:            logDir,
+				Format:         "json",
+				DurationFormat: "millis",
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			ctx := logx.NewContext(context.Background(), "request_id", "r1")
+			parent := logx.Start(ctx, "checkout", "cart", 7)
+			child := logx.Start(parent.Context(), "charge")
+			child.End(errors.New("card declined"))
+			slow := logx.Start(parent.Context(), "reserve").Threshold(time.Nanosecond)
+			time.Sleep(time.Millisecond)
+			slow.End(nil)
+			parent.End(nil)
+			parent.End(errors.New("ignored"))
+
+			content := readLogs(t, logDir)
+			for _, want := range []string{
+				`"msg":"charge"`, `"path":"checkout/charge"`, `"outcome":"error"`, `"error":"card declined"`,
+				`"path":"checkout/reserve"`, `"outcome":"slow"`,
+				`"msg":"checkout"`, `"cart":7`, `"path":"checkout"`, `"outcome":"ok"`, `"request_id":"r1"`,
+			} {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %s in output, got %q", want, content)
+				}
+			}
+			if strings.Contains(content, "ignored") {
+				t.Errorf("expected a single entry per span, got %q", content)
+			}
+			for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+				if strings.Contains(line, `"path":"checkout/reserve"`) && !strings.Contains(strings.ToLower(line), `"level":"warn"`) {
+					t.Errorf("expected the slow span at warn level, got %q", line)
+				}
+			}
+		})
+	}
+}
+
+// TestTimed tests that the function returned by Timed logs the duration with the configured encoding
+func TestTimed(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:           typ,
+				Level:          "info",
+				Dir:            logDir,
+				Format:         "json",
+				DurationFormat: "string",
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			func() {
+				defer logx.Timed("rebuild")()
+			}()
+			logx.Log(context.Background(), logx.InfoLevel, "fixed", "elapsed", 1500*time.Millisecond)
+
+			content := readLogs(t, logDir)
+			for _, want := range []string{`"msg":"rebuild"`, `"outcome":"ok"`, `"elapsed":"1.5s"`} {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %s in output, got %q", want, content)
+				}
+			}
+		})
+	}
+}
+
+// TestDurationFormat tests that both backends encode durations as seconds by default, and that unsupported formats are rejected
+func TestDurationFormat(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			if err := logx.Init(&logx.LoggerConfig{Type: typ, Level: "info", Dir: logDir, Format: "json"}); err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+			logx.Log(context.Background(), logx.InfoLevel, "fixed", "elapsed", 1500*time.Millisecond)
+			if content := readLogs(t, logDir); !strings.Contains(content, `"elapsed":1.5`) {
+				t.Errorf("expected the duration in seconds, got %q", content)
+			}
+		})
+	}
+
+	if err := logx.Init(&logx.LoggerConfig{Dir: t.TempDir(), DurationFormat: "minutes"}); err == nil {
+		t.Error("expected an error for an unsupported duration format")
+	}
+}
@@ -0,0 +1,317 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"time"
+)
+
+// loggingDriver wraps a driver.Driver.
+type loggingDriver struct {
+	parent driver.Driver
+	log    *opLogger
+}
+
+// Open implements the driver.Driver interface.
+func (d *loggingDriver) Open(name string) (driver.Conn, error) {
+	c, err := d.parent.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &loggingConn{parent: c, log: d.log}, nil
+}
+
+// OpenConnector implements the driver.DriverContext interface.
+func (d *loggingDriver) OpenConnector(name string) (driver.Connector, error) {
+	if dc, ok := d.parent.(driver.DriverContext); ok {
+		c, err := dc.OpenConnector(name)
+		if err != nil {
+			return nil, err
+		}
+		return &loggingConnector{parent: c, driver: d, log: d.log}, nil
+	}
+	return &dsnConnector{dsn: name, driver: d}, nil
+}
+
+// dsnConnector is a connector for drivers that do not implement
+// driver.DriverContext.
+type dsnConnector struct {
+	dsn    string
+	driver *loggingDriver
+}
+
+// Connect implements the driver.Connector interface.
+func (c *dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+// Driver implements the driver.Connector interface.
+func (c *dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// loggingConnector wraps a driver.Connector.
+type loggingConnector struct {
+	parent driver.Connector
+	driver *loggingDriver
+	log    *opLogger
+}
+
+// Connect implements the driver.Connector interface.
+func (c *loggingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.parent.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &loggingConn{parent: conn, log: c.log}, nil
+}
+
+// Driver implements the driver.Connector interface.
+func (c *loggingConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Close closes the wrapped connector if it implements io.Closer. sql.DB
+// calls it when the database is closed.
+func (c *loggingConnector) Close() error {
+	if cl, ok := c.parent.(io.Closer); ok {
+		return cl.Close()
+	}
+	return nil
+}
+
+// loggingConn wraps a driver.Conn. It implements every optional interface
+// and falls back to driver.ErrSkip or the documented default when the
+// wrapped connection does not.
+type loggingConn struct {
+	parent driver.Conn
+	log    *opLogger
+}
+
+// Prepare implements the driver.Conn interface.
+func (c *loggingConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext implements the driver.ConnPrepareContext interface.
+func (c *loggingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := time.Now()
+	var stmt driver.Stmt
+	var err error
+	if pc, ok := c.parent.(driver.ConnPrepareContext); ok {
+		stmt, err = pc.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.parent.Prepare(query)
+	}
+	c.log.log(ctx, OpPrepare, query, nil, start, nil, err)
+	if err != nil {
+		return nil, err
+	}
+	return &loggingStmt{parent: stmt, conn: c.parent, query: query, log: c.log}, nil
+}
+
+// Close implements the driver.Conn interface.
+func (c *loggingConn) Close() error {
+	return c.parent.Close()
+}
+
+// Begin implements the driver.Conn interface.
+func (c *loggingConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements the driver.ConnBeginTx interface.
+func (c *loggingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+	var tx driver.Tx
+	var err error
+	if bt, ok := c.parent.(driver.ConnBeginTx); ok {
+		tx, err = bt.BeginTx(ctx, opts)
+	} else if opts.Isolation != 0 || opts.ReadOnly {
+		err = errors.New("sqlx: driver does not support non-default transaction options")
+	} else {
+		tx, err = c.parent.Begin()
+	}
+	c.log.log(ctx, OpBegin, "", nil, start, nil, err)
+	if err != nil {
+		return nil, err
+	}
+	return &loggingTx{parent: tx, ctx: ctx, log: c.log}, nil
+}
+
+// ExecContext implements the driver.ExecerContext interface.
+func (c *loggingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	switch ec := c.parent.(type) {
+	case driver.ExecerContext:
+		res, err = ec.ExecContext(ctx, query, args)
+	case driver.Execer:
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			res, err = ec.Exec(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	c.log.log(ctx, OpExec, query, args, start, res, err)
+	return res, err
+}
+
+// QueryContext implements the driver.QueryerContext interface.
+func (c *loggingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	switch qc := c.parent.(type) {
+	case driver.QueryerContext:
+		rows, err = qc.QueryContext(ctx, query, args)
+	case driver.Queryer:
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = qc.Query(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	c.log.log(ctx, OpQuery, query, args, start, nil, err)
+	return rows, err
+}
+
+// Ping implements the driver.Pinger interface.
+func (c *loggingConn) Ping(ctx context.Context) error {
+	p, ok := c.parent.(driver.Pinger)
+	if !ok {
+		return nil
+	}
+	start := time.Now()
+	err := p.Ping(ctx)
+	c.log.log(ctx, OpPing, "", nil, start, nil, err)
+	return err
+}
+
+// ResetSession implements the driver.SessionResetter interface.
+func (c *loggingConn) ResetSession(ctx context.Context) error {
+	if sr, ok := c.parent.(driver.SessionResetter); ok {
+		return sr.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid implements the driver.Validator interface.
+func (c *loggingConn) IsValid() bool {
+	if v, ok := c.parent.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue implements the driver.NamedValueChecker interface.
+func (c *loggingConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := c.parent.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// loggingStmt wraps a driver.Stmt.
+type loggingStmt struct {
+	parent driver.Stmt
+	conn   driver.Conn
+	query  string
+	log    *opLogger
+}
+
+// Close implements the driver.Stmt interface.
+func (s *loggingStmt) Close() error {
+	return s.parent.Close()
+}
+
+// NumInput implements the driver.Stmt interface.
+func (s *loggingStmt) NumInput() int {
+	return s.parent.NumInput()
+}
+
+// Exec implements the driver.Stmt interface.
+func (s *loggingStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), valuesToNamedValues(args))
+}
+
+// Query implements the driver.Stmt interface.
+func (s *loggingStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), valuesToNamedValues(args))
+}
+
+// ExecContext implements the driver.StmtExecContext interface.
+func (s *loggingStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if ec, ok := s.parent.(driver.StmtExecContext); ok {
+		res, err = ec.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			res, err = s.parent.Exec(values)
+		}
+	}
+	s.log.log(ctx, OpExec, s.query, args, start, res, err)
+	return res, err
+}
+
+// QueryContext implements the driver.StmtQueryContext interface.
+func (s *loggingStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if qc, ok := s.parent.(driver.StmtQueryContext); ok {
+		rows, err = qc.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = namedValuesToValues(args); err == nil {
+			rows, err = s.parent.Query(values)
+		}
+	}
+	s.log.log(ctx, OpQuery, s.query, args, start, nil, err)
+	return rows, err
+}
+
+// CheckNamedValue implements the driver.NamedValueChecker interface.
+// database/sql only asks the connection when the statement does not
+// implement the interface, so the connection's checker is consulted here.
+func (s *loggingStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if nc, ok := s.parent.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	if nc, ok := s.conn.(driver.NamedValueChecker); ok {
+		return nc.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// loggingTx wraps a driver.Tx. It keeps the context of BeginTx so that
+// commit and rollback entries carry the request's fields.
+type loggingTx struct {
+	parent driver.Tx
+	ctx    context.Context
+	log    *opLogger
+}
+
+// Commit implements the driver.Tx interface.
+func (t *loggingTx) Commit() error {
+	start := time.Now()
+	err := t.parent.Commit()
+	t.log.log(t.ctx, OpCommit, "", nil, start, nil, err)
+	return err
+}
+
+// Rollback implements the driver.Tx interface.
+func (t *loggingTx) Rollback() error {
+	start := time.Now()
+	err := t.parent.Rollback()
+	t.log.log(t.ctx, OpRollback, "", nil, start, nil, err)
+	return err
+}
@@ -0,0 +1,203 @@
+// Package sqlx wraps database/sql drivers so that queries, exec calls and
+// transactions are logged through logx with their duration, rows affected
+// and errors.
+//
+// Entries are written with the logx context logger, so the fields attached to
+// the request context with logx.NewContext are added to every query log.
+//
+// Example usage:
+//
+//	connector, _ := pq.NewConnector(dsn)
+//	db := sql.OpenDB(sqlx.WrapConnector(connector, sqlx.Options{
+//	    Levels:        map[sqlx.Op]logx.Level{sqlx.OpQuery: logx.DebugLevel},
+//	    SlowThreshold: 200 * time.Millisecond,
+//	    MaxArgLength:  64,
+//	}))
+package sqlx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go4x/logx"
+)
+
+// Op identifies a database operation.
+type Op string
+
+const (
+	// OpPing is a connection health check.
+	OpPing Op = "ping"
+	// OpPrepare is the preparation of a statement.
+	OpPrepare Op = "prepare"
+	// OpQuery is a query returning rows.
+	OpQuery Op = "query"
+	// OpExec is a statement that does not return rows.
+	OpExec Op = "exec"
+	// OpBegin is the start of a transaction.
+	OpBegin Op = "begin"
+	// OpCommit is the commit of a transaction.
+	OpCommit Op = "commit"
+	// OpRollback is the rollback of a transaction.
+	OpRollback Op = "rollback"
+)
+
+// Options configures how database operations are logged.
+type Options struct {
+	// Logger receives the entries. If nil, the global logx logger is used.
+	Logger logx.Logger
+
+	// Levels sets the level of each operation. Operations that are not
+	// listed are logged at info. Failed operations are always logged at error.
+	Levels map[Op]logx.Level
+
+	// SlowThreshold raises operations that take at least this long to warn
+	// and marks them with slow=true (0 disables slow detection).
+	SlowThreshold time.Duration
+
+	// RedactArgs replaces every argument value with "?".
+	RedactArgs bool
+
+	// MaxArgLength truncates rendered argument values longer than this
+	// many bytes (0 means no limit).
+	MaxArgLength int
+}
+
+// Wrap returns a driver that logs the operations of the connections opened
+// by d.
+func Wrap(d driver.Driver, opts Options) driver.Driver {
+	return &loggingDriver{parent: d, log: &opLogger{opts: opts}}
+}
+
+// WrapConnector returns a connector that logs the operations of the
+// connections created by c. The result can be passed to sql.OpenDB.
+func WrapConnector(c driver.Connector, opts Options) driver.Connector {
+	l := &opLogger{opts: opts}
+	return &loggingConnector{parent: c, driver: &loggingDriver{parent: c.Driver(), log: l}, log: l}
+}
+
+// opLogger writes one entry per database operation.
+type opLogger struct {
+	opts Options
+}
+
+// log writes the entry for op. driver.ErrSkip is not logged because
+// database/sql retries the operation through another code path that is.
+func (l *opLogger) log(ctx context.Context, op Op, query string, args []driver.NamedValue, start time.Time, result driver.Result, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	duration := time.Since(start)
+
+	level, ok := l.opts.Levels[op]
+	if !ok {
+		level = logx.InfoLevel
+	}
+	slow := l.opts.SlowThreshold > 0 && duration >= l.opts.SlowThreshold
+	if slow && level < logx.WarnLevel {
+		level = logx.WarnLevel
+	}
+	if err != nil {
+		level = logx.ErrorLevel
+	}
+
+	fields := make([]any, 0, 12)
+	if query != "" {
+		fields = append(fields, "query", query)
+	}
+	if len(args) > 0 {
+		fields = append(fields, "args", l.formatArgs(args))
+	}
+	fields = append(fields, "duration", duration)
+	if result != nil && err == nil {
+		if n, rerr := result.RowsAffected(); rerr == nil {
+			fields = append(fields, "rows_affected", n)
+		}
+	}
+	if slow {
+		fields = append(fields, "slow", true)
+	}
+	if err != nil {
+		fields = append(fields, "error", err.Error())
+	}
+
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	msg := "sql " + string(op)
+	if l.opts.Logger != nil {
+		l.opts.Logger.Log(ctx, level, msg, fields...)
+		return
+	}
+	logx.Log(ctx, level, msg, fields...)
+}
+
+// formatArgs renders argument values for logging, applying redaction and
+// truncation.
+func (l *opLogger) formatArgs(args []driver.NamedValue) []string {
+	out := make([]string, len(args))
+	for i, arg := range args {
+		if l.opts.RedactArgs {
+			out[i] = "?"
+			continue
+		}
+		out[i] = truncate(formatValue(arg.Value), l.opts.MaxArgLength)
+	}
+	return out
+}
+
+// formatValue renders a driver.Value.
+func formatValue(v driver.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return v
+	case []byte:
+		return fmt.Sprintf("[%d bytes]", len(v))
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// truncate shortens s to at most max bytes without splitting a rune and
+// marks the cut with "...".
+func truncate(s string, max int) string {
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "..."
+}
+
+// namedValuesToValues converts named arguments for the pre-context driver
+// interfaces, which do not support names.
+func namedValuesToValues(named []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(named))
+	for i, nv := range named {
+		if nv.Name != "" {
+			return nil, errors.New("sqlx: driver does not support the use of named parameters")
+		}
+		values[i] = nv.Value
+	}
+	return values, nil
+}
+
+// valuesToNamedValues converts positional arguments to named arguments for
+// logging.
+func valuesToNamedValues(values []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(values))
+	for i, v := range values {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
@@ -0,0 +1,386 @@
+package sqlx_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go4x/logx"
+	"github.com/go4x/logx/sqlx"
+)
+
+// entry is a log entry captured by recordingLogger.
+type entry struct {
+	level  logx.Level
+	msg    string
+	fields map[string]any
+}
+
+// recordingLogger is a logx.Logger that keeps the entries written with Log.
+type recordingLogger struct {
+	mu      sync.Mutex
+	entries []entry
+}
+
+func (l *recordingLogger) Trace(args ...any)                      {}
+func (l *recordingLogger) Tracef(template string, args ...any)    {}
+func (l *recordingLogger) Debug(args ...any)                      {}
+func (l *recordingLogger) Debugf(template string, args ...any)    {}
+func (l *recordingLogger) Info(args ...any)                       {}
+func (l *recordingLogger) Infof(template string, args ...any)     {}
+func (l *recordingLogger) Notice(args ...any)                     {}
+func (l *recordingLogger) Noticef(template string, args ...any)   {}
+func (l *recordingLogger) Warn(args ...any)                       {}
+func (l *recordingLogger) Warnf(template string, args ...any)     {}
+func (l *recordingLogger) Error(args ...any)                      {}
+func (l *recordingLogger) Errorf(template string, args ...any)    {}
+func (l *recordingLogger) Critical(args ...any)                   {}
+func (l *recordingLogger) Criticalf(template string, args ...any) {}
+func (l *recordingLogger) Panic(args ...any)                      {}
+func (l *recordingLogger) Panicf(template string, args ...any)    {}
+func (l *recordingLogger) Fatal(args ...any)                      {}
+func (l *recordingLogger) Fatalf(template string, args ...any)    {}
+
+func (l *recordingLogger) Log(ctx context.Context, level logx.Level, msg string, keysAndValues ...any) {
+	fields := make(map[string]any)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		fields[keysAndValues[i].(string)] = keysAndValues[i+1]
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, entry{level: level, msg: msg, fields: fields})
+}
+
+// find returns the last entry with the given message.
+func (l *recordingLogger) find(t *testing.T, msg string) entry {
+	t.Helper()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i := len(l.entries) - 1; i >= 0; i-- {
+		if l.entries[i].msg == msg {
+			return l.entries[i]
+		}
+	}
+	t.Fatalf("no %q entry in %v", msg, l.entries)
+	return entry{}
+}
+
+// fakeConnector opens fakeConns. Queries containing "fail" return an error
+// and queries containing "slow" sleep for 20ms.
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return &fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{query: query}, nil
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := run(query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(args)), nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := run(query); err != nil {
+		return nil, err
+	}
+	return &fakeRows{values: []string{"a", "b"}}, nil
+}
+
+func run(query string) error {
+	if strings.Contains(query, "slow") {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if strings.Contains(query, "fail") {
+		return errors.New("fake failure")
+	}
+	return nil
+}
+
+type fakeStmt struct{ query string }
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	if err := run(s.query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(args)), nil
+}
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if err := run(s.query); err != nil {
+		return nil, err
+	}
+	return &fakeRows{values: []string{"a"}}, nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct {
+	values []string
+	i      int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"name"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.i >= len(r.values) {
+		return io.EOF
+	}
+	dest[0] = r.values[r.i]
+	r.i++
+	return nil
+}
+
+func openDB(t *testing.T, opts sqlx.Options) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(sqlx.WrapConnector(fakeConnector{}, opts))
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestExecLogsRowsAffected tests that exec calls are logged with rows affected and duration
+func TestExecLogsRowsAffected(t *testing.T) {
+	rec := &recordingLogger{}
+	db := openDB(t, sqlx.Options{Logger: rec})
+
+	if _, err := db.Exec("UPDATE users SET name = ? WHERE id = ?", "bob", 7); err != nil {
+		t.Fatalf("exec failed: %v", err)
+	}
+
+	e := rec.find(t, "sql exec")
+	if e.level != logx.InfoLevel {
+		t.Errorf("expected info level, got %v", e.level)
+	}
+	if e.fields["query"] != "UPDATE users SET name = ? WHERE id = ?" {
+		t.Errorf("unexpected query field: %v", e.fields["query"])
+	}
+	if e.fields["rows_affected"] != int64(2) {
+		t.Errorf("expected rows_affected 2, got %v", e.fields["rows_affected"])
+	}
+	if _, ok := e.fields["duration"].(time.Duration); !ok {
+		t.Errorf("expected duration field, got %v", e.fields["duration"])
+	}
+	if args, _ := e.fields["args"].([]string); strings.Join(args, ",") != "bob,7" {
+		t.Errorf("unexpected args field: %v", e.fields["args"])
+	}
+}
+
+// TestQueryLevelsPerOperation tests that each operation uses its configured level
+func TestQueryLevelsPerOperation(t *testing.T) {
+	rec := &recordingLogger{}
+	db := openDB(t, sqlx.Options{
+		Logger: rec,
+		Levels: map[sqlx.Op]logx.Level{
+			sqlx.OpQuery:  logx.DebugLevel,
+			sqlx.OpBegin:  logx.WarnLevel,
+			sqlx.OpCommit: logx.WarnLevel,
+		},
+	})
+
+	rows, err := db.Query("SELECT name FROM users")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	rows.Close()
+	if e := rec.find(t, "sql query"); e.level != logx.DebugLevel {
+		t.Errorf("expected debug level for query, got %v", e.level)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin failed: %v", err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if e := rec.find(t, "sql begin"); e.level != logx.WarnLevel {
+		t.Errorf("expected warn level for begin, got %v", e.level)
+	}
+	if e := rec.find(t, "sql commit"); e.level != logx.WarnLevel {
+		t.Errorf("expected warn level for commit, got %v", e.level)
+	}
+}
+
+// TestSlowQueryLoggedAtWarn tests that slow operations are raised to warn
+func TestSlowQueryLoggedAtWarn(t *testing.T) {
+	rec := &recordingLogger{}
+	db := openDB(t, sqlx.Options{
+		Logger:        rec,
+		Levels:        map[sqlx.Op]logx.Level{sqlx.OpQuery: logx.DebugLevel},
+		SlowThreshold: 10 * time.Millisecond,
+	})
+
+	rows, err := db.Query("SELECT slow")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	rows.Close()
+
+	e := rec.find(t, "sql query")
+	if e.level != logx.WarnLevel {
+		t.Errorf("expected warn level, got %v", e.level)
+	}
+	if e.fields["slow"] != true {
+		t.Errorf("expected slow=true, got %v", e.fields["slow"])
+	}
+}
+
+// TestFailedExecLoggedAtError tests that errors are logged at error level
+func TestFailedExecLoggedAtError(t *testing.T) {
+	rec := &recordingLogger{}
+	db := openDB(t, sqlx.Options{Logger: rec})
+
+	if _, err := db.Exec("DELETE fail"); err == nil {
+		t.Fatal("expected exec to fail")
+	}
+
+	e := rec.find(t, "sql exec")
+	if e.level != logx.ErrorLevel {
+		t.Errorf("expected error level, got %v", e.level)
+	}
+	if e.fields["error"] != "fake failure" {
+		t.Errorf("unexpected error field: %v", e.fields["error"])
+	}
+	if _, ok := e.fields["rows_affected"]; ok {
+		t.Error("rows_affected should not be logged for failed exec")
+	}
+}
+
+// TestArgsRedactedAndTruncated tests argument redaction and truncation
+func TestArgsRedactedAndTruncated(t *testing.T) {
+	t.Run("Redact", func(t *testing.T) {
+		rec := &recordingLogger{}
+		db := openDB(t, sqlx.Options{Logger: rec, RedactArgs: true})
+		if _, err := db.Exec("INSERT INTO users VALUES (?, ?)", "secret", []byte("pw")); err != nil {
+			t.Fatalf("exec failed: %v", err)
+		}
+		args, _ := rec.find(t, "sql exec").fields["args"].([]string)
+		if strings.Join(args, ",") != "?,?" {
+			t.Errorf("expected redacted args, got %v", args)
+		}
+	})
+
+	t.Run("Truncate", func(t *testing.T) {
+		rec := &recordingLogger{}
+		db := openDB(t, sqlx.Options{Logger: rec, MaxArgLength: 4})
+		if _, err := db.Exec("INSERT INTO users VALUES (?, ?, ?)", "abcdefgh", "héllo", nil); err != nil {
+			t.Fatalf("exec failed: %v", err)
+		}
+		args, _ := rec.find(t, "sql exec").fields["args"].([]string)
+		if strings.Join(args, ",") != "abcd...,hél...,NULL" {
+			t.Errorf("expected truncated args, got %v", args)
+		}
+	})
+}
+
+// TestPreparedStatementLogged tests that prepared statement calls are logged
+func TestPreparedStatementLogged(t *testing.T) {
+	rec := &recordingLogger{}
+	db := sql.OpenDB(mustConnector(t, sqlx.Wrap(fakeDriver{}, sqlx.Options{Logger: rec})))
+	defer db.Close()
+
+	stmt, err := db.Prepare("INSERT INTO users VALUES (?)")
+	if err != nil {
+		t.Fatalf("prepare failed: %v", err)
+	}
+	defer stmt.Close()
+	if _, err := stmt.Exec("alice"); err != nil {
+		t.Fatalf("exec failed: %v", err)
+	}
+
+	rec.find(t, "sql prepare")
+	e := rec.find(t, "sql exec")
+	if e.fields["query"] != "INSERT INTO users VALUES (?)" {
+		t.Errorf("unexpected query field: %v", e.fields["query"])
+	}
+	if e.fields["rows_affected"] != int64(1) {
+		t.Errorf("expected rows_affected 1, got %v", e.fields["rows_affected"])
+	}
+}
+
+func mustConnector(t *testing.T, d driver.Driver) driver.Connector {
+	t.Helper()
+	c, err := d.(driver.DriverContext).OpenConnector("")
+	if err != nil {
+		t.Fatalf("failed to open connector: %v", err)
+	}
+	return c
+}
+
+// TestQueryCarriesContextFields tests that query logs carry the request's context fields
+func TestQueryCarriesContextFields(t *testing.T) {
+	logDir := t.TempDir()
+	err := logx.Init(&logx.LoggerConfig{
+		Type:   logx.LoggerTypeZap,
+		Level:  "debug",
+		Dir:    logDir,
+		Format: "json",
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+
+	db := openDB(t, sqlx.Options{})
+	ctx := logx.NewContext(context.Background(), "request_id", "req-42")
+	rows, err := db.QueryContext(ctx, "SELECT name FROM users")
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	rows.Close()
+
+	files, _ := filepath.Glob(filepath.Join(logDir, "*.log"))
+	var content string
+	for _, f := range files {
+		data, _ := os.ReadFile(f)
+		content += string(data)
+	}
+	if !strings.Contains(content, `"msg":"sql query"`) || !strings.Contains(content, `"request_id":"req-42"`) {
+		t.Errorf("expected query log with request_id, got %q", content)
+	}
+}
+
+// closingConnector records whether it was closed.
+type closingConnector struct {
+	fakeConnector
+	closed bool
+}
+
+func (c *closingConnector) Close() error {
+	c.closed = true
+	return nil
+}
+
+// TestCloseClosesConnector tests that closing the database closes the wrapped connector
+func TestCloseClosesConnector(t *testing.T) {
+	c := &closingConnector{}
+	db := sql.OpenDB(sqlx.WrapConnector(c, sqlx.Options{Logger: &recordingLogger{}}))
+	if err := db.Close(); err != nil {
+		t.Fatalf("failed to close database: %v", err)
+	}
+	if !c.closed {
+		t.Error("the wrapped connector should be closed with the database")
+	}
+}
@@ -0,0 +1,244 @@
+// Package trace correlates log entries with distributed traces.
+//
+// When a context carries an OpenTelemetry span, or a span context parsed from
+// a W3C traceparent header, the logx context-aware API adds the trace id, span
+// id and trace flags to every entry. The field names and the id format can be
+// configured to match what a log vendor expects.
+package trace
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// TraceparentHeader is the name of the W3C trace context header.
+const TraceparentHeader = "traceparent"
+
+const (
+	// FormatW3C renders ids as lower-case hex, as in the traceparent header.
+	FormatW3C = "w3c"
+	// FormatDatadog renders the trace id as the decimal value of its lower
+	// 64 bits and the span id as a decimal number.
+	FormatDatadog = "datadog"
+	// FormatGoogleCloud renders the trace id as
+	// projects/<ProjectID>/traces/<hex> and the trace flags as a sampled bool.
+	FormatGoogleCloud = "gcp"
+)
+
+// SpanContext identifies a span within a trace.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+// IsValid reports whether both the trace id and the span id are non-zero.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// IsSampled reports whether the sampled flag is set.
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&0x01 != 0
+}
+
+// Traceparent renders the span context as a version 00 traceparent header value.
+func (sc SpanContext) Traceparent() string {
+	return "00-" + hex.EncodeToString(sc.TraceID[:]) + "-" + hex.EncodeToString(sc.SpanID[:]) + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+// ParseTraceparent parses a W3C traceparent header value such as
+// 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+func ParseTraceparent(h string) (SpanContext, error) {
+	var sc SpanContext
+	if len(h) < 55 || h[2] != '-' || h[35] != '-' || h[52] != '-' {
+		return sc, errors.New("trace: malformed traceparent")
+	}
+	version, err := decodeHex(h[0:2], 1)
+	if err != nil || version[0] == 0xff {
+		return sc, errors.New("trace: invalid traceparent version")
+	}
+	// Version 00 has a fixed length; later versions may append fields.
+	if version[0] == 0 && len(h) != 55 || len(h) > 55 && h[55] != '-' {
+		return sc, errors.New("trace: malformed traceparent")
+	}
+	traceID, err := decodeHex(h[3:35], 16)
+	if err != nil {
+		return sc, errors.New("trace: invalid trace id")
+	}
+	spanID, err := decodeHex(h[36:52], 8)
+	if err != nil {
+		return sc, errors.New("trace: invalid span id")
+	}
+	flags, err := decodeHex(h[53:55], 1)
+	if err != nil {
+		return sc, errors.New("trace: invalid trace flags")
+	}
+	copy(sc.TraceID[:], traceID)
+	copy(sc.SpanID[:], spanID)
+	sc.Flags = flags[0]
+	if !sc.IsValid() {
+		return SpanContext{}, errors.New("trace: all-zero trace id or span id")
+	}
+	return sc, nil
+}
+
+// decodeHex decodes lower-case hex, which is the only case allowed by the
+// trace context specification.
+func decodeHex(s string, n int) ([]byte, error) {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return nil, errors.New("trace: invalid hex")
+		}
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil || len(b) != n {
+		return nil, errors.New("trace: invalid hex")
+	}
+	return b, nil
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx that carries sc. It lets
+// services without an OpenTelemetry SDK correlate logs with incoming traces.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// ContextWithTraceparent parses a traceparent header value and returns a copy
+// of ctx that carries it. ctx is returned unchanged if the value is invalid.
+func ContextWithTraceparent(ctx context.Context, traceparent string) context.Context {
+	sc, err := ParseTraceparent(traceparent)
+	if err != nil {
+		return ctx
+	}
+	return ContextWithSpanContext(ctx, sc)
+}
+
+// SpanContextFromContext returns the span context carried by ctx. An active
+// OpenTelemetry span takes precedence over a span context stored with
+// ContextWithSpanContext.
+func SpanContextFromContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	if otelSC := oteltrace.SpanContextFromContext(ctx); otelSC.IsValid() {
+		return SpanContext{
+			TraceID: otelSC.TraceID(),
+			SpanID:  otelSC.SpanID(),
+			Flags:   byte(otelSC.TraceFlags()),
+		}, true
+	}
+	sc, ok := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// Middleware stores the span context of the traceparent header of each
+// request in the request context.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if h := r.Header.Get(TraceparentHeader); h != "" {
+			r = r.WithContext(ContextWithTraceparent(r.Context(), h))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Config selects the fields that carry trace correlation.
+type Config struct {
+	// Disabled turns trace correlation off.
+	Disabled bool `mapstructure:"disabled" yaml:"disabled"`
+
+	// TraceIDKey is the field name of the trace id (default "trace_id").
+	TraceIDKey string `mapstructure:"trace-id-key" yaml:"trace-id-key"`
+
+	// SpanIDKey is the field name of the span id (default "span_id").
+	SpanIDKey string `mapstructure:"span-id-key" yaml:"span-id-key"`
+
+	// TraceFlagsKey is the field name of the trace flags (default
+	// "trace_flags"). Use "-" to omit the field.
+	TraceFlagsKey string `mapstructure:"trace-flags-key" yaml:"trace-flags-key"`
+
+	// Format selects how ids are rendered (w3c, datadog or gcp, default w3c).
+	Format string `mapstructure:"format" yaml:"format"`
+
+	// ProjectID is the Google Cloud project used by the gcp format.
+	ProjectID string `mapstructure:"project-id" yaml:"project-id"`
+}
+
+// DatadogConfig returns a Config using Datadog's reserved attributes.
+func DatadogConfig() Config {
+	return Config{
+		TraceIDKey:    "dd.trace_id",
+		SpanIDKey:     "dd.span_id",
+		TraceFlagsKey: "-",
+		Format:        FormatDatadog,
+	}
+}
+
+// GoogleCloudConfig returns a Config using the special fields of Google Cloud
+// Logging for the given project.
+func GoogleCloudConfig(projectID string) Config {
+	return Config{
+		TraceIDKey:    "logging.googleapis.com/trace",
+		SpanIDKey:     "logging.googleapis.com/spanId",
+		TraceFlagsKey: "logging.googleapis.com/trace_sampled",
+		Format:        FormatGoogleCloud,
+		ProjectID:     projectID,
+	}
+}
+
+// Extractor returns a function that renders the span context carried by a
+// context as key-value pairs according to c, or nil if c is disabled.
+func (c Config) Extractor() func(context.Context) []any {
+	if c.Disabled {
+		return nil
+	}
+	traceKey := withDefault(c.TraceIDKey, "trace_id")
+	spanKey := withDefault(c.SpanIDKey, "span_id")
+	flagsKey := withDefault(c.TraceFlagsKey, "trace_flags")
+
+	return func(ctx context.Context) []any {
+		sc, ok := SpanContextFromContext(ctx)
+		if !ok {
+			return nil
+		}
+		fields := make([]any, 0, 6)
+		switch c.Format {
+		case FormatDatadog:
+			fields = append(fields,
+				traceKey, strconv.FormatUint(binary.BigEndian.Uint64(sc.TraceID[8:]), 10),
+				spanKey, strconv.FormatUint(binary.BigEndian.Uint64(sc.SpanID[:]), 10))
+		case FormatGoogleCloud:
+			fields = append(fields,
+				traceKey, "projects/"+c.ProjectID+"/traces/"+hex.EncodeToString(sc.TraceID[:]),
+				spanKey, hex.EncodeToString(sc.SpanID[:]))
+		default:
+			fields = append(fields,
+				traceKey, hex.EncodeToString(sc.TraceID[:]),
+				spanKey, hex.EncodeToString(sc.SpanID[:]))
+		}
+		if flagsKey != "-" {
+			if c.Format == FormatGoogleCloud {
+				fields = append(fields, flagsKey, sc.IsSampled())
+			} else {
+				fields = append(fields, flagsKey, hex.EncodeToString([]byte{sc.Flags}))
+			}
+		}
+		return fields
+	}
+}
+
+func withDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,132 @@
+package trace_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go4x/logx/trace"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+const validTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// TestParseTraceparent tests parsing of valid and invalid traceparent headers
+func TestParseTraceparent(t *testing.T) {
+	sc, err := trace.ParseTraceparent(validTraceparent)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !sc.IsSampled() || sc.Traceparent() != validTraceparent {
+		t.Errorf("unexpected span context: %+v", sc)
+	}
+
+	// future versions may append fields
+	if _, err := trace.ParseTraceparent("cc-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Errorf("expected future version to parse, got %v", err)
+	}
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00_4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+	}
+	for _, h := range invalid {
+		if _, err := trace.ParseTraceparent(h); err == nil {
+			t.Errorf("expected error for %q", h)
+		}
+	}
+}
+
+// TestExtractorFormats tests the field names and id formats of each preset
+func TestExtractorFormats(t *testing.T) {
+	ctx := trace.ContextWithTraceparent(context.Background(), validTraceparent)
+
+	testCases := []struct {
+		name   string
+		config trace.Config
+		want   []any
+	}{
+		{
+			name:   "Default",
+			config: trace.Config{},
+			want:   []any{"trace_id", "4bf92f3577b34da6a3ce929d0e0e4736", "span_id", "00f067aa0ba902b7", "trace_flags", "01"},
+		},
+		{
+			name:   "Datadog",
+			config: trace.DatadogConfig(),
+			want:   []any{"dd.trace_id", "11803532876627986230", "dd.span_id", "67667974448284343"},
+		},
+		{
+			name:   "GoogleCloud",
+			config: trace.GoogleCloudConfig("my-project"),
+			want: []any{
+				"logging.googleapis.com/trace", "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+				"logging.googleapis.com/spanId", "00f067aa0ba902b7",
+				"logging.googleapis.com/trace_sampled", true,
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.config.Extractor()(ctx)
+			if len(got) != len(tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+			for i := range got {
+				if got[i] != tc.want[i] {
+					t.Errorf("got %v, want %v", got, tc.want)
+					break
+				}
+			}
+		})
+	}
+
+	if (trace.Config{Disabled: true}).Extractor() != nil {
+		t.Error("expected nil extractor when disabled")
+	}
+	if fields := (trace.Config{}).Extractor()(context.Background()); fields != nil {
+		t.Errorf("expected no fields without a span, got %v", fields)
+	}
+}
+
+// TestOpenTelemetrySpan tests that an OpenTelemetry span context takes precedence
+func TestOpenTelemetrySpan(t *testing.T) {
+	otelSC := oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    oteltrace.TraceID{0x01, 0x02},
+		SpanID:     oteltrace.SpanID{0x03},
+		TraceFlags: oteltrace.FlagsSampled,
+	})
+	ctx := trace.ContextWithTraceparent(context.Background(), validTraceparent)
+	ctx = oteltrace.ContextWithSpanContext(ctx, otelSC)
+
+	sc, ok := trace.SpanContextFromContext(ctx)
+	if !ok {
+		t.Fatal("expected span context")
+	}
+	if sc.TraceID != otelSC.TraceID() || sc.SpanID != otelSC.SpanID() || !sc.IsSampled() {
+		t.Errorf("unexpected span context: %+v", sc)
+	}
+}
+
+// TestMiddleware tests that the middleware stores the traceparent of the request
+func TestMiddleware(t *testing.T) {
+	var got trace.SpanContext
+	handler := trace.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ = trace.SpanContextFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(trace.TraceparentHeader, validTraceparent)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if got.Traceparent() != validTraceparent {
+		t.Errorf("expected %s, got %s", validTraceparent, got.Traceparent())
+	}
+}
@@ -0,0 +1,105 @@
+package logx
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/module"
+)
+
+// globalVerbosity is the verbosity of V, as set by Init or SetVerbosity.
+var globalVerbosity atomic.Int32
+
+// globalVModules holds the verbosities of LoggerConfig.ModuleVerbosity, as
+// set by Init or SetModuleVerbosity.
+var globalVModules = module.New[int](nil)
+
+// Verbose writes the entries of a verbosity level, in the style of glog and
+// klog. It is returned by V.
+type Verbose struct {
+	enabled bool
+	level   Level
+}
+
+// V returns a Verbose that writes its entries if level is at most the
+// verbosity of the call site: the module verbosity of its package or file
+// (see LoggerConfig.ModuleVerbosity), or else the global verbosity. The
+// entries are written regardless of the configured level, at InfoLevel for
+// V(0) and at the levels below InfoLevel starting at DebugLevel for V(1),
+// which are written as DEBUG, DEBUG-1 and so on, down to TRACE for V(5).
+//
+// Example:
+//
+//	if v := logx.V(4); v.Enabled() {
+//	    v.Infow("cache state", "entries", dump(cache))
+//	}
+func V(level int) Verbose {
+	if globalLogger == nil {
+		return Verbose{}
+	}
+	verbosity := int(globalVerbosity.Load())
+	// the stack is only walked if module verbosities are set
+	if !globalVModules.Empty() {
+		if v, ok := globalVModules.Caller(0); ok {
+			verbosity = v
+		}
+	}
+	return Verbose{enabled: level <= verbosity, level: verbosityLevel(level)}
+}
+
+// verbosityLevel returns the level of the entries of a verbosity level.
+func verbosityLevel(v int) Level {
+	if v <= 0 {
+		return InfoLevel
+	}
+	return DebugLevel - Level(v-1)
+}
+
+// SetVerbosity sets the global verbosity of V at runtime.
+func SetVerbosity(v int) {
+	globalVerbosity.Store(int32(v))
+}
+
+// SetModuleVerbosity replaces the module verbosities of V at runtime, with
+// the syntax of LoggerConfig.ModuleVerbosity. A nil or empty map removes
+// them.
+func SetModuleVerbosity(verbosity map[string]int) {
+	globalVModules.Set(verbosity)
+}
+
+// Enabled reports whether v writes its entries.
+func (v Verbose) Enabled() bool {
+	return v.enabled
+}
+
+// Info logs a message built as by fmt.Sprint if v is enabled.
+func (v Verbose) Info(args ...any) {
+	if v.enabled {
+		v.log(message("", args))
+	}
+}
+
+// Infof logs a formatted message if v is enabled.
+func (v Verbose) Infof(template string, args ...any) {
+	if v.enabled {
+		v.log(message(template, args))
+	}
+}
+
+// Infow logs a message with the given key-value pairs if v is enabled.
+func (v Verbose) Infow(msg string, keysAndValues ...any) {
+	if v.enabled {
+		v.log(msg, keysAndValues...)
+	}
+}
+
+func (v Verbose) log(msg string, keysAndValues ...any) {
+	if globalLogger == nil {
+		return
+	}
+	// the level carried by the context lets the backends write the entry
+	// below the configured level
+	ctx := core.WithLevel(context.Background(), v.level)
+	logAt(globalLogger, core.CallerPC(1), ctx, v.level, msg, keysAndValues...)
+}
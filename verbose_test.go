@@ -0,0 +1,80 @@
+package logx_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/go4x/logx"
+)
+
+// TestVerbose tests that both backends write the entries of the verbosity levels up to the verbosity
+func TestVerbose(t *testing.T) {
+	for _, typ := range []logx.LoggerType{logx.LoggerTypeZap, logx.LoggerTypeSlog} {
+		t.Run(string(typ), func(t *testing.T) {
+			logDir := t.TempDir()
+			err := logx.Init(&logx.LoggerConfig{
+				Type:      typ,
+				Level:     "info",
+				Dir:       logDir,
+				Format:    "json",
+				Verbosity: 2,
+			})
+			if err != nil {
+				t.Fatalf("failed to initialize logger: %v", err)
+			}
+
+			if !logx.V(2).Enabled() || logx.V(3).Enabled() {
+				t.Error("expected verbosity levels up to 2 to be enabled")
+			}
+			logx.V(0).Info("verbosity ", 0)
+			logx.V(1).Infof("verbosity %d", 1)
+			logx.V(2).Infow("verbosity 2", "key", "value")
+			logx.V(3).Info("verbosity 3")
+
+			logx.SetVerbosity(0)
+			logx.V(1).Info("after SetVerbosity")
+
+			content := readLogs(t, logDir)
+			for _, want := range []string{"verbosity 0", "verbosity 1", "verbosity 2", `"key":"value"`} {
+				if !strings.Contains(content, want) {
+					t.Errorf("expected %s in output, got %q", want, content)
+				}
+			}
+			for _, unwanted := range []string{"verbosity 3", "after SetVerbosity"} {
+				if strings.Contains(content, unwanted) {
+					t.Errorf("unexpected %s in output: %q", unwanted, content)
+				}
+			}
+			if typ == logx.LoggerTypeSlog && !strings.Contains(content, "DEBUG-1") {
+				t.Errorf("expected V(2) at level DEBUG-1, got %q", content)
+			}
+		})
+	}
+}
+
+// TestModuleVerbosity tests that the module verbosity of the call site replaces the global verbosity
+func TestModuleVerbosity(t *testing.T) {
+	logDir := t.TempDir()
+	err := logx.Init(&logx.LoggerConfig{
+		Level:           "info",
+		Dir:             logDir,
+		Format:          "json",
+		ModuleVerbosity: map[string]int{"*/verbose_test.go": 3},
+	})
+	if err != nil {
+		t.Fatalf("failed to initialize logger: %v", err)
+	}
+	defer logx.SetModuleVerbosity(nil)
+
+	logx.V(3).Info("module verbosity")
+	logx.SetModuleVerbosity(map[string]int{"github.com/acme/*": 5})
+	logx.V(1).Info("global verbosity")
+
+	content := readLogs(t, logDir)
+	if !strings.Contains(content, "module verbosity") {
+		t.Errorf("expected the entry of the module verbosity, got %q", content)
+	}
+	if strings.Contains(content, "global verbosity") {
+		t.Errorf("unexpected entry above the global verbosity: %q", content)
+	}
+}
@@ -0,0 +1,77 @@
+package zap
+
+import (
+	"github.com/go4x/logx/core"
+	"go.uber.org/zap/zapcore"
+)
+
+// callerKey is the key of the field carrying the call site of an entry.
+const callerKey = "logx:caller"
+
+// callerField returns the field carrying the call site at pc of an entry.
+// Encoders skip it.
+func callerField(pc uintptr) zapcore.Field {
+	return zapcore.Field{Key: callerKey, Type: zapcore.SkipType, Integer: int64(pc)}
+}
+
+// callerCore is a zapcore.Core that replaces the caller and the stack trace
+// computed by zap, which start in the wrappers of logx, with those of the
+// call site carried by the fields.
+type callerCore struct {
+	zapcore.Core
+}
+
+// newCallerCore returns a zapcore.Core that writes the entries to c with
+// the call site carried by their fields.
+func newCallerCore(c zapcore.Core) zapcore.Core {
+	return &callerCore{Core: c}
+}
+
+// With implements the zapcore.Core interface.
+func (c *callerCore) With(fields []zapcore.Field) zapcore.Core {
+	return &callerCore{Core: c.Core.With(fields)}
+}
+
+// Check implements the zapcore.Core interface. Write is always called on
+// the wrapper, which finds the call site in the fields.
+func (c *callerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements the zapcore.Core interface.
+func (c *callerCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	for i, f := range fields {
+		if f.Key == callerKey && f.Type == zapcore.SkipType {
+			fields = append(fields[:i:i], fields[i+1:]...)
+			ent = withCaller(ent, uintptr(f.Integer))
+			break
+		}
+	}
+	// the wrapped core is checked with the call site, since it may record
+	// the entry
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+// withCaller returns ent with the caller and the stack trace of the call
+// site at pc, if zap added them.
+func withCaller(ent zapcore.Entry, pc uintptr) zapcore.Entry {
+	if ent.Caller.Defined {
+		if f, ok := core.CallerFrame(pc); ok {
+			ent.Caller = zapcore.EntryCaller{Defined: true, PC: pc, File: f.File, Line: f.Line, Function: f.Function}
+		} else {
+			ent.Caller = zapcore.EntryCaller{}
+		}
+	}
+	if ent.Stack != "" {
+		if stack := core.Stacktrace(pc); stack != "" {
+			ent.Stack = stack
+		}
+	}
+	return ent
+}
@@ -0,0 +1,78 @@
+package zap
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go4x/logx/dedup"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// dedupCore is a zapcore.Core that suppresses the entries repeating the
+// previous entry.
+type dedupCore struct {
+	zapcore.Core
+	d *dedup.Deduper
+	// context is the fingerprint of the fields added with With
+	context string
+}
+
+// NewDedupCore returns a zapcore.Core that suppresses the identical
+// consecutive entries written to c according to d.
+func NewDedupCore(c zapcore.Core, d *dedup.Deduper) zapcore.Core {
+	return &dedupCore{Core: c, d: d}
+}
+
+// With implements the zapcore.Core interface.
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupCore{Core: c.Core.With(fields), d: c.d, context: c.context + fingerprint(fields)}
+}
+
+// Check implements the zapcore.Core interface. The wrapped core is only
+// asked whether the level is enabled, so that Write is always called on
+// the wrapper, which needs the fields.
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements the zapcore.Core interface.
+func (c *dedupCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	key := strings.Join([]string{ent.Level.String(), ent.LoggerName, ent.Message, c.context, fingerprint(fields)}, "\x00")
+	if c.d.Check(key, ent.Time, c.summary(ent)) {
+		return nil
+	}
+	// the wrapped core is checked again, since it may be a tee of cores
+	// enabled at different levels
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+// summary returns the function writing the summary of the entries repeating
+// ent.
+func (c *dedupCore) summary(ent zapcore.Entry) func(dedup.Repeat) {
+	return func(r dedup.Repeat) {
+		sum := zapcore.Entry{Level: ent.Level, Time: r.Last, LoggerName: ent.LoggerName, Message: r.Message()}
+		if ce := c.Core.Check(sum, nil); ce != nil {
+			ce.Write(zap.Int("repeated", r.Count), zap.Time("first", r.First), zap.Time("last", r.Last))
+		}
+	}
+}
+
+// fingerprint returns a string identifying the values of fields.
+func fingerprint(fields []zapcore.Field) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	enc := zapcore.NewMapObjectEncoder()
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+	// maps are printed with sorted keys
+	return fmt.Sprint(enc.Fields)
+}
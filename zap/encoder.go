@@ -0,0 +1,89 @@
+package zap
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/go4x/logx/encoder"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+// applyEncoder sets the keys and the encoders of e to cfg. The JSON format
+// writes the time first, as slog does, with the encoder of newTimeFirstEncoder.
+func applyEncoder(cfg *zapcore.EncoderConfig, e encoder.Config) {
+	e = e.Keys()
+	cfg.MessageKey = e.MessageKey
+	cfg.LevelKey = e.LevelKey
+	cfg.TimeKey = e.TimeKey
+	cfg.CallerKey = e.CallerKey
+	cfg.StacktraceKey = e.StacktraceKey
+	cfg.EncodeTime = func(t time.Time, pae zapcore.PrimitiveArrayEncoder) {
+		switch v := e.Time(t).(type) {
+		case int64:
+			pae.AppendInt64(v)
+		case string:
+			pae.AppendString(v)
+		}
+	}
+	cfg.EncodeLevel = func(l zapcore.Level, pae zapcore.PrimitiveArrayEncoder) {
+		pae.AppendString(e.Level(coreLevel(l)))
+	}
+	cfg.EncodeCaller = func(c zapcore.EntryCaller, pae zapcore.PrimitiveArrayEncoder) {
+		pae.AppendString(e.Caller(c.Function, c.File, c.Line))
+	}
+}
+
+var bufferPool = buffer.NewPool()
+
+// timeFirstEncoder is a JSON zapcore.Encoder that writes the time before
+// the level, where zap writes it after.
+type timeFirstEncoder struct {
+	zapcore.Encoder
+	key string
+	e   encoder.Config
+}
+
+// newTimeFirstEncoder returns the JSON encoder of cfg, which writes the
+// time first under the time key of e.
+func newTimeFirstEncoder(cfg zapcore.EncoderConfig, e encoder.Config) zapcore.Encoder {
+	key := e.Keys().TimeKey
+	cfg.TimeKey = ""
+	return &timeFirstEncoder{Encoder: zapcore.NewJSONEncoder(cfg), key: key, e: e}
+}
+
+// Clone implements the zapcore.Encoder interface.
+func (enc *timeFirstEncoder) Clone() zapcore.Encoder {
+	return &timeFirstEncoder{Encoder: enc.Encoder.Clone(), key: enc.key, e: enc.e}
+}
+
+// EncodeEntry implements the zapcore.Encoder interface.
+func (enc *timeFirstEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	buf, err := enc.Encoder.EncodeEntry(ent, fields)
+	if err != nil || enc.key == "" || ent.Time.IsZero() {
+		return buf, err
+	}
+	defer buf.Free()
+	entry := buf.Bytes()
+	out := bufferPool.Get()
+	out.AppendByte('{')
+	appendJSON(out, enc.key)
+	out.AppendByte(':')
+	appendJSON(out, enc.e.Time(ent.Time))
+	if len(entry) > 1 && entry[1] != '}' {
+		out.AppendByte(',')
+	}
+	_, _ = out.Write(entry[1:])
+	return out, nil
+}
+
+// appendJSON appends the JSON encoding of a string or an integer to b,
+// without escaping HTML as the zap encoder does.
+func appendJSON(b *buffer.Buffer, v any) {
+	var w bytes.Buffer
+	je := json.NewEncoder(&w)
+	je.SetEscapeHTML(false)
+	_ = je.Encode(v)
+	_, _ = b.Write(bytes.TrimSuffix(w.Bytes(), []byte("\n")))
+}
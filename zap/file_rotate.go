@@ -2,17 +2,26 @@ package zap
 
 import (
 	"bufio"
+	"io"
 	"os"
 	"path"
 	"sync"
 	"time"
 
+	"github.com/go4x/logx/encrypt"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 // GetWriter get io.Writer
 func GetWriter(c *ZapConfig, level string) (zapcore.WriteSyncer, error) {
+	w, _, err := newWriter(c, level)
+	return w, err
+}
+
+// newWriter returns the writer of GetWriter, and the file output it writes
+// to, which must be closed to release it.
+func newWriter(c *ZapConfig, level string) (zapcore.WriteSyncer, io.Closer, error) {
 	now := time.Now().Format("2006-01-02")
 	filename := path.Join(c.Director, now+"-"+level+".log")
 	lumberjackLogger := &lumberjack.Logger{
@@ -36,15 +45,25 @@ func GetWriter(c *ZapConfig, level string) (zapcore.WriteSyncer, error) {
 	}
 
 	// Create buffered WriteSyncer
+	// Encrypt the file output if configured
+	var file io.WriteCloser = lumberjackLogger
+	if c.Encryption != nil {
+		w, err := encrypt.NewWriter(lumberjackLogger, *c.Encryption)
+		if err != nil {
+			return nil, nil, err
+		}
+		file = w
+	}
+
 	var syncer zapcore.WriteSyncer
 	if c.LogInConsole {
 		// Output to both console and file
 		consoleSyncer := zapcore.AddSync(os.Stdout)
-		fileSyncer := zapcore.AddSync(lumberjackLogger)
+		fileSyncer := zapcore.AddSync(file)
 		syncer = zapcore.NewMultiWriteSyncer(consoleSyncer, fileSyncer)
 	} else {
 		// Output only to file
-		fileSyncer := zapcore.AddSync(lumberjackLogger)
+		fileSyncer := zapcore.AddSync(file)
 		syncer = fileSyncer
 	}
 
@@ -57,7 +76,7 @@ func GetWriter(c *ZapConfig, level string) (zapcore.WriteSyncer, error) {
 		}
 	}
 
-	return syncer, nil
+	return syncer, file, nil
 }
 
 // BufferedWriteSyncer implements a buffered WriteSyncer
@@ -0,0 +1,108 @@
+package zap
+
+import (
+	"github.com/go4x/logx/flight"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// bufferField returns the field carrying the flight recorder buffer of an
+// entry. Encoders skip it.
+func bufferField(b *flight.Buffer) zapcore.Field {
+	return zapcore.Field{Type: zapcore.SkipType, Interface: b}
+}
+
+// recorded is the data of a recorded entry.
+type recorded struct {
+	core   zapcore.Core
+	ent    zapcore.Entry
+	fields []zapcore.Field
+}
+
+// flightCore is a zapcore.Core that records the entries below the level of
+// the wrapped core, and writes them when an entry at the trigger level of
+// the recorder is written.
+type flightCore struct {
+	zapcore.Core
+	r *flight.Recorder
+}
+
+// NewFlightCore returns a zapcore.Core that records the entries not enabled
+// by c with r.
+func NewFlightCore(c zapcore.Core, r *flight.Recorder) zapcore.Core {
+	return &flightCore{Core: c, r: r}
+}
+
+// Enabled implements the zapcore.Core interface.
+func (c *flightCore) Enabled(level zapcore.Level) bool {
+	return c.Core.Enabled(level) || coreLevel(level) >= c.r.Level()
+}
+
+// With implements the zapcore.Core interface.
+func (c *flightCore) With(fields []zapcore.Field) zapcore.Core {
+	return &flightCore{Core: c.Core.With(fields), r: c.r}
+}
+
+// Check implements the zapcore.Core interface. Write is always called on
+// the wrapper, which finds the buffer in the fields.
+func (c *flightCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements the zapcore.Core interface.
+func (c *flightCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var b *flight.Buffer
+	for i, f := range fields {
+		if fb, ok := f.Interface.(*flight.Buffer); ok && f.Type == zapcore.SkipType {
+			b = fb
+			fields = append(fields[:i:i], fields[i+1:]...)
+			break
+		}
+	}
+	level := coreLevel(ent.Level)
+	if !c.Core.Enabled(ent.Level) {
+		size := len(ent.Message)
+		for _, f := range fields {
+			size += len(f.Key) + len(f.String) + 16
+		}
+		c.r.Record(b, flight.Record{
+			Level:   level,
+			Time:    ent.Time,
+			Message: ent.Message,
+			Data:    recorded{core: c.Core, ent: ent, fields: append([]zapcore.Field(nil), fields...)},
+			Size:    size,
+		})
+		return nil
+	}
+	if level >= c.r.Trigger() {
+		c.dump(b, ent)
+	}
+	if ce := c.Core.Check(ent, nil); ce != nil {
+		ce.Write(fields...)
+	}
+	return nil
+}
+
+// dump writes the entries recorded in b at the level of the entry ent,
+// after a marker entry.
+func (c *flightCore) dump(b *flight.Buffer, ent zapcore.Entry) {
+	records, evicted := c.r.Dump(b)
+	if len(records) == 0 {
+		return
+	}
+	marker := zapcore.Entry{Level: ent.Level, Time: ent.Time, LoggerName: ent.LoggerName, Message: flight.DumpMessage}
+	if ce := c.Core.Check(marker, nil); ce != nil {
+		ce.Write(zap.Int("recorded", len(records)), zap.Int("evicted", evicted))
+	}
+	for _, rec := range records {
+		data := rec.Data.(recorded)
+		e := data.ent
+		e.Level = ent.Level
+		if ce := data.core.Check(e, nil); ce != nil {
+			ce.Write(append(data.fields, zap.String(flight.LevelKey, rec.Level.String()))...)
+		}
+	}
+}
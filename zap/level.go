@@ -0,0 +1,156 @@
+package zap
+
+import (
+	"strings"
+
+	"github.com/go4x/logx/core"
+	"go.uber.org/zap/zapcore"
+)
+
+// customBase is the zapcore.Level of core level 0 for the levels zap does
+// not define: core level l is zapcore.Level(customBase + l). The values are
+// below zapcore.DebugLevel, so that zap treats them as ordinary levels.
+const customBase = -64
+
+// zapLevel converts a core.Level to the corresponding zapcore.Level. The
+// levels zap defines map to them; the other levels map to values below
+// zapcore.DebugLevel, which only the functions of this file order and name.
+func zapLevel(level core.Level) zapcore.Level {
+	switch {
+	case level == core.DebugLevel:
+		return zapcore.DebugLevel
+	case level == core.InfoLevel:
+		return zapcore.InfoLevel
+	case level == core.WarnLevel:
+		return zapcore.WarnLevel
+	case level == core.ErrorLevel:
+		return zapcore.ErrorLevel
+	case level == core.PanicLevel:
+		return zapcore.PanicLevel
+	case level >= core.FatalLevel:
+		return zapcore.FatalLevel
+	default:
+		return zapcore.Level(customBase + max(-64, min(int(level), 62)))
+	}
+}
+
+// coreLevel converts a zapcore.Level to the corresponding core.Level.
+func coreLevel(level zapcore.Level) core.Level {
+	switch level {
+	case zapcore.DebugLevel:
+		return core.DebugLevel
+	case zapcore.InfoLevel:
+		return core.InfoLevel
+	case zapcore.WarnLevel:
+		return core.WarnLevel
+	case zapcore.ErrorLevel:
+		return core.ErrorLevel
+	case zapcore.DPanicLevel:
+		return core.CriticalLevel
+	case zapcore.PanicLevel:
+		return core.PanicLevel
+	case zapcore.FatalLevel:
+		return core.FatalLevel
+	default:
+		if level < zapcore.DebugLevel {
+			return core.Level(int(level) - customBase)
+		}
+		return core.FatalLevel
+	}
+}
+
+// fileLevel returns the level of the file the entries of level are written
+// to: the highest level zap defines that is at most level, or debug.
+func fileLevel(level zapcore.Level) zapcore.Level {
+	l := coreLevel(level)
+	switch {
+	case l < core.InfoLevel:
+		return zapcore.DebugLevel
+	case l < core.WarnLevel:
+		return zapcore.InfoLevel
+	case l < core.ErrorLevel:
+		return zapcore.WarnLevel
+	case l < core.PanicLevel:
+		return zapcore.ErrorLevel
+	case l < core.FatalLevel:
+		return zapcore.PanicLevel
+	default:
+		return zapcore.FatalLevel
+	}
+}
+
+// levelEnabler is a zapcore.LevelEnabler that enables the levels at or
+// above a core level.
+type levelEnabler core.Level
+
+// Enabled implements the zapcore.LevelEnabler interface.
+func (e levelEnabler) Enabled(level zapcore.Level) bool {
+	return coreLevel(level) >= core.Level(e)
+}
+
+// levelCore is a zapcore.Core that writes the entries enabled by a
+// zapcore.LevelEnabler only.
+type levelCore struct {
+	zapcore.Core
+	enab zapcore.LevelEnabler
+}
+
+// newLevelCore returns a zapcore.Core that writes the entries enabled by
+// both c and enab to c.
+func newLevelCore(c zapcore.Core, enab zapcore.LevelEnabler) zapcore.Core {
+	return &levelCore{Core: c, enab: enab}
+}
+
+// Enabled implements the zapcore.Core interface.
+func (c *levelCore) Enabled(level zapcore.Level) bool {
+	return c.enab.Enabled(level) && c.Core.Enabled(level)
+}
+
+// With implements the zapcore.Core interface.
+func (c *levelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelCore{Core: c.Core.With(fields), enab: c.enab}
+}
+
+// Check implements the zapcore.Core interface.
+func (c *levelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.enab.Enabled(ent.Level) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// levelEncoder returns a zapcore.LevelEncoder that encodes the levels zap
+// defines with enc, and the other levels with their core names, in the case
+// and color of enc.
+func levelEncoder(enc zapcore.LevelEncoder) zapcore.LevelEncoder {
+	return func(level zapcore.Level, pae zapcore.PrimitiveArrayEncoder) {
+		if level >= zapcore.DebugLevel {
+			enc(level, pae)
+			return
+		}
+		// the encoding of the file level shows the case and the color
+		var probe levelProbe
+		enc(fileLevel(level), &probe)
+		name := coreLevel(level).String()
+		fl := fileLevel(level).String()
+		i := strings.Index(strings.ToLower(probe.s), fl)
+		if i < 0 {
+			pae.AppendString(name)
+			return
+		}
+		if probe.s[i:i+len(fl)] != fl {
+			name = strings.ToUpper(name)
+		}
+		pae.AppendString(probe.s[:i] + name + probe.s[i+len(fl):])
+	}
+}
+
+// levelProbe is a zapcore.PrimitiveArrayEncoder that keeps the string
+// appended by a level encoder.
+type levelProbe struct {
+	zapcore.PrimitiveArrayEncoder
+	s string
+}
+
+// AppendString implements the zapcore.PrimitiveArrayEncoder interface.
+func (p *levelProbe) AppendString(s string) { p.s = s }
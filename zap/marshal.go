@@ -0,0 +1,113 @@
+package zap
+
+import (
+	"time"
+
+	"github.com/go4x/logx/core"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// needsArgs reports whether zapArgs converts some of keysAndValues.
+func needsArgs(keysAndValues []any) bool {
+	for _, v := range keysAndValues {
+		if _, ok := v.(core.Field); ok || core.IsMarshaler(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// zapArgs returns keysAndValues with its core.Field elements converted to
+// zap fields, which the sugared logger takes as they are, and the values
+// encoded by core.MarshalValue converted to zap objects and arrays.
+// keysAndValues is not modified.
+func zapArgs(keysAndValues []any) []any {
+	out := make([]any, 0, len(keysAndValues))
+	for i := 0; i < len(keysAndValues); i++ {
+		switch v := keysAndValues[i].(type) {
+		case zap.Field:
+			out = append(out, v)
+		case core.Field:
+			out = appendField(out, v.Key, v.Value)
+		case string:
+			if i+1 == len(keysAndValues) {
+				return append(out, v)
+			}
+			i++
+			out = appendField(out, v, keysAndValues[i])
+		default:
+			// left to the sugared logger, which reports the invalid keys
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// appendField appends the zap field of key and value to out, followed by
+// the error of the marshaler of value if any.
+func appendField(out []any, key string, value any) []any {
+	v, err := core.MarshalValue(value)
+	out = append(out, zapField(key, v))
+	if err != nil {
+		out = append(out, zap.String(key+"Error", err.Error()))
+	}
+	return out
+}
+
+// zapField returns the zap field of key and a value encoded by
+// core.MarshalValue.
+func zapField(key string, value any) zap.Field {
+	switch v := value.(type) {
+	case core.Object:
+		return zap.Object(key, zapObject(v))
+	case core.Array:
+		return zap.Array(key, zapArray(v))
+	}
+	return zap.Any(key, value)
+}
+
+// zapObject is a zapcore.ObjectMarshaler for a core.Object.
+type zapObject core.Object
+
+// MarshalLogObject implements the zapcore.ObjectMarshaler interface.
+func (o zapObject) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, f := range o {
+		zapField(f.Key, f.Value).AddTo(enc)
+	}
+	return nil
+}
+
+// zapArray is a zapcore.ArrayMarshaler for a core.Array.
+type zapArray core.Array
+
+// MarshalLogArray implements the zapcore.ArrayMarshaler interface.
+func (a zapArray) MarshalLogArray(enc zapcore.ArrayEncoder) error {
+	for _, v := range a {
+		switch v := v.(type) {
+		case core.Object:
+			_ = enc.AppendObject(zapObject(v))
+		case core.Array:
+			_ = enc.AppendArray(zapArray(v))
+		case string:
+			enc.AppendString(v)
+		case int64:
+			enc.AppendInt64(v)
+		case int:
+			enc.AppendInt(v)
+		case uint64:
+			enc.AppendUint64(v)
+		case float64:
+			enc.AppendFloat64(v)
+		case bool:
+			enc.AppendBool(v)
+		case time.Duration:
+			enc.AppendDuration(v)
+		case time.Time:
+			enc.AppendTime(v)
+		default:
+			_ = enc.AppendReflected(v)
+		}
+	}
+	return nil
+}
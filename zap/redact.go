@@ -0,0 +1,103 @@
+package zap
+
+import (
+	"github.com/go4x/logx/redact"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactCore is a zapcore.Core that redacts the message and the fields of
+// the entries before handing them to the wrapped core.
+type redactCore struct {
+	zapcore.Core
+	r *redact.Redactor
+}
+
+// NewRedactCore returns a zapcore.Core that applies r to the entries written
+// to c.
+func NewRedactCore(c zapcore.Core, r *redact.Redactor) zapcore.Core {
+	return &redactCore{Core: c, r: r}
+}
+
+// With implements the zapcore.Core interface.
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(redactFields(c.r, fields)), r: c.r}
+}
+
+// Check implements the zapcore.Core interface. The wrapped core is only
+// asked whether the level is enabled, so that Write is always called on
+// the wrapper.
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements the zapcore.Core interface.
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = c.r.String(ent.Message)
+	return c.Core.Write(ent, redactFields(c.r, fields))
+}
+
+// redactFields returns fields with r applied. The slice is only copied if a
+// field changes.
+func redactFields(r *redact.Redactor, fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		nf, changed, keep := redactField(r, f)
+		if !changed {
+			if out != nil {
+				out = append(out, f)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]zapcore.Field, i, len(fields))
+			copy(out, fields[:i])
+		}
+		if keep {
+			out = append(out, nf)
+		}
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// redactField applies r to one field. Numbers, booleans, durations and times
+// are only subject to the key rules.
+func redactField(r *redact.Redactor, f zapcore.Field) (zapcore.Field, bool, bool) {
+	var v any
+	switch f.Type {
+	case zapcore.SkipType, zapcore.NamespaceType:
+		return f, false, true
+	case zapcore.StringType:
+		v = f.String
+	case zapcore.BoolType, zapcore.DurationType, zapcore.TimeType, zapcore.TimeFullType,
+		zapcore.Float64Type, zapcore.Float32Type, zapcore.Complex128Type, zapcore.Complex64Type,
+		zapcore.Int64Type, zapcore.Int32Type, zapcore.Int16Type, zapcore.Int8Type,
+		zapcore.Uint64Type, zapcore.Uint32Type, zapcore.Uint16Type, zapcore.Uint8Type, zapcore.UintptrType:
+		if _, changed, keep := r.Field(f.Key, nil); !changed {
+			return f, false, true
+		} else if !keep {
+			return f, true, false
+		}
+		v = fieldValue(f)
+	default:
+		v = fieldValue(f)
+	}
+	out, changed, keep := r.Field(f.Key, v)
+	if !changed || !keep {
+		return f, changed, keep
+	}
+	return zap.Any(f.Key, out), true, true
+}
+
+// fieldValue returns the value of f as encoded by a MapObjectEncoder.
+func fieldValue(f zapcore.Field) any {
+	enc := zapcore.NewMapObjectEncoder()
+	f.AddTo(enc)
+	return enc.Fields[f.Key]
+}
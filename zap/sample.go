@@ -0,0 +1,54 @@
+package zap
+
+import (
+	"time"
+
+	"github.com/go4x/logx/sample"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// sampleCore is a zapcore.Core that drops the entries sampled out or
+// exceeding the rate limits of a sampler.
+type sampleCore struct {
+	zapcore.Core
+	s *sample.Sampler
+}
+
+// NewSampleCore returns a zapcore.Core that samples and rate limits the
+// entries written to c according to s. The entries are sampled by s rather
+// than by the zap sampler, which ignores the levels zap does not define.
+func NewSampleCore(c zapcore.Core, s *sample.Sampler) zapcore.Core {
+	if !s.Sampling() && !s.Limiting() {
+		return c
+	}
+	return &sampleCore{Core: c, s: s}
+}
+
+// With implements the zapcore.Core interface.
+func (c *sampleCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sampleCore{Core: c.Core.With(fields), s: c.s}
+}
+
+// Check implements the zapcore.Core interface.
+func (c *sampleCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+	level := coreLevel(ent.Level)
+	if !c.s.Sample(level, ent.Message) || !c.s.Allow(level, ent.Message) {
+		return ce
+	}
+	return c.Core.Check(ent, ce)
+}
+
+// summaryReporter returns the function writing the summaries of a sampler
+// to c, which must not be sampled.
+func summaryReporter(c zapcore.Core) func(sample.Summary) {
+	return func(sum sample.Summary) {
+		ent := zapcore.Entry{Level: zapcore.WarnLevel, Time: time.Now(), Message: sample.SummaryMessage}
+		if ce := c.Check(ent, nil); ce != nil {
+			ce.Write(zap.Uint64("sampled", sum.Sampled), zap.Uint64("rate_limited", sum.Limited))
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package zap
+
+import (
+	"fmt"
+
+	"github.com/go4x/logx/sanitize"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// sanitizeCore is a zapcore.Core that escapes the message of the entries and
+// truncates their string fields before handing them to the wrapped core.
+// Field values are escaped by the console encoder itself, which writes them
+// as JSON.
+type sanitizeCore struct {
+	zapcore.Core
+	c sanitize.Config
+}
+
+// NewSanitizeCore returns a zapcore.Core that sanitizes the entries written
+// to core according to c.
+func NewSanitizeCore(core zapcore.Core, c sanitize.Config) zapcore.Core {
+	return &sanitizeCore{Core: core, c: c}
+}
+
+// With implements the zapcore.Core interface.
+func (s *sanitizeCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sanitizeCore{Core: s.Core.With(s.fields(fields)), c: s.c}
+}
+
+// Check implements the zapcore.Core interface.
+func (s *sanitizeCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if s.Enabled(ent.Level) {
+		return ce.AddCore(ent, s)
+	}
+	return ce
+}
+
+// Write implements the zapcore.Core interface.
+func (s *sanitizeCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	ent.Message = s.c.Message(ent.Message)
+	ent.LoggerName = sanitize.Escape(ent.LoggerName)
+	return s.Core.Write(ent, s.fields(fields))
+}
+
+// fields returns fields with the long string values truncated. The slice is
+// only copied if a field changes.
+func (s *sanitizeCore) fields(fields []zapcore.Field) []zapcore.Field {
+	var out []zapcore.Field
+	for i, f := range fields {
+		var str string
+		switch f.Type {
+		case zapcore.StringType:
+			str = f.String
+		case zapcore.ByteStringType:
+			str = string(f.Interface.([]byte))
+		case zapcore.ErrorType:
+			str = f.Interface.(error).Error()
+		case zapcore.StringerType:
+			str = fmt.Sprint(f.Interface)
+		default:
+			continue
+		}
+		truncated := s.c.TruncateField(str)
+		if truncated == str {
+			continue
+		}
+		if out == nil {
+			out = append([]zapcore.Field(nil), fields...)
+		}
+		out[i] = zap.String(f.Key, truncated)
+	}
+	if out == nil {
+		return fields
+	}
+	return out
+}
+
+// sanitizing reports whether the console and file outputs are sanitized.
+func (z *ZapConfig) sanitizing() bool {
+	return z.Format != ZapFormatJSON && !z.Sanitize.Disabled
+}
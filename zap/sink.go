@@ -0,0 +1,78 @@
+package zap
+
+import (
+	"github.com/go4x/logx/core"
+	"go.uber.org/zap/zapcore"
+)
+
+// sinkCore is a zapcore.Core that converts entries to core.Entry and hands
+// them to a core.Sink.
+type sinkCore struct {
+	zapcore.LevelEnabler
+	sink   core.Sink
+	fields []zapcore.Field
+}
+
+// NewSinkCore returns a zapcore.Core that writes the entries enabled by
+// enab to s.
+func NewSinkCore(s core.Sink, enab zapcore.LevelEnabler) zapcore.Core {
+	return &sinkCore{LevelEnabler: enab, sink: s}
+}
+
+// With implements the zapcore.Core interface.
+func (c *sinkCore) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	clone.fields = append(clone.fields, c.fields...)
+	clone.fields = append(clone.fields, fields...)
+	return &clone
+}
+
+// Check implements the zapcore.Core interface.
+func (c *sinkCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+// Write implements the zapcore.Core interface.
+func (c *sinkCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	e := &core.Entry{
+		Time:    ent.Time,
+		Level:   coreLevel(ent.Level),
+		Message: ent.Message,
+		Fields:  make([]core.Field, 0, len(c.fields)+len(fields)),
+	}
+	if ent.Caller.Defined {
+		e.Caller = ent.Caller.TrimmedPath()
+	}
+	e.Fields = appendFields(e.Fields, c.fields)
+	e.Fields = appendFields(e.Fields, fields)
+	if err := c.sink.Write(e); err != nil {
+		return err
+	}
+	// the entries above error may be followed by a panic or an exit, so
+	// they are flushed as zapcore.ioCore does
+	if e.Level > core.ErrorLevel {
+		return c.sink.Sync()
+	}
+	return nil
+}
+
+// Sync implements the zapcore.Core interface.
+func (c *sinkCore) Sync() error {
+	return c.sink.Sync()
+}
+
+// appendFields converts zap fields to core fields, keeping their order.
+func appendFields(dst []core.Field, fields []zapcore.Field) []core.Field {
+	for _, f := range fields {
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		for k, v := range enc.Fields {
+			dst = append(dst, core.Field{Key: k, Value: v})
+		}
+	}
+	return dst
+}
@@ -5,10 +5,15 @@ package zap
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"time"
 
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/flight"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -21,6 +26,14 @@ const LoggerKey loggerKey = "zapLogger"
 // Logger wraps zap.SugaredLogger to implement the logx.Logger interface.
 type Logger struct {
 	*zap.SugaredLogger
+	contextFields func(ctx context.Context) []any
+	// min is the configured level
+	min core.Level
+	// verbose writes at all levels, for the contexts carrying a level
+	// below the configured level
+	verbose *zap.SugaredLogger
+	// files are the file outputs, closed by Close
+	files []io.Closer
 }
 
 // pathExists checks if the given path exists.
@@ -53,13 +66,82 @@ func NewLog(c *ZapConfig) (*Logger, error) {
 		return nil, fmt.Errorf("director is required")
 	}
 
+	if c.Encryption != nil && c.Encryption.Keys == nil {
+		return nil, fmt.Errorf("encryption requires a key provider")
+	}
+
 	cores := zapObj.GetZapCores()
-	logger := zap.New(zapcore.NewTee(cores...))
+	for _, zc := range cores {
+		if zc == nil {
+			return nil, fmt.Errorf("failed to create the log file writers")
+		}
+	}
 
+	var opts []zap.Option
 	if c.ShowCaller {
-		logger = logger.WithOptions(zap.AddCaller())
+		opts = append(opts, zap.AddCaller())
+	}
+	if c.StacktraceLevel != "" {
+		if l, err := core.ParseLevel(c.StacktraceLevel); err == nil {
+			opts = append(opts, zap.AddStacktrace(levelEnabler(l)))
+		}
+	}
+	min := c.MinLevel()
+	// the verbose logger writes all levels, for the entries logged with a
+	// context carrying a lower level; the files are opened on first write
+	return &Logger{
+		SugaredLogger: zap.New(c.buildCore(cores, levelEnabler(min)), opts...).Sugar(),
+		contextFields: c.ContextFields,
+		min:           min,
+		verbose:       zap.New(c.buildCore(cores, levelEnabler(math.MinInt)), opts...).Sugar(),
+		files:         zapObj.files,
+	}, nil
+}
+
+// Close flushes the buffered output and closes the files, stopping the
+// goroutines of their writers. The logger must not be used afterwards.
+func (l *Logger) Close() error {
+	errs := []error{l.Sync()}
+	for _, f := range l.files {
+		errs = append(errs, f.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// buildCore returns the core writing the entries enabled by level to the
+// file cores and to the sinks, with the processing stages of the
+// configuration and the call sites of the wrappers of logx.
+func (c *ZapConfig) buildCore(files []zapcore.Core, level zapcore.LevelEnabler) zapcore.Core {
+	cores := make([]zapcore.Core, 0, len(files)+len(c.Sinks))
+	for _, fc := range files {
+		if c.sanitizing() {
+			fc = NewSanitizeCore(fc, c.Sanitize)
+		}
+		cores = append(cores, fc)
+	}
+	for _, s := range c.Sinks {
+		cores = append(cores, NewSinkCore(s, level))
+	}
+	if c.Redactor != nil {
+		for i := range cores {
+			cores[i] = NewRedactCore(cores[i], c.Redactor)
+		}
 	}
-	return &Logger{SugaredLogger: logger.Sugar()}, nil
+	tee := newLevelCore(zapcore.NewTee(cores...), level)
+	if c.Deduper != nil {
+		tee = NewDedupCore(tee, c.Deduper)
+	}
+	if c.Recorder != nil {
+		tee = NewFlightCore(tee, c.Recorder)
+	}
+	if c.Sampler != nil {
+		// only the first call starts the summaries
+		c.Sampler.Start(summaryReporter(tee))
+		tee = NewSampleCore(tee, c.Sampler)
+	}
+	// first, so that the entries are recorded and sampled with their call
+	// site
+	return newCallerCore(tee)
 }
 
 var zapObj zapDef
@@ -67,21 +149,27 @@ var zapObj zapDef
 // zapDef holds the zap logger definition.
 type zapDef struct {
 	c *ZapConfig
+	// files are the file outputs of the cores
+	files []io.Closer
 }
 
 // GetEncoder returns a zapcore.Encoder based on the configuration.
 func (z *zapDef) GetEncoder() zapcore.Encoder {
 	switch z.c.Format {
 	case ZapFormatJSON:
+		if z.c.Encoder != nil {
+			return newTimeFirstEncoder(z.GetEncoderConfig(), *z.c.Encoder)
+		}
 		return zapcore.NewJSONEncoder(z.GetEncoderConfig())
 	default:
 		return zapcore.NewConsoleEncoder(z.GetEncoderConfig())
 	}
 }
 
-// GetEncoderConfig get zapcore.EncoderConfig
+// GetEncoderConfig get zapcore.EncoderConfig, with the keys and the
+// encoders of the Encoder configuration if set
 func (z *zapDef) GetEncoderConfig() zapcore.EncoderConfig {
-	return zapcore.EncoderConfig{
+	cfg := zapcore.EncoderConfig{
 		MessageKey:     "msg",
 		LevelKey:       "level",
 		TimeKey:        "ts",
@@ -90,21 +178,29 @@ func (z *zapDef) GetEncoderConfig() zapcore.EncoderConfig {
 		FunctionKey:    zapcore.OmitKey,
 		StacktraceKey:  z.c.StacktraceKey,
 		LineEnding:     zapcore.DefaultLineEnding,
-		EncodeLevel:    z.c.ZapEncodeLevel(),
+		EncodeLevel:    levelEncoder(z.c.ZapEncodeLevel()),
 		EncodeTime:     z.CustomTimeEncoder, // Log time
-		EncodeDuration: zapcore.SecondsDurationEncoder,
+		EncodeDuration: z.c.DurationEncoder(),
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
+	if z.c.Encoder != nil {
+		applyEncoder(&cfg, *z.c.Encoder)
+	}
+	return cfg
 }
 
 // GetEncoderCore get zapcore.Core
 func (z *zapDef) GetEncoderCore(l zapcore.Level, level zap.LevelEnablerFunc) zapcore.Core {
-	writer, err := GetWriter(z.c, l.String()) // use file-rotatelogs to split logs
+	writer, file, err := newWriter(z.c, l.String()) // use file-rotatelogs to split logs
 	if err != nil {
 		// Use proper error handling instead of fmt.Printf
 		// Consider using a fallback writer or returning error
 		return nil
 	}
+	z.files = append(z.files, file)
+	if z.c.sanitizing() {
+		writer = zapcore.AddSync(z.c.Sanitize.Writer(writer))
+	}
 	return zapcore.NewCore(z.GetEncoder(), writer, level)
 }
 
@@ -117,51 +213,119 @@ func (z *zapDef) CustomTimeEncoder(t time.Time, encoder zapcore.PrimitiveArrayEn
 	encoder.AppendString(prefix + t.Format("2006/01/02 15:04:05.000"))
 }
 
-// GetZapCores get []zapcore.Core
+// GetZapCores get []zapcore.Core, one per file level
 func (z *zapDef) GetZapCores() []zapcore.Core {
 	cores := make([]zapcore.Core, 0, 7)
-	for level := z.c.TransportLevel(); level <= zapcore.FatalLevel; level++ {
+	for level := zapcore.DebugLevel; level <= zapcore.FatalLevel; level++ {
 		cores = append(cores, z.GetEncoderCore(level, z.GetLevelPriority(level)))
 	}
 	return cores
 }
 
-// GetLevelPriority get zap.LevelEnablerFunc
+// GetLevelPriority get zap.LevelEnablerFunc, which enables the levels
+// written to the file of level (see fileLevel)
 func (z *zapDef) GetLevelPriority(level zapcore.Level) zap.LevelEnablerFunc {
-	switch level {
-	case zapcore.DebugLevel:
-		return func(level zapcore.Level) bool { // debug level
-			return level == zap.DebugLevel
-		}
-	case zapcore.InfoLevel:
-		return func(level zapcore.Level) bool { // info level
-			return level == zap.InfoLevel
-		}
-	case zapcore.WarnLevel:
-		return func(level zapcore.Level) bool { // warn level
-			return level == zap.WarnLevel
-		}
-	case zapcore.ErrorLevel:
-		return func(level zapcore.Level) bool { // error level
-			return level == zap.ErrorLevel
-		}
-	case zapcore.DPanicLevel:
-		return func(level zapcore.Level) bool { // dpanic level
-			return level == zap.DPanicLevel
-		}
-	case zapcore.PanicLevel:
-		return func(level zapcore.Level) bool { // panic level
-			return level == zap.PanicLevel
-		}
-	case zapcore.FatalLevel:
-		return func(level zapcore.Level) bool { // fatal level
-			return level == zap.FatalLevel
-		}
-	default:
-		return func(level zapcore.Level) bool { // debug level
-			return level == zap.DebugLevel
-		}
+	return func(l zapcore.Level) bool {
+		return fileLevel(l) == level
+	}
+}
+
+// Log logs a message at the given level with the key-value pairs carried by
+// ctx followed by the given key-value pairs. A level carried by ctx (see
+// core.WithLevel) lowers the configured level. Logging at core.PanicLevel
+// panics, and at core.FatalLevel or above exits.
+func (l *Logger) Log(ctx context.Context, level core.Level, msg string, keysAndValues ...any) {
+	l.LogCaller(ctx, core.CallerPC(0), level, msg, keysAndValues...)
+}
+
+// LogCaller implements the core.CallerLogger interface: it logs as Log
+// does, with the caller and the stack trace of the call site at pc.
+func (l *Logger) LogCaller(ctx context.Context, pc uintptr, level core.Level, msg string, keysAndValues ...any) {
+	keysAndValues = contextKeysAndValues(ctx, l.contextFields, keysAndValues)
+	if b := flight.FromContext(ctx); b != nil {
+		// first, so that a dangling key cannot take it as its value
+		keysAndValues = append([]any{bufferField(b)}, keysAndValues...)
+	}
+	logger := l.levelLogger(ctx, level)
+	// the values are only marshaled for the enabled entries
+	if needsArgs(keysAndValues) && logger.Desugar().Core().Enabled(zapLevel(level)) {
+		keysAndValues = zapArgs(keysAndValues)
+	}
+	logger.Logw(zapLevel(level), msg, append([]any{callerField(pc)}, keysAndValues...)...)
+}
+
+// LevelEnabled implements the core.CallerLogger interface.
+func (l *Logger) LevelEnabled(ctx context.Context, level core.Level) bool {
+	return l.levelLogger(ctx, level).Desugar().Core().Enabled(zapLevel(level))
+}
+
+// levelLogger returns the logger writing the entries at level logged with
+// ctx: the verbose logger if ctx carries a level at most level, below the
+// configured level.
+func (l *Logger) levelLogger(ctx context.Context, level core.Level) *zap.SugaredLogger {
+	if min, ok := core.LevelFrom(ctx); ok && level >= min && level < l.min && l.verbose != nil {
+		return l.verbose
+	}
+	return l.SugaredLogger
+}
+
+// Trace implements the Trace method of the Logger interface.
+func (l *Logger) Trace(args ...any) {
+	if l.LevelEnabled(context.Background(), core.TraceLevel) {
+		l.LogCaller(context.Background(), core.CallerPC(0), core.TraceLevel, fmt.Sprint(args...))
+	}
+}
+
+// Tracef implements the Tracef method of the Logger interface.
+func (l *Logger) Tracef(template string, args ...any) {
+	if l.LevelEnabled(context.Background(), core.TraceLevel) {
+		l.LogCaller(context.Background(), core.CallerPC(0), core.TraceLevel, fmt.Sprintf(template, args...))
+	}
+}
+
+// Notice implements the Notice method of the Logger interface.
+func (l *Logger) Notice(args ...any) {
+	if l.LevelEnabled(context.Background(), core.NoticeLevel) {
+		l.LogCaller(context.Background(), core.CallerPC(0), core.NoticeLevel, fmt.Sprint(args...))
+	}
+}
+
+// Noticef implements the Noticef method of the Logger interface.
+func (l *Logger) Noticef(template string, args ...any) {
+	if l.LevelEnabled(context.Background(), core.NoticeLevel) {
+		l.LogCaller(context.Background(), core.CallerPC(0), core.NoticeLevel, fmt.Sprintf(template, args...))
+	}
+}
+
+// Critical implements the Critical method of the Logger interface.
+func (l *Logger) Critical(args ...any) {
+	if l.LevelEnabled(context.Background(), core.CriticalLevel) {
+		l.LogCaller(context.Background(), core.CallerPC(0), core.CriticalLevel, fmt.Sprint(args...))
+	}
+}
+
+// Criticalf implements the Criticalf method of the Logger interface.
+func (l *Logger) Criticalf(template string, args ...any) {
+	if l.LevelEnabled(context.Background(), core.CriticalLevel) {
+		l.LogCaller(context.Background(), core.CallerPC(0), core.CriticalLevel, fmt.Sprintf(template, args...))
+	}
+}
+
+// contextKeysAndValues prepends the key-value pairs carried by ctx and those
+// returned by extract to keysAndValues.
+func contextKeysAndValues(ctx context.Context, extract func(context.Context) []any, keysAndValues []any) []any {
+	fields := core.Fields(ctx)
+	var extra []any
+	if extract != nil && ctx != nil {
+		extra = extract(ctx)
+	}
+	if len(fields) == 0 && len(extra) == 0 {
+		return keysAndValues
 	}
+	out := make([]any, 0, len(fields)+len(extra)+len(keysAndValues))
+	out = append(out, extra...)
+	out = append(out, fields...)
+	return append(out, keysAndValues...)
 }
 
 // NewContext add fields to the specified context
@@ -177,7 +341,7 @@ func (l *Logger) WithContext(ctx context.Context) *Logger {
 	zl := ctx.Value(LoggerKey)
 	ctxLogger, ok := zl.(*zap.SugaredLogger)
 	if ok {
-		return &Logger{ctxLogger}
+		return &Logger{SugaredLogger: ctxLogger, contextFields: l.contextFields, min: l.min, verbose: l.verbose, files: l.files}
 	}
 	return l
 }
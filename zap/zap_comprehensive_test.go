@@ -1,10 +1,15 @@
 package zap_test
 
 import (
+	"context"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/go4x/logx/core"
 	"github.com/go4x/logx/zap"
 )
 
@@ -394,3 +399,41 @@ func TestZapPerformance(t *testing.T) {
 
 	time.Sleep(2 * time.Second)
 }
+
+// TestZapWithContextLevel tests that the loggers returned by WithContext write the entries below the configured level logged with a context carrying a lower level
+func TestZapWithContextLevel(t *testing.T) {
+	logDir := t.TempDir()
+	logger, err := zap.NewLog(&zap.ZapConfig{
+		Level:    "info",
+		Format:   "json",
+		Director: logDir,
+	})
+	if err != nil {
+		t.Fatalf("expected logger to be created, but got error: %v", err)
+	}
+
+	ctx := logger.NewContext(context.Background(), "request_id", "42")
+	ctx = core.WithLevel(ctx, core.DebugLevel)
+	logger.WithContext(ctx).Log(ctx, core.DebugLevel, "context debug")
+	logger.WithContext(ctx).Log(context.Background(), core.DebugLevel, "hidden debug")
+	_ = logger.Sync()
+
+	var content strings.Builder
+	err = filepath.WalkDir(logDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		content.Write(data)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to read the logs: %v", err)
+	}
+	if !strings.Contains(content.String(), "context debug") {
+		t.Errorf("expected the debug entry logged with the context, got %q", content.String())
+	}
+	if strings.Contains(content.String(), "hidden debug") {
+		t.Errorf("expected the debug entry logged without the context to be dropped, got %q", content.String())
+	}
+}
@@ -2,8 +2,17 @@
 package zap
 
 import (
+	"context"
 	"strings"
 
+	"github.com/go4x/logx/core"
+	"github.com/go4x/logx/dedup"
+	"github.com/go4x/logx/encoder"
+	"github.com/go4x/logx/encrypt"
+	"github.com/go4x/logx/flight"
+	"github.com/go4x/logx/redact"
+	"github.com/go4x/logx/sample"
+	"github.com/go4x/logx/sanitize"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -25,7 +34,8 @@ const (
 
 // ZapConfig holds the configuration for the zap logger.
 type ZapConfig struct {
-	// Level specifies the minimum log level (debug, info, warn, error, fatal).
+	// Level specifies the minimum log level (trace, debug, info, notice,
+	// warn, error, critical, panic, fatal or a registered level name).
 	Level string `mapstructure:"level" yaml:"level"`
 	// Prefix specifies the log message prefix.
 	Prefix string `mapstructure:"prefix" yaml:"prefix"`
@@ -35,6 +45,16 @@ type ZapConfig struct {
 	Director string `mapstructure:"director" yaml:"director"`
 	// EncodeLevel specifies how to encode log levels.
 	EncodeLevel string `mapstructure:"encode-level" yaml:"encode-level"`
+	// DurationFormat specifies how to encode durations: seconds (default),
+	// millis, nanos or string.
+	DurationFormat string `mapstructure:"duration-format" yaml:"duration-format"`
+	// Encoder, if set, replaces the keys, the time format, the level
+	// encoding and the caller format of the entries, and the Prefix, the
+	// EncodeLevel and the StacktraceKey settings, as the slog backend does.
+	Encoder *encoder.Config `mapstructure:"encoder" yaml:"encoder"`
+	// StacktraceLevel, if set, adds the stack trace of the log site to the
+	// entries at or above this level, under StacktraceKey.
+	StacktraceLevel string `mapstructure:"stacktrace-level" yaml:"stacktrace-level"`
 	// StacktraceKey specifies the key for stacktrace in log output.
 	StacktraceKey string `mapstructure:"stacktrace-key" yaml:"stacktrace-key"`
 	// MaxAge specifies the maximum number of days to retain log files.
@@ -55,6 +75,29 @@ type ZapConfig struct {
 	BufferSize int `mapstructure:"buffer-size" yaml:"buffer-size"`
 	// FlushInterval specifies the interval in seconds to flush the buffer.
 	FlushInterval int `mapstructure:"flush-interval" yaml:"flush-interval"`
+	// ContextFields returns extra key-value pairs derived from the context
+	// passed to Log, such as trace correlation ids.
+	ContextFields func(ctx context.Context) []any `mapstructure:"-" yaml:"-"`
+	// Sinks receive every entry that passes the level filter, in addition
+	// to the console and file outputs.
+	Sinks []core.Sink `mapstructure:"-" yaml:"-"`
+	// Encryption, if set, encrypts the log files with AES-GCM.
+	Encryption *encrypt.Config `mapstructure:"encryption" yaml:"encryption"`
+	// Sanitize configures the escaping of control characters and the size
+	// limits of the text format, which are on by default.
+	Sanitize sanitize.Config `mapstructure:"sanitize" yaml:"sanitize"`
+	// Redactor, if set, scrubs sensitive data from the messages and fields
+	// of every entry before it is encoded.
+	Redactor *redact.Redactor `mapstructure:"-" yaml:"-"`
+	// Sampler, if set, samples and rate limits the entries written to all
+	// outputs, and reports the entries it drops in a summary entry.
+	Sampler *sample.Sampler `mapstructure:"-" yaml:"-"`
+	// Deduper, if set, suppresses the identical consecutive entries and
+	// writes a summary of the suppressed entries instead.
+	Deduper *dedup.Deduper `mapstructure:"-" yaml:"-"`
+	// Recorder, if set, records the entries below Level and writes them
+	// when an error is logged.
+	Recorder *flight.Recorder `mapstructure:"-" yaml:"-"`
 }
 
 // ZapEncodeLevel get zapcore.LevelEncoder
@@ -73,25 +116,31 @@ func (z *ZapConfig) ZapEncodeLevel() zapcore.LevelEncoder {
 	}
 }
 
-// TransportLevel get zapcore.Level
-func (z *ZapConfig) TransportLevel() zapcore.Level {
-	z.Level = strings.ToLower(z.Level)
-	switch z.Level {
-	case "debug":
-		return zapcore.DebugLevel
-	case "info":
-		return zapcore.InfoLevel
-	case "warn":
-		return zapcore.WarnLevel
-	case "error":
-		return zapcore.ErrorLevel
-	case "dpanic":
-		return zapcore.DPanicLevel
-	case "panic":
-		return zapcore.PanicLevel
-	case "fatal":
-		return zapcore.FatalLevel
+// DurationEncoder get zapcore.DurationEncoder
+func (z *ZapConfig) DurationEncoder() zapcore.DurationEncoder {
+	switch z.DurationFormat {
+	case "millis":
+		return zapcore.MillisDurationEncoder
+	case "nanos":
+		return zapcore.NanosDurationEncoder
+	case "string":
+		return zapcore.StringDurationEncoder
 	default:
-		return zapcore.DebugLevel
+		return zapcore.SecondsDurationEncoder
+	}
+}
+
+// MinLevel returns the core.Level of Level, which is debug if Level is
+// empty or invalid.
+func (z *ZapConfig) MinLevel() core.Level {
+	level, err := core.ParseLevel(z.Level)
+	if err != nil || strings.TrimSpace(z.Level) == "" {
+		return core.DebugLevel
 	}
+	return level
+}
+
+// TransportLevel get zapcore.Level
+func (z *ZapConfig) TransportLevel() zapcore.Level {
+	return zapLevel(z.MinLevel())
 }